@@ -10,8 +10,10 @@ import (
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
 	"wanikani-api/internal/api"
 	"wanikani-api/internal/config"
+	"wanikani-api/internal/domain"
 	"wanikani-api/internal/migrations"
 	"wanikani-api/internal/store/sqlite"
 	"wanikani-api/internal/sync"
@@ -29,6 +31,7 @@ func main() {
 
 	// Initialize structured logging
 	log := logger.Init(cfg.LogLevel)
+	logger.WithInstance(log, cfg.InstanceName)
 	log.Info("Starting WaniKani API application...")
 
 	log.WithFields(map[string]interface{}{
@@ -36,8 +39,16 @@ func main() {
 		"database_path": cfg.DatabasePath,
 		"sync_schedule": cfg.SyncSchedule,
 		"log_level":     cfg.LogLevel,
+		"timezone":      cfg.Timezone.String(),
+		"sync_enabled":  cfg.SyncEnabled,
 	}).Info("Configuration loaded")
 
+	if cfg.DBCreateDir {
+		if err := sqlite.EnsureDir(cfg.DatabasePath); err != nil {
+			log.WithError(err).Fatal("Failed to create database directory")
+		}
+	}
+
 	// Run database migrations
 	log.Info("Running database migrations...")
 	db, err := sql.Open("sqlite3", cfg.DatabasePath)
@@ -50,6 +61,11 @@ func main() {
 		log.WithError(err).Fatal("Failed to run database migrations")
 	}
 
+	if err := migrations.Verify(db); err != nil {
+		db.Close()
+		log.WithError(err).Fatal("Database schema verification failed")
+	}
+
 	version, err := migrations.Version(db)
 	if err != nil {
 		log.WithError(err).Warn("Failed to get migration version")
@@ -63,7 +79,12 @@ func main() {
 	}
 
 	// Initialize database store
-	store, err := sqlite.New(cfg.DatabasePath)
+	store, err := sqlite.New(cfg.DatabasePath,
+		sqlite.WithLocation(cfg.Timezone),
+		sqlite.WithCacheSize(cfg.DBCacheSizeKB),
+		sqlite.WithMmapSize(cfg.DBMmapSizeBytes),
+		sqlite.WithDataCompression(cfg.DBCompressData),
+	)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to initialize database")
 	}
@@ -74,23 +95,44 @@ func main() {
 	}()
 	log.Info("Database store initialized successfully")
 
-	// Initialize WaniKani API client
-	client := wanikani.NewClient(log)
-	client.SetAPIToken(cfg.WaniKaniAPIToken)
-	log.Info("WaniKani API client initialized")
-
-	// Initialize sync service
-	syncService := sync.NewService(client, store, log)
-	log.Info("Sync service initialized")
+	// Without syncing there is no need for a WaniKani client; the API serves stored data
+	var syncService domain.SyncService
+	var syncer *sync.Service
+	if cfg.SyncEnabled {
+		syncer = newSyncService(cfg, store, log)
+		syncService = syncer
+	} else {
+		log.Warn("Syncing disabled by SYNC_ENABLED=false, serving already-synced data only")
+	}
 
 	// Initialize API server
-	server := api.NewServer(store, syncService, cfg.APIPort, cfg.LocalAPIToken, log)
+	server := api.NewServer(store, syncService, cfg.APIPort, cfg.LocalAPIToken, log,
+		api.WithBasicAuth(cfg.AuthAllowBasic),
+		api.WithAdminGateWait(time.Duration(cfg.AdminGateWaitMS)*time.Millisecond),
+		api.WithHeavyEndpointConcurrency(cfg.MaxConcurrentHeavyRequests),
+		api.WithMaxResultRows(cfg.MaxResultRows),
+		api.WithSubjectCache(time.Duration(cfg.SubjectCacheRefreshSeconds)*time.Second),
+		api.WithProblemJSON(cfg.ProblemJSON),
+		api.WithMaxImportBytes(cfg.ImportMaxBytes),
+		api.WithHandlerTimeout(time.Duration(cfg.HandlerTimeoutSeconds)*time.Second),
+		api.WithInstanceName(cfg.InstanceName),
+		api.WithFeatures(cfg.Features),
+		api.WithPublicEndpoints(cfg.PublicEndpoints),
+		api.WithTLS(cfg.TLSCertFile, cfg.TLSKeyFile),
+		api.WithTrustedProxies(cfg.TrustedProxies),
+		api.WithRequestLogSampling(cfg.RequestLogSampleRate, time.Duration(cfg.RequestLogSlowMS)*time.Millisecond),
+		api.WithDailyReviewGoal(cfg.DailyReviewGoal),
+		api.WithContentSecurityPolicy(cfg.ContentSecurityPolicy),
+	)
 	log.WithField("port", cfg.APIPort).Info("API server initialized")
 
 	// Start API server in a goroutine
 	serverErrors := make(chan error, 1)
 	go func() {
-		log.WithField("port", cfg.APIPort).Info("API server listening")
+		log.WithFields(map[string]interface{}{
+			"port": cfg.APIPort,
+			"tls":  cfg.TLSCertFile != "",
+		}).Info("API server listening")
 		if err := server.Start(); err != nil {
 			serverErrors <- fmt.Errorf("API server error: %w", err)
 		}
@@ -117,6 +159,42 @@ func main() {
 			log.WithError(err).Error("Error during server shutdown")
 		}
 
+		// Let a sync that finished during shutdown deliver its webhook
+		if syncer != nil {
+			if err := syncer.WaitForWebhooks(ctx); err != nil {
+				log.WithError(err).Warn("Gave up waiting for sync webhook deliveries")
+			}
+		}
+
 		log.Info("Application shutdown complete")
 	}
 }
+
+// newSyncService creates the WaniKani client and the sync service that uses it
+func newSyncService(cfg *config.Config, store *sqlite.Store, log *logrus.Logger) *sync.Service {
+	client := wanikani.NewClient(log)
+	client.SetAPIToken(cfg.WaniKaniAPIToken)
+	client.SetRateLimitStore(store)
+	client.SetStrictParsing(cfg.WaniKaniStrictParsing)
+	if err := client.LoadRateLimitState(context.Background()); err != nil {
+		log.WithError(err).Warn("Failed to restore rate limit state")
+	}
+	log.Info("WaniKani API client initialized")
+
+	syncService := sync.NewService(client, store, log,
+		sync.WithStatisticsDedup(cfg.StatisticsDedup),
+		sync.WithStatisticsRetention(cfg.StatisticsRetentionRecent),
+		sync.WithStatisticsSync(cfg.SyncStatisticsEnabled),
+		sync.WithStatisticsSource(sync.StatisticsSource(cfg.StatisticsSource)),
+		sync.WithConditionalSync(cfg.SyncConditional),
+		sync.WithInitialSnapshot(cfg.InitialSnapshotEnabled),
+		sync.WithInitialReviewSyncDays(cfg.InitialReviewSyncDays),
+		sync.WithSyncOverlap(time.Duration(cfg.SyncOverlapSeconds)*time.Second),
+		sync.WithValidation(sync.ValidationMode(cfg.SyncValidation)),
+		sync.WithLevelUpWebhook(cfg.LevelUpWebhookURL),
+		sync.WithSyncWebhook(cfg.SyncWebhookURL),
+	)
+	log.Info("Sync service initialized")
+
+	return syncService
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"wanikani-api/internal/domain"
+)
+
+// SubjectCache keeps every subject in memory, keyed by ID, for joining assignments and
+// reviews with their subjects without re-reading the subjects table on each request.
+// The cache records the subjects' last sync time when it was built, so that Refresh can
+// tell whether a sync has happened since.
+type SubjectCache struct {
+	store domain.DataStore
+
+	mu       sync.RWMutex
+	subjects map[int64]*domain.Subject
+	syncedAt *time.Time // subjects' last sync time when the cache was built
+	built    bool
+}
+
+// NewSubjectCache creates an empty cache that is built on first use
+func NewSubjectCache(store domain.DataStore) *SubjectCache {
+	return &SubjectCache{store: store}
+}
+
+// Get returns the cached subjects, building the cache if it is empty. The returned map
+// is shared and must not be modified.
+func (c *SubjectCache) Get(ctx context.Context) (map[int64]*domain.Subject, error) {
+	c.mu.RLock()
+	if c.built {
+		subjects := c.subjects
+		c.mu.RUnlock()
+		return subjects, nil
+	}
+	c.mu.RUnlock()
+
+	if err := c.rebuild(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.subjects, nil
+}
+
+// Invalidate empties the cache so the next Get rebuilds it
+func (c *SubjectCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subjects = nil
+	c.syncedAt = nil
+	c.built = false
+}
+
+// Refresh rebuilds the cache if the subjects' last sync time differs from the one the
+// cache was built at, reporting whether it did. An empty cache is left to be built on use.
+func (c *SubjectCache) Refresh(ctx context.Context) (bool, error) {
+	lastSync, err := c.store.GetLastSyncTime(ctx, domain.DataTypeSubjects)
+	if err != nil {
+		return false, fmt.Errorf("failed to get subjects sync time: %w", err)
+	}
+
+	c.mu.RLock()
+	stale := c.built && !sameTime(c.syncedAt, lastSync)
+	c.mu.RUnlock()
+
+	if !stale {
+		return false, nil
+	}
+
+	if err := c.rebuild(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// rebuild reloads every subject from the store
+func (c *SubjectCache) rebuild(ctx context.Context) error {
+	// Read the sync time first: a sync finishing mid-rebuild then leaves the cache
+	// marked stale rather than marked fresh with old rows
+	syncedAt, err := c.store.GetLastSyncTime(ctx, domain.DataTypeSubjects)
+	if err != nil {
+		return fmt.Errorf("failed to get subjects sync time: %w", err)
+	}
+
+	subjects, err := c.store.GetSubjects(ctx, domain.SubjectFilters{})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve subjects: %w", err)
+	}
+
+	subjectMap := make(map[int64]*domain.Subject, len(subjects))
+	for i := range subjects {
+		subjectMap[subjects[i].ID] = &subjects[i]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subjects = subjectMap
+	c.syncedAt = syncedAt
+	c.built = true
+
+	return nil
+}
+
+// sameTime reports whether two optional timestamps are equal
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// cacheRefresher periodically refreshes a SubjectCache until stopped
+type cacheRefresher struct {
+	cache    *SubjectCache
+	interval time.Duration
+	logger   *logrus.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startCacheRefresher starts refreshing cache every interval in a background goroutine
+func startCacheRefresher(cache *SubjectCache, interval time.Duration, logger *logrus.Logger) *cacheRefresher {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &cacheRefresher{
+		cache:    cache,
+		interval: interval,
+		logger:   logger,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	go r.run(ctx)
+
+	return r
+}
+
+func (r *cacheRefresher) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rebuilt, err := r.cache.Refresh(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.logger.WithError(err).Warn("Failed to refresh subject cache")
+				}
+				continue
+			}
+			if rebuilt {
+				r.logger.Debug("Subject cache rebuilt after subjects sync")
+			}
+		}
+	}
+}
+
+// stop ends the refresher and waits for its goroutine to exit
+func (r *cacheRefresher) stop() {
+	r.cancel()
+	<-r.done
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wanikani-api/internal/domain"
+)
+
+func TestSubjectCacheRebuildsAfterSubjectsSync(t *testing.T) {
+	_, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	subject := func(id int64) domain.Subject {
+		return domain.Subject{ID: id, Object: "kanji", URL: "https://api.wanikani.com/v2/subjects/1", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}}
+	}
+	if err := store.UpsertSubjects(ctx, []domain.Subject{subject(1)}); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+	if err := store.SetLastSyncTime(ctx, domain.DataTypeSubjects, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to set sync time: %v", err)
+	}
+
+	cache := NewSubjectCache(store)
+	subjects, err := cache.Get(ctx)
+	if err != nil {
+		t.Fatalf("Failed to build cache: %v", err)
+	}
+	if len(subjects) != 1 {
+		t.Fatalf("Expected 1 cached subject, got %d", len(subjects))
+	}
+
+	// Nothing synced since the cache was built
+	if rebuilt, err := cache.Refresh(ctx); err != nil || rebuilt {
+		t.Errorf("Expected no rebuild without a new sync, got rebuilt=%v err=%v", rebuilt, err)
+	}
+
+	// An out-of-band sync adds a subject and moves the sync time
+	if err := store.UpsertSubjects(ctx, []domain.Subject{subject(2)}); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+	if err := store.SetLastSyncTime(ctx, domain.DataTypeSubjects, time.Now()); err != nil {
+		t.Fatalf("Failed to set sync time: %v", err)
+	}
+
+	subjects, _ = cache.Get(ctx)
+	if len(subjects) != 1 {
+		t.Errorf("Expected cache to serve stale subjects until refreshed, got %d", len(subjects))
+	}
+
+	rebuilt, err := cache.Refresh(ctx)
+	if err != nil {
+		t.Fatalf("Failed to refresh cache: %v", err)
+	}
+	if !rebuilt {
+		t.Error("Expected a changed sync time to trigger a rebuild")
+	}
+
+	subjects, _ = cache.Get(ctx)
+	if len(subjects) != 2 || subjects[2] == nil {
+		t.Errorf("Expected rebuilt cache to include the new subject, got %d subjects", len(subjects))
+	}
+}
+
+func TestCacheRefresherRunsInBackgroundAndStops(t *testing.T) {
+	_, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	cache := NewSubjectCache(store)
+	if _, err := cache.Get(ctx); err != nil {
+		t.Fatalf("Failed to build cache: %v", err)
+	}
+
+	refresher := startCacheRefresher(cache, 5*time.Millisecond, testLogger())
+
+	subject := domain.Subject{ID: 1, Object: "kanji", URL: "https://api.wanikani.com/v2/subjects/1", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}}
+	if err := store.UpsertSubjects(ctx, []domain.Subject{subject}); err != nil {
+		t.Fatalf("Failed to insert test subject: %v", err)
+	}
+	if err := store.SetLastSyncTime(ctx, domain.DataTypeSubjects, time.Now()); err != nil {
+		t.Fatalf("Failed to set sync time: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		subjects, err := cache.Get(ctx)
+		if err != nil {
+			t.Fatalf("Failed to read cache: %v", err)
+		}
+		if len(subjects) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected background refresher to rebuild the cache")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		refresher.stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected refresher to stop promptly")
+	}
+}
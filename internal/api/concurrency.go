@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// concurrencyRetryAfter is the Retry-After hint sent when a client has too many requests in flight
+const concurrencyRetryAfter = 1 * time.Second
+
+// ConcurrencyLimiter caps how many requests each client IP may have in flight at once.
+// It guards expensive endpoints whose queries would otherwise pile up on the single
+// SQLite connection.
+type ConcurrencyLimiter struct {
+	mu       sync.Mutex
+	max      int
+	inFlight map[string]int
+}
+
+// NewConcurrencyLimiter creates a limiter allowing max in-flight requests per client IP.
+// A max of zero or less disables the limit.
+func NewConcurrencyLimiter(max int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		max:      max,
+		inFlight: make(map[string]int),
+	}
+}
+
+// acquire reserves a slot for key, returning false if key is already at the limit
+func (l *ConcurrencyLimiter) acquire(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight[key] >= l.max {
+		return false
+	}
+	l.inFlight[key]++
+	return true
+}
+
+// release frees a slot reserved by acquire
+func (l *ConcurrencyLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight[key]--
+	if l.inFlight[key] <= 0 {
+		delete(l.inFlight, key)
+	}
+}
+
+// clientIP returns the IP address of the client that sent r. Forwarding headers are only
+// believed when the connection comes from one of trustedProxies: X-Forwarded-For is read
+// from the right, skipping trusted proxies, since the leftmost entries are whatever the
+// client sent; X-Real-IP is used when there is no X-Forwarded-For.
+func clientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	ip := remoteIP(r)
+	if !isTrustedProxy(ip, trustedProxies) {
+		return ip
+	}
+
+	var forwarded []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			forwarded = append(forwarded, strings.TrimSpace(hop))
+		}
+	}
+
+	if len(forwarded) == 0 {
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+			return realIP
+		}
+		return ip
+	}
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if net.ParseIP(forwarded[i]) == nil {
+			break // a malformed hop ends the chain we can vouch for
+		}
+		ip = forwarded[i]
+		if !isTrustedProxy(ip, trustedProxies) {
+			break
+		}
+	}
+	return ip
+}
+
+// remoteIP returns the IP address of the peer that opened the connection
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// isTrustedProxy reports whether ip lies in one of trustedProxies
+func isTrustedProxy(ip string, trustedProxies []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitConcurrency wraps an expensive endpoint so that a client exceeding its in-flight
+// limit gets 503 with a Retry-After header instead of queueing on the database
+func (h *Handler) limitConcurrency(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limiter := h.heavyLimiter
+		if limiter == nil || limiter.max <= 0 {
+			next(w, r)
+			return
+		}
+
+		ip := clientIP(r, h.trustedProxies)
+		if !limiter.acquire(ip) {
+			h.logger.WithField("client_ip", ip).Warn("Concurrent request limit reached")
+			w.Header().Set("Retry-After", strconv.Itoa(int(concurrencyRetryAfter.Seconds())))
+			h.writeError(w, http.StatusServiceUnavailable, ErrCodeTooManyConcurrent, "Too many concurrent requests", map[string]string{
+				"detail": "Wait for in-flight requests to finish before retrying",
+			})
+			return
+		}
+		defer limiter.release(ip)
+
+		next(w, r)
+	}
+}
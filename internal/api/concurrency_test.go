@@ -0,0 +1,144 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConcurrencyLimiterSaturation(t *testing.T) {
+	handler := NewHandler(NewService(&mockStore{}, &mockSyncService{}), testLogger())
+	handler.heavyLimiter = NewConcurrencyLimiter(1)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	limited := handler.limitConcurrency(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-unblock
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Hold the only slot for this client
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		limited(w, httptest.NewRequest("GET", "/api/reviews", nil))
+		done <- w.Code
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	limited(w, httptest.NewRequest("GET", "/api/reviews", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 while saturated, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header while saturated")
+	}
+
+	close(unblock)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("expected in-flight request to complete with 200, got %d", code)
+	}
+
+	// The slot is released once the request finishes
+	limiter := handler.heavyLimiter
+	if !limiter.acquire("192.0.2.1") {
+		t.Error("expected slot to be available after the request finished")
+	}
+}
+
+func TestConcurrencyLimiterIsPerClientAndHeavyEndpointsOnly(t *testing.T) {
+	server := NewServer(&mockStore{}, &mockSyncService{}, 8080, "", testLogger(), WithHeavyEndpointConcurrency(1))
+
+	// Saturate the default httptest client address
+	server.handler.heavyLimiter.acquire("192.0.2.1")
+
+	tests := []struct {
+		path       string
+		remoteAddr string
+		expected   int
+	}{
+		{path: "/api/reviews", remoteAddr: "192.0.2.1:1234", expected: http.StatusServiceUnavailable},
+		{path: "/api/assignments", remoteAddr: "192.0.2.1:1234", expected: http.StatusServiceUnavailable},
+		{path: "/api/reviews", remoteAddr: "198.51.100.7:1234", expected: http.StatusOK},
+		{path: "/api/subjects", remoteAddr: "192.0.2.1:1234", expected: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.RemoteAddr = tt.remoteAddr
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, req)
+
+		if w.Code != tt.expected {
+			t.Errorf("%s from %s: expected %d, got %d", tt.path, tt.remoteAddr, tt.expected, w.Code)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	_, proxyNet, _ := net.ParseCIDR("10.0.0.0/8")
+	trusted := []*net.IPNet{proxyNet}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{name: "direct connection", remoteAddr: "198.51.100.7:1234", expected: "198.51.100.7"},
+		{name: "spoofed forwarded for from untrusted peer", remoteAddr: "198.51.100.7:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.9"}, expected: "198.51.100.7"},
+		{name: "spoofed real ip from untrusted peer", remoteAddr: "198.51.100.7:1234", headers: map[string]string{"X-Real-IP": "203.0.113.9"}, expected: "198.51.100.7"},
+		{name: "trusted proxy forwarded for", remoteAddr: "10.0.0.2:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.9"}, expected: "203.0.113.9"},
+		{name: "trusted proxy real ip", remoteAddr: "10.0.0.2:1234", headers: map[string]string{"X-Real-IP": "203.0.113.9"}, expected: "203.0.113.9"},
+		{name: "chain of trusted proxies", remoteAddr: "10.0.0.2:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.9, 10.1.1.1"}, expected: "203.0.113.9"},
+		{name: "client-supplied entries left of the client", remoteAddr: "10.0.0.2:1234", headers: map[string]string{"X-Forwarded-For": "192.0.2.66, 203.0.113.9"}, expected: "203.0.113.9"},
+		{name: "malformed hop", remoteAddr: "10.0.0.2:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.9, not-an-ip"}, expected: "10.0.0.2"},
+		{name: "trusted proxy without headers", remoteAddr: "10.0.0.2:1234", expected: "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/reviews", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			if ip := clientIP(req, trusted); ip != tt.expected {
+				t.Errorf("expected client IP %s, got %s", tt.expected, ip)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimiterUsesForwardedClientIP(t *testing.T) {
+	_, proxyNet, _ := net.ParseCIDR("10.0.0.0/8")
+	server := NewServer(&mockStore{}, &mockSyncService{}, 8080, "", testLogger(),
+		WithHeavyEndpointConcurrency(1), WithTrustedProxies([]*net.IPNet{proxyNet}))
+
+	// Saturate one client behind the proxy
+	server.handler.heavyLimiter.acquire("203.0.113.9")
+
+	tests := []struct {
+		forwardedFor string
+		expected     int
+	}{
+		{forwardedFor: "203.0.113.9", expected: http.StatusServiceUnavailable},
+		{forwardedFor: "203.0.113.10", expected: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/reviews", nil)
+		req.RemoteAddr = "10.0.0.2:1234"
+		req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, req)
+
+		if w.Code != tt.expected {
+			t.Errorf("client %s behind proxy: expected %d, got %d", tt.forwardedFor, tt.expected, w.Code)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// defaultMaxImportBytes bounds the decompressed size of an import body (1 GiB)
+const defaultMaxImportBytes int64 = 1 << 30
+
+// errUnsupportedEncoding is returned by decodedBody for Content-Encodings other than gzip
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// decodedBody returns the request body with its Content-Encoding removed. Only gzip (and
+// identity) is supported; the caller closes the returned reader.
+func decodedBody(r *http.Request) (io.ReadCloser, error) {
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return r.Body, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		return zr, nil
+	default:
+		return nil, fmt.Errorf("%w %q", errUnsupportedEncoding, encoding)
+	}
+}
+
+// limitedBody reads at most limit bytes and records whether the body was longer. The
+// limit applies to the decompressed stream, so a small gzip body can't expand without bound.
+type limitedBody struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+	exceeded  bool
+}
+
+func newLimitedBody(r io.Reader, limit int64) *limitedBody {
+	return &limitedBody{r: r, limit: limit, remaining: limit}
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, fmt.Errorf("body exceeds %d bytes", l.limit)
+	}
+
+	if l.remaining <= 0 {
+		// Probe for one more byte to tell a body of exactly limit bytes from a longer one
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			l.exceeded = true
+			return 0, fmt.Errorf("body exceeds %d bytes", l.limit)
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
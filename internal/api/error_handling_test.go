@@ -3,13 +3,17 @@ package api
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"wanikani-api/internal/domain"
+	"wanikani-api/internal/wanikani"
 )
 
 // TestAuthenticationErrorHandling tests that authentication errors are properly handled
@@ -29,6 +33,47 @@ func TestAuthenticationErrorHandling(t *testing.T) {
 	}
 }
 
+// TestAuthErrorCodeConsistency tests that an auth failure reports the same error code
+// whether it is rejected by the middleware or surfaces from the service layer
+func TestAuthErrorCodeConsistency(t *testing.T) {
+	decodeCode := func(t *testing.T, w *httptest.ResponseRecorder) ErrorCode {
+		t.Helper()
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, got %d", w.Code)
+		}
+		var errResp ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+			t.Fatalf("failed to decode error response: %v", err)
+		}
+		return errResp.Error.Code
+	}
+
+	// Middleware rejection
+	middleware := AuthMiddleware("secret", false, testLogger())
+	protected := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be reached without a valid token")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/subjects", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	protected.ServeHTTP(w, req)
+	middlewareCode := decodeCode(t, w)
+
+	// Service-layer rejection
+	service := NewService(&errorMockStore{authError: true}, &mockSyncService{})
+	handler := NewHandler(service, testLogger())
+	w = httptest.NewRecorder()
+	handler.HandleGetSubjects(w, httptest.NewRequest(http.MethodGet, "/api/subjects", nil))
+	serviceCode := decodeCode(t, w)
+
+	if middlewareCode != ErrCodeUnauthorized {
+		t.Errorf("expected middleware code %s, got %s", ErrCodeUnauthorized, middlewareCode)
+	}
+	if serviceCode != middlewareCode {
+		t.Errorf("service auth code %s does not match middleware auth code %s", serviceCode, middlewareCode)
+	}
+}
+
 // TestNetworkErrorHandling tests that network errors are properly handled
 func TestNetworkErrorHandling(t *testing.T) {
 	store := &errorMockStore{networkError: true}
@@ -80,12 +125,173 @@ func TestInternalErrorHandling(t *testing.T) {
 	}
 }
 
+// TestTypedErrorMapping tests that typed client errors map to the correct HTTP status,
+// even when wrapped, and that error messages alone do not drive classification
+func TestTypedErrorMapping(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   ErrorCode
+	}{
+		{"auth error", &wanikani.AuthError{Message: "Invalid API token"}, http.StatusUnauthorized, ErrCodeUnauthorized},
+		{"wrapped auth error", fmt.Errorf("fetch subjects: %w", &wanikani.AuthError{Message: "API token not set"}), http.StatusUnauthorized, ErrCodeUnauthorized},
+		{"network error", &wanikani.NetworkError{Err: errors.New("dial tcp: connection refused")}, http.StatusServiceUnavailable, ErrCodeNetwork},
+		{"retries exhausted on network error", fmt.Errorf("max retries exceeded: %w", &wanikani.NetworkError{Err: errors.New("i/o timeout")}), http.StatusServiceUnavailable, ErrCodeNetwork},
+		{"deadline exceeded", fmt.Errorf("query failed: %w", context.DeadlineExceeded), http.StatusServiceUnavailable, ErrCodeNetwork},
+		{"server error", &wanikani.ServerError{StatusCode: 502, Body: "bad gateway"}, http.StatusServiceUnavailable, ErrCodeNetwork},
+		{"rate limit error", &wanikani.RateLimitError{RetryAfter: time.Minute}, http.StatusTooManyRequests, ErrCodeRateLimit},
+		{"untyped error mentioning connection", errors.New("database connection pool closed"), http.StatusInternalServerError, ErrCodeInternal},
+		{"untyped error mentioning rate limit", errors.New("rate limit table missing"), http.StatusInternalServerError, ErrCodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&errorMockStore{err: tt.err}, &mockSyncService{})
+			handler := NewHandler(service, testLogger())
+
+			w := httptest.NewRecorder()
+			handler.HandleGetSubjects(w, httptest.NewRequest(http.MethodGet, "/api/subjects", nil))
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			var errResp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+			if errResp.Error.Code != tt.expectedCode {
+				t.Errorf("expected code %s, got %s", tt.expectedCode, errResp.Error.Code)
+			}
+		})
+	}
+}
+
+// TestTriggerSyncUpstreamErrors tests that a sync failing because WaniKani is down is
+// reported as 502 UPSTREAM_ERROR, distinct from failures on our side, and that a sync
+// rejected because another one started meanwhile is reported as 409
+func TestTriggerSyncUpstreamErrors(t *testing.T) {
+	phaseFailure := func(err error) error {
+		// The shape SyncAll returns: phase error wrapping the client error
+		return fmt.Errorf("subjects sync failed: %w", fmt.Errorf("failed to fetch subjects: %w", err))
+	}
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   ErrorCode
+	}{
+		{"server error", phaseFailure(&wanikani.ServerError{StatusCode: 503, Body: "maintenance"}), http.StatusBadGateway, ErrCodeUpstream},
+		{"network error", phaseFailure(&wanikani.NetworkError{Err: errors.New("no such host")}), http.StatusBadGateway, ErrCodeUpstream},
+		{"timeout", phaseFailure(context.DeadlineExceeded), http.StatusBadGateway, ErrCodeUpstream},
+		{"auth error", phaseFailure(&wanikani.AuthError{Message: "Invalid API token"}), http.StatusUnauthorized, ErrCodeUnauthorized},
+		{"store error", fmt.Errorf("subjects sync failed: %w", errors.New("failed to store subjects: disk I/O error")), http.StatusInternalServerError, ErrCodeInternal},
+		{"sync started concurrently", domain.ErrSyncInProgress, http.StatusConflict, ErrCodeSyncInProgress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(NewService(&mockStore{}, &countingSyncService{err: tt.err}), testLogger())
+
+			w := httptest.NewRecorder()
+			handler.HandleTriggerSync(w, httptest.NewRequest(http.MethodPost, "/api/sync", nil))
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			var errResp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+			if errResp.Error.Code != tt.expectedCode {
+				t.Errorf("expected code %s, got %s", tt.expectedCode, errResp.Error.Code)
+			}
+		})
+	}
+}
+
+// partialSyncService returns the results SyncAll reports when a later phase fails
+type partialSyncService struct {
+	mockSyncService
+	results []domain.SyncResult
+	err     error
+}
+
+func (m *partialSyncService) SyncAll(ctx context.Context) ([]domain.SyncResult, error) {
+	return m.results, m.err
+}
+
+// TestTriggerSyncPartialResults tests that a sync failing after earlier phases succeeded
+// reports the completed results along with the failing phase
+func TestTriggerSyncPartialResults(t *testing.T) {
+	clientErr := &wanikani.ServerError{StatusCode: 503, Body: "maintenance"}
+	reviews := domain.SyncResult{DataType: domain.DataTypeReviews}
+	reviews.SetError(fmt.Errorf("failed to fetch reviews: %w", clientErr))
+
+	syncService := &partialSyncService{
+		results: []domain.SyncResult{
+			{DataType: domain.DataTypeSubjects, RecordsUpdated: 10, Success: true},
+			{DataType: domain.DataTypeLevelProgressions, RecordsUpdated: 1, Success: true},
+			{DataType: domain.DataTypeAssignments, RecordsUpdated: 5, Success: true},
+			reviews,
+		},
+		err: fmt.Errorf("reviews sync failed: %w", reviews.Err),
+	}
+	handler := NewHandler(NewService(&mockStore{}, syncService), testLogger())
+
+	w := httptest.NewRecorder()
+	handler.HandleTriggerSync(w, httptest.NewRequest(http.MethodPost, "/api/sync", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response SyncResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if !response.Partial {
+		t.Error("expected partial: true")
+	}
+	if len(response.Results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(response.Results))
+	}
+	if !response.Results[0].Success || response.Results[0].RecordsUpdated != 10 {
+		t.Errorf("expected the subjects result to be kept, got %+v", response.Results[0])
+	}
+	if response.Failure == nil {
+		t.Fatal("expected the failing phase to be described")
+	}
+	if response.Failure.DataType != domain.DataTypeReviews {
+		t.Errorf("expected failed data type reviews, got %s", response.Failure.DataType)
+	}
+	if response.Failure.Code != ErrCodeUpstream {
+		t.Errorf("expected failure code %s, got %s", ErrCodeUpstream, response.Failure.Code)
+	}
+	if response.Failure.Message != reviews.Error {
+		t.Errorf("expected failure message %q, got %q", reviews.Error, response.Failure.Message)
+	}
+
+	// A failure in the first phase stored nothing and is still reported as an error
+	syncService.results = syncService.results[3:]
+	w = httptest.NewRecorder()
+	handler.HandleTriggerSync(w, httptest.NewRequest(http.MethodPost, "/api/sync", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected status 502 when no phase succeeded, got %d", w.Code)
+	}
+}
+
 // errorMockStore is a mock store that returns specific error types
 type errorMockStore struct {
 	authError      bool
 	networkError   bool
 	rateLimitError bool
 	genericError   bool
+	err            error
 }
 
 func (m *errorMockStore) UpsertSubjects(ctx context.Context, subjects []domain.Subject) error {
@@ -96,6 +302,18 @@ func (m *errorMockStore) GetSubjects(ctx context.Context, filters domain.Subject
 	return nil, m.getError()
 }
 
+func (m *errorMockStore) StreamSubjects(ctx context.Context, filters domain.SubjectFilters, fn func(domain.Subject) error) error {
+	return m.getError()
+}
+
+func (m *errorMockStore) UpsertLevelProgressions(ctx context.Context, progressions []domain.LevelProgression) error {
+	return m.getError()
+}
+
+func (m *errorMockStore) GetLevelProgressions(ctx context.Context) ([]domain.LevelProgression, error) {
+	return nil, m.getError()
+}
+
 func (m *errorMockStore) UpsertAssignments(ctx context.Context, assignments []domain.Assignment) error {
 	return m.getError()
 }
@@ -104,6 +322,14 @@ func (m *errorMockStore) GetAssignments(ctx context.Context, filters domain.Assi
 	return nil, m.getError()
 }
 
+func (m *errorMockStore) GetAssignmentsPassedOn(ctx context.Context, date time.Time) ([]domain.Assignment, error) {
+	return nil, m.getError()
+}
+
+func (m *errorMockStore) GetAvailableLessons(ctx context.Context) ([]domain.Assignment, error) {
+	return nil, m.getError()
+}
+
 func (m *errorMockStore) UpsertReviews(ctx context.Context, reviews []domain.Review) error {
 	return m.getError()
 }
@@ -116,7 +342,7 @@ func (m *errorMockStore) InsertStatistics(ctx context.Context, stats domain.Stat
 	return m.getError()
 }
 
-func (m *errorMockStore) GetStatistics(ctx context.Context, dateRange *domain.DateRange) ([]domain.StatisticsSnapshot, error) {
+func (m *errorMockStore) GetStatistics(ctx context.Context, dateRange *domain.DateRange, opts domain.StatisticsOptions) ([]domain.StatisticsSnapshot, error) {
 	return nil, m.getError()
 }
 
@@ -124,6 +350,102 @@ func (m *errorMockStore) GetLatestStatistics(ctx context.Context) (*domain.Stati
 	return nil, m.getError()
 }
 
+func (m *errorMockStore) PruneStatistics(ctx context.Context, keepRecent int) (int, error) {
+	return 0, m.getError()
+}
+
+func (m *errorMockStore) GetReviewStreak(ctx context.Context) (int, int, time.Time, error) {
+	return 0, 0, time.Time{}, m.getError()
+}
+
+func (m *errorMockStore) GetReviewDateRange(ctx context.Context) (*time.Time, *time.Time, error) {
+	return nil, nil, m.getError()
+}
+
+func (m *errorMockStore) GetSubjectAccuracy(ctx context.Context) ([]domain.SubjectAccuracy, error) {
+	return nil, m.getError()
+}
+
+func (m *errorMockStore) GetReviewCountsByHour(ctx context.Context, filters domain.ReviewFilters) ([24]int, error) {
+	return [24]int{}, m.getError()
+}
+
+func (m *errorMockStore) GetReviewCountsByWeek(ctx context.Context, filters domain.ReviewFilters) (map[time.Time]int, error) {
+	return nil, m.getError()
+}
+
+func (m *errorMockStore) GetReviewCountsByDay(ctx context.Context, filters domain.ReviewFilters) (map[time.Time]int, error) {
+	return nil, m.getError()
+}
+
+func (m *errorMockStore) GetReviewCountToday(ctx context.Context) (time.Time, int, error) {
+	return time.Time{}, 0, m.getError()
+}
+
+func (m *errorMockStore) GetCurrentSRSGroupTotals(ctx context.Context) (map[string]map[string]int, error) {
+	return nil, m.getError()
+}
+
+func (m *errorMockStore) GetAssignmentCounts(ctx context.Context) (domain.AssignmentCounts, error) {
+	return domain.AssignmentCounts{}, m.getError()
+}
+
+func (m *errorMockStore) GetSubjectCountsByLevel(ctx context.Context) (map[int]map[string]int, error) {
+	return nil, m.getError()
+}
+
+func (m *errorMockStore) GetDistinctLevels(ctx context.Context) ([]int, error) {
+	return nil, m.getError()
+}
+
+func (m *errorMockStore) GetLastReviewPerSubject(ctx context.Context, subjectIDs []int64) (map[int64]time.Time, error) {
+	return nil, m.getError()
+}
+
+func (m *errorMockStore) GetSubjectsByIDs(ctx context.Context, ids []int64) ([]domain.Subject, error) {
+	return nil, m.getError()
+}
+
+func (m *errorMockStore) GetAssignmentsByIDs(ctx context.Context, ids []int64) ([]domain.Assignment, error) {
+	return nil, m.getError()
+}
+
+func (m *errorMockStore) GetLevelProgress(ctx context.Context, level int) (*domain.LevelProgress, error) {
+	return nil, m.getError()
+}
+
+func (m *errorMockStore) Export(ctx context.Context, w io.Writer) error {
+	return m.getError()
+}
+
+func (m *errorMockStore) Import(ctx context.Context, r io.Reader) (map[string]int, error) {
+	return nil, m.getError()
+}
+
+func (m *errorMockStore) CountSubjects(ctx context.Context) (int, error) {
+	return 0, m.getError()
+}
+
+func (m *errorMockStore) CountAssignments(ctx context.Context) (int, error) {
+	return 0, m.getError()
+}
+
+func (m *errorMockStore) CountReviews(ctx context.Context) (int, error) {
+	return 0, m.getError()
+}
+
+func (m *errorMockStore) CountAssignmentSnapshots(ctx context.Context) (int, error) {
+	return 0, m.getError()
+}
+
+func (m *errorMockStore) GetCurrentLevel(ctx context.Context) (int, error) {
+	return 0, m.getError()
+}
+
+func (m *errorMockStore) CheckIntegrity(ctx context.Context, repair bool) (*domain.IntegrityReport, error) {
+	return nil, m.getError()
+}
+
 func (m *errorMockStore) GetLastSyncTime(ctx context.Context, dataType domain.DataType) (*time.Time, error) {
 	return nil, m.getError()
 }
@@ -132,6 +454,10 @@ func (m *errorMockStore) SetLastSyncTime(ctx context.Context, dataType domain.Da
 	return m.getError()
 }
 
+func (m *errorMockStore) ClearSyncTimes(ctx context.Context, dataType *domain.DataType) (int, error) {
+	return 0, m.getError()
+}
+
 func (m *errorMockStore) BeginTx(ctx context.Context) (*sql.Tx, error) {
 	return nil, m.getError()
 }
@@ -149,14 +475,17 @@ func (m *errorMockStore) CalculateAssignmentSnapshot(ctx context.Context, date t
 }
 
 func (m *errorMockStore) getError() error {
+	if m.err != nil {
+		return m.err
+	}
 	if m.authError {
-		return errors.New("Invalid API token")
+		return &wanikani.AuthError{Message: "Invalid API token"}
 	}
 	if m.networkError {
-		return errors.New("network error: connection refused")
+		return &wanikani.NetworkError{Err: errors.New("connection refused")}
 	}
 	if m.rateLimitError {
-		return errors.New("rate limit exceeded")
+		return &wanikani.RateLimitError{RetryAfter: time.Minute}
 	}
 	if m.genericError {
 		return errors.New("database error")
@@ -0,0 +1,34 @@
+package api
+
+// ErrorCode is the machine-readable code returned in the "code" field of an error response
+type ErrorCode string
+
+const (
+	// ErrCodeValidation indicates that one or more request parameters were invalid
+	ErrCodeValidation ErrorCode = "VALIDATION_ERROR"
+	// ErrCodeUnauthorized indicates an authentication failure, whether the
+	// request's bearer token was rejected or the WaniKani API token was
+	ErrCodeUnauthorized ErrorCode = "UNAUTHORIZED"
+	// ErrCodeNotFound indicates that the requested resource does not exist
+	ErrCodeNotFound ErrorCode = "NOT_FOUND"
+	// ErrCodeNetwork indicates that the WaniKani API could not be reached
+	ErrCodeNetwork ErrorCode = "NETWORK_ERROR"
+	// ErrCodeUpstream indicates that a sync failed because the WaniKani API was down or unreachable
+	ErrCodeUpstream ErrorCode = "UPSTREAM_ERROR"
+	// ErrCodeRateLimit indicates that the WaniKani API rate limit was exceeded
+	ErrCodeRateLimit ErrorCode = "RATE_LIMIT_ERROR"
+	// ErrCodeSyncInProgress indicates that a sync was requested while another was running
+	ErrCodeSyncInProgress ErrorCode = "SYNC_IN_PROGRESS"
+	// ErrCodeSyncDisabled indicates that a sync was requested on a server with syncing disabled
+	ErrCodeSyncDisabled ErrorCode = "SYNC_DISABLED"
+	// ErrCodeMaintenance indicates that reads are paused while an admin operation runs
+	ErrCodeMaintenance ErrorCode = "MAINTENANCE_IN_PROGRESS"
+	// ErrCodeTooManyConcurrent indicates that the client has too many expensive requests in flight
+	ErrCodeTooManyConcurrent ErrorCode = "TOO_MANY_CONCURRENT_REQUESTS"
+	// ErrCodeTimeout indicates that a request took longer than the handler timeout
+	ErrCodeTimeout ErrorCode = "REQUEST_TIMEOUT"
+	// ErrCodePayloadTooLarge indicates that a request body exceeded its size limit
+	ErrCodePayloadTooLarge ErrorCode = "PAYLOAD_TOO_LARGE"
+	// ErrCodeInternal indicates an unexpected server-side failure
+	ErrCodeInternal ErrorCode = "INTERNAL_ERROR"
+)
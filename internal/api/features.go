@@ -0,0 +1,66 @@
+package api
+
+import "strings"
+
+// Names of the optional endpoints that can be turned off with the FEATURES setting
+const (
+	FeatureStreak          = "streak"            // GET /api/stats/streak
+	FeatureReviewHours     = "review-hours"      // GET /api/stats/review-hours
+	FeatureSRSTotals       = "srs-totals"        // GET /api/stats/srs-totals
+	FeatureSubjectsByLevel = "subjects-by-level" // GET /api/stats/subjects-by-level
+	FeatureWeeklyReviews   = "weekly-reviews"    // GET /api/reviews/weekly
+	FeatureLevels          = "levels"            // GET /api/meta/levels
+	FeatureProgress        = "progress"          // GET /api/stats/progress
+	FeatureToday           = "today"             // GET /api/stats/today
+	FeatureVelocity        = "velocity"          // GET /api/stats/velocity
+)
+
+// knownFeatures lists every feature name, for warning about typos in FEATURES
+var knownFeatures = []string{
+	FeatureStreak,
+	FeatureReviewHours,
+	FeatureSRSTotals,
+	FeatureSubjectsByLevel,
+	FeatureWeeklyReviews,
+	FeatureLevels,
+	FeatureProgress,
+	FeatureToday,
+	FeatureVelocity,
+}
+
+// featureSet is the set of enabled optional endpoints. A nil set enables every feature.
+type featureSet map[string]bool
+
+// newFeatureSet enables only the named features
+func newFeatureSet(names []string) featureSet {
+	set := make(featureSet, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(strings.ToLower(name)); name != "" {
+			set[name] = true
+		}
+	}
+	return set
+}
+
+// enabled reports whether the named feature's routes should be registered
+func (f featureSet) enabled(name string) bool {
+	return f == nil || f[name]
+}
+
+// unknown returns the enabled names that do not match any feature
+func (f featureSet) unknown() []string {
+	var names []string
+	for name := range f {
+		known := false
+		for _, feature := range knownFeatures {
+			if name == feature {
+				known = true
+				break
+			}
+		}
+		if !known {
+			names = append(names, name)
+		}
+	}
+	return names
+}
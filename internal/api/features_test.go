@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		features []string
+		expected map[string]int
+	}{
+		{
+			name:     "all enabled by default",
+			features: nil,
+			expected: map[string]int{
+				"/api/stats/streak":       http.StatusOK,
+				"/api/stats/review-hours": http.StatusOK,
+				"/api/subjects":           http.StatusOK,
+			},
+		},
+		{
+			name:     "only listed features",
+			features: []string{"review-hours", " Levels "},
+			expected: map[string]int{
+				"/api/stats/streak":       http.StatusNotFound,
+				"/api/stats/review-hours": http.StatusOK,
+				"/api/meta/levels":        http.StatusOK,
+				"/api/subjects":           http.StatusOK, // core endpoints are not flagged
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(&mockStore{}, &mockSyncService{}, 8080, "", testLogger(), WithFeatures(tt.features))
+
+			for path, status := range tt.expected {
+				w := httptest.NewRecorder()
+				server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+				if w.Code != status {
+					t.Errorf("GET %s: expected status %d, got %d", path, status, w.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"wanikani-api/internal/domain"
+)
+
+// subjectFields maps the field names accepted by ?fields= on subject responses to the
+// value each one projects. Besides the top-level subject fields, the commonly used data
+// fields can be requested directly so clients don't need the whole data object.
+var subjectFields = map[string]func(domain.Subject) interface{}{
+	"id":               func(s domain.Subject) interface{} { return s.ID },
+	"object":           func(s domain.Subject) interface{} { return s.Object },
+	"url":              func(s domain.Subject) interface{} { return s.URL },
+	"data_updated_at":  func(s domain.Subject) interface{} { return s.DataUpdatedAt },
+	"data":             func(s domain.Subject) interface{} { return s.Data },
+	"level":            func(s domain.Subject) interface{} { return s.Data.Level },
+	"characters":       func(s domain.Subject) interface{} { return s.Data.Characters },
+	"character_images": func(s domain.Subject) interface{} { return characterImages(s.Data) },
+	"meanings":         func(s domain.Subject) interface{} { return s.Data.Meanings },
+	"readings":         func(s domain.Subject) interface{} { return s.Data.Readings },
+	"primary_meaning":  func(s domain.Subject) interface{} { return primaryMeaning(s.Data) },
+}
+
+// characterImages returns the subject's character images, as an empty list rather than
+// null when it has none so clients can always iterate it
+func characterImages(data domain.SubjectData) []domain.CharacterImage {
+	if data.CharacterImages == nil {
+		return []domain.CharacterImage{}
+	}
+	return data.CharacterImages
+}
+
+// primaryMeaning returns the subject's primary meaning, or an empty string if it has none
+func primaryMeaning(data domain.SubjectData) string {
+	for _, m := range data.Meanings {
+		if m.Primary {
+			return m.Meaning
+		}
+	}
+	return ""
+}
+
+// parseSubjectFieldsParam parses the optional comma-separated fields query parameter, recording
+// a validation error for names not in subjectFields. It returns nil when the full object is wanted.
+func parseSubjectFieldsParam(r *http.Request, errs ValidationErrors) []string {
+	param := r.URL.Query().Get("fields")
+	if param == "" {
+		return nil
+	}
+
+	var fields []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := subjectFields[name]; !ok {
+			names := make([]string, 0, len(subjectFields))
+			for n := range subjectFields {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			errs.Add("fields", "Must be a comma-separated list of: "+strings.Join(names, ", "))
+			return nil
+		}
+		seen[name] = true
+		fields = append(fields, name)
+	}
+
+	if len(fields) == 0 {
+		errs.Add("fields", "Must name at least one field")
+	}
+	return fields
+}
+
+// projectSubjects reduces each subject to the requested fields
+func projectSubjects(subjects []domain.Subject, fields []string) []map[string]interface{} {
+	projected := make([]map[string]interface{}, len(subjects))
+	for i, s := range subjects {
+		projected[i] = projectSubject(s, fields)
+	}
+	return projected
+}
+
+// projectSubject reduces a subject to the requested fields
+func projectSubject(s domain.Subject, fields []string) map[string]interface{} {
+	p := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		p[name] = subjectFields[name](s)
+	}
+	return p
+}
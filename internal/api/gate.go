@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// gatePollInterval is how often a queued read retries the gate
+	gatePollInterval = 10 * time.Millisecond
+	// gateRetryAfter is the Retry-After hint sent with 503 responses while the gate is held
+	gateRetryAfter = 5 * time.Second
+)
+
+// AdminGate keeps readers from observing partially applied admin operations such as
+// backfills and purges. Admin operations hold it exclusively, while read endpoints
+// share it; uncontended reads only pay for a TryRLock.
+type AdminGate struct {
+	mu      sync.RWMutex
+	maxWait time.Duration
+}
+
+// NewAdminGate creates a gate where reads queue for up to maxWait while an admin
+// operation is running before being rejected. A zero maxWait rejects immediately.
+func NewAdminGate(maxWait time.Duration) *AdminGate {
+	return &AdminGate{maxWait: maxWait}
+}
+
+// Lock blocks new reads and waits for in-flight reads to finish before an admin operation
+func (g *AdminGate) Lock() {
+	g.mu.Lock()
+}
+
+// Unlock releases the gate after an admin operation
+func (g *AdminGate) Unlock() {
+	g.mu.Unlock()
+}
+
+// acquireRead takes a read hold on the gate, waiting up to maxWait or until the
+// request is cancelled. It returns false if the gate could not be acquired.
+func (g *AdminGate) acquireRead(r *http.Request) bool {
+	if g.mu.TryRLock() {
+		return true
+	}
+	if g.maxWait <= 0 {
+		return false
+	}
+
+	deadline := time.NewTimer(g.maxWait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(gatePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return false
+		case <-deadline.C:
+			return false
+		case <-ticker.C:
+			if g.mu.TryRLock() {
+				return true
+			}
+		}
+	}
+}
+
+// adminGateMiddleware rejects reads with 503 and a Retry-After header while an admin operation holds the gate
+func (h *Handler) adminGateMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.gate.acquireRead(r) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(gateRetryAfter.Seconds())))
+			h.writeError(w, http.StatusServiceUnavailable, ErrCodeMaintenance, "An admin operation is in progress", map[string]string{
+				"detail": "Data is temporarily unavailable. Please retry shortly",
+			})
+			return
+		}
+		defer h.gate.mu.RUnlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
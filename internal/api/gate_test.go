@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestAdminGateRejectsReadsDuringAdminOperation tests that reads get 503 with
+// Retry-After while the gate is held, and succeed again once it is released
+func TestAdminGateRejectsReadsDuringAdminOperation(t *testing.T) {
+	server := NewServer(&mockStore{}, &mockSyncService{}, 8080, "", testLogger())
+	gate := server.AdminGate()
+
+	gate.Lock()
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/subjects", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 during admin operation, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header on 503 response")
+	}
+
+	// Sync status stays available so clients can see what is running
+	w = httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/sync/status", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected sync status to remain available, got %d", w.Code)
+	}
+
+	gate.Unlock()
+
+	w = httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/subjects", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 after admin operation, got %d", w.Code)
+	}
+}
+
+// TestAdminGateQueuesReadsWithinWait tests that reads wait for a short admin
+// operation to finish when a wait is configured
+func TestAdminGateQueuesReadsWithinWait(t *testing.T) {
+	server := NewServer(&mockStore{}, &mockSyncService{}, 8080, "", testLogger(), WithAdminGateWait(2*time.Second))
+	gate := server.AdminGate()
+
+	gate.Lock()
+	time.AfterFunc(50*time.Millisecond, gate.Unlock)
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/subjects", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected queued read to succeed after admin operation, got %d", w.Code)
+	}
+}
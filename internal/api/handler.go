@@ -1,19 +1,39 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"runtime"
+	"runtime/debug"
 	"strconv"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"wanikani-api/internal/domain"
+	"wanikani-api/internal/wanikani"
 )
 
 // Handler handles HTTP requests
 type Handler struct {
 	service *Service
 	logger  *logrus.Logger
+	gate    *AdminGate
+
+	idempotency *idempotencyCache
+	// heavyLimiter caps per-client concurrency on expensive endpoints; nil disables it
+	heavyLimiter *ConcurrencyLimiter
+	// maxImportBytes bounds the decompressed size of an import body
+	maxImportBytes int64
+	// trustedProxies are the proxies whose forwarding headers identify the client
+	trustedProxies []*net.IPNet
+	// instanceName labels this instance in the health and version responses
+	instanceName string
 }
 
 // NewHandler creates a new HTTP handler
@@ -21,6 +41,10 @@ func NewHandler(service *Service, logger *logrus.Logger) *Handler {
 	return &Handler{
 		service: service,
 		logger:  logger,
+		gate:    NewAdminGate(0),
+
+		idempotency:    newIdempotencyCache(idempotencyKeyTTL),
+		maxImportBytes: defaultMaxImportBytes,
 	}
 }
 
@@ -31,16 +55,13 @@ type ErrorResponse struct {
 
 // ErrorDetail contains error information
 type ErrorDetail struct {
-	Code    string            `json:"code"`
+	Code    ErrorCode         `json:"code"`
 	Message string            `json:"message"`
 	Details map[string]string `json:"details,omitempty"`
 }
 
 // writeError writes an error response
-func (h *Handler) writeError(w http.ResponseWriter, code int, errorCode, message string, details map[string]string) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(code)
-
+func (h *Handler) writeError(w http.ResponseWriter, code int, errorCode ErrorCode, message string, details map[string]string) {
 	h.logger.WithFields(logrus.Fields{
 		"status_code": code,
 		"error_code":  errorCode,
@@ -48,68 +69,83 @@ func (h *Handler) writeError(w http.ResponseWriter, code int, errorCode, message
 		"details":     details,
 	}).Warn("API error response")
 
-	json.NewEncoder(w).Encode(ErrorResponse{
-		Error: ErrorDetail{
-			Code:    errorCode,
-			Message: message,
-			Details: details,
-		},
-	})
+	writeErrorResponse(w, code, errorCode, message, details)
 }
 
 // handleServiceError handles errors from the service layer and writes appropriate HTTP responses
 func (h *Handler) handleServiceError(w http.ResponseWriter, err error) {
-	// Check for specific error types by examining the error message
-	errMsg := err.Error()
-
-	// Authentication errors
-	if contains(errMsg, "Invalid API token") || contains(errMsg, "API token not set") {
-		h.writeError(w, http.StatusUnauthorized, "AUTH_ERROR", "Authentication failed", map[string]string{
+	var (
+		authErr      *wanikani.AuthError
+		networkErr   *wanikani.NetworkError
+		serverErr    *wanikani.ServerError
+		rateLimitErr *wanikani.RateLimitError
+	)
+
+	switch {
+	case errors.As(err, &authErr):
+		h.writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Authentication failed", map[string]string{
 			"detail": "Invalid or missing API token",
 		})
-		return
-	}
-
-	// Network errors
-	if contains(errMsg, "network error") || contains(errMsg, "connection") || contains(errMsg, "timeout") {
-		h.writeError(w, http.StatusServiceUnavailable, "NETWORK_ERROR", "Unable to connect to WaniKani API", map[string]string{
+	case errors.As(err, &networkErr), errors.Is(err, context.DeadlineExceeded):
+		h.writeError(w, http.StatusServiceUnavailable, ErrCodeNetwork, "Unable to connect to WaniKani API", map[string]string{
 			"detail": "Please check your network connection and try again",
 		})
-		return
-	}
-
-	// Rate limit errors
-	if contains(errMsg, "rate limit") {
-		h.writeError(w, http.StatusTooManyRequests, "RATE_LIMIT_ERROR", "Rate limit exceeded", map[string]string{
+	case errors.As(err, &serverErr):
+		h.writeError(w, http.StatusServiceUnavailable, ErrCodeNetwork, "WaniKani API is unavailable", map[string]string{
+			"detail": "WaniKani returned a server error. Please try again later",
+		})
+	case errors.As(err, &rateLimitErr):
+		h.writeError(w, http.StatusTooManyRequests, ErrCodeRateLimit, "Rate limit exceeded", map[string]string{
 			"detail": "Too many requests to WaniKani API. Please try again later",
 		})
-		return
+	default:
+		h.logger.WithError(err).Error("Unhandled service error")
+		h.writeError(w, http.StatusInternalServerError, ErrCodeInternal, "An internal error occurred", nil)
 	}
+}
 
-	// Default to internal server error
-	h.logger.WithError(err).Error("Unhandled service error")
-	h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "An internal error occurred", nil)
+// isUpstreamError reports whether err means the WaniKani API was down or unreachable, as
+// opposed to a failure on our side
+func isUpstreamError(err error) bool {
+	var (
+		networkErr *wanikani.NetworkError
+		serverErr  *wanikani.ServerError
+	)
+	return errors.As(err, &networkErr) || errors.As(err, &serverErr) || errors.Is(err, context.DeadlineExceeded)
 }
 
-// contains checks if a string contains a substring (case-insensitive)
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
-		(len(s) > 0 && len(substr) > 0 && stringContains(s, substr)))
+// jsonContentType is the Content-Type of every JSON response other than problem details
+const jsonContentType = "application/json; charset=utf-8"
+
+// writeJSON writes a JSON response, indented when the request asks for ?pretty=true
+func writeJSON(w http.ResponseWriter, r *http.Request, data interface{}) {
+	w.Header().Set("Content-Type", jsonContentType)
+	encoder := json.NewEncoder(w)
+	if pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty")); pretty {
+		encoder.SetIndent("", "  ")
+	}
+	encoder.Encode(data)
 }
 
-func stringContains(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
+// setTruncatedHeader tells the client that a list was cut to the maximum result size
+func setTruncatedHeader(w http.ResponseWriter, truncated bool) {
+	if truncated {
+		w.Header().Set("X-Result-Truncated", "true")
 	}
-	return false
 }
 
-// writeJSON writes a JSON response
-func writeJSON(w http.ResponseWriter, data interface{}) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	json.NewEncoder(w).Encode(data)
+// setDataSyncedAtHeader tells the client when the listed data type was last synced, so it
+// can judge how fresh the data is. The header is omitted if the type was never synced; a
+// failed lookup is logged rather than failing the request.
+func (h *Handler) setDataSyncedAtHeader(ctx context.Context, w http.ResponseWriter, dataType domain.DataType) {
+	syncedAt, err := h.service.GetLastSyncTime(ctx, dataType)
+	if err != nil {
+		h.logger.WithError(err).WithField("data_type", dataType).Warn("Failed to look up last sync time")
+		return
+	}
+	if syncedAt != nil {
+		w.Header().Set("X-Data-Synced-At", syncedAt.UTC().Format(time.RFC3339))
+	}
 }
 
 // HandleGetSubjects handles GET /api/subjects
@@ -119,49 +155,276 @@ func (h *Handler) HandleGetSubjects(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.WithField("endpoint", "GET /api/subjects").Debug("Handling request")
 
+	errs := ValidationErrors{}
+
 	// Parse type filter
 	if typeParam := r.URL.Query().Get("type"); typeParam != "" {
 		// Validate subject type
 		if typeParam != "radical" && typeParam != "kanji" && typeParam != "vocabulary" {
-			h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-				"type": "Must be one of: radical, kanji, vocabulary",
-			})
-			return
+			errs.Add("type", "Must be one of: radical, kanji, vocabulary")
+		} else {
+			filters.Type = typeParam
+		}
+	}
+
+	// Parse level filters
+	filters.Level = parseLevelParam(r, "level", errs)
+	filters.LevelMin = parseLevelParam(r, "level_min", errs)
+	filters.LevelMax = parseLevelParam(r, "level_max", errs)
+	if filters.LevelMin != nil && filters.LevelMax != nil && *filters.LevelMin > *filters.LevelMax {
+		errs.Add("level_min", "Must be less than or equal to level_max")
+	}
+
+	// Parse meaning search
+	if meaningParam := r.URL.Query().Get("meaning"); meaningParam != "" {
+		if domain.NormalizeMeaning(meaningParam) == "" {
+			errs.Add("meaning", "Must not be blank")
+		} else {
+			filters.Meaning = meaningParam
 		}
-		filters.Type = typeParam
 	}
 
-	// Parse level filter
-	if levelParam := r.URL.Query().Get("level"); levelParam != "" {
-		level, err := strconv.Atoi(levelParam)
+	// Parse include_raw flag
+	if includeRawParam := r.URL.Query().Get("include_raw"); includeRawParam != "" {
+		includeRaw, err := strconv.ParseBool(includeRawParam)
 		if err != nil {
-			h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-				"level": "Must be a valid integer",
-			})
-			return
+			errs.Add("include_raw", "Must be true or false")
+		} else {
+			filters.IncludeRaw = includeRaw
 		}
-		if level < 1 || level > 60 {
-			h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-				"level": "Must be between 1 and 60",
-			})
-			return
+	}
+
+	// Parse sort
+	if sortParam := r.URL.Query().Get("sort"); sortParam != "" {
+		sort := domain.SubjectSort(sortParam)
+		if sort != domain.SubjectSortID && sort != domain.SubjectSortPosition {
+			errs.Add("sort", "Must be one of: id, position")
+		} else {
+			filters.Sort = sort
+		}
+	}
+
+	// Parse sparse fieldset
+	fields := parseSubjectFieldsParam(r, errs)
+
+	// Parse stream flag
+	stream := false
+	if streamParam := r.URL.Query().Get("stream"); streamParam != "" {
+		parsed, err := strconv.ParseBool(streamParam)
+		if err != nil {
+			errs.Add("stream", "Must be true or false")
+		} else {
+			stream = parsed
 		}
-		filters.Level = &level
 	}
 
-	subjects, err := h.service.GetSubjects(ctx, filters)
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	if stream {
+		h.streamSubjects(w, r, filters, fields)
+		return
+	}
+
+	subjects, truncated, err := h.service.GetSubjects(ctx, filters)
 	if err != nil {
 		h.handleServiceError(w, err)
 		return
 	}
+	if subjects == nil {
+		// Encode an empty result as [] like the streamed response, not null
+		subjects = []domain.Subject{}
+	}
 
 	h.logger.WithFields(logrus.Fields{
 		"endpoint": "GET /api/subjects",
 		"count":    len(subjects),
 		"filters":  filters,
-	}).Info("Request completed successfully")
+	}).Debug("Request completed successfully")
+
+	setTruncatedHeader(w, truncated)
+	h.setDataSyncedAtHeader(ctx, w, domain.DataTypeSubjects)
+	if fields != nil {
+		writeJSON(w, r, projectSubjects(subjects, fields))
+		return
+	}
+	writeJSON(w, r, subjects)
+}
+
+// streamSubjects writes the subjects matching filters as a JSON array, encoding each one
+// as its row is read instead of loading them all first. The output matches the buffered
+// response, including pretty=true indentation, but whether the result was truncated is
+// only known at the end, so X-Result-Truncated is sent as a trailer.
+func (h *Handler) streamSubjects(w http.ResponseWriter, r *http.Request, filters domain.SubjectFilters, fields []string) {
+	ctx := r.Context()
+
+	h.setDataSyncedAtHeader(ctx, w, domain.DataTypeSubjects)
+
+	// Separators reproduce what json.Encoder writes for the whole array, with or without
+	// SetIndent("", "  ")
+	open, separator, closing := "[", ",", "]\n"
+	pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty"))
+	if pretty {
+		open, separator, closing = "[\n  ", ",\n  ", "\n]\n"
+	}
+
+	count := 0
+	truncated, err := h.service.StreamSubjects(ctx, filters, func(subject domain.Subject) error {
+		var item interface{} = subject
+		if fields != nil {
+			item = projectSubject(subject, fields)
+		}
+		var encoded []byte
+		var err error
+		if pretty {
+			encoded, err = json.MarshalIndent(item, "  ", "  ")
+		} else {
+			encoded, err = json.Marshal(item)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to encode subject %d: %w", subject.ID, err)
+		}
+
+		prefix := separator
+		if count == 0 {
+			w.Header().Set("Content-Type", jsonContentType)
+			w.Header().Set("Trailer", "X-Result-Truncated")
+			prefix = open
+		}
+		count++
+		_, err = w.Write(append([]byte(prefix), encoded...))
+		return err
+	})
+	if err != nil {
+		// Once streaming has started the status can no longer change; the client sees a truncated array
+		if count == 0 {
+			h.handleServiceError(w, err)
+			return
+		}
+		h.logger.WithError(err).Error("Subject stream failed after writing started")
+		return
+	}
+
+	if count == 0 {
+		w.Header().Set("Content-Type", jsonContentType)
+		w.Write([]byte("[]\n"))
+	} else {
+		w.Write([]byte(closing))
+	}
+	setTruncatedHeader(w, truncated)
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/subjects",
+		"count":    count,
+		"filters":  filters,
+		"stream":   true,
+	}).Debug("Request completed successfully")
+}
+
+// HandleSearchSubjects handles GET /api/subjects/search
+func (h *Handler) HandleSearchSubjects(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filters := domain.SubjectFilters{}
+
+	h.logger.WithField("endpoint", "GET /api/subjects/search").Debug("Handling request")
+
+	errs := ValidationErrors{}
+
+	query := r.URL.Query().Get("q")
+	if domain.NormalizeMeaning(query) == "" {
+		errs.Add("q", "Is required and must not be blank")
+	}
+
+	if typeParam := r.URL.Query().Get("type"); typeParam != "" {
+		if typeParam != "radical" && typeParam != "kanji" && typeParam != "vocabulary" {
+			errs.Add("type", "Must be one of: radical, kanji, vocabulary")
+		} else {
+			filters.Type = typeParam
+		}
+	}
+
+	filters.Level = parseLevelParam(r, "level", errs)
+	filters.LevelMin = parseLevelParam(r, "level_min", errs)
+	filters.LevelMax = parseLevelParam(r, "level_max", errs)
+	if filters.LevelMin != nil && filters.LevelMax != nil && *filters.LevelMin > *filters.LevelMax {
+		errs.Add("level_min", "Must be less than or equal to level_max")
+	}
+
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	results, truncated, err := h.service.SearchSubjects(ctx, query, filters)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/subjects/search",
+		"count":    len(results),
+	}).Debug("Request completed successfully")
+
+	setTruncatedHeader(w, truncated)
+	h.setDataSyncedAtHeader(ctx, w, domain.DataTypeSubjects)
+	writeJSON(w, r, results)
+}
+
+// HandleGetSubjectChanges handles GET /api/subjects/changes
+func (h *Handler) HandleGetSubjectChanges(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/subjects/changes").Debug("Handling request")
+
+	since, err := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+	if err != nil {
+		h.writeValidationErrors(w, ValidationErrors{"since": "Is required and must be an RFC 3339 timestamp"})
+		return
+	}
+
+	changes, truncated, err := h.service.GetSubjectChanges(ctx, since)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/subjects/changes",
+		"count":    len(changes.Subjects),
+	}).Debug("Request completed successfully")
+
+	setTruncatedHeader(w, truncated)
+	writeJSON(w, r, changes)
+}
+
+// parseLevelParam parses an optional level query parameter, recording a validation
+// error and returning nil if it is not an integer between 1 and 60
+func parseLevelParam(r *http.Request, name string, errs ValidationErrors) *int {
+	param := r.URL.Query().Get(name)
+	if param == "" {
+		return nil
+	}
+
+	return parseLevel(name, param, errs)
+}
+
+// parseLevel parses a level value, recording a validation error under name and
+// returning nil if it is not an integer between 1 and 60
+func parseLevel(name, param string, errs ValidationErrors) *int {
+	level, err := strconv.Atoi(param)
+	if err != nil {
+		errs.Add(name, "Must be a valid integer")
+		return nil
+	}
+	if level < 1 || level > 60 {
+		errs.Add(name, "Must be between 1 and 60")
+		return nil
+	}
 
-	writeJSON(w, subjects)
+	return &level
 }
 
 // HandleGetAssignments handles GET /api/assignments
@@ -171,26 +434,27 @@ func (h *Handler) HandleGetAssignments(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.WithField("endpoint", "GET /api/assignments").Debug("Handling request")
 
+	errs := ValidationErrors{}
+
 	// Parse srs_stage filter
 	if srsStageParam := r.URL.Query().Get("srs_stage"); srsStageParam != "" {
 		srsStage, err := strconv.Atoi(srsStageParam)
 		if err != nil {
-			h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-				"srs_stage": "Must be a valid integer",
-			})
-			return
+			errs.Add("srs_stage", "Must be a valid integer")
+		} else if srsStage < 0 || srsStage > 9 {
+			// WaniKani SRS stages range from 0 (initiate) to 9 (burned)
+			errs.Add("srs_stage", "Must be between 0 and 9")
+		} else {
+			filters.SRSStage = &srsStage
 		}
-		// WaniKani SRS stages range from 0 (initiate) to 9 (burned)
-		if srsStage < 0 || srsStage > 9 {
-			h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-				"srs_stage": "Must be between 0 and 9",
-			})
-			return
-		}
-		filters.SRSStage = &srsStage
 	}
 
-	assignments, err := h.service.GetAssignmentsWithSubjects(ctx, filters)
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	assignments, truncated, err := h.service.GetAssignmentsWithSubjects(ctx, filters)
 	if err != nil {
 		h.handleServiceError(w, err)
 		return
@@ -200,9 +464,49 @@ func (h *Handler) HandleGetAssignments(w http.ResponseWriter, r *http.Request) {
 		"endpoint": "GET /api/assignments",
 		"count":    len(assignments),
 		"filters":  filters,
-	}).Info("Request completed successfully")
+	}).Debug("Request completed successfully")
+
+	setTruncatedHeader(w, truncated)
+	h.setDataSyncedAtHeader(ctx, w, domain.DataTypeAssignments)
+	writeJSON(w, r, assignments)
+}
+
+// HandleGetPassedAssignments handles GET /api/assignments/passed
+func (h *Handler) HandleGetPassedAssignments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/assignments/passed").Debug("Handling request")
 
-	writeJSON(w, assignments)
+	errs := ValidationErrors{}
+	var date time.Time
+
+	dateParam := r.URL.Query().Get("date")
+	if dateParam == "" {
+		errs.Add("date", "Required, in YYYY-MM-DD format")
+	} else if parsed, err := time.Parse("2006-01-02", dateParam); err != nil {
+		errs.Add("date", "Must be in YYYY-MM-DD format")
+	} else {
+		date = parsed
+	}
+
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	assignments, err := h.service.GetAssignmentsPassedOn(ctx, date)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/assignments/passed",
+		"count":    len(assignments),
+		"date":     dateParam,
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, assignments)
 }
 
 // HandleGetReviews handles GET /api/reviews
@@ -212,39 +516,89 @@ func (h *Handler) HandleGetReviews(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.WithField("endpoint", "GET /api/reviews").Debug("Handling request")
 
+	errs := ValidationErrors{}
+
 	// Parse from date filter
 	if fromParam := r.URL.Query().Get("from"); fromParam != "" {
 		from, err := time.Parse("2006-01-02", fromParam)
 		if err != nil {
-			h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-				"from": "Must be in YYYY-MM-DD format",
-			})
-			return
+			errs.Add("from", "Must be in YYYY-MM-DD format")
+		} else {
+			filters.From = &from
 		}
-		filters.From = &from
 	}
 
 	// Parse to date filter
 	if toParam := r.URL.Query().Get("to"); toParam != "" {
 		to, err := time.Parse("2006-01-02", toParam)
 		if err != nil {
-			h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-				"to": "Must be in YYYY-MM-DD format",
-			})
-			return
+			errs.Add("to", "Must be in YYYY-MM-DD format")
+		} else {
+			filters.To = &to
 		}
-		filters.To = &to
 	}
 
 	// Validate date range
 	if filters.From != nil && filters.To != nil && filters.From.After(*filters.To) {
-		h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-			"from": "Must be before or equal to 'to' date",
-		})
+		errs.Add("from", "Must be before or equal to 'to' date")
+	}
+
+	// Parse accuracy filter; passed=false keeps only reviews with a mistake
+	if passedParam := r.URL.Query().Get("passed"); passedParam != "" {
+		passed, err := strconv.ParseBool(passedParam)
+		if err != nil {
+			errs.Add("passed", "Must be true or false")
+		} else {
+			filters.Passed = &passed
+		}
+	}
+
+	// Parse subject type filter
+	if typeParam := r.URL.Query().Get("subject_type"); typeParam != "" {
+		if typeParam != "radical" && typeParam != "kanji" && typeParam != "vocabulary" {
+			errs.Add("subject_type", "Must be one of: radical, kanji, vocabulary")
+		} else {
+			filters.SubjectType = typeParam
+		}
+	}
+
+	// Parse details flag; details=false skips the assignment and subject join
+	details := true
+	if detailsParam := r.URL.Query().Get("details"); detailsParam != "" {
+		parsed, err := strconv.ParseBool(detailsParam)
+		if err != nil {
+			errs.Add("details", "Must be true or false")
+		} else {
+			details = parsed
+		}
+	}
+
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	if !details {
+		reviews, truncated, err := h.service.GetReviews(ctx, filters)
+		if err != nil {
+			h.handleServiceError(w, err)
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"endpoint": "GET /api/reviews",
+			"count":    len(reviews),
+			"filters":  filters,
+			"details":  false,
+		}).Debug("Request completed successfully")
+
+		setTruncatedHeader(w, truncated)
+		h.setDataSyncedAtHeader(ctx, w, domain.DataTypeReviews)
+		writeJSON(w, r, reviews)
 		return
 	}
 
-	reviews, err := h.service.GetReviewsWithDetails(ctx, filters)
+	reviews, truncated, err := h.service.GetReviewsWithDetails(ctx, filters)
 	if err != nil {
 		h.handleServiceError(w, err)
 		return
@@ -254,9 +608,11 @@ func (h *Handler) HandleGetReviews(w http.ResponseWriter, r *http.Request) {
 		"endpoint": "GET /api/reviews",
 		"count":    len(reviews),
 		"filters":  filters,
-	}).Info("Request completed successfully")
+	}).Debug("Request completed successfully")
 
-	writeJSON(w, reviews)
+	setTruncatedHeader(w, truncated)
+	h.setDataSyncedAtHeader(ctx, w, domain.DataTypeReviews)
+	writeJSON(w, r, reviews)
 }
 
 // HandleGetLatestStatistics handles GET /api/statistics/latest
@@ -272,21 +628,27 @@ func (h *Handler) HandleGetLatestStatistics(w http.ResponseWriter, r *http.Reque
 	}
 
 	if snapshot == nil {
-		h.writeError(w, http.StatusNotFound, "NOT_FOUND", "No statistics found", nil)
+		h.writeError(w, http.StatusNotFound, ErrCodeNotFound, "No statistics found", nil)
 		return
 	}
 
-	h.logger.WithField("endpoint", "GET /api/statistics/latest").Info("Request completed successfully")
-	writeJSON(w, snapshot)
+	h.logger.WithField("endpoint", "GET /api/statistics/latest").Debug("Request completed successfully")
+	writeJSON(w, r, snapshot)
 }
 
+// maxStatisticsLimit bounds the limit query parameter on GET /api/statistics
+const maxStatisticsLimit = 1000
+
 // HandleGetStatistics handles GET /api/statistics
 func (h *Handler) HandleGetStatistics(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var dateRange *domain.DateRange
+	opts := domain.StatisticsOptions{}
 
 	h.logger.WithField("endpoint", "GET /api/statistics").Debug("Handling request")
 
+	errs := ValidationErrors{}
+
 	// Parse date range filters
 	fromParam := r.URL.Query().Get("from")
 	toParam := r.URL.Query().Get("to")
@@ -297,10 +659,7 @@ func (h *Handler) HandleGetStatistics(w http.ResponseWriter, r *http.Request) {
 		if fromParam != "" {
 			from, err := time.Parse("2006-01-02", fromParam)
 			if err != nil {
-				h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-					"from": "Must be in YYYY-MM-DD format",
-				})
-				return
+				errs.Add("from", "Must be in YYYY-MM-DD format")
 			}
 			dateRange.From = from
 		}
@@ -308,24 +667,45 @@ func (h *Handler) HandleGetStatistics(w http.ResponseWriter, r *http.Request) {
 		if toParam != "" {
 			to, err := time.Parse("2006-01-02", toParam)
 			if err != nil {
-				h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-					"to": "Must be in YYYY-MM-DD format",
-				})
-				return
+				errs.Add("to", "Must be in YYYY-MM-DD format")
 			}
 			dateRange.To = to
 		}
 
 		// Validate date range
-		if fromParam != "" && toParam != "" && dateRange.From.After(dateRange.To) {
-			h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-				"from": "Must be before or equal to 'to' date",
-			})
-			return
+		if !errs.HasErrors() && fromParam != "" && toParam != "" && dateRange.From.After(dateRange.To) {
+			errs.Add("from", "Must be before or equal to 'to' date")
 		}
 	}
 
-	snapshots, err := h.service.GetStatistics(ctx, dateRange)
+	// Parse limit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil {
+			errs.Add("limit", "Must be a valid integer")
+		} else if limit < 1 || limit > maxStatisticsLimit {
+			errs.Add("limit", fmt.Sprintf("Must be between 1 and %d", maxStatisticsLimit))
+		} else {
+			opts.Limit = limit
+		}
+	}
+
+	// Parse order
+	if orderParam := r.URL.Query().Get("order"); orderParam != "" {
+		order := domain.SortOrder(orderParam)
+		if order != domain.SortAsc && order != domain.SortDesc {
+			errs.Add("order", "Must be one of: asc, desc")
+		} else {
+			opts.Order = order
+		}
+	}
+
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	snapshots, err := h.service.GetStatistics(ctx, dateRange, opts)
 	if err != nil {
 		h.handleServiceError(w, err)
 		return
@@ -335,15 +715,57 @@ func (h *Handler) HandleGetStatistics(w http.ResponseWriter, r *http.Request) {
 		"endpoint":   "GET /api/statistics",
 		"count":      len(snapshots),
 		"date_range": dateRange,
-	}).Info("Request completed successfully")
+		"limit":      opts.Limit,
+		"order":      opts.Order,
+	}).Debug("Request completed successfully")
 
-	writeJSON(w, snapshots)
+	writeJSON(w, r, snapshots)
 }
 
 // SyncResponse represents the response from a sync operation
 type SyncResponse struct {
 	Message string              `json:"message"`
 	Results []domain.SyncResult `json:"results"`
+	// Partial is set when some data types synced before a later phase failed
+	Partial bool `json:"partial,omitempty"`
+	// Failure describes the phase that stopped a partial sync
+	Failure *SyncFailure `json:"failure,omitempty"`
+}
+
+// SyncFailure describes the sync phase that failed after earlier phases succeeded
+type SyncFailure struct {
+	DataType domain.DataType `json:"data_type"`
+	Code     ErrorCode       `json:"code"`
+	Message  string          `json:"message"`
+}
+
+// partialSyncFailure returns the failed phase of a sync that stored some data types
+// before failing, or nil if nothing succeeded. SyncAll stops at the first failing phase,
+// so it is the last result.
+func partialSyncFailure(results []domain.SyncResult, err error) *SyncFailure {
+	if len(results) < 2 || !results[0].Success {
+		return nil
+	}
+	failed := results[len(results)-1]
+	if failed.Success {
+		return nil
+	}
+
+	var (
+		authErr      *wanikani.AuthError
+		rateLimitErr *wanikani.RateLimitError
+	)
+	code := ErrCodeInternal
+	switch {
+	case isUpstreamError(err):
+		code = ErrCodeUpstream
+	case errors.As(err, &authErr):
+		code = ErrCodeUnauthorized
+	case errors.As(err, &rateLimitErr):
+		code = ErrCodeRateLimit
+	}
+
+	return &SyncFailure{DataType: failed.DataType, Code: code, Message: failed.Error}
 }
 
 // HandleTriggerSync handles POST /api/sync
@@ -352,10 +774,63 @@ func (h *Handler) HandleTriggerSync(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.WithField("endpoint", "POST /api/sync").Info("Manual sync triggered")
 
+	// A repeated Idempotency-Key replays the earlier result instead of syncing again
+	key := r.Header.Get(idempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		h.writeError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid Idempotency-Key header", map[string]string{
+			"header": idempotencyKeyHeader,
+			"detail": fmt.Sprintf("Must be at most %d characters", maxIdempotencyKeyLength),
+		})
+		return
+	}
+	if key != "" {
+		cached, inFlight := h.idempotency.begin(key)
+		if inFlight {
+			h.writeError(w, http.StatusConflict, ErrCodeSyncInProgress, "A sync operation is already in progress", nil)
+			return
+		}
+		if cached != nil {
+			h.logger.WithField("endpoint", "POST /api/sync").Info("Replaying sync result for repeated idempotency key")
+			w.Header().Set("Idempotent-Replayed", "true")
+			writeJSON(w, r, cached)
+			return
+		}
+	}
+
 	results, err := h.service.TriggerSync(ctx)
 	if err != nil {
-		if err.Error() == "sync already in progress" {
-			h.writeError(w, http.StatusConflict, "SYNC_IN_PROGRESS", "A sync operation is already in progress", nil)
+		if key != "" {
+			h.idempotency.release(key)
+		}
+		if errors.Is(err, domain.ErrSyncInProgress) {
+			h.writeError(w, http.StatusConflict, ErrCodeSyncInProgress, "A sync operation is already in progress", nil)
+			return
+		}
+		if errors.Is(err, errSyncDisabled) {
+			h.writeError(w, http.StatusConflict, ErrCodeSyncDisabled, "Syncing is disabled on this server", map[string]string{
+				"detail": "Set SYNC_ENABLED=true and WANIKANI_API_TOKEN to enable syncing",
+			})
+			return
+		}
+		// Report what was stored before a later phase failed rather than only the error
+		if failure := partialSyncFailure(results, err); failure != nil {
+			h.logger.WithFields(logrus.Fields{
+				"endpoint":  "POST /api/sync",
+				"data_type": failure.DataType,
+			}).WithError(err).Warn("Manual sync partially completed")
+			writeJSON(w, r, SyncResponse{
+				Message: "Sync partially completed",
+				Results: results,
+				Partial: true,
+				Failure: failure,
+			})
+			return
+		}
+		if isUpstreamError(err) {
+			h.logger.WithError(err).Warn("Sync failed because WaniKani is unavailable")
+			h.writeError(w, http.StatusBadGateway, ErrCodeUpstream, "WaniKani API was unreachable", map[string]string{
+				"detail": "The sync could not reach WaniKani or WaniKani returned a server error. Please try again later",
+			})
 			return
 		}
 		// Use the standard error handler for other errors
@@ -368,54 +843,113 @@ func (h *Handler) HandleTriggerSync(w http.ResponseWriter, r *http.Request) {
 		"results_count": len(results),
 	}).Info("Manual sync completed successfully")
 
-	writeJSON(w, SyncResponse{
+	response := SyncResponse{
 		Message: "Sync completed successfully",
 		Results: results,
-	})
+	}
+	if key != "" {
+		h.idempotency.complete(key, response)
+	}
+
+	writeJSON(w, r, response)
+}
+
+// HealthResponse represents the health check response
+type HealthResponse struct {
+	Status   string `json:"status"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// HandleHealth handles GET /api/health
+func (h *Handler) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, HealthResponse{Status: "ok", Instance: h.instanceName})
+}
+
+// VersionResponse describes the running build
+type VersionResponse struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	GoVersion string `json:"go_version"`
+	Instance  string `json:"instance,omitempty"`
+}
+
+// HandleVersion handles GET /api/version, reporting the module version and VCS revision
+// embedded in the binary by the Go toolchain
+func (h *Handler) HandleVersion(w http.ResponseWriter, r *http.Request) {
+	response := VersionResponse{Version: "unknown", GoVersion: runtime.Version(), Instance: h.instanceName}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		response.Version = info.Main.Version
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				response.Revision = setting.Value
+			}
+		}
+	}
+
+	writeJSON(w, r, response)
 }
 
 // SyncStatusResponse represents the sync status
 type SyncStatusResponse struct {
 	Syncing bool `json:"syncing"`
+	// StartedAt is when the sync in progress started; null when not syncing
+	StartedAt *time.Time `json:"started_at"`
 }
 
 // HandleGetSyncStatus handles GET /api/sync/status
 func (h *Handler) HandleGetSyncStatus(w http.ResponseWriter, r *http.Request) {
 	h.logger.WithField("endpoint", "GET /api/sync/status").Debug("Handling request")
 
-	syncing := h.service.GetSyncStatus()
+	startedAt := h.service.GetSyncStartedAt()
 
 	h.logger.WithFields(logrus.Fields{
 		"endpoint": "GET /api/sync/status",
-		"syncing":  syncing,
+		"syncing":  startedAt != nil,
 	}).Debug("Request completed successfully")
 
-	writeJSON(w, SyncStatusResponse{
-		Syncing: syncing,
+	writeJSON(w, r, SyncStatusResponse{
+		Syncing:   startedAt != nil,
+		StartedAt: startedAt,
 	})
 }
 
-// HandleGetAssignmentSnapshots handles GET /api/assignments/snapshots
-func (h *Handler) HandleGetAssignmentSnapshots(w http.ResponseWriter, r *http.Request) {
+// HandleGetSyncTimestamps handles GET /api/sync/timestamps
+func (h *Handler) HandleGetSyncTimestamps(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	var dateRange *domain.DateRange
-
-	h.logger.WithField("endpoint", "GET /api/assignments/snapshots").Debug("Handling request")
 
-	// Parse date range filters
-	fromParam := r.URL.Query().Get("from")
-	toParam := r.URL.Query().Get("to")
+	h.logger.WithField("endpoint", "GET /api/sync/timestamps").Debug("Handling request")
 
-	if fromParam != "" || toParam != "" {
+	timestamps, err := h.service.GetSyncTimestamps(ctx)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithField("endpoint", "GET /api/sync/timestamps").Debug("Request completed successfully")
+
+	writeJSON(w, r, timestamps)
+}
+
+// HandleGetAssignmentSnapshots handles GET /api/assignments/snapshots
+func (h *Handler) HandleGetAssignmentSnapshots(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var dateRange *domain.DateRange
+
+	h.logger.WithField("endpoint", "GET /api/assignments/snapshots").Debug("Handling request")
+
+	errs := ValidationErrors{}
+
+	// Parse date range filters
+	fromParam := r.URL.Query().Get("from")
+	toParam := r.URL.Query().Get("to")
+
+	if fromParam != "" || toParam != "" {
 		dateRange = &domain.DateRange{}
 
 		if fromParam != "" {
 			from, err := time.Parse("2006-01-02", fromParam)
 			if err != nil {
-				h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-					"from": "Must be in YYYY-MM-DD format",
-				})
-				return
+				errs.Add("from", "Must be in YYYY-MM-DD format")
 			}
 			dateRange.From = from
 		}
@@ -423,24 +957,37 @@ func (h *Handler) HandleGetAssignmentSnapshots(w http.ResponseWriter, r *http.Re
 		if toParam != "" {
 			to, err := time.Parse("2006-01-02", toParam)
 			if err != nil {
-				h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-					"to": "Must be in YYYY-MM-DD format",
-				})
-				return
+				errs.Add("to", "Must be in YYYY-MM-DD format")
 			}
 			dateRange.To = to
 		}
 
 		// Validate date range
-		if fromParam != "" && toParam != "" && dateRange.From.After(dateRange.To) {
-			h.writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid query parameters", map[string]string{
-				"from": "Must be before or equal to 'to' date",
-			})
-			return
+		if !errs.HasErrors() && fromParam != "" && toParam != "" && dateRange.From.After(dateRange.To) {
+			errs.Add("from", "Must be before or equal to 'to' date")
 		}
 	}
 
-	snapshots, err := h.service.GetAssignmentSnapshots(ctx, dateRange)
+	// Parse output format
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "map" && format != "ordered" {
+		errs.Add("format", "Must be one of: map, ordered")
+	}
+
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	var (
+		snapshots interface{}
+		err       error
+	)
+	if format == "ordered" {
+		snapshots, err = h.service.GetOrderedAssignmentSnapshots(ctx, dateRange)
+	} else {
+		snapshots, err = h.service.GetAssignmentSnapshots(ctx, dateRange)
+	}
 	if err != nil {
 		h.handleServiceError(w, err)
 		return
@@ -449,7 +996,707 @@ func (h *Handler) HandleGetAssignmentSnapshots(w http.ResponseWriter, r *http.Re
 	h.logger.WithFields(logrus.Fields{
 		"endpoint":   "GET /api/assignments/snapshots",
 		"date_range": dateRange,
-	}).Info("Request completed successfully")
+		"format":     format,
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, snapshots)
+}
+
+// HandleGetAssignmentSnapshotDiff handles GET /api/assignments/snapshots/diff
+func (h *Handler) HandleGetAssignmentSnapshotDiff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/assignments/snapshots/diff").Debug("Handling request")
+
+	errs := ValidationErrors{}
+	var from, to time.Time
+	var daily bool
+
+	fromParam := r.URL.Query().Get("from")
+	if fromParam == "" {
+		errs.Add("from", "Required, in YYYY-MM-DD format")
+	} else if parsed, err := time.Parse("2006-01-02", fromParam); err != nil {
+		errs.Add("from", "Must be in YYYY-MM-DD format")
+	} else {
+		from = parsed
+	}
+
+	toParam := r.URL.Query().Get("to")
+	if toParam == "" {
+		errs.Add("to", "Required, in YYYY-MM-DD format")
+	} else if parsed, err := time.Parse("2006-01-02", toParam); err != nil {
+		errs.Add("to", "Must be in YYYY-MM-DD format")
+	} else {
+		to = parsed
+	}
+
+	if !errs.HasErrors() && from.After(to) {
+		errs.Add("from", "Must be before or equal to 'to' date")
+	}
+
+	if dailyParam := r.URL.Query().Get("daily"); dailyParam != "" {
+		parsed, err := strconv.ParseBool(dailyParam)
+		if err != nil {
+			errs.Add("daily", "Must be true or false")
+		} else {
+			daily = parsed
+		}
+	}
+
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	diff, err := h.service.GetAssignmentSnapshotDiff(ctx, from, to, daily)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	if diff == nil {
+		h.writeError(w, http.StatusNotFound, ErrCodeNotFound, "No assignment snapshot found for the requested dates", map[string]string{
+			"detail": "Both 'from' and 'to' must have a recorded snapshot",
+		})
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/assignments/snapshots/diff",
+		"from":     diff.From,
+		"to":       diff.To,
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, diff)
+}
+
+// maxSeriesDays bounds the date range of /api/assignments/snapshots/series and
+// /api/stats/velocity, since every day in the range is returned even without data
+const maxSeriesDays = 3660
+
+// HandleGetAssignmentSnapshotSeries handles GET /api/assignments/snapshots/series
+func (h *Handler) HandleGetAssignmentSnapshotSeries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/assignments/snapshots/series").Debug("Handling request")
+
+	errs := ValidationErrors{}
+	var from, to time.Time
+
+	fromParam := r.URL.Query().Get("from")
+	if fromParam == "" {
+		errs.Add("from", "Required, in YYYY-MM-DD format")
+	} else if parsed, err := time.Parse("2006-01-02", fromParam); err != nil {
+		errs.Add("from", "Must be in YYYY-MM-DD format")
+	} else {
+		from = parsed
+	}
+
+	toParam := r.URL.Query().Get("to")
+	if toParam == "" {
+		errs.Add("to", "Required, in YYYY-MM-DD format")
+	} else if parsed, err := time.Parse("2006-01-02", toParam); err != nil {
+		errs.Add("to", "Must be in YYYY-MM-DD format")
+	} else {
+		to = parsed
+	}
+
+	if !errs.HasErrors() {
+		if from.After(to) {
+			errs.Add("from", "Must be before or equal to 'to' date")
+		} else if to.Sub(from) >= maxSeriesDays*24*time.Hour {
+			errs.Add("to", fmt.Sprintf("Range must span at most %d days", maxSeriesDays))
+		}
+	}
+
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	series, err := h.service.GetAssignmentSnapshotSeries(ctx, from, to)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/assignments/snapshots/series",
+		"from":     fromParam,
+		"to":       toParam,
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, series)
+}
+
+// HandleGetAvailableLessons handles GET /api/lessons/available
+func (h *Handler) HandleGetAvailableLessons(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/lessons/available").Debug("Handling request")
+
+	lessons, err := h.service.GetAvailableLessons(ctx)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/lessons/available",
+		"count":    len(lessons),
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, lessons)
+}
+
+// HandleGetTodayReviews handles GET /api/stats/today
+func (h *Handler) HandleGetTodayReviews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/stats/today").Debug("Handling request")
+
+	today, err := h.service.GetTodayReviews(ctx)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/stats/today",
+		"count":    today.Count,
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, today)
+}
+
+// HandleGetReviewStreak handles GET /api/stats/streak
+func (h *Handler) HandleGetReviewStreak(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/stats/streak").Debug("Handling request")
+
+	streak, err := h.service.GetReviewStreak(ctx)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint":       "GET /api/stats/streak",
+		"current_streak": streak.CurrentStreak,
+		"longest_streak": streak.LongestStreak,
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, streak)
+}
+
+// HandleGetSRSGroupTotals handles GET /api/stats/srs-totals
+func (h *Handler) HandleGetSRSGroupTotals(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/stats/srs-totals").Debug("Handling request")
+
+	totals, err := h.service.GetSRSGroupTotals(ctx)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithField("endpoint", "GET /api/stats/srs-totals").Debug("Request completed successfully")
+
+	writeJSON(w, r, totals)
+}
+
+// HandleGetProgressBreakdown handles GET /api/stats/progress
+func (h *Handler) HandleGetProgressBreakdown(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/stats/progress").Debug("Handling request")
+
+	breakdown, err := h.service.GetProgressBreakdown(ctx)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithField("endpoint", "GET /api/stats/progress").Debug("Request completed successfully")
+
+	writeJSON(w, r, breakdown)
+}
+
+// HandleGetSubjectCountsByLevel handles GET /api/stats/subjects-by-level
+func (h *Handler) HandleGetSubjectCountsByLevel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/stats/subjects-by-level").Debug("Handling request")
+
+	counts, err := h.service.GetSubjectCountsByLevel(ctx)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/stats/subjects-by-level",
+		"levels":   len(counts),
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, counts)
+}
+
+// HandleGetReviewHours handles GET /api/stats/review-hours
+func (h *Handler) HandleGetReviewHours(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filters := domain.ReviewFilters{}
+
+	h.logger.WithField("endpoint", "GET /api/stats/review-hours").Debug("Handling request")
+
+	errs := ValidationErrors{}
+	parseReviewDateRange(r, &filters, errs)
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	hours, err := h.service.GetReviewHours(ctx, filters)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/stats/review-hours",
+		"total":    hours.Total,
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, hours)
+}
+
+// HandleGetWeeklyReviews handles GET /api/reviews/weekly
+func (h *Handler) HandleGetWeeklyReviews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filters := domain.ReviewFilters{}
+
+	h.logger.WithField("endpoint", "GET /api/reviews/weekly").Debug("Handling request")
+
+	errs := ValidationErrors{}
+	parseReviewDateRange(r, &filters, errs)
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	weekly, err := h.service.GetWeeklyReviewCounts(ctx, filters)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/reviews/weekly",
+		"weeks":    len(weekly.Weeks),
+		"total":    weekly.Total,
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, weekly)
+}
+
+// HandleGetCriticalItems handles GET /api/reviews/critical
+func (h *Handler) HandleGetCriticalItems(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	threshold := 75
+
+	h.logger.WithField("endpoint", "GET /api/reviews/critical").Debug("Handling request")
+
+	if thresholdParam := r.URL.Query().Get("threshold"); thresholdParam != "" {
+		parsed, err := strconv.Atoi(thresholdParam)
+		if err != nil {
+			h.writeValidationErrors(w, ValidationErrors{"threshold": "Must be a valid integer"})
+			return
+		}
+		if parsed < 0 || parsed > 100 {
+			h.writeValidationErrors(w, ValidationErrors{"threshold": "Must be between 0 and 100"})
+			return
+		}
+		threshold = parsed
+	}
+
+	items, err := h.service.GetCriticalItems(ctx, threshold)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint":  "GET /api/reviews/critical",
+		"threshold": threshold,
+		"count":     len(items),
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, items)
+}
+
+// HandleGetReviewVelocity handles GET /api/stats/velocity
+func (h *Handler) HandleGetReviewVelocity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filters := domain.ReviewFilters{}
+	window := 7
+
+	h.logger.WithField("endpoint", "GET /api/stats/velocity").Debug("Handling request")
+
+	errs := ValidationErrors{}
+	if windowParam := r.URL.Query().Get("window"); windowParam != "" {
+		parsed, err := strconv.Atoi(windowParam)
+		if err != nil {
+			errs.Add("window", "Must be a valid integer")
+		} else if parsed < 1 || parsed > 30 {
+			errs.Add("window", "Must be between 1 and 30")
+		} else {
+			window = parsed
+		}
+	}
+
+	parseReviewDateRange(r, &filters, errs)
+	if filters.From != nil && filters.To != nil && filters.To.Sub(*filters.From) >= maxSeriesDays*24*time.Hour {
+		errs.Add("to", fmt.Sprintf("Range must span at most %d days", maxSeriesDays))
+	}
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	velocity, err := h.service.GetReviewVelocity(ctx, filters, window)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/stats/velocity",
+		"window":   window,
+		"days":     len(velocity.Days),
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, velocity)
+}
+
+// parseReviewDateRange parses the from and to calendar-day parameters of a review
+// aggregate endpoint into filters, recording problems in errs
+func parseReviewDateRange(r *http.Request, filters *domain.ReviewFilters, errs ValidationErrors) {
+	if fromParam := r.URL.Query().Get("from"); fromParam != "" {
+		from, err := time.Parse("2006-01-02", fromParam)
+		if err != nil {
+			errs.Add("from", "Must be in YYYY-MM-DD format")
+		} else {
+			filters.From = &from
+		}
+	}
+
+	if toParam := r.URL.Query().Get("to"); toParam != "" {
+		to, err := time.Parse("2006-01-02", toParam)
+		if err != nil {
+			errs.Add("to", "Must be in YYYY-MM-DD format")
+		} else {
+			filters.To = &to
+		}
+	}
+
+	if filters.From != nil && filters.To != nil && filters.From.After(*filters.To) {
+		errs.Add("from", "Must be before or equal to 'to' date")
+	}
+}
+
+// availableReviewsMaxAge is how long clients may cache the available review count.
+// The count only changes with the clock or a sync, so polling more often is wasted.
+const availableReviewsMaxAge = 30 * time.Second
+
+// HandleGetAvailableReviewCount handles GET /api/reviews/available/count
+func (h *Handler) HandleGetAvailableReviewCount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/reviews/available/count").Debug("Handling request")
+
+	count, err := h.service.GetAvailableReviewCount(ctx)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/reviews/available/count",
+		"count":    count.Count,
+	}).Debug("Request completed successfully")
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(availableReviewsMaxAge.Seconds())))
+	writeJSON(w, r, count)
+}
+
+// HandleGetSRSStages handles GET /api/meta/srs-stages
+func (h *Handler) HandleGetSRSStages(w http.ResponseWriter, r *http.Request) {
+	h.logger.WithField("endpoint", "GET /api/meta/srs-stages").Debug("Handling request")
+
+	writeJSON(w, r, h.service.GetSRSStageMetadata())
+}
+
+// HandleGetLevels handles GET /api/meta/levels
+func (h *Handler) HandleGetLevels(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/meta/levels").Debug("Handling request")
+
+	metadata, err := h.service.GetLevelsMetadata(ctx)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/meta/levels",
+		"levels":   len(metadata.Levels),
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, metadata)
+}
+
+// HandleGetReviewDateRange handles GET /api/meta/review-range
+func (h *Handler) HandleGetReviewDateRange(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/meta/review-range").Debug("Handling request")
+
+	dateRange, err := h.service.GetReviewDateRange(ctx)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithField("endpoint", "GET /api/meta/review-range").Debug("Request completed successfully")
+
+	writeJSON(w, r, dateRange)
+}
+
+// HandleGetLevelProgress handles GET /api/levels/{level}/progress
+func (h *Handler) HandleGetLevelProgress(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/levels/{level}/progress").Debug("Handling request")
+
+	errs := ValidationErrors{}
+	level := parseLevel("level", mux.Vars(r)["level"], errs)
+	if errs.HasErrors() {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	progress, err := h.service.GetLevelProgress(ctx, *level)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/levels/{level}/progress",
+		"level":    *level,
+		"subjects": progress.Total.Total,
+	}).Debug("Request completed successfully")
+
+	writeJSON(w, r, progress)
+}
+
+// countingWriter records whether anything has been written through it
+type countingWriter struct {
+	w       io.Writer
+	written int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.written += int64(n)
+	return n, err
+}
+
+// HandleExportData handles GET /api/admin/export
+func (h *Handler) HandleExportData(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/admin/export").Info("Export requested")
+
+	w.Header().Set("Content-Type", jsonContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="wanikani-backup-%s.json"`, time.Now().UTC().Format("20060102")))
+
+	out := &countingWriter{w: w}
+	if err := h.service.ExportData(ctx, out); err != nil {
+		// Once streaming has started the status can no longer change; the client sees a truncated document
+		if out.written == 0 {
+			h.handleServiceError(w, err)
+			return
+		}
+		h.logger.WithError(err).Error("Export failed after streaming started")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "GET /api/admin/export",
+		"bytes":    out.written,
+	}).Info("Export completed successfully")
+}
+
+// ResetSyncTimesResponse represents the response from clearing sync timestamps
+type ResetSyncTimesResponse struct {
+	Message string `json:"message"`
+	Cleared int    `json:"cleared"`
+}
+
+// HandleResetSyncTimes handles POST /api/admin/sync/reset-timestamps
+func (h *Handler) HandleResetSyncTimes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "POST /api/admin/sync/reset-timestamps").Info("Sync timestamp reset requested")
+
+	var dataType *domain.DataType
+	if typeParam := r.URL.Query().Get("type"); typeParam != "" {
+		for _, known := range domain.DataTypes {
+			if typeParam == string(known) {
+				dataType = &known
+				break
+			}
+		}
+		if dataType == nil {
+			h.writeValidationErrors(w, ValidationErrors{"type": "Must be one of: subjects, level_progressions, assignments, reviews, statistics"})
+			return
+		}
+	}
+
+	// A running sync would record its own timestamps after they were cleared
+	if h.service.GetSyncStatus() {
+		h.writeError(w, http.StatusConflict, ErrCodeSyncInProgress, "A sync operation is already in progress", nil)
+		return
+	}
+
+	cleared, err := h.service.ResetSyncTimes(ctx, dataType)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "POST /api/admin/sync/reset-timestamps",
+		"cleared":  cleared,
+	}).Info("Sync timestamps cleared, next sync will fetch everything")
+
+	writeJSON(w, r, ResetSyncTimesResponse{
+		Message: "Sync timestamps cleared",
+		Cleared: cleared,
+	})
+}
+
+// ImportResponse represents the response from a backup import
+type ImportResponse struct {
+	Message string         `json:"message"`
+	Counts  map[string]int `json:"counts"`
+}
+
+// HandleImportData handles POST /api/admin/import
+func (h *Handler) HandleImportData(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "POST /api/admin/import").Info("Import requested")
+
+	if h.service.GetSyncStatus() {
+		h.writeError(w, http.StatusConflict, ErrCodeSyncInProgress, "A sync operation is already in progress", nil)
+		return
+	}
+
+	body, err := decodedBody(r)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errUnsupportedEncoding) {
+			status = http.StatusUnsupportedMediaType
+		}
+		h.writeError(w, status, ErrCodeValidation, "Invalid request body", map[string]string{
+			"Content-Encoding": err.Error(),
+		})
+		return
+	}
+	defer body.Close()
+	limited := newLimitedBody(body, h.maxImportBytes)
+
+	// Importing replaces everything, so pause reads until it has been committed or rolled back
+	h.gate.Lock()
+	counts, err := h.service.ImportData(ctx, limited)
+	h.gate.Unlock()
+
+	if err != nil {
+		if limited.exceeded {
+			h.writeError(w, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, "Backup document is too large", map[string]string{
+				"max_bytes": strconv.FormatInt(h.maxImportBytes, 10),
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidBackup) {
+			h.writeError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid backup document", map[string]string{
+				"detail": err.Error(),
+			})
+			return
+		}
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"endpoint": "POST /api/admin/import",
+		"counts":   counts,
+	}).Info("Import completed successfully")
+
+	writeJSON(w, r, ImportResponse{
+		Message: "Import completed successfully",
+		Counts:  counts,
+	})
+}
+
+// HandleCheckIntegrity handles GET /api/admin/integrity
+func (h *Handler) HandleCheckIntegrity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	h.logger.WithField("endpoint", "GET /api/admin/integrity").Debug("Handling request")
+
+	repair := false
+	if repairParam := r.URL.Query().Get("repair"); repairParam != "" {
+		parsed, err := strconv.ParseBool(repairParam)
+		if err != nil {
+			h.writeValidationErrors(w, ValidationErrors{"repair": "Must be true or false"})
+			return
+		}
+		repair = parsed
+	}
+
+	if repair {
+		// A sync could re-insert rows while orphans are being removed
+		if h.service.GetSyncStatus() {
+			h.writeError(w, http.StatusConflict, ErrCodeSyncInProgress, "A sync operation is already in progress", nil)
+			return
+		}
+		h.gate.Lock()
+		defer h.gate.Unlock()
+	}
+
+	report, err := h.service.CheckIntegrity(ctx, repair)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	fields := logrus.Fields{
+		"endpoint":             "GET /api/admin/integrity",
+		"orphaned_assignments": report.OrphanedAssignments.Count,
+		"orphaned_reviews":     report.OrphanedReviews.Count,
+		"repaired":             report.Repaired,
+	}
+	if report.OrphanedAssignments.Count > 0 || report.OrphanedReviews.Count > 0 {
+		h.logger.WithFields(fields).Warn("Integrity check found orphaned records")
+	} else {
+		h.logger.WithFields(fields).Info("Integrity check completed successfully")
+	}
 
-	writeJSON(w, snapshots)
+	writeJSON(w, r, report)
 }
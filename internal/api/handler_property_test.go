@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"os"
 	"reflect"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	"github.com/leanovate/gopter/prop"
 
 	"wanikani-api/internal/domain"
+	"wanikani-api/internal/migrations"
 	"wanikani-api/internal/store/sqlite"
 )
 
@@ -31,7 +33,7 @@ func TestProperty_QueryFilterCorrectness(t *testing.T) {
 			dbPath := "test_filter_subjects_" + randomString(8) + ".db"
 			defer os.Remove(dbPath)
 
-			store, err := sqlite.New(dbPath)
+			store, err := newPropertyTestStore(dbPath)
 			if err != nil {
 				t.Logf("failed to create store: %v", err)
 				return false
@@ -106,7 +108,7 @@ func TestProperty_QueryFilterCorrectness(t *testing.T) {
 			dbPath := "test_filter_assignments_" + randomString(8) + ".db"
 			defer os.Remove(dbPath)
 
-			store, err := sqlite.New(dbPath)
+			store, err := newPropertyTestStore(dbPath)
 			if err != nil {
 				t.Logf("failed to create store: %v", err)
 				return false
@@ -177,7 +179,7 @@ func TestProperty_QueryFilterCorrectness(t *testing.T) {
 			dbPath := "test_filter_reviews_" + randomString(8) + ".db"
 			defer os.Remove(dbPath)
 
-			store, err := sqlite.New(dbPath)
+			store, err := newPropertyTestStore(dbPath)
 			if err != nil {
 				t.Logf("failed to create store: %v", err)
 				return false
@@ -287,7 +289,15 @@ type reviewTestData struct {
 // Generators
 
 func genSubjects() gopter.Gen {
-	return gen.SliceOfN(10, genSubject())
+	// IDs must be unique, since upserting a duplicate ID replaces the earlier subject
+	return gen.SliceOfN(10, genSubject()).Map(func(subjects []domain.Subject) []domain.Subject {
+		unique := make([]domain.Subject, len(subjects))
+		for i, subject := range subjects {
+			subject.ID = subject.ID*10 + int64(i)
+			unique[i] = subject
+		}
+		return unique
+	})
 }
 
 func genSubject() gopter.Gen {
@@ -306,7 +316,7 @@ func genSubject() gopter.Gen {
 		}
 
 		return domain.Subject{
-			ID:            id,
+			ID:            int64(id),
 			Object:        objType,
 			URL:           "https://api.wanikani.com/v2/subjects/" + string(rune(id)),
 			DataUpdatedAt: time.Now(),
@@ -348,7 +358,7 @@ func genAssignmentTestData() gopter.Gen {
 			assignments := make([]domain.Assignment, len(subjectList))
 			for i, subject := range subjectList {
 				assignments[i] = domain.Assignment{
-					ID:            100 + i,
+					ID:            int64(100 + i),
 					Object:        "assignment",
 					URL:           "https://api.wanikani.com/v2/assignments/" + string(rune(100+i)),
 					DataUpdatedAt: time.Now(),
@@ -404,7 +414,7 @@ func genReviewTestData() gopter.Gen {
 				// Truncate to second precision to match RFC3339 storage
 				createdAt := baseTime.Add(time.Duration(daysOffsets[i]) * 24 * time.Hour).Truncate(time.Second)
 				reviews[i] = domain.Review{
-					ID:            200 + i,
+					ID:            int64(200 + i),
 					Object:        "review",
 					URL:           "https://api.wanikani.com/v2/reviews/" + string(rune(200+i)),
 					DataUpdatedAt: time.Now(),
@@ -465,7 +475,7 @@ func TestProperty_APIAuthenticationEnforcement(t *testing.T) {
 			dbPath := "test_auth_" + randomString(8) + ".db"
 			defer os.Remove(dbPath)
 
-			store, err := sqlite.New(dbPath)
+			store, err := newPropertyTestStore(dbPath)
 			if err != nil {
 				t.Logf("failed to create store: %v", err)
 				return false
@@ -535,7 +545,7 @@ func TestProperty_APIAuthenticationEnforcement(t *testing.T) {
 			dbPath := "test_health_" + randomString(8) + ".db"
 			defer os.Remove(dbPath)
 
-			store, err := sqlite.New(dbPath)
+			store, err := newPropertyTestStore(dbPath)
 			if err != nil {
 				t.Logf("failed to create store: %v", err)
 				return false
@@ -570,7 +580,7 @@ func TestProperty_APIAuthenticationEnforcement(t *testing.T) {
 			dbPath := "test_no_auth_" + randomString(8) + ".db"
 			defer os.Remove(dbPath)
 
-			store, err := sqlite.New(dbPath)
+			store, err := newPropertyTestStore(dbPath)
 			if err != nil {
 				t.Logf("failed to create store: %v", err)
 				return false
@@ -601,12 +611,35 @@ func TestProperty_APIAuthenticationEnforcement(t *testing.T) {
 	properties.TestingRun(t, gopter.ConsoleReporter(false))
 }
 
+// newPropertyTestStore creates a store backed by a freshly migrated database
+func newPropertyTestStore(dbPath string) (*sqlite.Store, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrations.Run(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+
+	return sqlite.New(dbPath)
+}
+
 // Generators for authentication tests
 
 func genToken() gopter.Gen {
-	return gen.Identifier().SuchThat(func(s string) bool {
-		return len(s) >= 10 && len(s) <= 50
-	})
+	// Build tokens of the desired length directly rather than filtering, which
+	// discards so many candidates that gopter gives up
+	return gen.IntRange(10, 50).FlatMap(func(n interface{}) gopter.Gen {
+		return gen.SliceOfN(n.(int), gen.AlphaNumChar()).Map(func(chars []rune) string {
+			return string(chars)
+		})
+	}, reflect.TypeOf(""))
 }
 
 func genOptionalToken() gopter.Gen {
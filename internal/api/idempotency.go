@@ -0,0 +1,83 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// idempotencyKeyHeader is the request header clients use to make POST /api/sync retry-safe
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotencyKeyTTL is how long a completed sync is replayed for a repeated key
+	idempotencyKeyTTL = 10 * time.Minute
+	// maxIdempotencyKeyLength bounds the size of keys kept in memory
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyEntry is a sync started under an idempotency key. response is nil
+// while the sync is still running.
+type idempotencyEntry struct {
+	response *SyncResponse
+	expires  time.Time
+}
+
+// idempotencyCache remembers recent successful sync responses by idempotency key so
+// that retried requests replay the original response instead of starting a new sync
+type idempotencyCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*idempotencyEntry
+	now     func() time.Time
+}
+
+// newIdempotencyCache creates an empty cache whose entries expire after ttl
+func newIdempotencyCache(ttl time.Duration) *idempotencyCache {
+	return &idempotencyCache{
+		ttl:     ttl,
+		entries: make(map[string]*idempotencyEntry),
+		now:     time.Now,
+	}
+}
+
+// begin claims key for a new sync. If the key is already known it returns the
+// cached response, or inFlight if the original sync has not finished yet.
+func (c *idempotencyCache) begin(key string) (cached *SyncResponse, inFlight bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for k, entry := range c.entries {
+		if entry.response != nil && now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	if entry, ok := c.entries[key]; ok {
+		if entry.response == nil {
+			return nil, true
+		}
+		return entry.response, false
+	}
+
+	c.entries[key] = &idempotencyEntry{}
+	return nil, false
+}
+
+// complete stores the response for key so repeated requests replay it until the TTL elapses
+func (c *idempotencyCache) complete(key string, response SyncResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = &idempotencyEntry{
+		response: &response,
+		expires:  c.now().Add(c.ttl),
+	}
+}
+
+// release forgets key after a failed sync so the client can retry it
+func (c *idempotencyCache) release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
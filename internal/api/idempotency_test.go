@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wanikani-api/internal/domain"
+)
+
+// countingSyncService counts SyncAll calls and returns a distinct result for each
+type countingSyncService struct {
+	mockSyncService
+	calls int
+	err   error
+}
+
+func (m *countingSyncService) SyncAll(ctx context.Context) ([]domain.SyncResult, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []domain.SyncResult{{
+		DataType:       domain.DataTypeSubjects,
+		RecordsUpdated: m.calls,
+		Success:        true,
+		Timestamp:      time.Date(2024, 1, 15, 10, 0, m.calls, 0, time.UTC),
+	}}, nil
+}
+
+func triggerSync(handler *Handler, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/sync", nil)
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	handler.HandleTriggerSync(w, req)
+	return w
+}
+
+func TestTriggerSyncIdempotencyKey(t *testing.T) {
+	syncService := &countingSyncService{}
+	handler := NewHandler(NewService(&mockStore{}, syncService), testLogger())
+
+	first := triggerSync(handler, "retry-123")
+	second := triggerSync(handler, "retry-123")
+
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("expected both requests to succeed, got %d and %d", first.Code, second.Code)
+	}
+	if syncService.calls != 1 {
+		t.Errorf("expected one sync for a repeated key, got %d", syncService.calls)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("expected identical responses, got %s and %s", first.Body.String(), second.Body.String())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("expected replayed response to be marked with Idempotent-Replayed")
+	}
+
+	// A different key or no key starts a new sync
+	triggerSync(handler, "retry-456")
+	triggerSync(handler, "")
+	if syncService.calls != 3 {
+		t.Errorf("expected 3 syncs after a new key and a keyless request, got %d", syncService.calls)
+	}
+}
+
+func TestTriggerSyncIdempotencyKeyExpires(t *testing.T) {
+	syncService := &countingSyncService{}
+	handler := NewHandler(NewService(&mockStore{}, syncService), testLogger())
+
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	handler.idempotency.now = func() time.Time { return now }
+
+	triggerSync(handler, "retry-123")
+	now = now.Add(idempotencyKeyTTL + time.Second)
+	triggerSync(handler, "retry-123")
+
+	if syncService.calls != 2 {
+		t.Errorf("expected an expired key to start a new sync, got %d syncs", syncService.calls)
+	}
+}
+
+func TestTriggerSyncIdempotencyKeyNotCachedOnFailure(t *testing.T) {
+	syncService := &countingSyncService{err: errors.New("subjects sync failed")}
+	handler := NewHandler(NewService(&mockStore{}, syncService), testLogger())
+
+	if w := triggerSync(handler, "retry-123"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected failed sync to return 500, got %d", w.Code)
+	}
+
+	syncService.err = nil
+	if w := triggerSync(handler, "retry-123"); w.Code != http.StatusOK {
+		t.Fatalf("expected retry after failure to succeed, got %d", w.Code)
+	}
+	if syncService.calls != 2 {
+		t.Errorf("expected a failed sync not to be replayed, got %d syncs", syncService.calls)
+	}
+}
+
+func TestTriggerSyncIdempotencyKeyInFlight(t *testing.T) {
+	handler := NewHandler(NewService(&mockStore{}, &countingSyncService{}), testLogger())
+
+	// Simulate a sync still running under the key
+	handler.idempotency.begin("retry-123")
+
+	w := triggerSync(handler, "retry-123")
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 while the keyed sync is running, got %d", w.Code)
+	}
+}
@@ -1,16 +1,36 @@
 package api
 
 import (
+	"crypto/subtle"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
-// AuthMiddleware creates an authentication middleware
-func AuthMiddleware(token string, logger *logrus.Logger) func(http.Handler) http.Handler {
+// AuthMiddleware creates an authentication middleware. Bearer tokens are always
+// accepted; when allowBasic is set, HTTP Basic credentials whose username or
+// password equals the token are accepted as well.
+func AuthMiddleware(token string, allowBasic bool, logger *logrus.Logger) func(http.Handler) http.Handler {
+	formatDetail := "Authorization header must use Bearer token format"
+	if allowBasic {
+		formatDetail = "Authorization header must use Bearer token or Basic format"
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reject := func(detail string) {
+				if allowBasic {
+					// Let browsers prompt for Basic credentials
+					w.Header().Set("WWW-Authenticate", `Basic realm="wanikani-api"`)
+				}
+				writeAuthError(w, "Authentication required", detail)
+			}
+
 			// Extract Authorization header
 			authHeader := r.Header.Get("Authorization")
 
@@ -22,34 +42,38 @@ func AuthMiddleware(token string, logger *logrus.Logger) func(http.Handler) http
 					"remote": r.RemoteAddr,
 				}).Warn("Authentication failed: missing Authorization header")
 
-				writeAuthError(w, "Authentication required", "Authorization header with Bearer token is required")
+				reject("Authorization header with Bearer token is required")
 				return
 			}
 
-			// Check if it's a Bearer token
-			if !strings.HasPrefix(authHeader, "Bearer ") {
+			// Extract credentials from a Bearer token, or Basic credentials if allowed
+			var valid bool
+			switch {
+			case strings.HasPrefix(authHeader, "Bearer "):
+				valid = tokenMatches(strings.TrimPrefix(authHeader, "Bearer "), token)
+			case allowBasic && strings.HasPrefix(authHeader, "Basic "):
+				username, password, ok := r.BasicAuth()
+				valid = ok && (tokenMatches(password, token) || tokenMatches(username, token))
+			default:
 				logger.WithFields(logrus.Fields{
 					"path":   r.URL.Path,
 					"method": r.Method,
 					"remote": r.RemoteAddr,
 				}).Warn("Authentication failed: invalid Authorization header format")
 
-				writeAuthError(w, "Authentication required", "Authorization header must use Bearer token format")
+				reject(formatDetail)
 				return
 			}
 
-			// Extract token
-			providedToken := strings.TrimPrefix(authHeader, "Bearer ")
-
 			// Validate token
-			if providedToken != token {
+			if !valid {
 				logger.WithFields(logrus.Fields{
 					"path":   r.URL.Path,
 					"method": r.Method,
 					"remote": r.RemoteAddr,
 				}).Warn("Authentication failed: invalid token")
 
-				writeAuthError(w, "Authentication required", "Invalid authentication token")
+				reject("Invalid authentication token")
 				return
 			}
 
@@ -59,6 +83,26 @@ func AuthMiddleware(token string, logger *logrus.Logger) func(http.Handler) http
 	}
 }
 
+// tokenMatches compares a provided credential to the configured token in constant time
+func tokenMatches(provided, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// SecurityHeadersMiddleware stops browsers from sniffing response types or framing
+// responses, and sets contentSecurityPolicy as the Content-Security-Policy unless it is empty
+func SecurityHeadersMiddleware(contentSecurityPolicy string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			w.Header().Set("X-Frame-Options", "DENY")
+			if contentSecurityPolicy != "" {
+				w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // CORSMiddleware adds CORS headers to allow cross-origin requests
 func CORSMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -86,7 +130,8 @@ func CORSMiddleware() func(http.Handler) http.Handler {
 			if allowed {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
+				w.Header().Set("Access-Control-Expose-Headers", "X-Result-Truncated, X-Data-Synced-At")
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
 				w.Header().Set("Access-Control-Max-Age", "86400")
 			}
@@ -103,11 +148,74 @@ func CORSMiddleware() func(http.Handler) http.Handler {
 	}
 }
 
+// AccessLogMiddleware logs one line per request, labeled by the matched route
+// template rather than the raw path so that IDs don't create unbounded label values.
+// The client IP is read from forwarding headers only for requests from trustedProxies.
+// Only a sampleRate fraction of successful requests is logged; errors (status 400 and
+// above) and requests taking at least slowThreshold are always logged. A zero
+// slowThreshold disables the slow request exemption.
+func AccessLogMiddleware(logger *logrus.Logger, trustedProxies []*net.IPNet, sampleRate float64, slowThreshold time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(recorder, r)
+
+			duration := time.Since(start)
+			isError := recorder.status >= http.StatusBadRequest
+			isSlow := slowThreshold > 0 && duration >= slowThreshold
+			if !isError && !isSlow && !sampled(sampleRate) {
+				return
+			}
+
+			logger.WithFields(logrus.Fields{
+				"method":      r.Method,
+				"route":       routeLabel(r),
+				"client_ip":   clientIP(r, trustedProxies),
+				"status_code": recorder.status,
+				"duration_ms": duration.Milliseconds(),
+			}).Info("HTTP request")
+		})
+	}
+}
+
+// sampled reports whether a request falls within the sampled fraction rate
+func sampled(rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	return rate > 0 && rand.Float64() < rate
+}
+
+// routeLabel returns the path template of the matched route (e.g. /api/subjects/{id}),
+// or "unmatched" if the request did not match a route
+func routeLabel(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "unmatched"
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return "unmatched"
+	}
+
+	return template
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
 // writeAuthError writes an authentication error response
 func writeAuthError(w http.ResponseWriter, message, detail string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
-
-	// Write JSON response directly
-	w.Write([]byte(`{"error":{"code":"UNAUTHORIZED","message":"` + message + `","details":{"header":"` + detail + `"}}}`))
+	writeErrorResponse(w, http.StatusUnauthorized, ErrCodeUnauthorized, message, map[string]string{"header": detail})
 }
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+// TestAuthMiddlewareBasicAuth tests Basic Auth handling with and without AUTH_ALLOW_BASIC
+func TestAuthMiddlewareBasicAuth(t *testing.T) {
+	const token = "local-secret"
+
+	tests := []struct {
+		name           string
+		allowBasic     bool
+		setAuth        func(r *http.Request)
+		expectedStatus int
+	}{
+		{
+			name:           "basic auth with token as password",
+			allowBasic:     true,
+			setAuth:        func(r *http.Request) { r.SetBasicAuth("anyone", token) },
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "basic auth with token as username",
+			allowBasic:     true,
+			setAuth:        func(r *http.Request) { r.SetBasicAuth(token, "") },
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "basic auth with wrong credentials",
+			allowBasic:     true,
+			setAuth:        func(r *http.Request) { r.SetBasicAuth("user", "wrong") },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "malformed basic credentials",
+			allowBasic:     true,
+			setAuth:        func(r *http.Request) { r.Header.Set("Authorization", "Basic not-base64!") },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "bearer still accepted when basic allowed",
+			allowBasic:     true,
+			setAuth:        func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) },
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "basic auth rejected when not allowed",
+			allowBasic:     false,
+			setAuth:        func(r *http.Request) { r.SetBasicAuth("user", token) },
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := AuthMiddleware(token, tt.allowBasic, testLogger())
+			protected := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/subjects", nil)
+			tt.setAuth(req)
+			w := httptest.NewRecorder()
+
+			protected.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			challenge := w.Header().Get("WWW-Authenticate")
+			expectChallenge := tt.allowBasic && tt.expectedStatus == http.StatusUnauthorized
+			if expectChallenge && challenge == "" {
+				t.Error("expected WWW-Authenticate challenge on 401 when basic auth is allowed")
+			}
+			if !expectChallenge && challenge != "" {
+				t.Errorf("unexpected WWW-Authenticate header %q", challenge)
+			}
+		})
+	}
+}
+
+// TestAccessLogMiddlewareRouteLabel tests that requests for different IDs are
+// labeled with the same route template
+func TestAccessLogMiddlewareRouteLabel(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+
+	router := mux.NewRouter()
+	router.Use(AccessLogMiddleware(logger, nil, 1, 0))
+	router.HandleFunc("/api/subjects/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("GET")
+
+	for _, path := range []string{"/api/subjects/123", "/api/subjects/456"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	entries := hook.AllEntries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 access log entries, got %d", len(entries))
+	}
+
+	for _, entry := range entries {
+		if route := entry.Data["route"]; route != "/api/subjects/{id}" {
+			t.Errorf("expected route label /api/subjects/{id}, got %v", route)
+		}
+		if status := entry.Data["status_code"]; status != http.StatusNoContent {
+			t.Errorf("expected status_code %d, got %v", http.StatusNoContent, status)
+		}
+	}
+}
+
+// TestAccessLogMiddlewareClientIP tests that the logged client IP comes from forwarding
+// headers only for requests from a trusted proxy
+func TestAccessLogMiddlewareClientIP(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+	_, proxyNet, _ := net.ParseCIDR("10.0.0.0/8")
+
+	router := mux.NewRouter()
+	router.Use(AccessLogMiddleware(logger, []*net.IPNet{proxyNet}, 1, 0))
+	router.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+
+	tests := []struct {
+		remoteAddr string
+		expected   string
+	}{
+		{remoteAddr: "10.0.0.2:1234", expected: "203.0.113.9"},
+		{remoteAddr: "198.51.100.7:1234", expected: "198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		hook.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.9")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		entry := hook.LastEntry()
+		if entry == nil {
+			t.Fatalf("expected an access log entry for %s", tt.remoteAddr)
+		}
+		if ip := entry.Data["client_ip"]; ip != tt.expected {
+			t.Errorf("request from %s: expected client_ip %s, got %v", tt.remoteAddr, tt.expected, ip)
+		}
+	}
+}
+
+// TestAccessLogMiddlewareSampling tests that unsampled successful requests are dropped
+// while errors and slow requests are always logged
+func TestAccessLogMiddlewareSampling(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+
+	router := mux.NewRouter()
+	router.Use(AccessLogMiddleware(logger, nil, 0, 20*time.Millisecond))
+	router.HandleFunc("/api/ok", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+	router.HandleFunc("/api/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Methods("GET")
+	router.HandleFunc("/api/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}).Methods("GET")
+	router.HandleFunc("/api/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+	}).Methods("GET")
+
+	tests := []struct {
+		path   string
+		logged bool
+	}{
+		{path: "/api/ok", logged: false},
+		{path: "/api/missing", logged: true},
+		{path: "/api/fail", logged: true},
+		{path: "/api/slow", logged: true},
+	}
+
+	for _, tt := range tests {
+		hook.Reset()
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if logged := len(hook.AllEntries()) > 0; logged != tt.logged {
+			t.Errorf("%s: expected logged=%v with sample rate 0, got %v", tt.path, tt.logged, logged)
+		}
+	}
+}
+
+// TestSampledOutRequestsLogNothingAtInfo tests that a successful request dropped by
+// access log sampling leaves no Info entries from the handlers either
+func TestSampledOutRequestsLogNothingAtInfo(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+	logger.SetLevel(logrus.InfoLevel)
+	server := NewServer(&mockStore{}, &mockSyncService{}, 8080, "", logger, WithRequestLogSampling(0, time.Minute))
+	hook.Reset()
+
+	for _, path := range []string{"/api/subjects", "/api/assignments", "/api/reviews", "/api/statistics", "/api/sync/status"} {
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code >= http.StatusBadRequest {
+			t.Fatalf("%s: expected success, got %d", path, w.Code)
+		}
+	}
+
+	for _, entry := range hook.AllEntries() {
+		if entry.Level <= logrus.InfoLevel {
+			t.Errorf("expected no Info entries for sampled-out requests, got %s: %q", entry.Level, entry.Message)
+		}
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []ServerOption
+		path   string
+		status int
+		csp    string
+	}{
+		{name: "default policy", path: "/api/health", status: http.StatusOK, csp: DefaultContentSecurityPolicy},
+		{name: "auth error", path: "/api/subjects", status: http.StatusUnauthorized, csp: DefaultContentSecurityPolicy},
+		{name: "custom policy", opts: []ServerOption{WithContentSecurityPolicy("default-src 'self'")}, path: "/api/health", status: http.StatusOK, csp: "default-src 'self'"},
+		{name: "policy disabled", opts: []ServerOption{WithContentSecurityPolicy("")}, path: "/api/health", status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(&mockStore{}, &mockSyncService{}, 8080, "secret", testLogger(), tt.opts...)
+
+			w := httptest.NewRecorder()
+			server.getRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			for header, expected := range map[string]string{
+				"X-Content-Type-Options":  "nosniff",
+				"X-Frame-Options":         "DENY",
+				"Content-Security-Policy": tt.csp,
+				"Content-Type":            "application/json; charset=utf-8",
+			} {
+				if got := w.Header().Get(header); got != expected {
+					t.Errorf("expected %s %q, got %q", header, expected, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// problemContentType is the RFC 7807 media type for problem details
+const problemContentType = "application/problem+json"
+
+// problemTypePrefix is prefixed to the lower-cased error code to form a problem type URI
+const problemTypePrefix = "urn:wanikani-api:problem:"
+
+// ProblemDetails is an RFC 7807 problem details object. The error code and the field
+// details of ErrorResponse are kept as the code and errors extension members.
+type ProblemDetails struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     ErrorCode         `json:"code"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// problemResponseWriter marks a response whose errors are written as problem details
+type problemResponseWriter struct {
+	http.ResponseWriter
+	instance string
+}
+
+// ProblemJSONMiddleware negotiates the error format of each request. Errors are written as
+// application/problem+json when byDefault is set or the Accept header asks for it, and in
+// the ErrorResponse format otherwise.
+func ProblemJSONMiddleware(byDefault bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if byDefault || acceptsProblemJSON(r) {
+				w = &problemResponseWriter{ResponseWriter: w, instance: r.URL.Path}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// acceptsProblemJSON reports whether the Accept header lists application/problem+json
+func acceptsProblemJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil || mediaType != problemContentType {
+				continue
+			}
+			if q, ok := params["q"]; ok && strings.Trim(q, "0.") == "" {
+				continue // q=0 explicitly refuses the type
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// writeErrorResponse writes an error in the format negotiated by ProblemJSONMiddleware
+func writeErrorResponse(w http.ResponseWriter, status int, errorCode ErrorCode, message string, details map[string]string) {
+	contentType, body := errorResponseBody(w, status, errorCode, message, details)
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(body)
+}
+
+// errorResponseBody returns the content type and body of an error written to w
+func errorResponseBody(w http.ResponseWriter, status int, errorCode ErrorCode, message string, details map[string]string) (string, interface{}) {
+	if pw, ok := w.(*problemResponseWriter); ok {
+		return problemContentType, ProblemDetails{
+			Type:     problemTypePrefix + strings.ReplaceAll(strings.ToLower(string(errorCode)), "_", "-"),
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   message,
+			Instance: pw.instance,
+			Code:     errorCode,
+			Errors:   details,
+		}
+	}
+
+	return jsonContentType, ErrorResponse{
+		Error: ErrorDetail{
+			Code:    errorCode,
+			Message: message,
+			Details: details,
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestErrorFormatNegotiation tests that errors keep the ErrorResponse format by default and
+// are written as problem details when the client or the server configuration asks for it
+func TestErrorFormatNegotiation(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []ServerOption
+		accept      string
+		wantProblem bool
+	}{
+		{name: "default format", accept: "", wantProblem: false},
+		{name: "plain json accepted", accept: "application/json", wantProblem: false},
+		{name: "problem json accepted", accept: "application/json, application/problem+json", wantProblem: true},
+		{name: "problem json refused", accept: "application/problem+json;q=0", wantProblem: false},
+		{name: "problem json by default", opts: []ServerOption{WithProblemJSON(true)}, wantProblem: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(&mockStore{}, &mockSyncService{}, 8080, "", testLogger(), tt.opts...)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/subjects?level=99", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			server.getRouter().ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", w.Code)
+			}
+
+			if !tt.wantProblem {
+				if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+					t.Errorf("expected application/json content type, got %q", ct)
+				}
+				var resp ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode error response: %v", err)
+				}
+				if resp.Error.Code != ErrCodeValidation || resp.Error.Details["level"] == "" {
+					t.Errorf("unexpected error response: %+v", resp)
+				}
+				return
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+				t.Errorf("expected %s content type, got %q", problemContentType, ct)
+			}
+			var problem ProblemDetails
+			if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+				t.Fatalf("failed to decode problem details: %v", err)
+			}
+			if problem.Type != "urn:wanikani-api:problem:validation-error" {
+				t.Errorf("unexpected type %q", problem.Type)
+			}
+			if problem.Title != "Bad Request" || problem.Status != http.StatusBadRequest {
+				t.Errorf("unexpected title/status %q/%d", problem.Title, problem.Status)
+			}
+			if problem.Detail == "" || problem.Instance != "/api/subjects" {
+				t.Errorf("unexpected detail/instance %q/%q", problem.Detail, problem.Instance)
+			}
+			if problem.Code != ErrCodeValidation || problem.Errors["level"] == "" {
+				t.Errorf("unexpected code/errors %q/%v", problem.Code, problem.Errors)
+			}
+		})
+	}
+}
+
+// TestAuthErrorProblemJSON tests that authentication failures are negotiated as well
+func TestAuthErrorProblemJSON(t *testing.T) {
+	server := NewServer(&mockStore{}, &mockSyncService{}, 8080, "secret", testLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/subjects", nil)
+	req.Header.Set("Accept", problemContentType)
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+		t.Errorf("expected %s content type, got %q", problemContentType, ct)
+	}
+	var problem ProblemDetails
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatalf("failed to decode problem details: %v", err)
+	}
+	if problem.Status != http.StatusUnauthorized || problem.Code != ErrCodeUnauthorized {
+		t.Errorf("unexpected problem details: %+v", problem)
+	}
+}
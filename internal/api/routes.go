@@ -2,55 +2,123 @@ package api
 
 import (
 	"net/http"
+	"sort"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
 // setupRoutes configures all API routes
-func setupRoutes(router *mux.Router, handler *Handler, token string, logger *logrus.Logger) {
-	// Add CORS middleware to the main router
+func setupRoutes(router *mux.Router, handler *Handler, token string, logger *logrus.Logger, cfg serverConfig) {
+	// Add access logging, security header and CORS middleware to the main router
+	router.Use(AccessLogMiddleware(logger, cfg.trustedProxies, cfg.requestLogSampleRate, cfg.slowRequestThreshold))
+	router.Use(SecurityHeadersMiddleware(cfg.contentSecurityPolicy))
 	router.Use(CORSMiddleware())
+	router.Use(ProblemJSONMiddleware(cfg.problemJSON))
+	router.Use(TimeoutMiddleware(cfg.handlerTimeout,
+		[]string{"/api/sync", "/api/admin/export", "/api/admin/import"},
+		[]string{"/api/subjects"}))
 
 	// API routes
 	api := router.PathPrefix("/api").Subrouter()
 
 	// Health check endpoint (no authentication required)
-	api.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok"}`))
-	}).Methods("GET")
+	api.HandleFunc("/health", handler.HandleHealth).Methods("GET")
+	api.HandleFunc("/version", handler.HandleVersion).Methods("GET")
 
 	// Create authenticated subrouter for protected endpoints
 	authAPI := api.NewRoute().Subrouter()
 
 	// Apply authentication middleware if token is configured
 	if token != "" {
-		authAPI.Use(AuthMiddleware(token, logger))
-		logger.Info("API authentication enabled")
+		authAPI.Use(AuthMiddleware(token, cfg.allowBasicAuth, logger))
+		logger.WithField("basic_auth", cfg.allowBasicAuth).Info("API authentication enabled")
 	} else {
 		logger.Warn("LOCAL_API_TOKEN not configured - API running without authentication")
 	}
 
-	// Data endpoints (OPTIONS bypass auth, GET/POST require auth)
-	api.HandleFunc("/subjects", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
-	authAPI.HandleFunc("/subjects", handler.HandleGetSubjects).Methods("GET")
+	// Read endpoints are paused while an admin operation holds the admin gate
+	readAPI := authAPI.NewRoute().Subrouter()
+	readAPI.Use(handler.adminGateMiddleware)
 
-	api.HandleFunc("/assignments", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
-	authAPI.HandleFunc("/assignments", handler.HandleGetAssignments).Methods("GET")
+	// Endpoints listed in PUBLIC_ENDPOINTS are registered here instead, skipping
+	// authentication but still waiting on the admin gate where the private route would
+	publicAPI := api.NewRoute().Subrouter()
+	publicReadAPI := publicAPI.NewRoute().Subrouter()
+	publicReadAPI.Use(handler.adminGateMiddleware)
 
-	api.HandleFunc("/assignments/snapshots", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
-	authAPI.HandleFunc("/assignments/snapshots", handler.HandleGetAssignmentSnapshots).Methods("GET")
+	// route picks the public or private router for a GET endpoint, recording public ones
+	publicPaths := make(map[string]bool)
+	route := func(path string, private, public *mux.Router) *mux.Router {
+		if template := "/api" + path; cfg.publicEndpoints[template] {
+			publicPaths[template] = true
+			return public
+		}
+		return private
+	}
+
+	// read registers a GET data endpoint
+	read := func(path string, handle http.HandlerFunc) {
+		api.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
+		route(path, readAPI, publicReadAPI).HandleFunc(path, handle).Methods("GET")
+	}
+
+	// optional registers a read endpoint only if its feature is enabled in FEATURES
+	optional := func(feature, path string, handle http.HandlerFunc) {
+		if cfg.features.enabled(feature) {
+			read(path, handle)
+		}
+	}
+
+	// Data endpoints (OPTIONS bypass auth, GET requires auth unless listed in PUBLIC_ENDPOINTS)
+	read("/subjects", handler.HandleGetSubjects)
+	read("/subjects/search", handler.HandleSearchSubjects)
+	read("/subjects/changes", handler.HandleGetSubjectChanges)
+	read("/assignments", handler.limitConcurrency(handler.HandleGetAssignments))
+	read("/assignments/passed", handler.HandleGetPassedAssignments)
+	read("/assignments/snapshots", handler.HandleGetAssignmentSnapshots)
+	read("/assignments/snapshots/diff", handler.HandleGetAssignmentSnapshotDiff)
+	read("/assignments/snapshots/series", handler.HandleGetAssignmentSnapshotSeries)
+	read("/lessons/available", handler.HandleGetAvailableLessons)
+	read("/reviews", handler.limitConcurrency(handler.HandleGetReviews))
+	optional(FeatureWeeklyReviews, "/reviews/weekly", handler.HandleGetWeeklyReviews)
+	read("/reviews/available/count", handler.HandleGetAvailableReviewCount)
+	read("/reviews/critical", handler.HandleGetCriticalItems)
+	read("/statistics/latest", handler.HandleGetLatestStatistics)
+	read("/statistics", handler.HandleGetStatistics)
+	read("/levels/{level}/progress", handler.HandleGetLevelProgress)
+
+	// Stats endpoints
+	optional(FeatureStreak, "/stats/streak", handler.HandleGetReviewStreak)
+	optional(FeatureToday, "/stats/today", handler.HandleGetTodayReviews)
+	optional(FeatureReviewHours, "/stats/review-hours", handler.HandleGetReviewHours)
+	optional(FeatureSRSTotals, "/stats/srs-totals", handler.HandleGetSRSGroupTotals)
+	optional(FeatureSubjectsByLevel, "/stats/subjects-by-level", handler.HandleGetSubjectCountsByLevel)
+	optional(FeatureProgress, "/stats/progress", handler.HandleGetProgressBreakdown)
+	optional(FeatureVelocity, "/stats/velocity", handler.HandleGetReviewVelocity)
+
+	// Metadata endpoints (static, so not behind the admin gate)
+	api.HandleFunc("/meta/srs-stages", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
+	route("/meta/srs-stages", authAPI, publicAPI).HandleFunc("/meta/srs-stages", handler.HandleGetSRSStages).Methods("GET")
 
-	api.HandleFunc("/reviews", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
-	authAPI.HandleFunc("/reviews", handler.HandleGetReviews).Methods("GET")
+	// Levels and the review date range are read from the synced data, so unlike the static
+	// metadata they wait on the gate
+	optional(FeatureLevels, "/meta/levels", handler.HandleGetLevels)
+	read("/meta/review-range", handler.HandleGetReviewDateRange)
 
-	api.HandleFunc("/statistics/latest", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
-	authAPI.HandleFunc("/statistics/latest", handler.HandleGetLatestStatistics).Methods("GET")
+	// Admin endpoints. Export is a read and waits on the admin gate like other reads;
+	// import and integrity repair take the gate themselves.
+	api.HandleFunc("/admin/export", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
+	readAPI.HandleFunc("/admin/export", handler.HandleExportData).Methods("GET")
 
-	api.HandleFunc("/statistics", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
-	authAPI.HandleFunc("/statistics", handler.HandleGetStatistics).Methods("GET")
+	api.HandleFunc("/admin/import", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
+	authAPI.HandleFunc("/admin/import", handler.HandleImportData).Methods("POST")
+
+	api.HandleFunc("/admin/integrity", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
+	authAPI.HandleFunc("/admin/integrity", handler.HandleCheckIntegrity).Methods("GET")
+
+	api.HandleFunc("/admin/sync/reset-timestamps", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
+	authAPI.HandleFunc("/admin/sync/reset-timestamps", handler.HandleResetSyncTimes).Methods("POST")
 
 	// Sync endpoints
 	api.HandleFunc("/sync", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
@@ -58,4 +126,25 @@ func setupRoutes(router *mux.Router, handler *Handler, token string, logger *log
 
 	api.HandleFunc("/sync/status", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
 	authAPI.HandleFunc("/sync/status", handler.HandleGetSyncStatus).Methods("GET")
+
+	api.HandleFunc("/sync/timestamps", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
+	authAPI.HandleFunc("/sync/timestamps", handler.HandleGetSyncTimestamps).Methods("GET")
+
+	// Admin and sync endpoints, and anything disabled by FEATURES, are never made public
+	var served, ignored []string
+	for template := range cfg.publicEndpoints {
+		if publicPaths[template] {
+			served = append(served, template)
+		} else {
+			ignored = append(ignored, template)
+		}
+	}
+	sort.Strings(served)
+	sort.Strings(ignored)
+	if len(served) > 0 && token != "" {
+		logger.WithField("endpoints", served).Warn("Serving PUBLIC_ENDPOINTS without authentication")
+	}
+	if len(ignored) > 0 {
+		logger.WithField("endpoints", ignored).Warn("Ignoring PUBLIC_ENDPOINTS entries that are not public-capable read endpoints")
+	}
 }
@@ -3,7 +3,9 @@ package api
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
@@ -12,31 +14,234 @@ import (
 
 // Server represents the API server
 type Server struct {
-	router  *mux.Router
-	server  *http.Server
-	handler *Handler
-	logger  *logrus.Logger
+	router      *mux.Router
+	server      *http.Server
+	handler     *Handler
+	logger      *logrus.Logger
+	refresher   *cacheRefresher
+	tlsCertFile string
+	tlsKeyFile  string
 }
 
-// NewServer creates a new API server
-func NewServer(store domain.DataStore, syncService domain.SyncService, port int, token string, logger *logrus.Logger) *Server {
+// serverConfig holds optional server settings applied through ServerOption
+type serverConfig struct {
+	allowBasicAuth       bool
+	adminGateWait        time.Duration
+	maxHeavyConcurrency  int
+	maxResultRows        int
+	subjectCacheRefresh  time.Duration
+	problemJSON          bool
+	maxImportBytes       int64
+	handlerTimeout       time.Duration
+	instanceName         string
+	features             featureSet
+	trustedProxies       []*net.IPNet
+	requestLogSampleRate float64
+	slowRequestThreshold time.Duration
+	dailyReviewGoal      int
+	publicEndpoints      map[string]bool
+	tlsCertFile          string
+	tlsKeyFile           string
+
+	contentSecurityPolicy string
+}
+
+// ServerOption configures optional Server behavior
+type ServerOption func(*serverConfig)
+
+// WithBasicAuth allows clients to authenticate with HTTP Basic Auth, using the
+// local API token as either the username or the password
+func WithBasicAuth(allowed bool) ServerOption {
+	return func(c *serverConfig) {
+		c.allowBasicAuth = allowed
+	}
+}
+
+// WithAdminGateWait sets how long reads queue while an admin operation holds the
+// admin gate before being rejected with 503 (default: reject immediately)
+func WithAdminGateWait(wait time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.adminGateWait = wait
+	}
+}
+
+// WithHeavyEndpointConcurrency limits each client IP to max in-flight requests on the
+// expensive /api/reviews and /api/assignments endpoints. Zero disables the limit.
+func WithHeavyEndpointConcurrency(max int) ServerOption {
+	return func(c *serverConfig) {
+		c.maxHeavyConcurrency = max
+	}
+}
+
+// WithMaxResultRows caps the number of rows returned by /api/subjects, /api/assignments and
+// /api/reviews. Truncated responses carry an X-Result-Truncated: true header. Zero means unlimited.
+func WithMaxResultRows(max int) ServerOption {
+	return func(c *serverConfig) {
+		c.maxResultRows = max
+	}
+}
+
+// WithSubjectCache keeps subjects in memory for joining assignments and reviews, checking
+// every refresh interval whether subjects were synced since the cache was built and
+// rebuilding it if so. Zero disables the cache.
+func WithSubjectCache(refresh time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.subjectCacheRefresh = refresh
+	}
+}
+
+// WithProblemJSON writes every error as RFC 7807 application/problem+json. Without it,
+// only requests whose Accept header asks for problem+json get that format.
+func WithProblemJSON(enabled bool) ServerOption {
+	return func(c *serverConfig) {
+		c.problemJSON = enabled
+	}
+}
+
+// WithMaxImportBytes bounds the size of POST /api/admin/import bodies after decompression
+// (default 1 GiB). Zero or less keeps the default.
+func WithMaxImportBytes(n int64) ServerOption {
+	return func(c *serverConfig) {
+		c.maxImportBytes = n
+	}
+}
+
+// WithHandlerTimeout bounds how long a request may run before it is answered with 503.
+// Sync, export and import are exempt since they are expected to run long. Zero disables it.
+func WithHandlerTimeout(d time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.handlerTimeout = d
+	}
+}
+
+// WithInstanceName sets the instance label reported by /api/health and /api/version
+func WithInstanceName(name string) ServerOption {
+	return func(c *serverConfig) {
+		c.instanceName = name
+	}
+}
+
+// WithFeatures registers only the named optional endpoints (see the Feature constants),
+// leaving the others unrouted so they answer 404. An empty list enables every feature.
+func WithFeatures(names []string) ServerOption {
+	return func(c *serverConfig) {
+		if len(names) == 0 {
+			c.features = nil
+			return
+		}
+		c.features = newFeatureSet(names)
+	}
+}
+
+// WithTrustedProxies believes the X-Forwarded-For and X-Real-IP headers of requests from
+// these networks when identifying clients for the per-IP concurrency limit and access logs.
+// Without it, the connection's remote address is always used.
+func WithTrustedProxies(networks []*net.IPNet) ServerOption {
+	return func(c *serverConfig) {
+		c.trustedProxies = networks
+	}
+}
+
+// WithRequestLogSampling logs only a sampleRate fraction (0 to 1) of successful requests
+// in the access log. Errors and requests taking at least slowThreshold are always logged;
+// a zero slowThreshold disables that exemption. Without it, every request is logged.
+func WithRequestLogSampling(sampleRate float64, slowThreshold time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.requestLogSampleRate = sampleRate
+		c.slowRequestThreshold = slowThreshold
+	}
+}
+
+// DefaultContentSecurityPolicy forbids loading or framing anything, which suits an API that
+// only serves JSON
+const DefaultContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+
+// WithContentSecurityPolicy sets the Content-Security-Policy header sent with every
+// response (default: DefaultContentSecurityPolicy). An empty policy omits the header.
+func WithContentSecurityPolicy(policy string) ServerOption {
+	return func(c *serverConfig) {
+		c.contentSecurityPolicy = policy
+	}
+}
+
+// WithDailyReviewGoal sets the number of reviews per day that /api/stats/today reports
+// progress toward. Zero or less means no goal.
+func WithDailyReviewGoal(goal int) ServerOption {
+	return func(c *serverConfig) {
+		c.dailyReviewGoal = goal
+	}
+}
+
+// WithPublicEndpoints serves the listed GET route templates, such as /api/subjects or
+// /api/levels/{level}/progress, without authentication even when a token is configured.
+// Admin and sync endpoints cannot be made public.
+func WithPublicEndpoints(templates []string) ServerOption {
+	return func(c *serverConfig) {
+		c.publicEndpoints = make(map[string]bool, len(templates))
+		for _, template := range templates {
+			c.publicEndpoints[template] = true
+		}
+	}
+}
+
+// WithTLS serves HTTPS, and with it HTTP/2, using the given PEM certificate and key files
+// instead of plain HTTP
+func WithTLS(certFile, keyFile string) ServerOption {
+	return func(c *serverConfig) {
+		c.tlsCertFile = certFile
+		c.tlsKeyFile = keyFile
+	}
+}
+
+// NewServer creates a new API server. A nil syncService serves the stored data with
+// syncing disabled.
+func NewServer(store domain.DataStore, syncService domain.SyncService, port int, token string, logger *logrus.Logger, opts ...ServerOption) *Server {
+	cfg := serverConfig{requestLogSampleRate: 1, contentSecurityPolicy: DefaultContentSecurityPolicy}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	// Create service layer
 	service := NewService(store, syncService)
+	service.maxResultRows = cfg.maxResultRows
+	service.dailyReviewGoal = cfg.dailyReviewGoal
+
+	var refresher *cacheRefresher
+	if cfg.subjectCacheRefresh > 0 {
+		service.subjectCache = NewSubjectCache(store)
+		refresher = startCacheRefresher(service.subjectCache, cfg.subjectCacheRefresh, logger)
+	}
 
 	// Create handler layer
 	handler := NewHandler(service, logger)
+	handler.gate = NewAdminGate(cfg.adminGateWait)
+	if cfg.maxHeavyConcurrency > 0 {
+		handler.heavyLimiter = NewConcurrencyLimiter(cfg.maxHeavyConcurrency)
+	}
+	if cfg.maxImportBytes > 0 {
+		handler.maxImportBytes = cfg.maxImportBytes
+	}
+	handler.instanceName = cfg.instanceName
+	handler.trustedProxies = cfg.trustedProxies
+
+	if unknown := cfg.features.unknown(); len(unknown) > 0 {
+		logger.WithField("features", unknown).Warn("Ignoring unknown FEATURES entries")
+	}
 
 	// Create router
 	router := mux.NewRouter()
 
 	// Setup routes with authentication
-	setupRoutes(router, handler, token, logger)
+	setupRoutes(router, handler, token, logger, cfg)
 
 	// Create HTTP server
 	s := &Server{
-		router:  router,
-		handler: handler,
-		logger:  logger,
+		router:      router,
+		handler:     handler,
+		logger:      logger,
+		refresher:   refresher,
+		tlsCertFile: cfg.tlsCertFile,
+		tlsKeyFile:  cfg.tlsKeyFile,
 		server: &http.Server{
 			Addr:    fmt.Sprintf(":%d", port),
 			Handler: router,
@@ -46,12 +251,32 @@ func NewServer(store domain.DataStore, syncService domain.SyncService, port int,
 	return s
 }
 
-// Start starts the API server
+// AdminGate returns the gate that admin operations hold to pause reads
+func (s *Server) AdminGate() *AdminGate {
+	return s.handler.gate
+}
+
+// Start starts the API server, serving HTTPS if TLS is configured
 func (s *Server) Start() error {
-	return s.server.ListenAndServe()
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	return s.serve(listener)
 }
 
-// Shutdown gracefully shuts down the server
+// serve accepts connections on listener until the server is shut down
+func (s *Server) serve(listener net.Listener) error {
+	if s.tlsCertFile != "" {
+		return s.server.ServeTLS(listener, s.tlsCertFile, s.tlsKeyFile)
+	}
+	return s.server.Serve(listener)
+}
+
+// Shutdown gracefully shuts down the server and stops background work
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.refresher != nil {
+		s.refresher.stop()
+	}
 	return s.server.Shutdown(ctx)
 }
@@ -1,12 +1,30 @@
 package api
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"database/sql"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,7 +38,7 @@ import (
 )
 
 // setupTestServer creates a test server with a properly migrated database
-func setupTestServer(t *testing.T) (*Server, *sqlite.Store) {
+func setupTestServer(t testing.TB) (*Server, *sqlite.Store) {
 	t.Helper()
 
 	// Create temporary database file
@@ -130,6 +148,311 @@ func TestGetSubjects(t *testing.T) {
 	}
 }
 
+func TestGetSubjectsIncludeRaw(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// Decode the subject as it would arrive from WaniKani, including a field SubjectData does not model
+	rawData := `{"level":1,"characters":"一","meanings":[{"meaning":"one","primary":true}],"meaning_mnemonic":"One line"}`
+	var subject domain.Subject
+	if err := json.Unmarshal([]byte(`{"id":1,"object":"kanji","url":"https://api.wanikani.com/v2/subjects/1","data_updated_at":"2024-01-01T00:00:00Z","data":`+rawData+`}`), &subject); err != nil {
+		t.Fatalf("Failed to decode test subject: %v", err)
+	}
+
+	if err := store.UpsertSubjects(ctx, []domain.Subject{subject}); err != nil {
+		t.Fatalf("Failed to insert test subject: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		expectRaw bool
+	}{
+		{name: "omitted by default", query: "", expectRaw: false},
+		{name: "omitted when false", query: "?include_raw=false", expectRaw: false},
+		{name: "included when requested", query: "?include_raw=true", expectRaw: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/subjects"+tt.query, nil)
+			w := httptest.NewRecorder()
+			server.getRouter().ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+
+			var subjects []map[string]json.RawMessage
+			if err := json.NewDecoder(w.Body).Decode(&subjects); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if len(subjects) != 1 {
+				t.Fatalf("Expected 1 subject, got %d", len(subjects))
+			}
+
+			raw, ok := subjects[0]["raw"]
+			if ok != tt.expectRaw {
+				t.Fatalf("Expected raw present=%v, got %v", tt.expectRaw, ok)
+			}
+			if tt.expectRaw && string(raw) != rawData {
+				t.Errorf("Expected raw %s, got %s", rawData, raw)
+			}
+		})
+	}
+
+	// Invalid flag values are rejected
+	req := httptest.NewRequest("GET", "/api/subjects?include_raw=maybe", nil)
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid include_raw, got %d", w.Code)
+	}
+}
+
+func TestGetSubjectsSparseFields(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	subjects := []domain.Subject{
+		{ID: 1, Object: "kanji", URL: "https://api.wanikani.com/v2/subjects/1", DataUpdatedAt: time.Now(), Data: domain.SubjectData{
+			Level:      1,
+			Characters: "一",
+			Meanings:   []domain.Meaning{{Meaning: "Uno", Primary: false}, {Meaning: "One", Primary: true}},
+		}},
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/subjects?fields=id,characters,primary_meaning", nil)
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var projected []map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&projected); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(projected) != 1 {
+		t.Fatalf("Expected 1 subject, got %d", len(projected))
+	}
+
+	want := map[string]interface{}{"id": float64(1), "characters": "一", "primary_meaning": "One"}
+	if len(projected[0]) != len(want) {
+		t.Errorf("Expected only fields %v, got %v", want, projected[0])
+	}
+	for field, value := range want {
+		if projected[0][field] != value {
+			t.Errorf("Expected %s to be %v, got %v", field, value, projected[0][field])
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/api/subjects?fields=id,password", nil)
+	w = httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown field, got %d", w.Code)
+	}
+}
+
+func TestMaxResultRowsTruncation(t *testing.T) {
+	_, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	var subjects []domain.Subject
+	var assignments []domain.Assignment
+	for id := int64(1); id <= 3; id++ {
+		subjects = append(subjects, domain.Subject{ID: id, Object: "kanji", URL: "https://api.wanikani.com/v2/subjects/" + strconv.FormatInt(id, 10), DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}})
+		assignments = append(assignments, domain.Assignment{ID: id, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: id}})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("Failed to insert test assignments: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		maxRows       int
+		expectedCount int
+		truncated     bool
+	}{
+		{name: "over the cap", maxRows: 2, expectedCount: 2, truncated: true},
+		{name: "exactly the cap", maxRows: 3, expectedCount: 3, truncated: false},
+		{name: "unlimited", maxRows: 0, expectedCount: 3, truncated: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(store, &mockSyncService{}, 8080, "", testLogger(), WithMaxResultRows(tt.maxRows))
+
+			for _, path := range []string{"/api/subjects", "/api/assignments"} {
+				req := httptest.NewRequest("GET", path, nil)
+				w := httptest.NewRecorder()
+				server.getRouter().ServeHTTP(w, req)
+
+				if w.Code != http.StatusOK {
+					t.Fatalf("%s: expected status 200, got %d", path, w.Code)
+				}
+
+				var results []map[string]json.RawMessage
+				if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+					t.Fatalf("%s: failed to decode response: %v", path, err)
+				}
+				if len(results) != tt.expectedCount {
+					t.Errorf("%s: expected %d results, got %d", path, tt.expectedCount, len(results))
+				}
+
+				header := w.Header().Get("X-Result-Truncated")
+				if tt.truncated && header != "true" {
+					t.Errorf("%s: expected X-Result-Truncated: true, got %q", path, header)
+				}
+				if !tt.truncated && header != "" {
+					t.Errorf("%s: expected no X-Result-Truncated header, got %q", path, header)
+				}
+			}
+		})
+	}
+}
+
+func TestGetSubjectsStream(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	var subjects []domain.Subject
+	for i := 1; i <= 5; i++ {
+		subjects = append(subjects, domain.Subject{ID: int64(i), Object: "kanji", URL: fmt.Sprintf("https://api.wanikani.com/v2/subjects/%d", i), DataUpdatedAt: time.Now(), Data: domain.SubjectData{
+			Level:          6 - i,
+			LessonPosition: i,
+			Characters:     "<字>",
+			Meanings:       []domain.Meaning{{Meaning: fmt.Sprintf("Meaning %d", i), Primary: true}},
+		}})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+
+	get := func(server *Server, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/subjects?"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", query, w.Code)
+		}
+		return w
+	}
+
+	// The streamed body is byte-for-byte the buffered one
+	for _, query := range []string{"", "sort=position", "level_max=2", "level=60", "fields=id,characters,primary_meaning", "include_raw=true", "pretty=true", "level=60&pretty=true", "fields=id,characters&pretty=true"} {
+		buffered := get(server, query).Body.String()
+		if strings.HasPrefix(query, "level=60") && buffered != "[]\n" {
+			t.Errorf("%q: expected an empty result to be encoded as [], got %q", query, buffered)
+		}
+		if streamed := get(server, query+"&stream=true").Body.String(); streamed != buffered {
+			t.Errorf("%q: expected streamed output\n%s\nto equal buffered output\n%s", query, streamed, buffered)
+		}
+	}
+
+	// Truncation is reported in a trailer, since the header is sent before the end is known
+	limited := NewServer(store, &mockSyncService{}, 8080, "", testLogger(), WithMaxResultRows(2))
+	w := get(limited, "stream=true")
+	var streamed []domain.Subject
+	if err := json.Unmarshal(w.Body.Bytes(), &streamed); err != nil {
+		t.Fatalf("Failed to decode streamed response: %v", err)
+	}
+	if len(streamed) != 2 {
+		t.Errorf("Expected 2 streamed subjects, got %d", len(streamed))
+	}
+	if trailer := w.Result().Trailer.Get("X-Result-Truncated"); trailer != "true" {
+		t.Errorf("Expected X-Result-Truncated trailer, got %q", trailer)
+	}
+
+	w = httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/subjects?stream=maybe", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid stream flag, got %d", w.Code)
+	}
+}
+
+func TestGetSubjectsAnswerFields(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// A vocabulary subject in WaniKani's shape: readings without a type, an unaccepted
+	// alternative meaning, and both kinds of auxiliary meaning
+	var subject domain.Subject
+	if err := json.Unmarshal([]byte(`{
+		"id": 2467, "object": "vocabulary", "url": "https://api.wanikani.com/v2/subjects/2467",
+		"data_updated_at": "2024-01-01T00:00:00Z",
+		"data": {
+			"level": 1, "characters": "一",
+			"meanings": [
+				{"meaning": "One", "primary": true, "accepted_answer": true},
+				{"meaning": "Uno", "primary": false, "accepted_answer": false}
+			],
+			"auxiliary_meanings": [
+				{"meaning": "1", "type": "whitelist"},
+				{"meaning": "First", "type": "blacklist"}
+			],
+			"readings": [
+				{"reading": "いち", "primary": true, "accepted_answer": true}
+			]
+		}
+	}`), &subject); err != nil {
+		t.Fatalf("Failed to decode test subject: %v", err)
+	}
+
+	if err := store.UpsertSubjects(ctx, []domain.Subject{subject}); err != nil {
+		t.Fatalf("Failed to insert test subject: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/subjects", nil)
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var subjects []struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&subjects); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(subjects) != 1 {
+		t.Fatalf("Expected 1 subject, got %d", len(subjects))
+	}
+
+	expected := map[string]string{
+		"meanings":           `[{"meaning":"One","primary":true,"accepted_answer":true},{"meaning":"Uno","primary":false,"accepted_answer":false}]`,
+		"auxiliary_meanings": `[{"meaning":"1","type":"whitelist"},{"meaning":"First","type":"blacklist"}]`,
+		"readings":           `[{"reading":"いち","primary":true,"accepted_answer":true}]`,
+	}
+	for field, want := range expected {
+		if got := string(subjects[0].Data[field]); got != want {
+			t.Errorf("Expected %s %s, got %s", field, want, got)
+		}
+	}
+}
+
 func TestGetSubjectsWithFilters(t *testing.T) {
 	server, store := setupTestServer(t)
 	defer store.Close()
@@ -194,6 +517,83 @@ func TestGetSubjectsWithFilters(t *testing.T) {
 	}
 }
 
+func TestSearchSubjects(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	subject := func(id int64, level int, characters, primary string, others ...string) domain.Subject {
+		meanings := []domain.Meaning{{Meaning: primary, Primary: true}}
+		for _, other := range others {
+			meanings = append(meanings, domain.Meaning{Meaning: other})
+		}
+		return domain.Subject{
+			ID:            id,
+			Object:        "vocabulary",
+			DataUpdatedAt: time.Now(),
+			Data:          domain.SubjectData{Level: level, Characters: characters, Meanings: meanings},
+		}
+	}
+	subjects := []domain.Subject{
+		subject(1, 10, "大きめ", "Bigger"),              // prefix
+		subject(2, 3, "大", "Big"),                    // exact primary meaning
+		subject(3, 1, "巨大", "Very Big"),              // substring
+		subject(4, 2, "大物", "Bigwig", "Big Shot"),    // prefix, lower level than 1
+		subject(5, 1, "小", "Small"),                  // reading only
+		subject(6, 4, "大人", "Adult", "Grown Up Big"), // substring, higher level than 3
+	}
+	subjects[4].Data.Readings = []domain.Reading{{Reading: "しょう", Primary: true}}
+	if err := store.UpsertSubjects(context.Background(), subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+
+	search := func(query string) []SubjectSearchResult {
+		t.Helper()
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/subjects/search?q="+url.QueryEscape(query), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %q, got %d: %s", query, w.Code, w.Body.String())
+		}
+		var results []SubjectSearchResult
+		if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return results
+	}
+
+	tests := []struct {
+		query    string
+		expected [][2]int64 // subject ID, score
+	}{
+		{"big", [][2]int64{
+			{2, domain.SearchScorePrimaryMeaning},
+			{4, domain.SearchScorePrefix},
+			{1, domain.SearchScorePrefix},
+			{3, domain.SearchScoreSubstring},
+			{6, domain.SearchScoreSubstring},
+		}},
+		{"大", [][2]int64{{2, domain.SearchScoreCharacters}}},
+		{"しょ", [][2]int64{{5, domain.SearchScorePrefix}}},
+		{"nothing", [][2]int64{}},
+	}
+
+	for _, tt := range tests {
+		results := search(tt.query)
+		got := make([][2]int64, len(results))
+		for i, result := range results {
+			got[i] = [2]int64{result.Subject.ID, int64(result.Score)}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%q: expected [id score] %v, got %v", tt.query, tt.expected, got)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/subjects/search?q=+", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a blank query, got %d", w.Code)
+	}
+}
+
 func TestGetSubjectsInvalidLevel(t *testing.T) {
 	server, store := setupTestServer(t)
 	defer store.Close()
@@ -290,6 +690,348 @@ func TestGetAssignments(t *testing.T) {
 	if assignments[0].Subject.ID != 1 {
 		t.Errorf("Expected subject ID 1, got %d", assignments[0].Subject.ID)
 	}
+
+	if assignments[0].LastReviewedAt != nil {
+		t.Errorf("Expected no last review before any review, got %v", assignments[0].LastReviewedAt)
+	}
+
+	// The latest of the subject's reviews is reported
+	reviewedAt := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	if err := store.UpsertReviews(ctx, []domain.Review{
+		{ID: 1, Object: "review", DataUpdatedAt: reviewedAt, Data: domain.ReviewData{AssignmentID: 1, SubjectID: 1, CreatedAt: reviewedAt}},
+		{ID: 2, Object: "review", DataUpdatedAt: reviewedAt, Data: domain.ReviewData{AssignmentID: 1, SubjectID: 1, CreatedAt: reviewedAt.Add(-24 * time.Hour)}},
+	}); err != nil {
+		t.Fatalf("Failed to insert test reviews: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/assignments", nil))
+	assignments = nil
+	if err := json.NewDecoder(w.Body).Decode(&assignments); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(assignments) != 1 || assignments[0].LastReviewedAt == nil || !assignments[0].LastReviewedAt.Equal(reviewedAt) {
+		t.Errorf("Expected last review at %v, got %+v", reviewedAt, assignments)
+	}
+}
+
+func TestGetPassedAssignments(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	onDay := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	nextDay := onDay.AddDate(0, 0, 1)
+	subjects := []domain.Subject{
+		{ID: 1, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1, Characters: "一"}},
+		{ID: 2, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1, Characters: "二"}},
+		{ID: 3, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1, Characters: "三"}},
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+	assignments := []domain.Assignment{
+		{ID: 1, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 1, SRSStage: 5, PassedAt: &onDay}},
+		{ID: 2, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 2, SRSStage: 5, PassedAt: &nextDay}},
+		{ID: 3, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 3, SRSStage: 2}},
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("Failed to insert test assignments: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/assignments/passed?date=2024-03-10", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var passed []AssignmentWithSubject
+	if err := json.NewDecoder(w.Body).Decode(&passed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(passed) != 1 || passed[0].ID != 1 {
+		t.Fatalf("Expected only assignment 1 passed on 2024-03-10, got %+v", passed)
+	}
+	if passed[0].Subject == nil || passed[0].Subject.Data.Characters != "一" {
+		t.Errorf("Expected subject 一 to be joined, got %+v", passed[0].Subject)
+	}
+
+	for _, query := range []string{"", "?date=2024-3-10", "?date=yesterday"} {
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/assignments/passed"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestGetAvailableLessons(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	unlocked := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	started := unlocked.Add(time.Hour)
+	subjects := []domain.Subject{
+		{ID: 1, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1, Characters: "一", LessonPosition: 1}},
+		{ID: 2, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1, Characters: "二", LessonPosition: 0}},
+		{ID: 3, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1, Characters: "三", LessonPosition: 2}},
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+	assignments := []domain.Assignment{
+		{ID: 1, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 1, UnlockedAt: &unlocked}},
+		{ID: 2, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 2, UnlockedAt: &unlocked}},
+		{ID: 3, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 3, SRSStage: 1, UnlockedAt: &unlocked, StartedAt: &started}},
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("Failed to insert test assignments: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/lessons/available", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var lessons []AssignmentWithSubject
+	if err := json.NewDecoder(w.Body).Decode(&lessons); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(lessons) != 2 || lessons[0].ID != 2 || lessons[1].ID != 1 {
+		t.Fatalf("Expected assignments 2 and 1 in lesson order, got %+v", lessons)
+	}
+	if lessons[0].Subject == nil || lessons[0].Subject.Data.Characters != "二" {
+		t.Errorf("Expected subject 二 to be joined, got %+v", lessons[0].Subject)
+	}
+}
+
+// seedReviews stores n subjects, each with one assignment and one review
+func seedReviews(t testing.TB, store *sqlite.Store, n int) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	subjects := make([]domain.Subject, n)
+	assignments := make([]domain.Assignment, n)
+	reviews := make([]domain.Review, n)
+	for i := 0; i < n; i++ {
+		id := int64(i + 1)
+		subjects[i] = domain.Subject{ID: id, Object: "kanji", DataUpdatedAt: now, Data: domain.SubjectData{Level: 1, Characters: "字"}}
+		assignments[i] = domain.Assignment{ID: id, Object: "assignment", DataUpdatedAt: now, Data: domain.AssignmentData{SubjectID: id, SRSStage: 1}}
+		reviews[i] = domain.Review{ID: id, Object: "review", DataUpdatedAt: now, Data: domain.ReviewData{AssignmentID: id, SubjectID: id, CreatedAt: now}}
+	}
+
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("Failed to insert test assignments: %v", err)
+	}
+	if err := store.UpsertReviews(ctx, reviews); err != nil {
+		t.Fatalf("Failed to insert test reviews: %v", err)
+	}
+}
+
+func TestGetReviewsByPassed(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+	seedReviews(t, store, 4)
+
+	// Reviews 2 and 3 had mistakes
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	failed := []domain.Review{
+		{ID: 2, Object: "review", DataUpdatedAt: now, Data: domain.ReviewData{AssignmentID: 2, SubjectID: 2, CreatedAt: now, IncorrectMeaningAnswers: 1}},
+		{ID: 3, Object: "review", DataUpdatedAt: now, Data: domain.ReviewData{AssignmentID: 3, SubjectID: 3, CreatedAt: now, IncorrectReadingAnswers: 1}},
+	}
+	if err := store.UpsertReviews(context.Background(), failed); err != nil {
+		t.Fatalf("Failed to update test reviews: %v", err)
+	}
+
+	tests := []struct {
+		query       string
+		expectedIDs string
+	}{
+		{"", "[1 2 3 4]"},
+		{"&passed=true", "[1 4]"},
+		{"&passed=false", "[2 3]"},
+		{"&passed=false&from=2024-01-16", "[]"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/reviews?details=false"+tt.query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d", tt.query, w.Code)
+		}
+
+		var reviews []domain.Review
+		if err := json.NewDecoder(w.Body).Decode(&reviews); err != nil {
+			t.Fatalf("%q: failed to decode response: %v", tt.query, err)
+		}
+		ids := []int64{}
+		for _, r := range reviews {
+			ids = append(ids, r.ID)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		if fmt.Sprint(ids) != tt.expectedIDs {
+			t.Errorf("%q: expected reviews %s, got %v", tt.query, tt.expectedIDs, ids)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/reviews?passed=maybe", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid passed value, got %d", w.Code)
+	}
+}
+
+func TestGetReviewsWithoutDetails(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+	seedReviews(t, store, 3)
+
+	tests := []struct {
+		query         string
+		expectDetails bool
+	}{
+		{query: "", expectDetails: true},
+		{query: "?details=true", expectDetails: true},
+		{query: "?details=false", expectDetails: false},
+	}
+
+	for _, tt := range tests {
+		t.Run("reviews"+tt.query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/reviews"+tt.query, nil)
+			w := httptest.NewRecorder()
+			server.getRouter().ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+
+			var results []map[string]json.RawMessage
+			if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+				t.Fatalf("Failed to decode response as an array: %v", err)
+			}
+			if len(results) != 3 {
+				t.Fatalf("Expected 3 reviews, got %d", len(results))
+			}
+
+			for _, review := range results {
+				if _, ok := review["data"]; !ok {
+					t.Error("Expected review data in every response")
+				}
+				_, hasAssignment := review["assignment"]
+				_, hasSubject := review["subject"]
+				if hasAssignment != tt.expectDetails || hasSubject != tt.expectDetails {
+					t.Errorf("Expected assignment/subject present=%v, got assignment=%v subject=%v",
+						tt.expectDetails, hasAssignment, hasSubject)
+				}
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/api/reviews?details=maybe", nil)
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid details, got %d", w.Code)
+	}
+}
+
+func TestGetReviewsWithDetailsJoin(t *testing.T) {
+	_, store := setupTestServer(t)
+	defer store.Close()
+	ctx := context.Background()
+
+	cached := NewService(store, &mockSyncService{})
+	cached.subjectCache = NewSubjectCache(store)
+	services := map[string]*Service{
+		"uncached": NewService(store, &mockSyncService{}),
+		"cached":   cached,
+	}
+
+	// 800 reviews need more than one ID lookup query; 1200 exceed maxTargetedLookupIDs,
+	// so the whole tables are loaded instead
+	for _, n := range []int{800, 1200} {
+		seedReviews(t, store, n)
+
+		// Build the expected join from full loads
+		assignments, err := store.GetAssignments(ctx, domain.AssignmentFilters{})
+		if err != nil {
+			t.Fatalf("Failed to load assignments: %v", err)
+		}
+		subjects, err := store.GetSubjects(ctx, domain.SubjectFilters{})
+		if err != nil {
+			t.Fatalf("Failed to load subjects: %v", err)
+		}
+		assignmentMap := make(map[int64]*domain.Assignment)
+		for i := range assignments {
+			assignmentMap[assignments[i].ID] = &assignments[i]
+		}
+		subjectMap := make(map[int64]*domain.Subject)
+		for i := range subjects {
+			subjectMap[subjects[i].ID] = &subjects[i]
+		}
+
+		for name, service := range services {
+			if service.subjectCache != nil {
+				service.subjectCache.Invalidate()
+			}
+
+			results, _, err := service.GetReviewsWithDetails(ctx, domain.ReviewFilters{})
+			if err != nil {
+				t.Fatalf("%s/%d: failed to get reviews: %v", name, n, err)
+			}
+			if len(results) != n {
+				t.Fatalf("%s/%d: expected %d reviews, got %d", name, n, n, len(results))
+			}
+
+			for _, result := range results {
+				want, got := assignmentMap[result.Data.AssignmentID], result.Assignment
+				if got == nil || !reflect.DeepEqual(*got, *want) {
+					t.Fatalf("%s/%d: review %d: expected assignment %+v, got %+v", name, n, result.ID, want, got)
+				}
+				wantSubject, gotSubject := subjectMap[result.Data.SubjectID], result.Subject
+				if gotSubject == nil || !reflect.DeepEqual(*gotSubject, *wantSubject) {
+					t.Fatalf("%s/%d: review %d: expected subject %+v, got %+v", name, n, result.ID, wantSubject, gotSubject)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkGetReviewsDetails(b *testing.B) {
+	server, store := setupTestServer(b)
+	defer store.Close()
+	seedReviews(b, store, 2000)
+
+	// A few reviews on a later day, so a narrow range joins a small part of the data
+	later := time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)
+	recent := make([]domain.Review, 10)
+	for i := range recent {
+		id := int64(i + 1)
+		recent[i] = domain.Review{ID: 2000 + id, Object: "review", DataUpdatedAt: later, Data: domain.ReviewData{AssignmentID: id, SubjectID: id, CreatedAt: later}}
+	}
+	if err := store.UpsertReviews(context.Background(), recent); err != nil {
+		b.Fatalf("Failed to insert test reviews: %v", err)
+	}
+
+	for _, query := range []string{"?details=true", "?details=false", "?details=true&from=2024-02-01"} {
+		b.Run(query, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest("GET", "/api/reviews"+query, nil)
+				w := httptest.NewRecorder()
+				server.getRouter().ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					b.Fatalf("Expected status 200, got %d", w.Code)
+				}
+			}
+		})
+	}
 }
 
 func TestGetLatestStatistics(t *testing.T) {
@@ -307,7 +1049,7 @@ func TestGetLatestStatistics(t *testing.T) {
 			Lessons: []domain.LessonStatistics{
 				{
 					AvailableAt: time.Now(),
-					SubjectIDs:  []int{1, 2, 3},
+					SubjectIDs:  []int64{1, 2, 3},
 				},
 			},
 		},
@@ -337,28 +1079,839 @@ func TestGetLatestStatistics(t *testing.T) {
 	}
 }
 
-func TestGetSyncStatus(t *testing.T) {
+func TestGetAvailableReviewCount(t *testing.T) {
 	server, store := setupTestServer(t)
 	defer store.Close()
 
-	// Test GET /api/sync/status
-	req := httptest.NewRequest("GET", "/api/sync/status", nil)
-	w := httptest.NewRecorder()
-	server.getRouter().ServeHTTP(w, req)
-
-	if w.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", w.Code)
-	}
-
-	var status SyncStatusResponse
-	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
-		t.Fatalf("Failed to decode response: %v", err)
+	now := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	server.handler.service.now = func() time.Time { return now }
+
+	get := func() AvailableReviewCount {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/reviews/available/count", nil)
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != "private, max-age=30" {
+			t.Errorf("Expected short Cache-Control, got %q", cc)
+		}
+
+		var count AvailableReviewCount
+		if err := json.NewDecoder(w.Body).Decode(&count); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return count
 	}
 
-	if status.Syncing {
-		t.Error("Expected syncing to be false initially")
+	// No snapshot yet
+	if count := get(); count.Count != 0 || !count.AsOf.Equal(now) {
+		t.Errorf("Expected count 0 as of %v without a snapshot, got %+v", now, count)
 	}
-}
+
+	stats := domain.Statistics{
+		Object:        "report",
+		DataUpdatedAt: now.Add(-time.Hour),
+		Data: domain.StatisticsData{
+			Reviews: []domain.ReviewStatistics{
+				{AvailableAt: now.Add(-time.Hour), SubjectIDs: []int64{1, 2, 3}},
+				{AvailableAt: now, SubjectIDs: []int64{4}},
+				{AvailableAt: now.Add(time.Hour), SubjectIDs: []int64{5, 6}},
+			},
+		},
+	}
+	if err := store.InsertStatistics(context.Background(), stats, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to insert test statistics: %v", err)
+	}
+
+	// Buckets available now or earlier count; the future bucket doesn't
+	if count := get(); count.Count != 4 {
+		t.Errorf("Expected 4 available reviews, got %d", count.Count)
+	}
+}
+
+func TestHealthAndVersionReportInstanceName(t *testing.T) {
+	server := NewServer(&mockStore{}, &mockSyncService{}, 8080, "secret", testLogger(), WithInstanceName("tokyo-1"))
+
+	// Neither endpoint requires authentication
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var health HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&health); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if health.Status != "ok" || health.Instance != "tokyo-1" {
+		t.Errorf("Expected status ok for instance tokyo-1, got %+v", health)
+	}
+
+	w = httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/version", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var version VersionResponse
+	if err := json.NewDecoder(w.Body).Decode(&version); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if version.Instance != "tokyo-1" || version.Version == "" || version.GoVersion == "" {
+		t.Errorf("Expected version info for instance tokyo-1, got %+v", version)
+	}
+}
+
+func TestPublicEndpoints(t *testing.T) {
+	server := NewServer(&mockStore{}, &mockSyncService{}, 8080, "secret", testLogger(),
+		WithPublicEndpoints([]string{"/api/subjects", "/api/meta/srs-stages", "/api/sync/status"}))
+
+	tests := []struct {
+		path     string
+		token    string
+		expected int
+	}{
+		{"/api/subjects", "", http.StatusOK},
+		{"/api/subjects", "secret", http.StatusOK},
+		{"/api/meta/srs-stages", "", http.StatusOK},
+		{"/api/assignments", "", http.StatusUnauthorized},
+		{"/api/assignments", "secret", http.StatusOK},
+		// Sync endpoints cannot be made public
+		{"/api/sync/status", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, req)
+		if w.Code != tt.expected {
+			t.Errorf("GET %s with token %q: expected status %d, got %d", tt.path, tt.token, tt.expected, w.Code)
+		}
+	}
+}
+
+// writeSelfSignedCert writes a self-signed certificate for 127.0.0.1 and its key to dir,
+// returning the file paths and a pool that trusts the certificate
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string, pool *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+func TestServerTLS(t *testing.T) {
+	certFile, keyFile, pool := writeSelfSignedCert(t, t.TempDir())
+	server := NewServer(&mockStore{}, &mockSyncService{}, 0, "", testLogger(), WithTLS(certFile, keyFile))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- server.serve(listener) }()
+	defer func() {
+		server.Shutdown(context.Background())
+		if err := <-served; err != http.ErrServerClosed {
+			t.Errorf("Expected http.ErrServerClosed after shutdown, got %v", err)
+		}
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get("https://" + listener.Addr().String() + "/api/health")
+	if err != nil {
+		t.Fatalf("HTTPS request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2 over TLS, got %s", resp.Proto)
+	}
+	if resp.TLS == nil {
+		t.Error("Expected a TLS connection")
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	server := NewServer(&mockStore{}, &mockSyncService{}, 8080, "", testLogger(), WithInstanceName("tokyo-1"))
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"", `{"status":"ok","instance":"tokyo-1"}` + "\n"},
+		{"?pretty=false", `{"status":"ok","instance":"tokyo-1"}` + "\n"},
+		{"?pretty=true", "{\n  \"status\": \"ok\",\n  \"instance\": \"tokyo-1\"\n}\n"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/health"+tt.query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d", tt.query, w.Code)
+		}
+		if body := w.Body.String(); body != tt.expected {
+			t.Errorf("%q: expected body %q, got %q", tt.query, tt.expected, body)
+		}
+	}
+}
+
+func TestGetSubjectChanges(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	since := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	subjects := []domain.Subject{
+		{ID: 1, Object: "radical", DataUpdatedAt: since.Add(-time.Hour), Data: domain.SubjectData{Level: 1}},
+		{ID: 2, Object: "kanji", DataUpdatedAt: since, Data: domain.SubjectData{Level: 1}},
+		{ID: 3, Object: "kanji", DataUpdatedAt: since.Add(time.Hour), Data: domain.SubjectData{Level: 2}},
+		{ID: 4, Object: "vocabulary", DataUpdatedAt: since.Add(2 * time.Hour), Data: domain.SubjectData{Level: 2}},
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/subjects/changes"+query, nil))
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) SubjectChanges {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var changes SubjectChanges
+		if err := json.NewDecoder(w.Body).Decode(&changes); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return changes
+	}
+
+	for _, query := range []string{"", "?since=yesterday", "?since=2024-03-01"} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", query, w.Code)
+		}
+	}
+
+	// Only subjects updated strictly after since are returned; before any sync, the next
+	// poll starts from since
+	changes := decode(get("?since=" + url.QueryEscape(since.Format(time.RFC3339))))
+	var ids []int64
+	for _, subject := range changes.Subjects {
+		ids = append(ids, subject.ID)
+	}
+	if fmt.Sprint(ids) != "[3 4]" {
+		t.Errorf("Expected subjects [3 4], got %v", ids)
+	}
+	if !changes.NextSince.Equal(since) {
+		t.Errorf("Expected next_since %v before any sync, got %v", since, changes.NextSince)
+	}
+
+	// After a sync the next poll starts from when it started
+	syncedAt := since.Add(3 * time.Hour)
+	if err := store.SetLastSyncTime(ctx, domain.DataTypeSubjects, syncedAt); err != nil {
+		t.Fatalf("Failed to set sync time: %v", err)
+	}
+	changes = decode(get("?since=" + url.QueryEscape(syncedAt.Format(time.RFC3339))))
+	if len(changes.Subjects) != 0 {
+		t.Errorf("Expected no subjects changed since the sync, got %d", len(changes.Subjects))
+	}
+	if !changes.NextSince.Equal(syncedAt) {
+		t.Errorf("Expected next_since %v, got %v", syncedAt, changes.NextSince)
+	}
+}
+
+// TestGetSubjectChangesTruncated tests that truncated pages of changes are polled in
+// data_updated_at order without losing subjects that share a second across pages
+func TestGetSubjectChangesTruncated(t *testing.T) {
+	_, store := setupTestServer(t)
+	defer store.Close()
+	server := NewServer(store, &mockSyncService{}, 8080, "", testLogger(), WithMaxResultRows(2))
+
+	ctx := context.Background()
+	since := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	subjects := []domain.Subject{
+		{ID: 1, Object: "kanji", DataUpdatedAt: since.Add(3 * time.Hour), Data: domain.SubjectData{Level: 1}},
+		{ID: 2, Object: "kanji", DataUpdatedAt: since.Add(time.Hour), Data: domain.SubjectData{Level: 1}},
+		{ID: 3, Object: "kanji", DataUpdatedAt: since.Add(2 * time.Hour), Data: domain.SubjectData{Level: 1}},
+		{ID: 4, Object: "kanji", DataUpdatedAt: since.Add(2 * time.Hour), Data: domain.SubjectData{Level: 1}},
+		{ID: 5, Object: "kanji", DataUpdatedAt: since.Add(4 * time.Hour), Data: domain.SubjectData{Level: 1}},
+		{ID: 6, Object: "kanji", DataUpdatedAt: since.Add(-time.Hour), Data: domain.SubjectData{Level: 1}},
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+
+	// The first page ends inside the second shared by subjects 3 and 4, so it stops before
+	// them; the second page holds only that second and is returned whole
+	expected := []struct {
+		ids       string
+		truncated bool
+		nextSince time.Time
+	}{
+		{ids: "[2]", truncated: true, nextSince: since.Add(time.Hour)},
+		{ids: "[3 4]", truncated: true, nextSince: since.Add(2 * time.Hour)},
+		{ids: "[1 5]", truncated: false, nextSince: since.Add(2 * time.Hour)},
+	}
+
+	poll := since
+	for i, page := range expected {
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/subjects/changes?since="+url.QueryEscape(poll.Format(time.RFC3339)), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Poll %d: expected status 200, got %d: %s", i+1, w.Code, w.Body.String())
+		}
+		var changes SubjectChanges
+		if err := json.NewDecoder(w.Body).Decode(&changes); err != nil {
+			t.Fatalf("Poll %d: failed to decode response: %v", i+1, err)
+		}
+
+		var ids []int64
+		for _, subject := range changes.Subjects {
+			ids = append(ids, subject.ID)
+		}
+		if fmt.Sprint(ids) != page.ids {
+			t.Errorf("Poll %d: expected subjects %s, got %v", i+1, page.ids, ids)
+		}
+		if truncated := w.Header().Get("X-Result-Truncated") == "true"; truncated != page.truncated {
+			t.Errorf("Poll %d: expected truncated=%v, got %v", i+1, page.truncated, truncated)
+		}
+		if !changes.NextSince.Equal(page.nextSince) {
+			t.Errorf("Poll %d: expected next_since %v, got %v", i+1, page.nextSince, changes.NextSince)
+		}
+		poll = changes.NextSince
+	}
+}
+
+func TestGetSyncStatus(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	// Test GET /api/sync/status
+	req := httptest.NewRequest("GET", "/api/sync/status", nil)
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	var status SyncStatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if status.Syncing {
+		t.Error("Expected syncing to be false initially")
+	}
+	if status.StartedAt != nil {
+		t.Errorf("Expected no start time when not syncing, got %v", status.StartedAt)
+	}
+}
+
+func TestServerWithSyncDisabled(t *testing.T) {
+	_, store := setupTestServer(t)
+	defer store.Close()
+	seedReviews(t, store, 2)
+
+	// No sync service: stored data is served read-only
+	server := NewServer(store, nil, 8080, "", testLogger())
+	get := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	if w := get("GET", "/api/subjects"); w.Code != http.StatusOK {
+		t.Errorf("Expected stored subjects to be served, got %d", w.Code)
+	}
+
+	w := get("GET", "/api/sync/status")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for sync status, got %d", w.Code)
+	}
+	var status SyncStatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if status.Syncing || status.StartedAt != nil {
+		t.Errorf("Expected no sync running, got %+v", status)
+	}
+
+	w = get("POST", "/api/sync")
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 when triggering a sync, got %d", w.Code)
+	}
+	var errResp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if errResp.Error.Code != ErrCodeSyncDisabled {
+		t.Errorf("Expected error code %s, got %s", ErrCodeSyncDisabled, errResp.Error.Code)
+	}
+}
+
+func TestGetSyncTimestamps(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	subjectsSyncedAt := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	reviewsSyncedAt := time.Date(2024, 1, 15, 10, 31, 0, 0, time.UTC)
+	if err := store.SetLastSyncTime(context.Background(), domain.DataTypeSubjects, subjectsSyncedAt); err != nil {
+		t.Fatalf("Failed to set sync time: %v", err)
+	}
+	if err := store.SetLastSyncTime(context.Background(), domain.DataTypeReviews, reviewsSyncedAt); err != nil {
+		t.Fatalf("Failed to set sync time: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/sync/timestamps", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var timestamps map[string]*time.Time
+	if err := json.NewDecoder(w.Body).Decode(&timestamps); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(timestamps) != len(domain.DataTypes) {
+		t.Errorf("Expected %d data types, got %d: %v", len(domain.DataTypes), len(timestamps), timestamps)
+	}
+	for _, dataType := range domain.DataTypes {
+		ts, ok := timestamps[string(dataType)]
+		if !ok {
+			t.Errorf("Expected %s in response", dataType)
+			continue
+		}
+		switch dataType {
+		case domain.DataTypeSubjects:
+			if ts == nil || !ts.Equal(subjectsSyncedAt) {
+				t.Errorf("Expected subjects synced at %v, got %v", subjectsSyncedAt, ts)
+			}
+		case domain.DataTypeReviews:
+			if ts == nil || !ts.Equal(reviewsSyncedAt) {
+				t.Errorf("Expected reviews synced at %v, got %v", reviewsSyncedAt, ts)
+			}
+		default:
+			if ts != nil {
+				t.Errorf("Expected null for never-synced %s, got %v", dataType, ts)
+			}
+		}
+	}
+}
+
+func TestGetSRSStages(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	req := httptest.NewRequest("GET", "/api/meta/srs-stages", nil)
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var metadata SRSStageMetadata
+	if err := json.NewDecoder(w.Body).Decode(&metadata); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	expectedGroups := []string{
+		"initiate",
+		"apprentice", "apprentice", "apprentice", "apprentice",
+		"guru", "guru",
+		"master",
+		"enlightened",
+		"burned",
+	}
+	if len(metadata.Stages) != len(expectedGroups) {
+		t.Fatalf("Expected %d stages, got %d", len(expectedGroups), len(metadata.Stages))
+	}
+	for i, group := range expectedGroups {
+		stage := metadata.Stages[i]
+		if int(stage.Stage) != i {
+			t.Errorf("Stage %d: expected stage number %d, got %d", i, i, stage.Stage)
+		}
+		if stage.Group != group {
+			t.Errorf("Stage %d: expected group %q, got %q", i, group, stage.Group)
+		}
+		if stage.Name == "" {
+			t.Errorf("Stage %d: expected a display name", i)
+		}
+	}
+
+	expectedMembership := map[string][]int{
+		"initiate":    {0},
+		"apprentice":  {1, 2, 3, 4},
+		"guru":        {5, 6},
+		"master":      {7},
+		"enlightened": {8},
+		"burned":      {9},
+	}
+	if len(metadata.Groups) != len(expectedMembership) {
+		t.Fatalf("Expected %d groups, got %d", len(expectedMembership), len(metadata.Groups))
+	}
+	for _, group := range metadata.Groups {
+		expected, ok := expectedMembership[group.Name]
+		if !ok {
+			t.Errorf("Unexpected group %q", group.Name)
+			continue
+		}
+		if len(group.Stages) != len(expected) {
+			t.Errorf("Group %q: expected stages %v, got %v", group.Name, expected, group.Stages)
+			continue
+		}
+		for i, stage := range group.Stages {
+			if int(stage) != expected[i] {
+				t.Errorf("Group %q: expected stages %v, got %v", group.Name, expected, group.Stages)
+				break
+			}
+		}
+	}
+}
+
+func TestGetLevelProgress(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	subjects := []domain.Subject{
+		{ID: 1, Object: "kanji", URL: "https://api.wanikani.com/v2/subjects/1", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 3}},
+		{ID: 2, Object: "kanji", URL: "https://api.wanikani.com/v2/subjects/2", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 3}},
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+	unlockedAt := time.Now()
+	assignments := []domain.Assignment{
+		{ID: 1, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 1, SRSStage: 6, UnlockedAt: &unlockedAt}},
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("Failed to insert test assignments: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/levels/3/progress", nil)
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var progress domain.LevelProgress
+	if err := json.NewDecoder(w.Body).Decode(&progress); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	want := domain.LevelProgressCounts{Total: 2, Passed: 1, InProgress: 0, Locked: 1}
+	if progress.Level != 3 || progress.SubjectTypes["kanji"] != want {
+		t.Errorf("Expected level 3 kanji progress %+v, got %+v", want, progress)
+	}
+
+	for _, level := range []string{"0", "61", "abc"} {
+		req := httptest.NewRequest("GET", "/api/levels/"+level+"/progress", nil)
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for level %q, got %d", level, w.Code)
+		}
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	subjects := []domain.Subject{
+		{ID: 1, Object: "kanji", URL: "https://api.wanikani.com/v2/subjects/1", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1, Characters: "人"}},
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/admin/export", nil)
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Disposition"), "attachment;") {
+		t.Errorf("Expected attachment Content-Disposition, got %q", w.Header().Get("Content-Disposition"))
+	}
+	backup := w.Body.Bytes()
+
+	// Change the data, then restore the backup over it
+	subjects[0].Data.Characters = "大"
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to update test subject: %v", err)
+	}
+
+	req = httptest.NewRequest("POST", "/api/admin/import", bytes.NewReader(backup))
+	w = httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp ImportResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Counts["subjects"] != 1 {
+		t.Errorf("Expected 1 subject imported, got %d", resp.Counts["subjects"])
+	}
+
+	restored, err := store.GetSubjects(ctx, domain.SubjectFilters{})
+	if err != nil {
+		t.Fatalf("Failed to get subjects: %v", err)
+	}
+	if len(restored) != 1 || restored[0].Data.Characters != "人" {
+		t.Errorf("Expected subject to be restored from backup, got %+v", restored)
+	}
+
+	// A malformed document is rejected without touching the data
+	req = httptest.NewRequest("POST", "/api/admin/import", strings.NewReader(`{"version":1,"subjects":[`))
+	w = httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for malformed backup, got %d", w.Code)
+	}
+}
+
+func TestImportGzipBody(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+	seedReviews(t, store, 5)
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/admin/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	backup := w.Body.Bytes()
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(backup)
+	zw.Close()
+
+	post := func(body []byte, encoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/admin/import", bytes.NewReader(body))
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, req)
+		return w
+	}
+
+	for _, tt := range []struct {
+		name     string
+		body     []byte
+		encoding string
+	}{
+		{name: "plain", body: backup},
+		{name: "gzip", body: compressed.Bytes(), encoding: "gzip"},
+	} {
+		w := post(tt.body, tt.encoding)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", tt.name, w.Code, w.Body.String())
+		}
+		var resp ImportResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", tt.name, err)
+		}
+		if resp.Counts["reviews"] != 5 {
+			t.Errorf("%s: expected 5 reviews imported, got %d", tt.name, resp.Counts["reviews"])
+		}
+	}
+
+	if w := post(backup, "gzip"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a body that is not gzip, got %d", w.Code)
+	}
+	if w := post(backup, "br"); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status 415 for an unsupported encoding, got %d", w.Code)
+	}
+
+	// The limit applies to the decompressed size, not the much smaller gzip body
+	server.handler.maxImportBytes = int64(len(backup)) / 2
+	if int64(compressed.Len()) > server.handler.maxImportBytes {
+		t.Fatal("Expected the compressed backup to be smaller than the limit")
+	}
+	w = post(compressed.Bytes(), "gzip")
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status 413 for an oversized decompressed body, got %d: %s", w.Code, w.Body.String())
+	}
+
+	server.handler.maxImportBytes = int64(len(backup))
+	if w := post(compressed.Bytes(), "gzip"); w.Code != http.StatusOK {
+		t.Errorf("Expected a body of exactly the limit to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	for _, query := range []string{"", "?repair=true"} {
+		req := httptest.NewRequest("GET", "/api/admin/integrity"+query, nil)
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %q, got %d", query, w.Code)
+		}
+
+		var report domain.IntegrityReport
+		if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if report.OrphanedAssignments.Count != 0 || report.OrphanedReviews.Count != 0 {
+			t.Errorf("Expected no orphans in an empty database, got %+v", report)
+		}
+		if report.Repaired != (query != "") {
+			t.Errorf("Expected repaired=%v for %q, got %v", query != "", query, report.Repaired)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/admin/integrity?repair=maybe", nil)
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid repair flag, got %d", w.Code)
+	}
+}
+
+func TestResetSyncTimes(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	syncedAt := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	for _, dataType := range []domain.DataType{domain.DataTypeSubjects, domain.DataTypeAssignments, domain.DataTypeReviews} {
+		if err := store.SetLastSyncTime(ctx, dataType, syncedAt); err != nil {
+			t.Fatalf("Failed to set sync time: %v", err)
+		}
+	}
+
+	reset := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest("POST", "/api/admin/sync/reset-timestamps"+query, nil))
+		return w
+	}
+	cleared := func(w *httptest.ResponseRecorder) int {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp ResetSyncTimesResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp.Cleared
+	}
+
+	if w := reset("?type=kanji"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown data type, got %d", w.Code)
+	}
+
+	// Clearing one type leaves the others alone
+	if n := cleared(reset("?type=reviews")); n != 1 {
+		t.Errorf("Expected 1 timestamp cleared, got %d", n)
+	}
+	if ts, _ := store.GetLastSyncTime(ctx, domain.DataTypeReviews); ts != nil {
+		t.Errorf("Expected reviews sync time to be cleared, got %v", ts)
+	}
+	if ts, _ := store.GetLastSyncTime(ctx, domain.DataTypeSubjects); ts == nil {
+		t.Error("Expected subjects sync time to be kept")
+	}
+
+	// Without a type every timestamp is cleared
+	if n := cleared(reset("")); n != 2 {
+		t.Errorf("Expected 2 timestamps cleared, got %d", n)
+	}
+	for _, dataType := range domain.DataTypes {
+		if ts, _ := store.GetLastSyncTime(ctx, dataType); ts != nil {
+			t.Errorf("Expected %s sync time to be cleared, got %v", dataType, ts)
+		}
+	}
+}
+
+// syncingService reports a sync in progress
+type syncingService struct {
+	mockSyncService
+}
+
+func (m *syncingService) IsSyncing() bool {
+	return true
+}
+
+func TestResetSyncTimesDuringSync(t *testing.T) {
+	handler := NewHandler(NewService(&mockStore{}, &syncingService{}), testLogger())
+
+	w := httptest.NewRecorder()
+	handler.HandleResetSyncTimes(w, httptest.NewRequest("POST", "/api/admin/sync/reset-timestamps", nil))
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 while a sync is running, got %d", w.Code)
+	}
+}
 
 func TestInvalidDateFormat(t *testing.T) {
 	server, store := setupTestServer(t)
@@ -382,3 +1935,420 @@ func TestInvalidDateFormat(t *testing.T) {
 		t.Errorf("Expected error code VALIDATION_ERROR, got %s", errResp.Error.Code)
 	}
 }
+
+// seedAccuracyReviews stores subjects whose reviews give a mix of accuracies: subject 1
+// 100%, 2 66.67%, the radical 3 exactly 75% and 4 40%
+func seedAccuracyReviews(t *testing.T, store *sqlite.Store) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	objects := map[int64]string{1: "kanji", 2: "kanji", 3: "radical", 4: "vocabulary"}
+	var subjects []domain.Subject
+	var assignments []domain.Assignment
+	for id := int64(1); id <= 4; id++ {
+		subjects = append(subjects, domain.Subject{ID: id, Object: objects[id], DataUpdatedAt: now, Data: domain.SubjectData{Level: 1}})
+		assignments = append(assignments, domain.Assignment{ID: id, Object: "assignment", DataUpdatedAt: now, Data: domain.AssignmentData{SubjectID: id, SRSStage: 1}})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("Failed to insert test assignments: %v", err)
+	}
+
+	var reviews []domain.Review
+	review := func(subjectID int64, incorrectMeaning, incorrectReading int) {
+		reviews = append(reviews, domain.Review{ID: int64(len(reviews) + 1), Object: "review", DataUpdatedAt: now, Data: domain.ReviewData{
+			AssignmentID: subjectID, SubjectID: subjectID, CreatedAt: now,
+			IncorrectMeaningAnswers: incorrectMeaning, IncorrectReadingAnswers: incorrectReading,
+		}})
+	}
+	for i := 0; i < 4; i++ {
+		review(1, 0, 0) // 8 correct, 0 incorrect
+	}
+	review(2, 1, 0) // 4 correct, 2 incorrect
+	review(2, 0, 1)
+	review(3, 1, 0) // radicals have no reading: 3 correct, 1 incorrect
+	review(3, 0, 0)
+	review(3, 0, 0)
+	review(4, 0, 3) // 2 correct, 3 incorrect
+	if err := store.UpsertReviews(ctx, reviews); err != nil {
+		t.Fatalf("Failed to insert test reviews: %v", err)
+	}
+}
+
+func TestGetCriticalItems(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+	seedAccuracyReviews(t, store)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/reviews/critical"+query, nil))
+		return w
+	}
+
+	tests := []struct {
+		query    string
+		expected []CriticalItem
+	}{
+		// 75% is not below the default threshold of 75
+		{"", []CriticalItem{
+			{Subject: &domain.Subject{ID: 4}, Reviews: 1, IncorrectAnswers: 3, PercentageCorrect: 40},
+			{Subject: &domain.Subject{ID: 2}, Reviews: 2, IncorrectAnswers: 2, PercentageCorrect: 66.67},
+		}},
+		{"?threshold=80", []CriticalItem{
+			{Subject: &domain.Subject{ID: 4}, Reviews: 1, IncorrectAnswers: 3, PercentageCorrect: 40},
+			{Subject: &domain.Subject{ID: 2}, Reviews: 2, IncorrectAnswers: 2, PercentageCorrect: 66.67},
+			{Subject: &domain.Subject{ID: 3}, Reviews: 3, IncorrectAnswers: 1, PercentageCorrect: 75},
+		}},
+		{"?threshold=50", []CriticalItem{
+			{Subject: &domain.Subject{ID: 4}, Reviews: 1, IncorrectAnswers: 3, PercentageCorrect: 40},
+		}},
+		{"?threshold=0", []CriticalItem{}},
+	}
+
+	for _, tt := range tests {
+		w := get(tt.query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d: %s", tt.query, w.Code, w.Body.String())
+		}
+
+		var items []CriticalItem
+		if err := json.NewDecoder(w.Body).Decode(&items); err != nil {
+			t.Fatalf("%q: failed to decode response: %v", tt.query, err)
+		}
+		if items == nil {
+			t.Errorf("%q: expected an array, got null", tt.query)
+		}
+		if len(items) != len(tt.expected) {
+			t.Fatalf("%q: expected %d items, got %d: %+v", tt.query, len(tt.expected), len(items), items)
+		}
+		for i, item := range items {
+			want := tt.expected[i]
+			if item.Subject == nil || item.Subject.ID != want.Subject.ID || item.Subject.Data.Level != 1 {
+				t.Errorf("%q: item %d: expected subject %d with details, got %+v", tt.query, i, want.Subject.ID, item.Subject)
+				continue
+			}
+			if item.Reviews != want.Reviews || item.IncorrectAnswers != want.IncorrectAnswers || item.PercentageCorrect != want.PercentageCorrect {
+				t.Errorf("%q: item %d: expected %+v, got %+v", tt.query, i, want, item)
+			}
+		}
+	}
+
+	for _, query := range []string{"?threshold=-1", "?threshold=101", "?threshold=high"} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestGetWeeklyReviews(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+	seedReviews(t, store, 3) // all created on Monday 2024-01-15, ISO week 2024-W03
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/reviews/weekly?from=2024-01-03&to=2024-01-24", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var weekly WeeklyReviews
+	if err := json.NewDecoder(w.Body).Decode(&weekly); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// Weeks of the range without reviews are zero-filled
+	expected := []WeeklyReviewCount{
+		{Week: "2024-W01", WeekStart: "2024-01-01", Count: 0},
+		{Week: "2024-W02", WeekStart: "2024-01-08", Count: 0},
+		{Week: "2024-W03", WeekStart: "2024-01-15", Count: 3},
+		{Week: "2024-W04", WeekStart: "2024-01-22", Count: 0},
+	}
+	if fmt.Sprint(weekly.Weeks) != fmt.Sprint(expected) || weekly.Total != 3 {
+		t.Errorf("Expected %v with total 3, got %v with total %d", expected, weekly.Weeks, weekly.Total)
+	}
+
+	w = httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/reviews/weekly?from=2024-02-01&to=2024-01-01", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an inverted date range, got %d", w.Code)
+	}
+}
+
+func TestGetReviewDateRange(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/meta/review-range", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != `{"earliest":null,"latest":null}` {
+		t.Errorf("Expected null bounds without reviews, got %s", body)
+	}
+
+	seedReviews(t, store, 2) // all created at 2024-01-15T10:00:00Z
+
+	w = httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/meta/review-range", nil))
+	if body := strings.TrimSpace(w.Body.String()); body != `{"earliest":"2024-01-15T10:00:00Z","latest":"2024-01-15T10:00:00Z"}` {
+		t.Errorf("Expected the seeded review time as both bounds, got %s", body)
+	}
+}
+
+func TestGetReviewHours(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+	seedReviews(t, store, 3) // all created at 10:00 UTC on 2024-01-15
+
+	tests := []struct {
+		query         string
+		expectedTotal int
+	}{
+		{query: "", expectedTotal: 3},
+		{query: "?from=2024-01-15&to=2024-01-15", expectedTotal: 3},
+		{query: "?from=2024-01-16", expectedTotal: 0},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/stats/review-hours"+tt.query, nil)
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d", tt.query, w.Code)
+		}
+
+		var hours ReviewHours
+		if err := json.NewDecoder(w.Body).Decode(&hours); err != nil {
+			t.Fatalf("%q: failed to decode response: %v", tt.query, err)
+		}
+		if len(hours.Hours) != 24 {
+			t.Fatalf("%q: expected 24 zero-filled hours, got %d", tt.query, len(hours.Hours))
+		}
+		if hours.Total != tt.expectedTotal || hours.Hours[10].Count != tt.expectedTotal {
+			t.Errorf("%q: expected %d reviews at hour 10, got total %d and %+v", tt.query, tt.expectedTotal, hours.Total, hours.Hours[10])
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/stats/review-hours?from=2024-01-16&to=2024-01-15", nil)
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an inverted range, got %d", w.Code)
+	}
+}
+
+func TestDataSyncedAtHeader(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	endpoints := map[string]domain.DataType{
+		"/api/subjects":              domain.DataTypeSubjects,
+		"/api/assignments":           domain.DataTypeAssignments,
+		"/api/reviews":               domain.DataTypeReviews,
+		"/api/reviews?details=false": domain.DataTypeReviews,
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", path, w.Code)
+		}
+		return w
+	}
+
+	for path := range endpoints {
+		if header := get(path).Header().Get("X-Data-Synced-At"); header != "" {
+			t.Errorf("%s: expected no header before the first sync, got %q", path, header)
+		}
+	}
+
+	// Give each data type a distinct sync time
+	syncedAt := map[domain.DataType]time.Time{
+		domain.DataTypeSubjects:    time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		domain.DataTypeAssignments: time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC),
+		domain.DataTypeReviews:     time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+	}
+	for dataType, ts := range syncedAt {
+		if err := store.SetLastSyncTime(context.Background(), dataType, ts); err != nil {
+			t.Fatalf("Failed to set sync time: %v", err)
+		}
+	}
+
+	for path, dataType := range endpoints {
+		want := syncedAt[dataType].Format(time.RFC3339)
+		if header := get(path).Header().Get("X-Data-Synced-At"); header != want {
+			t.Errorf("%s: expected X-Data-Synced-At %q, got %q", path, want, header)
+		}
+	}
+}
+
+func TestGetSubjectCountsByLevel(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	subjects := []domain.Subject{
+		{ID: 1, Object: "radical", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}},
+		{ID: 2, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}},
+		{ID: 3, Object: "vocabulary", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 3}},
+	}
+	if err := store.UpsertSubjects(context.Background(), subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/stats/subjects-by-level", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var counts []LevelSubjectCounts
+	if err := json.NewDecoder(w.Body).Decode(&counts); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// Level 2 has no subjects but is zero-filled up to the highest synced level
+	expected := []LevelSubjectCounts{
+		{Level: 1, SubjectTypes: map[string]int{"radical": 1, "kanji": 1, "vocabulary": 0}, Total: 2},
+		{Level: 2, SubjectTypes: map[string]int{"radical": 0, "kanji": 0, "vocabulary": 0}, Total: 0},
+		{Level: 3, SubjectTypes: map[string]int{"radical": 0, "kanji": 0, "vocabulary": 1}, Total: 1},
+	}
+	if fmt.Sprint(counts) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, counts)
+	}
+}
+
+func TestGetLevels(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/meta/levels", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != `{"levels":[],"current_level":null}` {
+		t.Errorf("Expected empty levels and null current level, got %s", body)
+	}
+
+	subjects := []domain.Subject{
+		{ID: 1, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 12}},
+		{ID: 2, Object: "radical", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}},
+		{ID: 3, Object: "vocabulary", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 4}},
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+	unlockedAt := time.Now()
+	assignments := []domain.Assignment{
+		{ID: 1, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 3, SRSStage: 1, UnlockedAt: &unlockedAt}},
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("Failed to insert test assignments: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/meta/levels", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var metadata LevelsMetadata
+	if err := json.NewDecoder(w.Body).Decode(&metadata); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if fmt.Sprint(metadata.Levels) != "[1 4 12]" {
+		t.Errorf("Expected levels [1 4 12], got %v", metadata.Levels)
+	}
+	if metadata.CurrentLevel == nil || *metadata.CurrentLevel != 4 {
+		t.Errorf("Expected current level 4, got %v", metadata.CurrentLevel)
+	}
+}
+
+func TestGetProgressBreakdown(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	unlockedAt := time.Now()
+	subjects := make([]domain.Subject, 0, 6)
+	for id := int64(1); id <= 6; id++ {
+		subjects = append(subjects, domain.Subject{ID: id, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("Failed to insert test subjects: %v", err)
+	}
+
+	data := []domain.AssignmentData{
+		{SubjectID: 1, SRSStage: 0},                                                  // locked
+		{SubjectID: 2, SRSStage: 0, UnlockedAt: &unlockedAt},                         // lesson
+		{SubjectID: 3, SRSStage: 1, UnlockedAt: &unlockedAt, StartedAt: &unlockedAt}, // in progress
+		{SubjectID: 4, SRSStage: 8, UnlockedAt: &unlockedAt, StartedAt: &unlockedAt}, // in progress
+		{SubjectID: 5, SRSStage: 9, UnlockedAt: &unlockedAt, StartedAt: &unlockedAt}, // burned
+		{SubjectID: 6, SRSStage: 0, UnlockedAt: &unlockedAt},                         // lesson
+	}
+
+	assignments := make([]domain.Assignment, 0, len(data))
+	for i, d := range data {
+		assignments = append(assignments, domain.Assignment{ID: int64(i + 1), Object: "assignment", DataUpdatedAt: time.Now(), Data: d})
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("Failed to insert test assignments: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/stats/progress", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var breakdown ProgressBreakdown
+	if err := json.NewDecoder(w.Body).Decode(&breakdown); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if expected := (ProgressBreakdown{Lessons: 2, InProgress: 2, Burned: 1}); breakdown != expected {
+		t.Errorf("Expected %+v, got %+v", expected, breakdown)
+	}
+}
+
+func TestGetLatestStatisticsEmpty(t *testing.T) {
+	server, store := setupTestServer(t)
+	defer store.Close()
+
+	w := httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/statistics/latest", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404 with no snapshots, got %d", w.Code)
+	}
+	var errResp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if errResp.Error.Code != ErrCodeNotFound {
+		t.Errorf("Expected code %s, got %s", ErrCodeNotFound, errResp.Error.Code)
+	}
+
+	// Endpoints derived from the latest snapshot report no data rather than failing
+	w = httptest.NewRecorder()
+	server.getRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/reviews/available/count", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for the available review count, got %d", w.Code)
+	}
+	var count AvailableReviewCount
+	if err := json.NewDecoder(w.Body).Decode(&count); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if count.Count != 0 {
+		t.Errorf("Expected 0 available reviews without statistics, got %d", count.Count)
+	}
+}
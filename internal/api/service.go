@@ -2,7 +2,13 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"time"
 
 	"wanikani-api/internal/domain"
 )
@@ -11,54 +17,330 @@ import (
 type Service struct {
 	store       domain.DataStore
 	syncService domain.SyncService
+
+	// maxResultRows caps the rows returned by list endpoints; 0 means unlimited
+	maxResultRows int
+
+	// subjectCache, if set, serves the subject lookups used to join assignments and reviews
+	subjectCache *SubjectCache
+
+	// dailyReviewGoal is the number of reviews to aim for each day; 0 means no goal
+	dailyReviewGoal int
+
+	now func() time.Time
 }
 
-// NewService creates a new API service
+// NewService creates a new API service. syncService may be nil to serve stored data
+// without syncing.
 func NewService(store domain.DataStore, syncService domain.SyncService) *Service {
 	return &Service{
 		store:       store,
 		syncService: syncService,
+		now:         time.Now,
+	}
+}
+
+// resultLimit returns the store limit for a capped query: one row over the cap, so that
+// truncateResults can tell whether the cap was exceeded
+func (s *Service) resultLimit() int {
+	if s.maxResultRows <= 0 {
+		return 0
+	}
+	return s.maxResultRows + 1
+}
+
+// truncateResults cuts rows to max, reporting whether any were dropped. A max of zero or
+// less leaves rows untouched.
+func truncateResults[T any](rows []T, max int) ([]T, bool) {
+	if max <= 0 || len(rows) <= max {
+		return rows, false
+	}
+	return rows[:max], true
+}
+
+// maxTargetedLookupIDs is the most distinct IDs looked up by ID when joining details.
+// Beyond it, loading the whole table is faster than the batched IN (...) queries.
+const maxTargetedLookupIDs = 1000
+
+// subjectLookup returns the given subjects keyed by ID. With a subject cache the cached
+// map of all subjects is returned; otherwise only the given IDs are loaded, or every
+// subject if there are more than maxTargetedLookupIDs of them.
+func (s *Service) subjectLookup(ctx context.Context, ids []int64) (map[int64]*domain.Subject, error) {
+	if s.subjectCache != nil {
+		return s.subjectCache.Get(ctx)
+	}
+
+	var subjects []domain.Subject
+	var err error
+	if ids = uniqueIDs(ids); len(ids) > maxTargetedLookupIDs {
+		subjects, err = s.store.GetSubjects(ctx, domain.SubjectFilters{})
+	} else {
+		subjects, err = s.store.GetSubjectsByIDs(ctx, ids)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve subjects: %w", err)
+	}
+
+	subjectMap := make(map[int64]*domain.Subject, len(subjects))
+	for i := range subjects {
+		subjectMap[subjects[i].ID] = &subjects[i]
+	}
+	return subjectMap, nil
+}
+
+// assignmentLookup returns the given assignments keyed by ID, loading every assignment
+// instead if there are more than maxTargetedLookupIDs of them
+func (s *Service) assignmentLookup(ctx context.Context, ids []int64) (map[int64]*domain.Assignment, error) {
+	var assignments []domain.Assignment
+	var err error
+	if ids = uniqueIDs(ids); len(ids) > maxTargetedLookupIDs {
+		assignments, err = s.store.GetAssignments(ctx, domain.AssignmentFilters{})
+	} else {
+		assignments, err = s.store.GetAssignmentsByIDs(ctx, ids)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve assignments: %w", err)
+	}
+
+	assignmentMap := make(map[int64]*domain.Assignment, len(assignments))
+	for i := range assignments {
+		assignmentMap[assignments[i].ID] = &assignments[i]
+	}
+	return assignmentMap, nil
+}
+
+// uniqueIDs returns a sorted copy of ids with duplicates removed
+func uniqueIDs(ids []int64) []int64 {
+	unique := slices.Clone(ids)
+	slices.Sort(unique)
+	return slices.Compact(unique)
+}
+
+// invalidateSubjectCache drops cached subjects after an operation that may have changed them
+func (s *Service) invalidateSubjectCache() {
+	if s.subjectCache != nil {
+		s.subjectCache.Invalidate()
+	}
+}
+
+// GetLastSyncTime retrieves when a data type was last synced successfully, or nil if never
+func (s *Service) GetLastSyncTime(ctx context.Context, dataType domain.DataType) (*time.Time, error) {
+	return s.store.GetLastSyncTime(ctx, dataType)
+}
+
+// GetSyncTimestamps retrieves the last successful sync time of every data type, with nil
+// for types that have never been synced
+func (s *Service) GetSyncTimestamps(ctx context.Context) (map[domain.DataType]*time.Time, error) {
+	timestamps := make(map[domain.DataType]*time.Time, len(domain.DataTypes))
+	for _, dataType := range domain.DataTypes {
+		syncedAt, err := s.store.GetLastSyncTime(ctx, dataType)
+		if err != nil {
+			return nil, err
+		}
+		timestamps[dataType] = syncedAt
+	}
+	return timestamps, nil
+}
+
+// GetSubjects retrieves subjects with optional filters. truncated reports whether the
+// result was cut to the maximum result size.
+func (s *Service) GetSubjects(ctx context.Context, filters domain.SubjectFilters) (subjects []domain.Subject, truncated bool, err error) {
+	filters.Limit = s.resultLimit()
+	subjects, err = s.store.GetSubjects(ctx, filters)
+	if err != nil {
+		return nil, false, err
 	}
+	subjects, truncated = truncateResults(subjects, s.maxResultRows)
+	return subjects, truncated, nil
 }
 
-// GetSubjects retrieves subjects with optional filters
-func (s *Service) GetSubjects(ctx context.Context, filters domain.SubjectFilters) ([]domain.Subject, error) {
-	return s.store.GetSubjects(ctx, filters)
+// SubjectChanges is the subjects changed since a poll time
+type SubjectChanges struct {
+	Subjects []domain.Subject `json:"subjects"`
+	// NextSince is the since value for the next poll: the start of the last subjects
+	// sync, since every change made before it has been stored, or the data_updated_at
+	// of the last returned subject if the result was truncated
+	NextSince time.Time `json:"next_since"`
+}
+
+// GetSubjectChanges retrieves the subjects whose data_updated_at is after since, oldest
+// change first. truncated reports whether the result was cut to the maximum result size;
+// the remaining changes are then returned by polling again from NextSince.
+func (s *Service) GetSubjectChanges(ctx context.Context, since time.Time) (*SubjectChanges, bool, error) {
+	// Read the sync time first: a sync finishing during the query is then polled again
+	syncedAt, err := s.store.GetLastSyncTime(ctx, domain.DataTypeSubjects)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get subjects sync time: %w", err)
+	}
+
+	filters := domain.SubjectFilters{DataUpdatedAfter: &since, Sort: domain.SubjectSortDataUpdated}
+	subjects, truncated, err := s.GetSubjects(ctx, filters)
+	if err != nil {
+		return nil, false, err
+	}
+	if subjects == nil {
+		subjects = []domain.Subject{}
+	}
+
+	changes := &SubjectChanges{Subjects: subjects, NextSince: since.UTC()}
+	if truncated {
+		changes.Subjects, changes.NextSince = trimSubjectChanges(subjects)
+	} else if syncedAt != nil {
+		changes.NextSince = syncedAt.UTC()
+	}
+	return changes, truncated, nil
+}
+
+// trimSubjectChanges picks where the next poll resumes after a truncated page of changes
+// sorted by data_updated_at. data_updated_at is stored to the second and the next poll
+// only returns later changes, so the subjects sharing the last second are dropped and
+// returned again by that poll, unless the whole page shares it.
+func trimSubjectChanges(subjects []domain.Subject) ([]domain.Subject, time.Time) {
+	last := subjects[len(subjects)-1].DataUpdatedAt
+	end := len(subjects)
+	for end > 0 && subjects[end-1].DataUpdatedAt.Equal(last) {
+		end--
+	}
+	if end == 0 {
+		return subjects, last.UTC()
+	}
+	return subjects[:end], subjects[end-1].DataUpdatedAt.UTC()
+}
+
+// StreamSubjects calls fn with each subject matching filters, up to the maximum result
+// size, as it is read from the store. truncated reports whether more subjects matched.
+func (s *Service) StreamSubjects(ctx context.Context, filters domain.SubjectFilters, fn func(domain.Subject) error) (truncated bool, err error) {
+	filters.Limit = s.resultLimit()
+	streamed := 0
+	err = s.store.StreamSubjects(ctx, filters, func(subject domain.Subject) error {
+		if s.maxResultRows > 0 && streamed == s.maxResultRows {
+			truncated = true
+			return nil
+		}
+		streamed++
+		return fn(subject)
+	})
+	return truncated, err
+}
+
+// SubjectSearchResult is a subject matched by a search, with its relevance (one of the
+// domain.SearchScore tiers; higher is better)
+type SubjectSearchResult struct {
+	Subject domain.Subject `json:"subject"`
+	Score   int            `json:"score"`
+}
+
+// SearchSubjects ranks the subjects matching filters against query by relevance: exact
+// characters first, then exact primary meaning, then prefix and finally substring matches
+// of meanings and readings. Ties are ordered by level, then ID. truncated reports whether
+// the result was cut to the maximum result size.
+func (s *Service) SearchSubjects(ctx context.Context, query string, filters domain.SubjectFilters) ([]SubjectSearchResult, bool, error) {
+	// Ranking needs every candidate, so the result limit is applied after scoring
+	candidates, err := s.store.GetSubjects(ctx, filters)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to retrieve subjects: %w", err)
+	}
+
+	results := make([]SubjectSearchResult, 0)
+	for _, subject := range candidates {
+		if score := subject.Data.SearchScore(query); score > 0 {
+			results = append(results, SubjectSearchResult{Subject: subject, Score: score})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Subject.Data.Level != b.Subject.Data.Level {
+			return a.Subject.Data.Level < b.Subject.Data.Level
+		}
+		return a.Subject.ID < b.Subject.ID
+	})
+
+	results, truncated := truncateResults(results, s.maxResultRows)
+	return results, truncated, nil
 }
 
 // AssignmentWithSubject represents an assignment with its associated subject
 type AssignmentWithSubject struct {
 	domain.Assignment
-	Subject *domain.Subject `json:"subject"`
+	Subject        *domain.Subject `json:"subject"`
+	LastReviewedAt *time.Time      `json:"last_reviewed_at"` // nil if the subject was never reviewed
 }
 
-// GetAssignmentsWithSubjects retrieves assignments and joins them with their subjects
-func (s *Service) GetAssignmentsWithSubjects(ctx context.Context, filters domain.AssignmentFilters) ([]AssignmentWithSubject, error) {
+// GetAssignmentsWithSubjects retrieves assignments and joins them with their subjects.
+// truncated reports whether the result was cut to the maximum result size.
+func (s *Service) GetAssignmentsWithSubjects(ctx context.Context, filters domain.AssignmentFilters) ([]AssignmentWithSubject, bool, error) {
 	// Fetch assignments
+	filters.Limit = s.resultLimit()
 	assignments, err := s.store.GetAssignments(ctx, filters)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve assignments: %w", err)
+		return nil, false, fmt.Errorf("failed to retrieve assignments: %w", err)
 	}
+	assignments, truncated := truncateResults(assignments, s.maxResultRows)
 
-	// Fetch all subjects once
-	subjects, err := s.store.GetSubjects(ctx, domain.SubjectFilters{})
+	result, err := s.joinAssignments(ctx, assignments)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve subjects: %w", err)
+		return nil, false, err
 	}
 
-	// Create a map for quick lookup
-	subjectMap := make(map[int]*domain.Subject)
-	for i := range subjects {
-		subjectMap[subjects[i].ID] = &subjects[i]
+	return result, truncated, nil
+}
+
+// GetAssignmentsPassedOn retrieves the assignments passed on the given calendar day and
+// joins them with their subjects
+func (s *Service) GetAssignmentsPassedOn(ctx context.Context, date time.Time) ([]AssignmentWithSubject, error) {
+	assignments, err := s.store.GetAssignmentsPassedOn(ctx, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve passed assignments: %w", err)
+	}
+
+	return s.joinAssignments(ctx, assignments)
+}
+
+// GetAvailableLessons retrieves the assignments waiting in the lesson queue, joined with
+// their subjects and in lesson order
+func (s *Service) GetAvailableLessons(ctx context.Context) ([]AssignmentWithSubject, error) {
+	assignments, err := s.store.GetAvailableLessons(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve available lessons: %w", err)
+	}
+
+	return s.joinAssignments(ctx, assignments)
+}
+
+// joinAssignments pairs each assignment with its subject and the time of its last review
+func (s *Service) joinAssignments(ctx context.Context, assignments []domain.Assignment) ([]AssignmentWithSubject, error) {
+	subjectIDs := make([]int64, 0, len(assignments))
+	for _, assignment := range assignments {
+		subjectIDs = append(subjectIDs, assignment.Data.SubjectID)
+	}
+
+	// Look up the returned subjects once
+	subjectMap, err := s.subjectLookup(ctx, subjectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Look up the last review of every returned subject in one pass
+	lastReviews, err := s.store.GetLastReviewPerSubject(ctx, subjectIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve last reviews: %w", err)
 	}
 
 	// Join with subjects
 	result := make([]AssignmentWithSubject, 0, len(assignments))
 	for _, assignment := range assignments {
-		result = append(result, AssignmentWithSubject{
+		entry := AssignmentWithSubject{
 			Assignment: assignment,
 			Subject:    subjectMap[assignment.Data.SubjectID],
-		})
+		}
+		if lastReviewedAt, ok := lastReviews[assignment.Data.SubjectID]; ok {
+			entry.LastReviewedAt = &lastReviewedAt
+		}
+		result = append(result, entry)
 	}
 
 	return result, nil
@@ -71,34 +353,33 @@ type ReviewWithDetails struct {
 	Subject    *domain.Subject    `json:"subject"`
 }
 
-// GetReviewsWithDetails retrieves reviews and joins them with assignments and subjects
-func (s *Service) GetReviewsWithDetails(ctx context.Context, filters domain.ReviewFilters) ([]ReviewWithDetails, error) {
+// GetReviewsWithDetails retrieves reviews and joins them with assignments and subjects.
+// truncated reports whether the result was cut to the maximum result size.
+func (s *Service) GetReviewsWithDetails(ctx context.Context, filters domain.ReviewFilters) ([]ReviewWithDetails, bool, error) {
 	// Fetch reviews
+	filters.Limit = s.resultLimit()
 	reviews, err := s.store.GetReviews(ctx, filters)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve reviews: %w", err)
+		return nil, false, fmt.Errorf("failed to retrieve reviews: %w", err)
 	}
+	reviews, truncated := truncateResults(reviews, s.maxResultRows)
 
-	// Fetch all assignments and subjects once
-	assignments, err := s.store.GetAssignments(ctx, domain.AssignmentFilters{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve assignments: %w", err)
+	// Look up only the assignments and subjects the reviews refer to
+	assignmentIDs := make([]int64, 0, len(reviews))
+	subjectIDs := make([]int64, 0, len(reviews))
+	for _, review := range reviews {
+		assignmentIDs = append(assignmentIDs, review.Data.AssignmentID)
+		subjectIDs = append(subjectIDs, review.Data.SubjectID)
 	}
 
-	subjects, err := s.store.GetSubjects(ctx, domain.SubjectFilters{})
+	assignmentMap, err := s.assignmentLookup(ctx, assignmentIDs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve subjects: %w", err)
+		return nil, false, err
 	}
 
-	// Create maps for quick lookup
-	assignmentMap := make(map[int]*domain.Assignment)
-	for i := range assignments {
-		assignmentMap[assignments[i].ID] = &assignments[i]
-	}
-
-	subjectMap := make(map[int]*domain.Subject)
-	for i := range subjects {
-		subjectMap[subjects[i].ID] = &subjects[i]
+	subjectMap, err := s.subjectLookup(ctx, subjectIDs)
+	if err != nil {
+		return nil, false, err
 	}
 
 	// Join with assignments and subjects
@@ -111,7 +392,23 @@ func (s *Service) GetReviewsWithDetails(ctx context.Context, filters domain.Revi
 		})
 	}
 
-	return result, nil
+	return result, truncated, nil
+}
+
+// GetReviews retrieves reviews without joining them with assignments and subjects.
+// The bool result reports whether the rows were cut at the result cap.
+func (s *Service) GetReviews(ctx context.Context, filters domain.ReviewFilters) ([]domain.Review, bool, error) {
+	filters.Limit = s.resultLimit()
+	reviews, err := s.store.GetReviews(ctx, filters)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to retrieve reviews: %w", err)
+	}
+
+	reviews, truncated := truncateResults(reviews, s.maxResultRows)
+	if reviews == nil {
+		reviews = []domain.Review{}
+	}
+	return reviews, truncated, nil
 }
 
 // GetLatestStatistics retrieves the most recent statistics snapshot
@@ -120,23 +417,39 @@ func (s *Service) GetLatestStatistics(ctx context.Context) (*domain.StatisticsSn
 }
 
 // GetStatistics retrieves statistics snapshots within a date range
-func (s *Service) GetStatistics(ctx context.Context, dateRange *domain.DateRange) ([]domain.StatisticsSnapshot, error) {
-	return s.store.GetStatistics(ctx, dateRange)
+func (s *Service) GetStatistics(ctx context.Context, dateRange *domain.DateRange, opts domain.StatisticsOptions) ([]domain.StatisticsSnapshot, error) {
+	return s.store.GetStatistics(ctx, dateRange, opts)
 }
 
+// errSyncDisabled is returned when a sync is requested on a server without a sync service
+var errSyncDisabled = errors.New("syncing is disabled")
+
 // TriggerSync triggers a manual sync operation
 func (s *Service) TriggerSync(ctx context.Context) ([]domain.SyncResult, error) {
+	if s.syncService == nil {
+		return nil, errSyncDisabled
+	}
+
 	// Check if sync is already in progress
 	if s.syncService.IsSyncing() {
-		return nil, fmt.Errorf("sync already in progress")
+		return nil, domain.ErrSyncInProgress
 	}
 
+	defer s.invalidateSubjectCache()
 	return s.syncService.SyncAll(ctx)
 }
 
 // GetSyncStatus returns whether a sync is currently in progress
 func (s *Service) GetSyncStatus() bool {
-	return s.syncService.IsSyncing()
+	return s.syncService != nil && s.syncService.IsSyncing()
+}
+
+// GetSyncStartedAt returns when the sync in progress started, or nil if none is running
+func (s *Service) GetSyncStartedAt() *time.Time {
+	if s.syncService == nil {
+		return nil
+	}
+	return s.syncService.SyncStartedAt()
 }
 
 // GetAssignmentSnapshots retrieves assignment snapshots and transforms them into nested structure
@@ -180,3 +493,679 @@ func (s *Service) GetAssignmentSnapshots(ctx context.Context, dateRange *domain.
 
 	return result, nil
 }
+
+// OrderedSnapshot is an assignment snapshot for one date with its stage groups in SRS order
+type OrderedSnapshot struct {
+	Date   string             `json:"date"`
+	Stages []OrderedStageData `json:"stages"`
+}
+
+// OrderedStageData holds the per-subject-type counts for one SRS stage group
+type OrderedStageData struct {
+	Name   string         `json:"name"`
+	Counts map[string]int `json:"counts"` // subject type -> count
+	Total  int            `json:"total"`
+}
+
+// GetOrderedAssignmentSnapshots retrieves assignment snapshots as a list sorted by date
+// ascending, with stage groups in canonical SRS order and unrecognized groups last
+func (s *Service) GetOrderedAssignmentSnapshots(ctx context.Context, dateRange *domain.DateRange) ([]OrderedSnapshot, error) {
+	snapshots, err := s.GetAssignmentSnapshots(ctx, dateRange)
+	if err != nil {
+		return nil, err
+	}
+
+	stageRank := make(map[string]int, len(domain.SRSStageGroupOrder))
+	for i, name := range domain.SRSStageGroupOrder {
+		stageRank[name] = i
+	}
+	rank := func(name string) int {
+		if r, ok := stageRank[name]; ok {
+			return r
+		}
+		return len(stageRank)
+	}
+
+	result := make([]OrderedSnapshot, 0, len(snapshots))
+	for date, stages := range snapshots {
+		ordered := OrderedSnapshot{
+			Date:   date,
+			Stages: make([]OrderedStageData, 0, len(stages)),
+		}
+
+		for name, counts := range stages {
+			stage := OrderedStageData{
+				Name:   name,
+				Counts: make(map[string]int, len(counts)),
+				Total:  counts["total"],
+			}
+			for subjectType, count := range counts {
+				if subjectType != "total" {
+					stage.Counts[subjectType] = count
+				}
+			}
+			ordered.Stages = append(ordered.Stages, stage)
+		}
+
+		sort.Slice(ordered.Stages, func(i, j int) bool {
+			ri, rj := rank(ordered.Stages[i].Name), rank(ordered.Stages[j].Name)
+			if ri != rj {
+				return ri < rj
+			}
+			return ordered.Stages[i].Name < ordered.Stages[j].Name
+		})
+
+		result = append(result, ordered)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date < result[j].Date
+	})
+
+	return result, nil
+}
+
+// SnapshotDiff contains the change in assignment counts between two snapshot dates
+type SnapshotDiff struct {
+	From    string                    `json:"from"`
+	To      string                    `json:"to"`
+	Changes map[string]map[string]int `json:"changes"` // SRS stage name -> subject type -> delta
+	Daily   []DailySnapshotDiff       `json:"daily,omitempty"`
+}
+
+// DailySnapshotDiff contains the change since the previous available snapshot date
+type DailySnapshotDiff struct {
+	Date    string                    `json:"date"`
+	Changes map[string]map[string]int `json:"changes"`
+}
+
+// GetAssignmentSnapshotDiff computes the change in assignment counts between two dates.
+// Returns nil if either date has no snapshot. When daily is true, the change between
+// each consecutive pair of snapshot dates in the range is included as well.
+func (s *Service) GetAssignmentSnapshotDiff(ctx context.Context, from, to time.Time, daily bool) (*SnapshotDiff, error) {
+	snapshots, err := s.GetAssignmentSnapshots(ctx, &domain.DateRange{From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+
+	fromStr := from.Format("2006-01-02")
+	toStr := to.Format("2006-01-02")
+
+	if snapshots[fromStr] == nil || snapshots[toStr] == nil {
+		return nil, nil
+	}
+
+	diff := &SnapshotDiff{
+		From:    fromStr,
+		To:      toStr,
+		Changes: diffSnapshots(snapshots[fromStr], snapshots[toStr]),
+	}
+
+	if daily {
+		dates := make([]string, 0, len(snapshots))
+		for date := range snapshots {
+			dates = append(dates, date)
+		}
+		sort.Strings(dates)
+
+		diff.Daily = make([]DailySnapshotDiff, 0, len(dates))
+		for i := 1; i < len(dates); i++ {
+			diff.Daily = append(diff.Daily, DailySnapshotDiff{
+				Date:    dates[i],
+				Changes: diffSnapshots(snapshots[dates[i-1]], snapshots[dates[i]]),
+			})
+		}
+	}
+
+	return diff, nil
+}
+
+// diffSnapshots computes to - from for every SRS stage name and subject type present in
+// either day of the nested snapshot structure. Missing entries count as zero.
+func diffSnapshots(from, to map[string]map[string]int) map[string]map[string]int {
+	result := make(map[string]map[string]int)
+
+	for stageName, counts := range to {
+		if result[stageName] == nil {
+			result[stageName] = make(map[string]int)
+		}
+		for subjectType, count := range counts {
+			result[stageName][subjectType] += count
+		}
+	}
+
+	for stageName, counts := range from {
+		if result[stageName] == nil {
+			result[stageName] = make(map[string]int)
+		}
+		for subjectType, count := range counts {
+			result[stageName][subjectType] -= count
+		}
+	}
+
+	return result
+}
+
+// SeriesPoint is the assignment count of one stage group on one date
+type SeriesPoint struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// GetAssignmentSnapshotSeries retrieves assignment snapshot totals between from and to as
+// one date-sorted time series per SRS stage group, for charting
+func (s *Service) GetAssignmentSnapshotSeries(ctx context.Context, from, to time.Time) (map[string][]SeriesPoint, error) {
+	snapshots, err := s.GetAssignmentSnapshots(ctx, &domain.DateRange{From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+
+	return pivotSnapshotSeries(snapshots, from, to), nil
+}
+
+// pivotSnapshotSeries turns the date -> stage name -> subject type structure into a series
+// of totals per stage group with one point for every date from from to to. Every group in
+// SRSStageGroupOrder gets a series, and dates without a snapshot count as zero.
+func pivotSnapshotSeries(snapshots map[string]map[string]map[string]int, from, to time.Time) map[string][]SeriesPoint {
+	series := make(map[string][]SeriesPoint, len(domain.SRSStageGroupOrder))
+	for _, name := range domain.SRSStageGroupOrder {
+		series[name] = nil
+	}
+	for _, stages := range snapshots {
+		for name := range stages {
+			series[name] = nil
+		}
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		for name := range series {
+			series[name] = append(series[name], SeriesPoint{
+				Date:  date,
+				Count: snapshots[date][name]["total"],
+			})
+		}
+	}
+
+	for name, points := range series {
+		if points == nil {
+			series[name] = []SeriesPoint{}
+		}
+	}
+
+	return series
+}
+
+// SRSStageInfo describes a single SRS stage and the group it belongs to
+type SRSStageInfo struct {
+	Stage domain.SRSStage `json:"stage"`
+	Name  string          `json:"name"`
+	Group string          `json:"group"`
+}
+
+// SRSStageMetadata lists every SRS stage and the stage group definitions
+type SRSStageMetadata struct {
+	Stages []SRSStageInfo         `json:"stages"`
+	Groups []domain.SRSStageGroup `json:"groups"`
+}
+
+// GetSRSStageMetadata returns the SRS stages and groups as defined by the domain package
+func (s *Service) GetSRSStageMetadata() SRSStageMetadata {
+	stages := domain.SRSStages()
+	metadata := SRSStageMetadata{
+		Stages: make([]SRSStageInfo, 0, len(stages)),
+		Groups: domain.SRSStageGroups(),
+	}
+	for _, stage := range stages {
+		metadata.Stages = append(metadata.Stages, SRSStageInfo{
+			Stage: stage,
+			Name:  stage.Name(),
+			Group: stage.Group(),
+		})
+	}
+	return metadata
+}
+
+// LevelsMetadata lists the levels present in the synced data
+type LevelsMetadata struct {
+	Levels       []int `json:"levels"`
+	CurrentLevel *int  `json:"current_level"`
+}
+
+// GetLevelsMetadata returns the levels that have subjects, plus the learner's current
+// level, which is nil until an assignment has been unlocked
+func (s *Service) GetLevelsMetadata(ctx context.Context) (*LevelsMetadata, error) {
+	levels, err := s.store.GetDistinctLevels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.store.GetCurrentLevel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &LevelsMetadata{Levels: levels}
+	if current > 0 {
+		metadata.CurrentLevel = &current
+	}
+	return metadata, nil
+}
+
+// ReviewDateRange is when the first and last stored reviews were done, for bounding date
+// pickers. Both are nil when there are no reviews.
+type ReviewDateRange struct {
+	Earliest *time.Time `json:"earliest"`
+	Latest   *time.Time `json:"latest"`
+}
+
+// GetReviewDateRange retrieves when the earliest and latest reviews were created
+func (s *Service) GetReviewDateRange(ctx context.Context) (*ReviewDateRange, error) {
+	earliest, latest, err := s.store.GetReviewDateRange(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ReviewDateRange{Earliest: earliest, Latest: latest}, nil
+}
+
+// GetLevelProgress retrieves per-type progress counts for the subjects of a level
+func (s *Service) GetLevelProgress(ctx context.Context, level int) (*domain.LevelProgress, error) {
+	return s.store.GetLevelProgress(ctx, level)
+}
+
+// ExportData streams a JSON backup of all stored data to w
+func (s *Service) ExportData(ctx context.Context, w io.Writer) error {
+	return s.store.Export(ctx, w)
+}
+
+// ImportData replaces all stored data with a JSON backup read from r
+func (s *Service) ImportData(ctx context.Context, r io.Reader) (map[string]int, error) {
+	defer s.invalidateSubjectCache()
+	return s.store.Import(ctx, r)
+}
+
+// CheckIntegrity reports orphaned assignments and reviews, deleting them if repair is true
+func (s *Service) CheckIntegrity(ctx context.Context, repair bool) (*domain.IntegrityReport, error) {
+	return s.store.CheckIntegrity(ctx, repair)
+}
+
+// ResetSyncTimes clears the last sync time of dataType, or of every data type if nil, so
+// the next sync fetches everything again
+func (s *Service) ResetSyncTimes(ctx context.Context, dataType *domain.DataType) (int, error) {
+	return s.store.ClearSyncTimes(ctx, dataType)
+}
+
+// CriticalItem is a subject answered correctly less often than the critical threshold
+type CriticalItem struct {
+	Subject           *domain.Subject `json:"subject"`
+	Reviews           int             `json:"reviews"`
+	IncorrectAnswers  int             `json:"incorrect_answers"`
+	PercentageCorrect float64         `json:"percentage_correct"`
+}
+
+// GetCriticalItems retrieves the reviewed subjects whose percentage of correct answers is
+// below threshold, joined with their subjects, least accurate first
+func (s *Service) GetCriticalItems(ctx context.Context, threshold int) ([]CriticalItem, error) {
+	accuracy, err := s.store.GetSubjectAccuracy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve subject accuracy: %w", err)
+	}
+
+	var critical []domain.SubjectAccuracy
+	var subjectIDs []int64
+	for _, a := range accuracy {
+		if a.PercentageCorrect() < float64(threshold) {
+			critical = append(critical, a)
+			subjectIDs = append(subjectIDs, a.SubjectID)
+		}
+	}
+
+	subjectMap, err := s.subjectLookup(ctx, subjectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]CriticalItem, 0, len(critical))
+	for _, a := range critical {
+		items = append(items, CriticalItem{
+			Subject:           subjectMap[a.SubjectID],
+			Reviews:           a.Reviews,
+			IncorrectAnswers:  a.IncorrectAnswers,
+			PercentageCorrect: math.Round(a.PercentageCorrect()*100) / 100,
+		})
+	}
+
+	// Ties keep subject ID order, as returned by the store
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].PercentageCorrect < items[j].PercentageCorrect
+	})
+
+	return items, nil
+}
+
+// AvailableReviewCount is the number of reviews available right now
+type AvailableReviewCount struct {
+	Count int       `json:"count"`
+	AsOf  time.Time `json:"as_of"`
+}
+
+// GetAvailableReviewCount counts the subjects in the latest statistics snapshot's review
+// buckets that have become available by now. It returns a count of 0 if no snapshot exists.
+func (s *Service) GetAvailableReviewCount(ctx context.Context) (*AvailableReviewCount, error) {
+	now := s.now().UTC()
+
+	snapshot, err := s.store.GetLatestStatistics(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AvailableReviewCount{AsOf: now}
+	if snapshot == nil {
+		return result, nil
+	}
+
+	for _, bucket := range snapshot.Statistics.Data.Reviews {
+		if !bucket.AvailableAt.After(now) {
+			result.Count += len(bucket.SubjectIDs)
+		}
+	}
+
+	return result, nil
+}
+
+// GetSRSGroupTotals retrieves the current number of assignments in each SRS stage group by
+// subject type. Every group in domain.SRSStageGroupOrder is present, empty if it has none.
+func (s *Service) GetSRSGroupTotals(ctx context.Context) (map[string]map[string]int, error) {
+	totals, err := s.store.GetCurrentSRSGroupTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range domain.SRSStageGroupOrder {
+		if totals[group] == nil {
+			totals[group] = make(map[string]int)
+		}
+	}
+
+	return totals, nil
+}
+
+// ProgressBreakdown counts assignments by state: waiting in lessons, in progress and burned
+type ProgressBreakdown struct {
+	Lessons    int `json:"lessons"`
+	InProgress int `json:"in_progress"`
+	Burned     int `json:"burned"`
+}
+
+// GetProgressBreakdown reads the lesson, in progress and burned totals from the store's
+// assignment counts. Locked assignments are left out.
+func (s *Service) GetProgressBreakdown(ctx context.Context) (*ProgressBreakdown, error) {
+	counts, err := s.store.GetAssignmentCounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count assignments: %w", err)
+	}
+
+	return &ProgressBreakdown{
+		Lessons:    counts.Total.Lessons,
+		InProgress: counts.Total.InProgress,
+		Burned:     counts.Total.Burned,
+	}, nil
+}
+
+// subjectTypes are the subject types every level count is reported for, even when zero
+var subjectTypes = []string{"radical", "kanji", "vocabulary"}
+
+// LevelSubjectCounts is the number of subjects of each type on a level
+type LevelSubjectCounts struct {
+	Level        int            `json:"level"`
+	SubjectTypes map[string]int `json:"subject_types"`
+	Total        int            `json:"total"`
+}
+
+// GetSubjectCountsByLevel retrieves subject counts for every level from 1 up to the highest
+// level with synced subjects, zero-filling levels and types without subjects
+func (s *Service) GetSubjectCountsByLevel(ctx context.Context) ([]LevelSubjectCounts, error) {
+	counts, err := s.store.GetSubjectCountsByLevel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	maxLevel := 0
+	for level := range counts {
+		if level > maxLevel {
+			maxLevel = level
+		}
+	}
+
+	result := make([]LevelSubjectCounts, 0, maxLevel)
+	for level := domain.MinLevel; level <= maxLevel; level++ {
+		entry := LevelSubjectCounts{Level: level, SubjectTypes: make(map[string]int)}
+		for _, subjectType := range subjectTypes {
+			entry.SubjectTypes[subjectType] = 0
+		}
+		for subjectType, count := range counts[level] {
+			entry.SubjectTypes[subjectType] = count
+			entry.Total += count
+		}
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+// ReviewHourCount is the number of reviews done in one hour of the day
+type ReviewHourCount struct {
+	Hour  int `json:"hour"`
+	Count int `json:"count"`
+}
+
+// ReviewHours is the distribution of reviews over the hours of the day
+type ReviewHours struct {
+	Hours []ReviewHourCount `json:"hours"`
+	Total int               `json:"total"`
+}
+
+// GetReviewHours retrieves review counts for all 24 hours of the day, including empty ones
+func (s *Service) GetReviewHours(ctx context.Context, filters domain.ReviewFilters) (*ReviewHours, error) {
+	counts, err := s.store.GetReviewCountsByHour(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReviewHours{Hours: make([]ReviewHourCount, 0, len(counts))}
+	for hour, count := range counts {
+		result.Hours = append(result.Hours, ReviewHourCount{Hour: hour, Count: count})
+		result.Total += count
+	}
+
+	return result, nil
+}
+
+// WeeklyReviewCount is the number of reviews done in one ISO week
+type WeeklyReviewCount struct {
+	Week      string `json:"week"`       // ISO week, e.g. 2024-W03
+	WeekStart string `json:"week_start"` // the week's Monday, YYYY-MM-DD
+	Count     int    `json:"count"`
+}
+
+// WeeklyReviews is the number of reviews done in each week of a date range
+type WeeklyReviews struct {
+	Weeks []WeeklyReviewCount `json:"weeks"`
+	Total int                 `json:"total"`
+}
+
+// GetWeeklyReviewCounts retrieves review counts per ISO week. Every week from the one
+// containing filters.From to the one containing filters.To is returned, with zero counts
+// for weeks without reviews; an open end of the range stops at the first or last week
+// with reviews.
+func (s *Service) GetWeeklyReviewCounts(ctx context.Context, filters domain.ReviewFilters) (*WeeklyReviews, error) {
+	counts, err := s.store.GetReviewCountsByWeek(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var first, last time.Time
+	for week := range counts {
+		if first.IsZero() || week.Before(first) {
+			first = week
+		}
+		if last.IsZero() || week.After(last) {
+			last = week
+		}
+	}
+	if filters.From != nil {
+		first = weekStart(*filters.From)
+	}
+	if filters.To != nil {
+		last = weekStart(*filters.To)
+	}
+
+	result := &WeeklyReviews{Weeks: []WeeklyReviewCount{}}
+	if first.IsZero() || last.IsZero() {
+		return result, nil
+	}
+	for week := first; !week.After(last); week = week.AddDate(0, 0, 7) {
+		year, number := week.ISOWeek()
+		result.Weeks = append(result.Weeks, WeeklyReviewCount{
+			Week:      fmt.Sprintf("%04d-W%02d", year, number),
+			WeekStart: week.Format("2006-01-02"),
+			Count:     counts[week],
+		})
+		result.Total += counts[week]
+	}
+
+	return result, nil
+}
+
+// VelocityDay is the number of reviews done on one day and their rolling daily average
+// over the window ending on that day
+type VelocityDay struct {
+	Date    string  `json:"date"` // YYYY-MM-DD
+	Count   int     `json:"count"`
+	Average float64 `json:"average"`
+}
+
+// ReviewVelocity is the rolling average of daily review counts over a date range
+type ReviewVelocity struct {
+	Window int           `json:"window"`
+	Days   []VelocityDay `json:"days"`
+}
+
+// GetReviewVelocity retrieves the daily review counts of a date range with their rolling
+// average over window days. Days are chosen as for GetWeeklyReviewCounts; the first days
+// of the range average over fewer days, since the days before it are not counted.
+func (s *Service) GetReviewVelocity(ctx context.Context, filters domain.ReviewFilters, window int) (*ReviewVelocity, error) {
+	counts, err := s.store.GetReviewCountsByDay(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var first, last time.Time
+	for day := range counts {
+		if first.IsZero() || day.Before(first) {
+			first = day
+		}
+		if last.IsZero() || day.After(last) {
+			last = day
+		}
+	}
+	if filters.From != nil {
+		first = *filters.From
+	}
+	if filters.To != nil {
+		last = *filters.To
+	}
+
+	result := &ReviewVelocity{Window: window, Days: []VelocityDay{}}
+	if first.IsZero() || last.IsZero() {
+		return result, nil
+	}
+
+	var daily []int
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		daily = append(daily, counts[day])
+	}
+	for i, average := range rollingAverage(daily, window) {
+		result.Days = append(result.Days, VelocityDay{
+			Date:    first.AddDate(0, 0, i).Format("2006-01-02"),
+			Count:   daily[i],
+			Average: math.Round(average*100) / 100,
+		})
+	}
+
+	return result, nil
+}
+
+// rollingAverage averages each value with the window-1 values before it. Values closer than
+// window to the start average over the values available so far.
+func rollingAverage(values []int, window int) []float64 {
+	averages := make([]float64, len(values))
+	sum := 0
+	for i, value := range values {
+		sum += value
+		if i >= window {
+			sum -= values[i-window]
+		}
+		averages[i] = float64(sum) / float64(min(i+1, window))
+	}
+	return averages
+}
+
+// weekStart returns the Monday of the ISO week containing the calendar date of t, as a
+// UTC-midnight date
+func weekStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+}
+
+// TodayReviews is today's review count and the progress toward the daily review goal.
+// Goal and Percent are nil when no goal is configured.
+type TodayReviews struct {
+	Date    string   `json:"date"`
+	Count   int      `json:"count"`
+	Goal    *int     `json:"goal"`
+	Percent *float64 `json:"percent"` // count / goal * 100, one decimal; may exceed 100
+}
+
+// GetTodayReviews counts the reviews done today and compares them to the daily goal
+func (s *Service) GetTodayReviews(ctx context.Context) (*TodayReviews, error) {
+	day, count, err := s.store.GetReviewCountToday(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	today := &TodayReviews{Date: day.Format("2006-01-02"), Count: count}
+	if s.dailyReviewGoal > 0 {
+		goal := s.dailyReviewGoal
+		percent := math.Round(float64(count)*1000/float64(goal)) / 10
+		today.Goal = &goal
+		today.Percent = &percent
+	}
+
+	return today, nil
+}
+
+// ReviewStreak represents the learner's streaks of consecutive review days
+type ReviewStreak struct {
+	CurrentStreak  int     `json:"current_streak"`
+	LongestStreak  int     `json:"longest_streak"`
+	LastReviewDate *string `json:"last_review_date"`
+}
+
+// GetReviewStreak retrieves the current and longest review streaks
+func (s *Service) GetReviewStreak(ctx context.Context) (*ReviewStreak, error) {
+	current, longest, lastDay, err := s.store.GetReviewStreak(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	streak := &ReviewStreak{
+		CurrentStreak: current,
+		LongestStreak: longest,
+	}
+	if !lastDay.IsZero() {
+		date := lastDay.Format("2006-01-02")
+		streak.LastReviewDate = &date
+	}
+
+	return streak, nil
+}
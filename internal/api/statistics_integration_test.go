@@ -57,7 +57,7 @@ func TestStatisticsHistoricalTrackingIntegration(t *testing.T) {
 
 	// Create router
 	router := mux.NewRouter()
-	setupRoutes(router, handler, "", logger)
+	setupRoutes(router, handler, "", logger, serverConfig{})
 
 	ctx := context.Background()
 
@@ -72,7 +72,7 @@ func TestStatisticsHistoricalTrackingIntegration(t *testing.T) {
 				Lessons: []domain.LessonStatistics{
 					{
 						AvailableAt: baseTime.Add(time.Duration(i) * 24 * time.Hour),
-						SubjectIDs:  []int{i + 1, i + 2, i + 3},
+						SubjectIDs:  []int64{int64(i) + 1, int64(i) + 2, int64(i) + 3},
 					},
 				},
 			},
@@ -182,7 +182,7 @@ func TestStatisticsHistoricalTrackingIntegration(t *testing.T) {
 					Lessons: []domain.LessonStatistics{
 						{
 							AvailableAt: baseTime.Add(time.Duration(i) * 24 * time.Hour),
-							SubjectIDs:  []int{i + 1},
+							SubjectIDs:  []int64{int64(i) + 1},
 						},
 					},
 				},
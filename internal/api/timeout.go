@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// TimeoutMiddleware gives each request timeout to complete. A handler that runs longer has
+// its context cancelled and the client gets 503 with a REQUEST_TIMEOUT error instead. The
+// response is buffered until the handler finishes, so paths expected to run long (sync,
+// export, import) are listed in exempt and pass through untouched. Paths in streamable
+// are only passed through when the request asks for a streamed response with
+// stream=true. A timeout of zero or less disables the middleware.
+func TimeoutMiddleware(timeout time.Duration, exempt, streamable []string) mux.MiddlewareFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+	canStream := make(map[string]bool, len(streamable))
+	for _, path := range streamable {
+		canStream[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip[r.URL.Path] || canStream[r.URL.Path] && isStreamRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// http.TimeoutHandler hands the handler its own writer, so carry the negotiated
+			// error format over to it
+			inner := next
+			if pw, ok := w.(*problemResponseWriter); ok {
+				inner = http.HandlerFunc(func(tw http.ResponseWriter, r *http.Request) {
+					next.ServeHTTP(&problemResponseWriter{ResponseWriter: tw, instance: pw.instance}, r)
+				})
+			}
+
+			// The timeout body is written as-is, so render it in the negotiated format now.
+			// Headers set here are replaced by the handler's own if it finishes in time.
+			message := fmt.Sprintf("The request did not complete within %s", timeout)
+			contentType, body := errorResponseBody(w, http.StatusServiceUnavailable, ErrCodeTimeout, message, nil)
+			encoded, _ := json.Marshal(body)
+			w.Header().Set("Content-Type", contentType)
+
+			http.TimeoutHandler(inner, timeout, string(encoded)+"\n").ServeHTTP(w, r)
+		})
+	}
+}
+
+// isStreamRequest reports whether the request asks for a streamed response
+func isStreamRequest(r *http.Request) bool {
+	stream, _ := strconv.ParseBool(r.URL.Query().Get("stream"))
+	return stream
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// TestTimeoutMiddleware tests that slow handlers are answered with a 503 error in the
+// negotiated format while fast, exempt and streamed handlers are unaffected
+func TestTimeoutMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(ProblemJSONMiddleware(false))
+	router.Use(TimeoutMiddleware(20*time.Millisecond, []string{"/slow/exempt"}, []string{"/slow/stream"}))
+
+	handlerCancelled := make(chan bool, 4)
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			handlerCancelled <- true
+		case <-time.After(200 * time.Millisecond):
+			writeJSON(w, r, map[string]string{"status": "done"})
+		}
+	}
+	router.HandleFunc("/slow", slow)
+	router.HandleFunc("/slow/exempt", slow)
+	router.HandleFunc("/slow/stream", slow)
+	router.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, map[string]string{"status": "done"})
+	})
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/slow", "")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 for a slow handler, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("expected JSON content type, got %q", ct)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode timeout response: %v", err)
+	}
+	if resp.Error.Code != ErrCodeTimeout {
+		t.Errorf("expected %s, got %s", ErrCodeTimeout, resp.Error.Code)
+	}
+	select {
+	case <-handlerCancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the slow handler's context to be cancelled")
+	}
+
+	w = serve("/slow", problemContentType)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 for a slow handler, got %d", w.Code)
+	}
+	var problem ProblemDetails
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatalf("failed to decode problem details: %v", err)
+	}
+	if w.Header().Get("Content-Type") != problemContentType || problem.Code != ErrCodeTimeout || problem.Instance != "/slow" {
+		t.Errorf("unexpected problem timeout response %q %+v", w.Header().Get("Content-Type"), problem)
+	}
+
+	if w := serve("/fast", ""); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("expected a fast handler to succeed, got %d", w.Code)
+	}
+	if w := serve("/slow/exempt", ""); w.Code != http.StatusOK {
+		t.Errorf("expected an exempt slow handler to complete, got %d", w.Code)
+	}
+	if w := serve("/slow/stream?stream=true", ""); w.Code != http.StatusOK {
+		t.Errorf("expected a streamed slow handler to complete, got %d", w.Code)
+	}
+
+	// stream=true only bypasses the timeout on paths that can stream
+	if w := serve("/slow?stream=true", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected stream=true on a non-streaming path to time out, got %d", w.Code)
+	}
+	if w := serve("/slow/stream", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected a buffered request on a streaming path to time out, got %d", w.Code)
+	}
+}
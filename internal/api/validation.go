@@ -0,0 +1,26 @@
+package api
+
+import "net/http"
+
+// ValidationErrors collects field-level validation failures so that a single
+// response can report every invalid query parameter at once
+type ValidationErrors map[string]string
+
+// Add records a validation failure for a field, keeping the first message if
+// the field has already failed
+func (v ValidationErrors) Add(field, message string) {
+	if _, exists := v[field]; exists {
+		return
+	}
+	v[field] = message
+}
+
+// HasErrors returns true if any field failed validation
+func (v ValidationErrors) HasErrors() bool {
+	return len(v) > 0
+}
+
+// writeValidationErrors writes a single VALIDATION_ERROR response containing all collected field errors
+func (h *Handler) writeValidationErrors(w http.ResponseWriter, errs ValidationErrors) {
+	h.writeError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid query parameters", errs)
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -159,6 +161,56 @@ func TestLevelValidation(t *testing.T) {
 	}
 }
 
+// TestLevelRangeValidation tests validation of the level_min and level_max parameters
+func TestLevelRangeValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedFields []string
+	}{
+		{name: "valid range", query: "level_min=1&level_max=10", expectedStatus: http.StatusOK},
+		{name: "single-value range", query: "level_min=5&level_max=5", expectedStatus: http.StatusOK},
+		{name: "min only", query: "level_min=59", expectedStatus: http.StatusOK},
+		{name: "range with exact level", query: "level=3&level_min=1&level_max=10", expectedStatus: http.StatusOK},
+		{name: "min greater than max", query: "level_min=10&level_max=1", expectedStatus: http.StatusBadRequest, expectedFields: []string{"level_min"}},
+		{name: "min out of range", query: "level_min=0", expectedStatus: http.StatusBadRequest, expectedFields: []string{"level_min"}},
+		{name: "max out of range", query: "level_max=61", expectedStatus: http.StatusBadRequest, expectedFields: []string{"level_max"}},
+		{name: "both invalid", query: "level_min=abc&level_max=xyz", expectedStatus: http.StatusBadRequest, expectedFields: []string{"level_min", "level_max"}},
+		{name: "sorted by position", query: "level=3&sort=position", expectedStatus: http.StatusOK},
+		{name: "invalid sort", query: "level=3&sort=level", expectedStatus: http.StatusBadRequest, expectedFields: []string{"sort"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(NewService(&mockStore{}, &mockSyncService{}), testLogger())
+
+			req := httptest.NewRequest(http.MethodGet, "/api/subjects?"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.HandleGetSubjects(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if len(tt.expectedFields) == 0 {
+				return
+			}
+
+			var errResp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+			for _, field := range tt.expectedFields {
+				if _, ok := errResp.Error.Details[field]; !ok {
+					t.Errorf("expected error for field %q, got %v", field, errResp.Error.Details)
+				}
+			}
+		})
+	}
+}
+
 // TestSRSStageValidation tests validation of SRS stage parameter
 func TestSRSStageValidation(t *testing.T) {
 	tests := []struct {
@@ -436,6 +488,18 @@ func (m *mockStore) GetSubjects(ctx context.Context, filters domain.SubjectFilte
 	return []domain.Subject{}, nil
 }
 
+func (m *mockStore) StreamSubjects(ctx context.Context, filters domain.SubjectFilters, fn func(domain.Subject) error) error {
+	return nil
+}
+
+func (m *mockStore) UpsertLevelProgressions(ctx context.Context, progressions []domain.LevelProgression) error {
+	return nil
+}
+
+func (m *mockStore) GetLevelProgressions(ctx context.Context) ([]domain.LevelProgression, error) {
+	return []domain.LevelProgression{}, nil
+}
+
 func (m *mockStore) UpsertAssignments(ctx context.Context, assignments []domain.Assignment) error {
 	return nil
 }
@@ -444,6 +508,14 @@ func (m *mockStore) GetAssignments(ctx context.Context, filters domain.Assignmen
 	return []domain.Assignment{}, nil
 }
 
+func (m *mockStore) GetAssignmentsPassedOn(ctx context.Context, date time.Time) ([]domain.Assignment, error) {
+	return []domain.Assignment{}, nil
+}
+
+func (m *mockStore) GetAvailableLessons(ctx context.Context) ([]domain.Assignment, error) {
+	return []domain.Assignment{}, nil
+}
+
 func (m *mockStore) UpsertReviews(ctx context.Context, reviews []domain.Review) error {
 	return nil
 }
@@ -456,12 +528,109 @@ func (m *mockStore) InsertStatistics(ctx context.Context, stats domain.Statistic
 	return nil
 }
 
-func (m *mockStore) GetStatistics(ctx context.Context, dateRange *domain.DateRange) ([]domain.StatisticsSnapshot, error) {
+func (m *mockStore) GetStatistics(ctx context.Context, dateRange *domain.DateRange, opts domain.StatisticsOptions) ([]domain.StatisticsSnapshot, error) {
 	return []domain.StatisticsSnapshot{}, nil
 }
 
 func (m *mockStore) GetLatestStatistics(ctx context.Context) (*domain.StatisticsSnapshot, error) {
-	return &domain.StatisticsSnapshot{}, nil
+	return nil, nil
+}
+
+func (m *mockStore) PruneStatistics(ctx context.Context, keepRecent int) (int, error) {
+	return 0, nil
+}
+
+func (m *mockStore) GetReviewStreak(ctx context.Context) (int, int, time.Time, error) {
+	return 0, 0, time.Time{}, nil
+}
+
+func (m *mockStore) GetReviewDateRange(ctx context.Context) (*time.Time, *time.Time, error) {
+	return nil, nil, nil
+}
+
+func (m *mockStore) GetSubjectAccuracy(ctx context.Context) ([]domain.SubjectAccuracy, error) {
+	return []domain.SubjectAccuracy{}, nil
+}
+
+func (m *mockStore) GetReviewCountsByHour(ctx context.Context, filters domain.ReviewFilters) ([24]int, error) {
+	return [24]int{}, nil
+}
+
+func (m *mockStore) GetReviewCountsByWeek(ctx context.Context, filters domain.ReviewFilters) (map[time.Time]int, error) {
+	return map[time.Time]int{}, nil
+}
+
+func (m *mockStore) GetReviewCountsByDay(ctx context.Context, filters domain.ReviewFilters) (map[time.Time]int, error) {
+	return map[time.Time]int{}, nil
+}
+
+func (m *mockStore) GetReviewCountToday(ctx context.Context) (time.Time, int, error) {
+	return time.Time{}, 0, nil
+}
+
+func (m *mockStore) GetCurrentSRSGroupTotals(ctx context.Context) (map[string]map[string]int, error) {
+	return map[string]map[string]int{}, nil
+}
+
+func (m *mockStore) GetAssignmentCounts(ctx context.Context) (domain.AssignmentCounts, error) {
+	return domain.AssignmentCounts{SubjectTypes: map[string]domain.AssignmentStateCounts{}}, nil
+}
+
+func (m *mockStore) GetSubjectCountsByLevel(ctx context.Context) (map[int]map[string]int, error) {
+	return map[int]map[string]int{}, nil
+}
+
+func (m *mockStore) GetDistinctLevels(ctx context.Context) ([]int, error) {
+	return []int{}, nil
+}
+
+func (m *mockStore) GetLastReviewPerSubject(ctx context.Context, subjectIDs []int64) (map[int64]time.Time, error) {
+	return map[int64]time.Time{}, nil
+}
+
+func (m *mockStore) GetSubjectsByIDs(ctx context.Context, ids []int64) ([]domain.Subject, error) {
+	return []domain.Subject{}, nil
+}
+
+func (m *mockStore) GetAssignmentsByIDs(ctx context.Context, ids []int64) ([]domain.Assignment, error) {
+	return []domain.Assignment{}, nil
+}
+
+func (m *mockStore) GetLevelProgress(ctx context.Context, level int) (*domain.LevelProgress, error) {
+	return &domain.LevelProgress{Level: level, SubjectTypes: map[string]domain.LevelProgressCounts{}}, nil
+}
+
+func (m *mockStore) Export(ctx context.Context, w io.Writer) error {
+	_, err := io.WriteString(w, `{"version":1}`)
+	return err
+}
+
+func (m *mockStore) Import(ctx context.Context, r io.Reader) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+func (m *mockStore) CountSubjects(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockStore) CountAssignments(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockStore) CountReviews(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockStore) CountAssignmentSnapshots(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockStore) GetCurrentLevel(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockStore) CheckIntegrity(ctx context.Context, repair bool) (*domain.IntegrityReport, error) {
+	return &domain.IntegrityReport{}, nil
 }
 
 func (m *mockStore) GetLastSyncTime(ctx context.Context, dataType domain.DataType) (*time.Time, error) {
@@ -472,6 +641,10 @@ func (m *mockStore) SetLastSyncTime(ctx context.Context, dataType domain.DataTyp
 	return nil
 }
 
+func (m *mockStore) ClearSyncTimes(ctx context.Context, dataType *domain.DataType) (int, error) {
+	return 0, nil
+}
+
 func (m *mockStore) BeginTx(ctx context.Context) (*sql.Tx, error) {
 	return nil, nil
 }
@@ -498,10 +671,18 @@ func (m *mockSyncService) SyncSubjects(ctx context.Context) domain.SyncResult {
 	return domain.SyncResult{}
 }
 
+func (m *mockSyncService) SyncLevelProgressions(ctx context.Context) domain.SyncResult {
+	return domain.SyncResult{}
+}
+
 func (m *mockSyncService) SyncAssignments(ctx context.Context) domain.SyncResult {
 	return domain.SyncResult{}
 }
 
+func (m *mockSyncService) SyncAvailableAssignments(ctx context.Context) domain.SyncResult {
+	return domain.SyncResult{}
+}
+
 func (m *mockSyncService) SyncReviews(ctx context.Context) domain.SyncResult {
 	return domain.SyncResult{}
 }
@@ -514,6 +695,10 @@ func (m *mockSyncService) IsSyncing() bool {
 	return false
 }
 
+func (m *mockSyncService) SyncStartedAt() *time.Time {
+	return nil
+}
+
 func (m *mockSyncService) CreateAssignmentSnapshot(ctx context.Context) error {
 	return nil
 }
@@ -839,3 +1024,618 @@ func TestAssignmentSnapshotsAggregation(t *testing.T) {
 		t.Fatal("expected guru stage in result")
 	}
 }
+
+// TestMultipleValidationErrors tests that every invalid parameter is reported in a single response
+func TestMultipleValidationErrors(t *testing.T) {
+	store := &mockStore{}
+	syncService := &mockSyncService{}
+	service := NewService(store, syncService)
+	handler := NewHandler(service, testLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/subjects?type=invalid&level=99", nil)
+	w := httptest.NewRecorder()
+
+	handler.HandleGetSubjects(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	var errResp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+
+	if errResp.Error.Code != "VALIDATION_ERROR" {
+		t.Errorf("expected VALIDATION_ERROR, got %s", errResp.Error.Code)
+	}
+	if errResp.Error.Details["type"] == "" {
+		t.Error("expected type field in error details")
+	}
+	if errResp.Error.Details["level"] == "" {
+		t.Error("expected level field in error details")
+	}
+	if len(errResp.Error.Details) != 2 {
+		t.Errorf("expected 2 error details, got %d: %v", len(errResp.Error.Details), errResp.Error.Details)
+	}
+}
+
+// TestReviewsMultipleValidationErrors tests that every invalid reviews parameter is reported
+// in a single response
+func TestReviewsMultipleValidationErrors(t *testing.T) {
+	handler := NewHandler(NewService(&mockStore{}, &mockSyncService{}), testLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/reviews?from=yesterday&to=2024-13-01&passed=maybe&subject_type=kana&details=some", nil)
+	w := httptest.NewRecorder()
+
+	handler.HandleGetReviews(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	var errResp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	for _, field := range []string{"from", "to", "passed", "subject_type", "details"} {
+		if errResp.Error.Details[field] == "" {
+			t.Errorf("expected %s field in error details, got %v", field, errResp.Error.Details)
+		}
+	}
+	if len(errResp.Error.Details) != 5 {
+		t.Errorf("expected 5 error details, got %d: %v", len(errResp.Error.Details), errResp.Error.Details)
+	}
+}
+
+// TestAssignmentSnapshotDiff tests the day-over-day snapshot diff endpoint
+func TestAssignmentSnapshotDiff(t *testing.T) {
+	date1, _ := time.Parse("2006-01-02", "2024-01-15")
+	date2, _ := time.Parse("2006-01-02", "2024-01-16")
+	date3, _ := time.Parse("2006-01-02", "2024-01-17")
+
+	testSnapshots := []domain.AssignmentSnapshot{
+		{Date: date1, SRSStage: 1, SubjectType: "kanji", Count: 10},
+		{Date: date1, SRSStage: 5, SubjectType: "radical", Count: 4},
+		{Date: date2, SRSStage: 2, SubjectType: "kanji", Count: 8},
+		{Date: date2, SRSStage: 5, SubjectType: "radical", Count: 6},
+		{Date: date2, SRSStage: 9, SubjectType: "vocabulary", Count: 1},
+		{Date: date3, SRSStage: 2, SubjectType: "kanji", Count: 5},
+		{Date: date3, SRSStage: 5, SubjectType: "radical", Count: 6},
+		{Date: date3, SRSStage: 9, SubjectType: "vocabulary", Count: 3},
+	}
+
+	customStore := &customMockStore{snapshots: testSnapshots}
+	service := NewService(customStore, &mockSyncService{})
+	handler := NewHandler(service, testLogger())
+
+	t.Run("diff between two dates", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/assignments/snapshots/diff?from=2024-01-15&to=2024-01-16", nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleGetAssignmentSnapshotDiff(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+
+		var diff SnapshotDiff
+		if err := json.NewDecoder(w.Body).Decode(&diff); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if diff.Changes["apprentice"]["kanji"] != -2 {
+			t.Errorf("expected apprentice kanji delta -2, got %d", diff.Changes["apprentice"]["kanji"])
+		}
+		if diff.Changes["apprentice"]["total"] != -2 {
+			t.Errorf("expected apprentice total delta -2, got %d", diff.Changes["apprentice"]["total"])
+		}
+		if diff.Changes["guru"]["radical"] != 2 {
+			t.Errorf("expected guru radical delta 2, got %d", diff.Changes["guru"]["radical"])
+		}
+		// Stage groups only present on the later date count from zero
+		if diff.Changes["burned"]["vocabulary"] != 1 {
+			t.Errorf("expected burned vocabulary delta 1, got %d", diff.Changes["burned"]["vocabulary"])
+		}
+		if diff.Daily != nil {
+			t.Errorf("expected no daily breakdown unless requested, got %v", diff.Daily)
+		}
+	})
+
+	t.Run("daily breakdown", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/assignments/snapshots/diff?from=2024-01-15&to=2024-01-17&daily=true", nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleGetAssignmentSnapshotDiff(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+
+		var diff SnapshotDiff
+		if err := json.NewDecoder(w.Body).Decode(&diff); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if len(diff.Daily) != 2 {
+			t.Fatalf("expected 2 daily diffs, got %d", len(diff.Daily))
+		}
+		if diff.Daily[0].Date != "2024-01-16" || diff.Daily[1].Date != "2024-01-17" {
+			t.Errorf("unexpected daily dates: %s, %s", diff.Daily[0].Date, diff.Daily[1].Date)
+		}
+		if diff.Daily[1].Changes["apprentice"]["kanji"] != -3 {
+			t.Errorf("expected apprentice kanji delta -3 on 2024-01-17, got %d", diff.Daily[1].Changes["apprentice"]["kanji"])
+		}
+		if diff.Changes["burned"]["vocabulary"] != 3 {
+			t.Errorf("expected overall burned vocabulary delta 3, got %d", diff.Changes["burned"]["vocabulary"])
+		}
+	})
+
+	t.Run("missing snapshot returns 404", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/assignments/snapshots/diff?from=2024-01-14&to=2024-01-16", nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleGetAssignmentSnapshotDiff(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("invalid date range", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/assignments/snapshots/diff?from=2024-01-16&to=2024-01-15", nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleGetAssignmentSnapshotDiff(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("missing dates", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/assignments/snapshots/diff", nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleGetAssignmentSnapshotDiff(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", w.Code)
+		}
+
+		var errResp ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+			t.Fatalf("failed to decode error response: %v", err)
+		}
+		if errResp.Error.Details["from"] == "" || errResp.Error.Details["to"] == "" {
+			t.Errorf("expected both from and to in error details, got %v", errResp.Error.Details)
+		}
+	})
+}
+
+// TestAssignmentSnapshotSeries tests the per-stage-group time series endpoint
+func TestAssignmentSnapshotSeries(t *testing.T) {
+	date1, _ := time.Parse("2006-01-02", "2024-01-15")
+	date3, _ := time.Parse("2006-01-02", "2024-01-17")
+
+	testSnapshots := []domain.AssignmentSnapshot{
+		{Date: date1, SRSStage: 1, SubjectType: "kanji", Count: 10},
+		{Date: date1, SRSStage: 3, SubjectType: "radical", Count: 2},
+		{Date: date1, SRSStage: 5, SubjectType: "radical", Count: 4},
+		{Date: date3, SRSStage: 2, SubjectType: "kanji", Count: 5},
+		{Date: date3, SRSStage: 9, SubjectType: "vocabulary", Count: 3},
+	}
+
+	customStore := &customMockStore{snapshots: testSnapshots}
+	service := NewService(customStore, &mockSyncService{})
+	handler := NewHandler(service, testLogger())
+
+	t.Run("series per stage group with zero-filled dates", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/assignments/snapshots/series?from=2024-01-15&to=2024-01-18", nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleGetAssignmentSnapshotSeries(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+
+		var series map[string][]SeriesPoint
+		if err := json.NewDecoder(w.Body).Decode(&series); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		for _, name := range domain.SRSStageGroupOrder {
+			if _, ok := series[name]; !ok {
+				t.Errorf("expected a series for stage group %s", name)
+			}
+		}
+
+		expected := map[string][]int{
+			"apprentice":  {12, 0, 5, 0},
+			"guru":        {4, 0, 0, 0},
+			"master":      {0, 0, 0, 0},
+			"enlightened": {0, 0, 0, 0},
+			"burned":      {0, 0, 3, 0},
+		}
+		dates := []string{"2024-01-15", "2024-01-16", "2024-01-17", "2024-01-18"}
+		for name, counts := range expected {
+			points := series[name]
+			if len(points) != len(dates) {
+				t.Fatalf("%s: expected %d points, got %d", name, len(dates), len(points))
+			}
+			for i, point := range points {
+				if point.Date != dates[i] || point.Count != counts[i] {
+					t.Errorf("%s point %d: expected %s=%d, got %s=%d", name, i, dates[i], counts[i], point.Date, point.Count)
+				}
+			}
+		}
+	})
+
+	t.Run("invalid date range", func(t *testing.T) {
+		for _, query := range []string{
+			"from=2024-01-16&to=2024-01-15",
+			"from=2024-01-15",
+			"from=2024-01-15&to=2024-13-01",
+			"from=2000-01-01&to=2024-01-01",
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/api/assignments/snapshots/series?"+query, nil)
+			w := httptest.NewRecorder()
+
+			handler.HandleGetAssignmentSnapshotSeries(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", query, w.Code)
+			}
+		}
+	})
+}
+
+// statisticsOptionsMockStore records the options passed to GetStatistics
+type statisticsOptionsMockStore struct {
+	mockStore
+	opts domain.StatisticsOptions
+}
+
+func (m *statisticsOptionsMockStore) GetStatistics(ctx context.Context, dateRange *domain.DateRange, opts domain.StatisticsOptions) ([]domain.StatisticsSnapshot, error) {
+	m.opts = opts
+	return []domain.StatisticsSnapshot{}, nil
+}
+
+// TestStatisticsLimitAndOrderValidation tests limit and order parameter handling for statistics
+func TestStatisticsLimitAndOrderValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedFields []string
+		expectedOpts   domain.StatisticsOptions
+	}{
+		{name: "no params", query: "", expectedStatus: http.StatusOK},
+		{name: "limit and asc", query: "limit=30&order=asc", expectedStatus: http.StatusOK, expectedOpts: domain.StatisticsOptions{Limit: 30, Order: domain.SortAsc}},
+		{name: "desc", query: "order=desc", expectedStatus: http.StatusOK, expectedOpts: domain.StatisticsOptions{Order: domain.SortDesc}},
+		{name: "max limit", query: "limit=1000", expectedStatus: http.StatusOK, expectedOpts: domain.StatisticsOptions{Limit: 1000}},
+		{name: "non-numeric limit", query: "limit=abc", expectedStatus: http.StatusBadRequest, expectedFields: []string{"limit"}},
+		{name: "zero limit", query: "limit=0", expectedStatus: http.StatusBadRequest, expectedFields: []string{"limit"}},
+		{name: "limit too large", query: "limit=1001", expectedStatus: http.StatusBadRequest, expectedFields: []string{"limit"}},
+		{name: "invalid order", query: "order=sideways", expectedStatus: http.StatusBadRequest, expectedFields: []string{"order"}},
+		{name: "all invalid", query: "from=bad&limit=-1&order=up", expectedStatus: http.StatusBadRequest, expectedFields: []string{"from", "limit", "order"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &statisticsOptionsMockStore{}
+			service := NewService(store, &mockSyncService{})
+			handler := NewHandler(service, testLogger())
+
+			req := httptest.NewRequest(http.MethodGet, "/api/statistics?"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.HandleGetStatistics(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				if store.opts != tt.expectedOpts {
+					t.Errorf("expected options %+v, got %+v", tt.expectedOpts, store.opts)
+				}
+				return
+			}
+
+			var errResp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+			if len(errResp.Error.Details) != len(tt.expectedFields) {
+				t.Errorf("expected %d field errors, got %v", len(tt.expectedFields), errResp.Error.Details)
+			}
+			for _, field := range tt.expectedFields {
+				if _, ok := errResp.Error.Details[field]; !ok {
+					t.Errorf("expected error for field %q, got %v", field, errResp.Error.Details)
+				}
+			}
+		})
+	}
+}
+
+// streakMockStore returns a fixed review streak
+type streakMockStore struct {
+	mockStore
+	current, longest int
+	lastDay          time.Time
+}
+
+func (m *streakMockStore) GetReviewStreak(ctx context.Context) (int, int, time.Time, error) {
+	return m.current, m.longest, m.lastDay, nil
+}
+
+// TestReviewStreakEndpoint tests the response shape of GET /api/stats/streak
+func TestReviewStreakEndpoint(t *testing.T) {
+	tests := []struct {
+		name             string
+		store            *streakMockStore
+		expectedLastDate *string
+	}{
+		{name: "empty history", store: &streakMockStore{}},
+		{
+			name:             "active streak",
+			store:            &streakMockStore{current: 3, longest: 10, lastDay: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)},
+			expectedLastDate: stringPtr("2024-03-10"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(NewService(tt.store, &mockSyncService{}), testLogger())
+
+			w := httptest.NewRecorder()
+			handler.HandleGetReviewStreak(w, httptest.NewRequest(http.MethodGet, "/api/stats/streak", nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+
+			var streak ReviewStreak
+			if err := json.NewDecoder(w.Body).Decode(&streak); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if streak.CurrentStreak != tt.store.current || streak.LongestStreak != tt.store.longest {
+				t.Errorf("expected streaks %d/%d, got %d/%d", tt.store.current, tt.store.longest, streak.CurrentStreak, streak.LongestStreak)
+			}
+
+			switch {
+			case tt.expectedLastDate == nil && streak.LastReviewDate != nil:
+				t.Errorf("expected null last_review_date, got %s", *streak.LastReviewDate)
+			case tt.expectedLastDate != nil && (streak.LastReviewDate == nil || *streak.LastReviewDate != *tt.expectedLastDate):
+				t.Errorf("expected last_review_date %s, got %v", *tt.expectedLastDate, streak.LastReviewDate)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// TestAssignmentSnapshotsOrderedFormat tests that ?format=ordered sorts dates ascending
+// and stage groups in canonical SRS order
+func TestAssignmentSnapshotsOrderedFormat(t *testing.T) {
+	day1, _ := time.Parse("2006-01-02", "2024-01-15")
+	day2, _ := time.Parse("2006-01-02", "2024-01-16")
+	day3, _ := time.Parse("2006-01-02", "2024-01-17")
+
+	store := &customMockStore{snapshots: []domain.AssignmentSnapshot{
+		{Date: day3, SRSStage: 9, SubjectType: "kanji", Count: 1},
+		{Date: day3, SRSStage: 1, SubjectType: "kanji", Count: 2},
+		{Date: day1, SRSStage: 8, SubjectType: "vocabulary", Count: 3},
+		{Date: day1, SRSStage: 7, SubjectType: "kanji", Count: 4},
+		{Date: day1, SRSStage: 5, SubjectType: "radical", Count: 5},
+		{Date: day1, SRSStage: 6, SubjectType: "radical", Count: 1},
+		{Date: day1, SRSStage: 2, SubjectType: "kanji", Count: 6},
+		{Date: day1, SRSStage: 9, SubjectType: "radical", Count: 7},
+		{Date: day2, SRSStage: 5, SubjectType: "kanji", Count: 8},
+	}}
+	handler := NewHandler(NewService(store, &mockSyncService{}), testLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/assignments/snapshots?format=ordered", nil)
+	w := httptest.NewRecorder()
+
+	handler.HandleGetAssignmentSnapshots(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var result []OrderedSnapshot
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	var dates []string
+	for _, snapshot := range result {
+		dates = append(dates, snapshot.Date)
+	}
+	if got, want := strings.Join(dates, ","), "2024-01-15,2024-01-16,2024-01-17"; got != want {
+		t.Fatalf("expected dates %s, got %s", want, got)
+	}
+
+	var stages []string
+	for _, stage := range result[0].Stages {
+		stages = append(stages, stage.Name)
+	}
+	if got, want := strings.Join(stages, ","), "apprentice,guru,master,enlightened,burned"; got != want {
+		t.Errorf("expected stage order %s, got %s", want, got)
+	}
+
+	guru := result[0].Stages[1]
+	if guru.Counts["radical"] != 6 || guru.Total != 6 {
+		t.Errorf("expected guru radical count and total of 6, got %+v", guru)
+	}
+	if _, ok := guru.Counts["total"]; ok {
+		t.Error("expected total to be reported separately from counts")
+	}
+
+	if got := result[2].Stages[0].Name + "," + result[2].Stages[1].Name; got != "apprentice,burned" {
+		t.Errorf("expected apprentice before burned, got %s", got)
+	}
+}
+
+// TestAssignmentSnapshotsFormatValidation tests that an unknown format is rejected
+func TestAssignmentSnapshotsFormatValidation(t *testing.T) {
+	handler := NewHandler(NewService(&mockStore{}, &mockSyncService{}), testLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/assignments/snapshots?format=csv", nil)
+	w := httptest.NewRecorder()
+
+	handler.HandleGetAssignmentSnapshots(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+// todayMockStore returns a fixed review count for today
+type todayMockStore struct {
+	mockStore
+	count int
+}
+
+func (m *todayMockStore) GetReviewCountToday(ctx context.Context) (time.Time, int, error) {
+	return time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), m.count, nil
+}
+
+// TestTodayReviewsEndpoint tests the goal handling of GET /api/stats/today
+func TestTodayReviewsEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		count    int
+		goal     int
+		expected string
+	}{
+		{name: "no goal", count: 42, goal: 0, expected: `{"date":"2024-03-10","count":42,"goal":null,"percent":null}`},
+		{name: "toward goal", count: 42, goal: 150, expected: `{"date":"2024-03-10","count":42,"goal":150,"percent":28}`},
+		{name: "rounded", count: 1, goal: 3, expected: `{"date":"2024-03-10","count":1,"goal":3,"percent":33.3}`},
+		{name: "past goal", count: 120, goal: 100, expected: `{"date":"2024-03-10","count":120,"goal":100,"percent":120}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(&todayMockStore{count: tt.count}, &mockSyncService{}, 8080, "", testLogger(), WithDailyReviewGoal(tt.goal))
+
+			w := httptest.NewRecorder()
+			server.getRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats/today", nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, body)
+			}
+		})
+	}
+}
+
+// velocityMockStore returns fixed review counts per day
+type velocityMockStore struct {
+	mockStore
+	counts map[time.Time]int
+}
+
+func (m *velocityMockStore) GetReviewCountsByDay(ctx context.Context, filters domain.ReviewFilters) (map[time.Time]int, error) {
+	return m.counts, nil
+}
+
+// TestReviewVelocityEndpoint tests the rolling averages of GET /api/stats/velocity
+func TestReviewVelocityEndpoint(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	store := &velocityMockStore{counts: map[time.Time]int{
+		day(1): 10, day(2): 20, day(3): 30, day(5): 40, day(6): 5,
+	}}
+	server := NewServer(store, &mockSyncService{}, 8080, "", testLogger())
+
+	tests := []struct {
+		name     string
+		query    string
+		dates    []string
+		averages []float64
+	}{
+		{
+			// The first two days average over the days available so far
+			name:     "leading partial windows",
+			query:    "window=3&from=2024-01-01&to=2024-01-06",
+			dates:    []string{"2024-01-01", "2024-01-02", "2024-01-03", "2024-01-04", "2024-01-05", "2024-01-06"},
+			averages: []float64{10, 15, 20, 16.67, 23.33, 15},
+		},
+		{
+			name:     "days without reviews extend the range",
+			query:    "window=2&from=2023-12-31&to=2024-01-02",
+			dates:    []string{"2023-12-31", "2024-01-01", "2024-01-02"},
+			averages: []float64{0, 5, 15},
+		},
+		{
+			name:     "window of one is the daily count",
+			query:    "window=1&from=2024-01-03&to=2024-01-05",
+			dates:    []string{"2024-01-03", "2024-01-04", "2024-01-05"},
+			averages: []float64{30, 0, 40},
+		},
+		{
+			name:     "open range spans the days with reviews",
+			query:    "window=30",
+			dates:    []string{"2024-01-01", "2024-01-02", "2024-01-03", "2024-01-04", "2024-01-05", "2024-01-06"},
+			averages: []float64{10, 15, 20, 15, 20, 17.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.getRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats/velocity?"+tt.query, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var velocity ReviewVelocity
+			if err := json.NewDecoder(w.Body).Decode(&velocity); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(velocity.Days) != len(tt.dates) {
+				t.Fatalf("expected %d days, got %+v", len(tt.dates), velocity.Days)
+			}
+			for i, day := range velocity.Days {
+				if day.Date != tt.dates[i] || day.Average != tt.averages[i] {
+					t.Errorf("day %d: expected %s=%v, got %s=%v", i, tt.dates[i], tt.averages[i], day.Date, day.Average)
+				}
+			}
+		})
+	}
+
+	t.Run("default window", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.getRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats/velocity", nil))
+
+		var velocity ReviewVelocity
+		if err := json.NewDecoder(w.Body).Decode(&velocity); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if velocity.Window != 7 {
+			t.Errorf("expected a default window of 7, got %d", velocity.Window)
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"window=0", "window=31", "window=abc", "from=2024-01-05&to=2024-01-01", "from=2000-01-01&to=2024-01-01"} {
+			w := httptest.NewRecorder()
+			server.getRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats/velocity?"+query, nil))
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", query, w.Code)
+			}
+		}
+	})
+}
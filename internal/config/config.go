@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -13,9 +16,60 @@ type Config struct {
 	WaniKaniAPIToken string
 	LocalAPIToken    string
 	DatabasePath     string
+	DBCacheSizeKB    int
+	DBMmapSizeBytes  int64
+	DBCompressData   bool
+	DBCreateDir      bool
 	SyncSchedule     string
 	APIPort          int
+	TLSCertFile      string
+	TLSKeyFile       string
 	LogLevel         string
+	Timezone         *time.Location
+	AuthAllowBasic   bool
+	AdminGateWaitMS  int
+	ProblemJSON      bool
+	ImportMaxBytes   int64
+	InstanceName     string
+	// WaniKaniStrictParsing warns about response fields the domain types don't model; noisy, meant for debugging
+	WaniKaniStrictParsing bool
+	// MaxConcurrentHeavyRequests limits in-flight requests per client IP on expensive endpoints
+	MaxConcurrentHeavyRequests int
+	// MaxResultRows caps the rows returned by list endpoints (0 = unlimited)
+	MaxResultRows int
+	// SubjectCacheRefreshSeconds is how often the in-memory subject cache checks for a newer sync (0 disables the cache)
+	SubjectCacheRefreshSeconds int
+	// HandlerTimeoutSeconds bounds how long a request may run (0 disables the timeout)
+	HandlerTimeoutSeconds int
+	// Features lists the optional endpoints to enable; empty enables all of them
+	Features []string
+	// DailyReviewGoal is the number of reviews per day /api/stats/today measures against (0 = no goal)
+	DailyReviewGoal int
+	// PublicEndpoints are GET route templates served without authentication
+	PublicEndpoints []string
+	// RequestLogSampleRate is the fraction (0 to 1) of successful requests written to the access log
+	RequestLogSampleRate float64
+	// RequestLogSlowMS is the latency at which a request is always logged (0 disables)
+	RequestLogSlowMS int
+	// ContentSecurityPolicy is the Content-Security-Policy header sent with every response
+	ContentSecurityPolicy string
+	// TrustedProxies are the proxy networks whose X-Forwarded-For and X-Real-IP headers are believed
+	TrustedProxies []*net.IPNet
+
+	StatisticsDedup           bool
+	StatisticsRetentionRecent int
+	SyncStatisticsEnabled     bool
+	StatisticsSource          string // summary or assignments
+	SyncConditional           bool
+	InitialSnapshotEnabled    bool
+	InitialReviewSyncDays     int
+	SyncOverlapSeconds        int
+	SyncValidation            string // off, skip or strict
+	LevelUpWebhookURL         string
+	SyncWebhookURL            string
+	// SyncEnabled turns off the WaniKani client and syncing when false, so the API only
+	// serves already-synced data and WaniKaniAPIToken is not required
+	SyncEnabled bool
 }
 
 // Load loads configuration from .env file and environment variables with defaults
@@ -27,19 +81,135 @@ func Load() (*Config, error) {
 		WaniKaniAPIToken: getEnv("WANIKANI_API_TOKEN", ""),
 		LocalAPIToken:    getEnv("LOCAL_API_TOKEN", ""),
 		DatabasePath:     getEnv("DATABASE_PATH", "./wanikani.db"),
+		DBCacheSizeKB:    getEnvAsInt("DB_CACHE_SIZE_KB", 16384),
+		DBMmapSizeBytes:  int64(getEnvAsInt("DB_MMAP_SIZE_BYTES", 268435456)),
+		DBCompressData:   getEnvAsBool("DB_COMPRESS_DATA", false),
+		DBCreateDir:      getEnvAsBool("DB_CREATE_DIR", true),
 		SyncSchedule:     getEnv("SYNC_SCHEDULE", "0 2 * * *"),
 		APIPort:          getEnvAsInt("API_PORT", 8080),
+		TLSCertFile:      getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:       getEnv("TLS_KEY_FILE", ""),
 		LogLevel:         getEnv("LOG_LEVEL", "info"),
+		AuthAllowBasic:   getEnvAsBool("AUTH_ALLOW_BASIC", false),
+		AdminGateWaitMS:  getEnvAsInt("ADMIN_GATE_WAIT_MS", 0),
+		ProblemJSON:      getEnvAsBool("PROBLEM_JSON_ERRORS", false),
+		ImportMaxBytes:   int64(getEnvAsInt("IMPORT_MAX_BYTES", 1073741824)),
+		InstanceName:     getEnv("INSTANCE_NAME", hostname()),
+
+		WaniKaniStrictParsing: getEnvAsBool("WANIKANI_STRICT_PARSING", false),
+
+		MaxConcurrentHeavyRequests: getEnvAsInt("MAX_CONCURRENT_HEAVY_REQUESTS", 4),
+		MaxResultRows:              getEnvAsInt("MAX_RESULT_ROWS", 100000),
+		SubjectCacheRefreshSeconds: getEnvAsInt("SUBJECT_CACHE_REFRESH_SECONDS", 60),
+		HandlerTimeoutSeconds:      getEnvAsInt("HANDLER_TIMEOUT_SECONDS", 30),
+		Features:                   getEnvAsList("FEATURES"),
+		DailyReviewGoal:            getEnvAsInt("DAILY_REVIEW_GOAL", 0),
+		PublicEndpoints:            getEnvAsList("PUBLIC_ENDPOINTS"),
+		RequestLogSampleRate:       getEnvAsFloat("REQUEST_LOG_SAMPLE_RATE", 1),
+		RequestLogSlowMS:           getEnvAsInt("REQUEST_LOG_SLOW_MS", 1000),
+		ContentSecurityPolicy:      getEnv("CONTENT_SECURITY_POLICY", "default-src 'none'; frame-ancestors 'none'"),
+
+		StatisticsDedup:           getEnvAsBool("STATISTICS_DEDUP", false),
+		StatisticsRetentionRecent: getEnvAsInt("STATISTICS_RETENTION_RECENT", 0),
+		SyncStatisticsEnabled:     getEnvAsBool("SYNC_STATISTICS_ENABLED", true),
+		StatisticsSource:          getEnv("STATISTICS_SOURCE", "summary"),
+		SyncConditional:           getEnvAsBool("SYNC_CONDITIONAL", false),
+		InitialSnapshotEnabled:    getEnvAsBool("INITIAL_SNAPSHOT_ENABLED", true),
+		InitialReviewSyncDays:     getEnvAsInt("INITIAL_REVIEW_SYNC_DAYS", 0),
+		SyncOverlapSeconds:        getEnvAsInt("SYNC_OVERLAP_SECONDS", 60),
+		SyncValidation:            getEnv("SYNC_VALIDATION", "skip"),
+		LevelUpWebhookURL:         getEnv("LEVELUP_WEBHOOK_URL", ""),
+		SyncWebhookURL:            getEnv("SYNC_WEBHOOK_URL", ""),
+		SyncEnabled:               getEnvAsBool("SYNC_ENABLED", true),
 	}
 
 	// Validate required configuration
-	if config.WaniKaniAPIToken == "" {
-		return nil, fmt.Errorf("WANIKANI_API_TOKEN environment variable is required")
+	if config.SyncEnabled && config.WaniKaniAPIToken == "" {
+		return nil, fmt.Errorf("WANIKANI_API_TOKEN environment variable is required unless SYNC_ENABLED is false")
+	}
+
+	timezone := getEnv("TIMEZONE", "UTC")
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TIMEZONE %q: %w", timezone, err)
+	}
+	config.Timezone = location
+
+	trustedProxies, err := parseNetworks(getEnvAsList("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	config.TrustedProxies = trustedProxies
+
+	if config.RequestLogSampleRate < 0 || config.RequestLogSampleRate > 1 {
+		return nil, fmt.Errorf("invalid REQUEST_LOG_SAMPLE_RATE %v: must be between 0 and 1", config.RequestLogSampleRate)
+	}
+
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if config.TLSCertFile != "" {
+		for _, file := range []struct{ name, path string }{
+			{"TLS_CERT_FILE", config.TLSCertFile},
+			{"TLS_KEY_FILE", config.TLSKeyFile},
+		} {
+			if _, err := os.Stat(file.path); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", file.name, file.path, err)
+			}
+		}
+	}
+
+	switch config.SyncValidation {
+	case "off", "skip", "strict":
+	default:
+		return nil, fmt.Errorf("invalid SYNC_VALIDATION %q: must be off, skip or strict", config.SyncValidation)
+	}
+
+	switch config.StatisticsSource {
+	case "summary", "assignments":
+	default:
+		return nil, fmt.Errorf("invalid STATISTICS_SOURCE %q: must be summary or assignments", config.StatisticsSource)
 	}
 
 	return config, nil
 }
 
+// parseNetworks parses IP addresses and CIDR ranges; a bare address becomes a network
+// containing only that address
+func parseNetworks(entries []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a valid CIDR range", entry)
+			}
+			networks = append(networks, network)
+			continue
+		}
+
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("%q is not a valid IP address", entry)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return networks, nil
+}
+
+// hostname returns the machine's host name, or an empty string if it cannot be determined
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
 // getEnv retrieves an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -48,6 +218,18 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvAsList retrieves a comma-separated environment variable as a list, skipping empty
+// entries; it returns nil if the variable is unset or empty
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // getEnvAsInt retrieves an environment variable as an integer or returns a default value
 func getEnvAsInt(key string, defaultValue int) int {
 	valueStr := os.Getenv(key)
@@ -62,3 +244,33 @@ func getEnvAsInt(key string, defaultValue int) int {
 
 	return value
 }
+
+// getEnvAsFloat retrieves an environment variable as a float or returns a default value
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvAsBool retrieves an environment variable as a boolean or returns a default value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad_WithEnvironmentVariables(t *testing.T) {
@@ -40,6 +42,8 @@ func TestLoad_WithDefaults(t *testing.T) {
 	os.Unsetenv("SYNC_SCHEDULE")
 	os.Unsetenv("API_PORT")
 	os.Unsetenv("LOG_LEVEL")
+	os.Unsetenv("TIMEZONE")
+	os.Unsetenv("AUTH_ALLOW_BASIC")
 
 	// Set only required variable
 	os.Setenv("WANIKANI_API_TOKEN", "test-token")
@@ -68,6 +72,215 @@ func TestLoad_WithDefaults(t *testing.T) {
 	if config.LogLevel != "info" {
 		t.Errorf("expected default log level 'info', got '%s'", config.LogLevel)
 	}
+
+	if config.Timezone != time.UTC {
+		t.Errorf("expected default timezone UTC, got '%s'", config.Timezone)
+	}
+
+	if config.DBCacheSizeKB != 16384 {
+		t.Errorf("expected default DB cache size 16384 KiB, got %d", config.DBCacheSizeKB)
+	}
+
+	if config.DBMmapSizeBytes != 268435456 {
+		t.Errorf("expected default DB mmap size 268435456 bytes, got %d", config.DBMmapSizeBytes)
+	}
+
+	if config.DBCompressData {
+		t.Error("expected DB data compression to be disabled by default")
+	}
+
+	if config.AuthAllowBasic {
+		t.Error("expected basic auth to be disabled by default")
+	}
+
+	if config.MaxConcurrentHeavyRequests != 4 {
+		t.Errorf("expected default heavy request concurrency 4, got %d", config.MaxConcurrentHeavyRequests)
+	}
+
+	if config.MaxResultRows != 100000 {
+		t.Errorf("expected default max result rows 100000, got %d", config.MaxResultRows)
+	}
+
+	if config.SubjectCacheRefreshSeconds != 60 {
+		t.Errorf("expected default subject cache refresh 60s, got %d", config.SubjectCacheRefreshSeconds)
+	}
+
+	if config.StatisticsDedup {
+		t.Error("expected statistics de-duplication to be disabled by default")
+	}
+
+	if config.StatisticsRetentionRecent != 0 {
+		t.Errorf("expected statistics retention to be disabled by default, got %d", config.StatisticsRetentionRecent)
+	}
+
+	if !config.SyncStatisticsEnabled {
+		t.Error("expected statistics sync to be enabled by default")
+	}
+
+	if !config.InitialSnapshotEnabled {
+		t.Error("expected initial snapshot to be enabled by default")
+	}
+
+	if config.HandlerTimeoutSeconds != 30 {
+		t.Errorf("expected handler timeout to default to 30 seconds, got %d", config.HandlerTimeoutSeconds)
+	}
+
+	if config.ImportMaxBytes != 1<<30 {
+		t.Errorf("expected import body limit to default to 1 GiB, got %d", config.ImportMaxBytes)
+	}
+
+	if config.ProblemJSON {
+		t.Error("expected problem+json errors to be disabled by default")
+	}
+
+	if config.Features != nil {
+		t.Errorf("expected no feature list by default (all enabled), got %v", config.Features)
+	}
+
+	if config.DailyReviewGoal != 0 {
+		t.Errorf("expected no daily review goal by default, got %d", config.DailyReviewGoal)
+	}
+
+	if config.TLSCertFile != "" || config.TLSKeyFile != "" {
+		t.Errorf("expected plain HTTP by default, got TLS files %q and %q", config.TLSCertFile, config.TLSKeyFile)
+	}
+	if config.PublicEndpoints != nil {
+		t.Errorf("expected no public endpoints by default, got %v", config.PublicEndpoints)
+	}
+	if config.TrustedProxies != nil {
+		t.Errorf("expected no trusted proxies by default, got %v", config.TrustedProxies)
+	}
+	if config.RequestLogSampleRate != 1 || config.RequestLogSlowMS != 1000 {
+		t.Errorf("expected request log sample rate 1 and slow threshold 1000ms by default, got %v and %d",
+			config.RequestLogSampleRate, config.RequestLogSlowMS)
+	}
+
+	if host, _ := os.Hostname(); config.InstanceName != host {
+		t.Errorf("expected instance name to default to the hostname %q, got %q", host, config.InstanceName)
+	}
+
+	if config.InitialReviewSyncDays != 0 {
+		t.Errorf("expected initial review sync days to default to 0, got %d", config.InitialReviewSyncDays)
+	}
+
+	if config.SyncOverlapSeconds != 60 {
+		t.Errorf("expected sync overlap to default to 60 seconds, got %d", config.SyncOverlapSeconds)
+	}
+
+	if config.SyncConditional {
+		t.Error("expected conditional sync to be disabled by default")
+	}
+
+	if config.SyncValidation != "skip" {
+		t.Errorf("expected default sync validation 'skip', got '%s'", config.SyncValidation)
+	}
+
+	if config.StatisticsSource != "summary" {
+		t.Errorf("expected default statistics source 'summary', got '%s'", config.StatisticsSource)
+	}
+}
+
+func TestLoad_SyncValidation(t *testing.T) {
+	os.Setenv("WANIKANI_API_TOKEN", "test-token")
+	defer func() {
+		os.Unsetenv("WANIKANI_API_TOKEN")
+		os.Unsetenv("SYNC_VALIDATION")
+	}()
+
+	os.Setenv("SYNC_VALIDATION", "strict")
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if config.SyncValidation != "strict" {
+		t.Errorf("expected sync validation 'strict', got '%s'", config.SyncValidation)
+	}
+
+	os.Setenv("SYNC_VALIDATION", "lenient")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for invalid SYNC_VALIDATION, got nil")
+	}
+}
+
+func TestLoad_StatisticsSource(t *testing.T) {
+	os.Setenv("WANIKANI_API_TOKEN", "test-token")
+	defer func() {
+		os.Unsetenv("WANIKANI_API_TOKEN")
+		os.Unsetenv("STATISTICS_SOURCE")
+	}()
+
+	os.Setenv("STATISTICS_SOURCE", "assignments")
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if config.StatisticsSource != "assignments" {
+		t.Errorf("expected statistics source 'assignments', got '%s'", config.StatisticsSource)
+	}
+
+	os.Setenv("STATISTICS_SOURCE", "reviews")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for invalid STATISTICS_SOURCE, got nil")
+	}
+}
+
+func TestLoad_TLSFiles(t *testing.T) {
+	os.Setenv("WANIKANI_API_TOKEN", "test-token")
+	defer func() {
+		os.Unsetenv("WANIKANI_API_TOKEN")
+		os.Unsetenv("TLS_CERT_FILE")
+		os.Unsetenv("TLS_KEY_FILE")
+	}()
+
+	dir := t.TempDir()
+	certFile, keyFile := dir+"/cert.pem", dir+"/key.pem"
+	for _, path := range []string{certFile, keyFile} {
+		if err := os.WriteFile(path, []byte("pem"), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	os.Setenv("TLS_CERT_FILE", certFile)
+	os.Setenv("TLS_KEY_FILE", keyFile)
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if config.TLSCertFile != certFile || config.TLSKeyFile != keyFile {
+		t.Errorf("expected TLS files %s and %s, got %s and %s", certFile, keyFile, config.TLSCertFile, config.TLSKeyFile)
+	}
+
+	os.Setenv("TLS_KEY_FILE", dir+"/missing.pem")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for a missing TLS_KEY_FILE, got nil")
+	}
+
+	os.Unsetenv("TLS_KEY_FILE")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for TLS_CERT_FILE without TLS_KEY_FILE, got nil")
+	}
+}
+
+func TestLoad_Timezone(t *testing.T) {
+	os.Setenv("WANIKANI_API_TOKEN", "test-token")
+	defer func() {
+		os.Unsetenv("WANIKANI_API_TOKEN")
+		os.Unsetenv("TIMEZONE")
+	}()
+
+	os.Setenv("TIMEZONE", "Europe/Stockholm")
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if config.Timezone.String() != "Europe/Stockholm" {
+		t.Errorf("expected timezone 'Europe/Stockholm', got '%s'", config.Timezone)
+	}
+
+	os.Setenv("TIMEZONE", "Not/AZone")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for invalid TIMEZONE, got nil")
+	}
 }
 
 func TestLoad_MissingRequiredToken(t *testing.T) {
@@ -79,3 +292,93 @@ func TestLoad_MissingRequiredToken(t *testing.T) {
 		t.Error("expected error when WANIKANI_API_TOKEN is missing, got nil")
 	}
 }
+
+func TestLoad_SyncDisabledWithoutToken(t *testing.T) {
+	os.Unsetenv("WANIKANI_API_TOKEN")
+	defer os.Unsetenv("SYNC_ENABLED")
+
+	os.Setenv("SYNC_ENABLED", "false")
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("expected read-only config to load without a token, got %v", err)
+	}
+	if config.SyncEnabled {
+		t.Error("expected sync to be disabled")
+	}
+
+	os.Unsetenv("SYNC_ENABLED")
+	if _, err := Load(); err == nil {
+		t.Error("expected error when WANIKANI_API_TOKEN is missing with sync enabled by default, got nil")
+	}
+}
+
+func TestLoad_Features(t *testing.T) {
+	os.Setenv("WANIKANI_API_TOKEN", "test-token")
+	os.Setenv("FEATURES", " streak, ,weekly-reviews,")
+	defer func() {
+		os.Unsetenv("WANIKANI_API_TOKEN")
+		os.Unsetenv("FEATURES")
+	}()
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if len(config.Features) != 2 || config.Features[0] != "streak" || config.Features[1] != "weekly-reviews" {
+		t.Errorf("expected features [streak weekly-reviews], got %v", config.Features)
+	}
+}
+
+func TestLoad_TrustedProxies(t *testing.T) {
+	os.Setenv("WANIKANI_API_TOKEN", "test-token")
+	defer func() {
+		os.Unsetenv("WANIKANI_API_TOKEN")
+		os.Unsetenv("TRUSTED_PROXIES")
+	}()
+
+	os.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.10,::1")
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	var networks []string
+	for _, network := range config.TrustedProxies {
+		networks = append(networks, network.String())
+	}
+	if strings.Join(networks, ",") != "10.0.0.0/8,192.0.2.10/32,::1/128" {
+		t.Errorf("expected trusted proxies [10.0.0.0/8 192.0.2.10/32 ::1/128], got %v", networks)
+	}
+
+	for _, invalid := range []string{"10.0.0.0/33", "proxy.local"} {
+		os.Setenv("TRUSTED_PROXIES", invalid)
+		if _, err := Load(); err == nil {
+			t.Errorf("expected error for invalid TRUSTED_PROXIES %q, got nil", invalid)
+		}
+	}
+}
+
+func TestLoad_RequestLogSampleRate(t *testing.T) {
+	os.Setenv("WANIKANI_API_TOKEN", "test-token")
+	defer func() {
+		os.Unsetenv("WANIKANI_API_TOKEN")
+		os.Unsetenv("REQUEST_LOG_SAMPLE_RATE")
+	}()
+
+	os.Setenv("REQUEST_LOG_SAMPLE_RATE", "0.1")
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if config.RequestLogSampleRate != 0.1 {
+		t.Errorf("expected request log sample rate 0.1, got %v", config.RequestLogSampleRate)
+	}
+
+	for _, invalid := range []string{"-0.5", "1.5"} {
+		os.Setenv("REQUEST_LOG_SAMPLE_RATE", invalid)
+		if _, err := Load(); err == nil {
+			t.Errorf("expected error for invalid REQUEST_LOG_SAMPLE_RATE %q, got nil", invalid)
+		}
+	}
+}
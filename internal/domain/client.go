@@ -5,6 +5,27 @@ import (
 	"time"
 )
 
+// FetchOptions narrows a collection fetch using the WaniKani API's query filters
+type FetchOptions struct {
+	// UpdatedAfter limits results to records modified after this time
+	UpdatedAfter *time.Time
+
+	// ImmediatelyAvailableForReview limits assignments to those with a review due now
+	ImmediatelyAvailableForReview bool
+
+	// Started limits assignments to those whose lessons were (true) or were not (false)
+	// completed; nil does not filter
+	Started *bool
+
+	// Unlocked limits assignments to those that are (true) or are not (false) unlocked;
+	// nil does not filter
+	Unlocked *bool
+
+	// IncludeHidden also returns subjects WaniKani has hidden from lessons and reviews,
+	// with their hidden_at set; otherwise only visible subjects are fetched
+	IncludeHidden bool
+}
+
 // WaniKaniClient defines the interface for communicating with the WaniKani API
 type WaniKaniClient interface {
 	// SetAPIToken sets the API token for authentication
@@ -14,10 +35,20 @@ type WaniKaniClient interface {
 	// If updatedAfter is provided, only subjects modified after that time are returned
 	FetchSubjects(ctx context.Context, updatedAfter *time.Time) ([]Subject, error)
 
+	// FetchSubjectsWithOptions retrieves subjects narrowed by the given options
+	FetchSubjectsWithOptions(ctx context.Context, opts FetchOptions) ([]Subject, error)
+
+	// FetchLevelProgressions retrieves level progressions from the WaniKani API
+	// If updatedAfter is provided, only level progressions modified after that time are returned
+	FetchLevelProgressions(ctx context.Context, updatedAfter *time.Time) ([]LevelProgression, error)
+
 	// FetchAssignments retrieves assignments from the WaniKani API
 	// If updatedAfter is provided, only assignments modified after that time are returned
 	FetchAssignments(ctx context.Context, updatedAfter *time.Time) ([]Assignment, error)
 
+	// FetchAssignmentsWithOptions retrieves assignments narrowed by the given options
+	FetchAssignmentsWithOptions(ctx context.Context, opts FetchOptions) ([]Assignment, error)
+
 	// FetchReviews retrieves reviews from the WaniKani API
 	// If updatedAfter is provided, only reviews modified after that time are returned
 	FetchReviews(ctx context.Context, updatedAfter *time.Time) ([]Review, error)
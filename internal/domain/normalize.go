@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+)
+
+// accentFolds maps accented Latin letters to their unaccented base letters
+var accentFolds = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae",
+	'ç': "c", 'ć': "c", 'ĉ': "c", 'ċ': "c", 'č': "c",
+	'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ĕ': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ĝ': "g", 'ğ': "g", 'ġ': "g", 'ģ': "g",
+	'ĥ': "h", 'ħ': "h",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ĩ': "i", 'ī': "i", 'ĭ': "i", 'į': "i", 'ı': "i",
+	'ĵ': "j",
+	'ķ': "k",
+	'ĺ': "l", 'ļ': "l", 'ľ': "l", 'ŀ': "l", 'ł': "l",
+	'ñ': "n", 'ń': "n", 'ņ': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ŏ': "o", 'ő': "o",
+	'œ': "oe",
+	'ŕ': "r", 'ŗ': "r", 'ř': "r",
+	'ś': "s", 'ŝ': "s", 'ş': "s", 'š': "s", 'ß': "ss",
+	'ţ': "t", 'ť': "t", 'ŧ': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ũ': "u", 'ū': "u", 'ŭ': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ŵ': "w",
+	'ý': "y", 'ÿ': "y", 'ŷ': "y",
+	'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// NormalizeMeaning folds a meaning or search query for comparison: lowercased,
+// accents removed ("Über" -> "uber"), and whitespace collapsed to single spaces
+func NormalizeMeaning(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	space := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsSpace(r) {
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		if fold, ok := accentFolds[r]; ok {
+			b.WriteString(fold)
+		} else if !unicode.Is(unicode.Mn, r) {
+			// Drop combining marks left over from decomposed input
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// SearchableMeanings returns the meanings a learner may answer with: primary and
+// alternative meanings plus whitelisted auxiliary meanings
+func (d SubjectData) SearchableMeanings() []string {
+	meanings := make([]string, 0, len(d.Meanings)+len(d.AuxiliaryMeanings))
+	for _, m := range d.Meanings {
+		meanings = append(meanings, m.Meaning)
+	}
+	for _, m := range d.AuxiliaryMeanings {
+		if m.Type == AuxiliaryMeaningWhitelist {
+			meanings = append(meanings, m.Meaning)
+		}
+	}
+	return meanings
+}
+
+// MatchesMeaning reports whether any searchable meaning contains query, ignoring case and accents
+func (d SubjectData) MatchesMeaning(query string) bool {
+	query = NormalizeMeaning(query)
+	if query == "" {
+		return true
+	}
+	for _, meaning := range d.SearchableMeanings() {
+		if strings.Contains(NormalizeMeaning(meaning), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// Search relevance tiers returned by SearchScore, best first
+const (
+	SearchScoreCharacters     = 4 // the query is the subject's characters
+	SearchScorePrimaryMeaning = 3 // the query is the primary meaning
+	SearchScorePrefix         = 2 // a searchable meaning or a reading starts with the query
+	SearchScoreSubstring      = 1 // a searchable meaning or a reading contains the query
+)
+
+// SearchScore rates how well the subject matches query, ignoring case and accents, as
+// one of the SearchScore tiers. It returns 0 if the subject does not match.
+func (d SubjectData) SearchScore(query string) int {
+	query = NormalizeMeaning(query)
+	if query == "" {
+		return 0
+	}
+
+	if d.Characters != "" && NormalizeMeaning(d.Characters) == query {
+		return SearchScoreCharacters
+	}
+	for _, m := range d.Meanings {
+		if m.Primary && NormalizeMeaning(m.Meaning) == query {
+			return SearchScorePrimaryMeaning
+		}
+	}
+
+	candidates := d.SearchableMeanings()
+	for _, r := range d.Readings {
+		candidates = append(candidates, r.Reading)
+	}
+
+	score := 0
+	for _, candidate := range candidates {
+		candidate = NormalizeMeaning(candidate)
+		if strings.HasPrefix(candidate, query) {
+			return SearchScorePrefix
+		}
+		if strings.Contains(candidate, query) {
+			score = SearchScoreSubstring
+		}
+	}
+	return score
+}
@@ -3,9 +3,15 @@ package domain
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"io"
 	"time"
 )
 
+// ErrInvalidBackup is returned by DataStore.Import when the backup document is malformed
+// or inconsistent, as opposed to a failure of the store itself
+var ErrInvalidBackup = errors.New("invalid backup document")
+
 // DataStore defines the interface for persisting and querying WaniKani data
 type DataStore interface {
 	// UpsertSubjects inserts or updates subjects in the data store
@@ -14,12 +20,30 @@ type DataStore interface {
 	// GetSubjects retrieves subjects matching the provided filters
 	GetSubjects(ctx context.Context, filters SubjectFilters) ([]Subject, error)
 
+	// StreamSubjects calls fn with each subject matching the provided filters, in the order
+	// GetSubjects returns them, stopping at the first error fn returns
+	StreamSubjects(ctx context.Context, filters SubjectFilters, fn func(Subject) error) error
+
+	// UpsertLevelProgressions inserts or updates level progressions in the data store
+	UpsertLevelProgressions(ctx context.Context, progressions []LevelProgression) error
+
+	// GetLevelProgressions retrieves all level progressions ordered by level
+	GetLevelProgressions(ctx context.Context) ([]LevelProgression, error)
+
 	// UpsertAssignments inserts or updates assignments in the data store
 	UpsertAssignments(ctx context.Context, assignments []Assignment) error
 
 	// GetAssignments retrieves assignments matching the provided filters
 	GetAssignments(ctx context.Context, filters AssignmentFilters) ([]Assignment, error)
 
+	// GetAssignmentsPassedOn retrieves the assignments passed on the calendar day of date,
+	// in the store's timezone
+	GetAssignmentsPassedOn(ctx context.Context, date time.Time) ([]Assignment, error)
+
+	// GetAvailableLessons retrieves the assignments that are unlocked but not yet started and
+	// whose subject is not hidden, ordered by their subject's level and lesson position
+	GetAvailableLessons(ctx context.Context) ([]Assignment, error)
+
 	// UpsertReviews inserts or updates reviews in the data store
 	UpsertReviews(ctx context.Context, reviews []Review) error
 
@@ -30,11 +54,16 @@ type DataStore interface {
 	InsertStatistics(ctx context.Context, stats Statistics, timestamp time.Time) error
 
 	// GetStatistics retrieves statistics snapshots within the provided date range
-	GetStatistics(ctx context.Context, dateRange *DateRange) ([]StatisticsSnapshot, error)
+	GetStatistics(ctx context.Context, dateRange *DateRange, opts StatisticsOptions) ([]StatisticsSnapshot, error)
 
-	// GetLatestStatistics retrieves the most recent statistics snapshot
+	// GetLatestStatistics retrieves the most recent statistics snapshot. It returns nil and
+	// no error when no snapshot has been stored yet; callers treat that as "no data yet".
 	GetLatestStatistics(ctx context.Context) (*StatisticsSnapshot, error)
 
+	// PruneStatistics keeps the keepRecent most recent statistics snapshots and the latest
+	// snapshot of each earlier day, deleting the rest. It returns the number deleted.
+	PruneStatistics(ctx context.Context, keepRecent int) (int, error)
+
 	// UpsertAssignmentSnapshot inserts or updates an assignment snapshot
 	UpsertAssignmentSnapshot(ctx context.Context, snapshot AssignmentSnapshot) error
 
@@ -44,12 +73,109 @@ type DataStore interface {
 	// CalculateAssignmentSnapshot computes a snapshot from current assignments for a given date
 	CalculateAssignmentSnapshot(ctx context.Context, date time.Time) ([]AssignmentSnapshot, error)
 
+	// GetReviewStreak computes the current and longest streaks of consecutive review days
+	// and the most recent day with reviews (zero if there are none)
+	GetReviewStreak(ctx context.Context) (current int, longest int, lastDay time.Time, err error)
+
+	// GetReviewDateRange returns when the earliest and latest reviews were created, both nil
+	// when there are no reviews
+	GetReviewDateRange(ctx context.Context) (min, max *time.Time, err error)
+
+	// GetSubjectAccuracy sums the correct and incorrect answers of every reviewed subject
+	GetSubjectAccuracy(ctx context.Context) ([]SubjectAccuracy, error)
+
+	// GetReviewCountsByHour counts reviews by the hour of day (0-23) they were created in,
+	// in the store's timezone. From and To in filters, when set, are calendar days in that
+	// timezone and are both inclusive.
+	GetReviewCountsByHour(ctx context.Context, filters ReviewFilters) ([24]int, error)
+
+	// GetReviewCountsByWeek counts reviews by the ISO week they were created in, in the
+	// store's timezone, keyed by the week's Monday as a UTC-midnight date. Weeks without
+	// reviews are absent. From and To are handled as for GetReviewCountsByHour.
+	GetReviewCountsByWeek(ctx context.Context, filters ReviewFilters) (map[time.Time]int, error)
+
+	// GetReviewCountsByDay counts reviews by the calendar day they were created on, in the
+	// store's timezone, keyed by UTC-midnight dates. Days without reviews are absent. From
+	// and To are handled as for GetReviewCountsByHour.
+	GetReviewCountsByDay(ctx context.Context, filters ReviewFilters) (map[time.Time]int, error)
+
+	// GetReviewCountToday counts the reviews created today in the store's timezone,
+	// returning today's date as a calendar day in that timezone
+	GetReviewCountToday(ctx context.Context) (day time.Time, count int, err error)
+
+	// GetCurrentSRSGroupTotals counts current assignments past lessons by SRS stage group
+	// and subject type (group -> subject type -> count)
+	GetCurrentSRSGroupTotals(ctx context.Context) (map[string]map[string]int, error)
+
+	// GetAssignmentCounts counts current assignments by state and subject type in a single
+	// query. Subject types without assignments are absent from SubjectTypes.
+	GetAssignmentCounts(ctx context.Context) (AssignmentCounts, error)
+
+	// GetSubjectCountsByLevel counts subjects by level and type (level -> subject type ->
+	// count); levels without subjects are absent
+	GetSubjectCountsByLevel(ctx context.Context) (map[int]map[string]int, error)
+
+	// GetDistinctLevels returns the levels that have at least one subject, in ascending order
+	GetDistinctLevels(ctx context.Context) ([]int, error)
+
+	// GetSubjectsByIDs retrieves the subjects with the given IDs, skipping IDs not stored
+	GetSubjectsByIDs(ctx context.Context, ids []int64) ([]Subject, error)
+
+	// GetAssignmentsByIDs retrieves the assignments with the given IDs, skipping IDs not stored
+	GetAssignmentsByIDs(ctx context.Context, ids []int64) ([]Assignment, error)
+
+	// GetLastReviewPerSubject returns when each of the given subjects was last reviewed.
+	// Subjects without reviews are absent from the map.
+	GetLastReviewPerSubject(ctx context.Context, subjectIDs []int64) (map[int64]time.Time, error)
+
+	// GetLevelProgress counts the subjects of a level by type and progress
+	GetLevelProgress(ctx context.Context, level int) (*LevelProgress, error)
+
+	// CountSubjects returns the number of stored subjects
+	CountSubjects(ctx context.Context) (int, error)
+
+	// CountAssignments returns the number of stored assignments
+	CountAssignments(ctx context.Context) (int, error)
+
+	// CountReviews returns the number of stored reviews
+	CountReviews(ctx context.Context) (int, error)
+
+	// CountAssignmentSnapshots returns the number of stored assignment snapshot rows
+	CountAssignmentSnapshots(ctx context.Context) (int, error)
+
+	// GetCurrentLevel returns the highest subject level with an unlocked assignment, or 0 if none
+	GetCurrentLevel(ctx context.Context) (int, error)
+
+	// CheckIntegrity finds assignments and reviews whose parent records are missing,
+	// deleting them in a single transaction if repair is true
+	CheckIntegrity(ctx context.Context, repair bool) (*IntegrityReport, error)
+
 	// GetLastSyncTime retrieves the last successful sync timestamp for a data type
 	GetLastSyncTime(ctx context.Context, dataType DataType) (*time.Time, error)
 
 	// SetLastSyncTime updates the last successful sync timestamp for a data type
 	SetLastSyncTime(ctx context.Context, dataType DataType, timestamp time.Time) error
 
+	// ClearSyncTimes removes the last sync timestamp of dataType, or of every data type if
+	// dataType is nil, so the next sync fetches everything. It returns the number cleared.
+	ClearSyncTimes(ctx context.Context, dataType *DataType) (int, error)
+
+	// Export writes all stored data to w as a single JSON backup document
+	Export(ctx context.Context, w io.Writer) error
+
+	// Import replaces all stored data with a JSON backup document read from r, returning
+	// the number of rows imported per table. Nothing is changed if the import fails.
+	Import(ctx context.Context, r io.Reader) (map[string]int, error)
+
 	// BeginTx starts a new database transaction
 	BeginTx(ctx context.Context) (*sql.Tx, error)
 }
+
+// RateLimitStore persists the WaniKani client's rate limit state across restarts
+type RateLimitStore interface {
+	// SaveRateLimitState stores the most recently observed rate limit information
+	SaveRateLimitState(ctx context.Context, info RateLimitInfo) error
+
+	// LoadRateLimitState retrieves the persisted rate limit information, or nil if none exists
+	LoadRateLimitState(ctx context.Context) (*RateLimitInfo, error)
+}
@@ -1,6 +1,13 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrSyncInProgress is returned when a sync is requested while another one is running
+var ErrSyncInProgress = errors.New("sync already in progress")
 
 // SyncService defines the interface for orchestrating data synchronization
 type SyncService interface {
@@ -10,9 +17,15 @@ type SyncService interface {
 	// SyncSubjects syncs only subjects
 	SyncSubjects(ctx context.Context) SyncResult
 
+	// SyncLevelProgressions syncs only level progressions
+	SyncLevelProgressions(ctx context.Context) SyncResult
+
 	// SyncAssignments syncs only assignments
 	SyncAssignments(ctx context.Context) SyncResult
 
+	// SyncAvailableAssignments refreshes only the assignments immediately available for review
+	SyncAvailableAssignments(ctx context.Context) SyncResult
+
 	// SyncReviews syncs only reviews
 	SyncReviews(ctx context.Context) SyncResult
 
@@ -24,4 +37,7 @@ type SyncService interface {
 
 	// IsSyncing returns true if a sync operation is currently in progress
 	IsSyncing() bool
+
+	// SyncStartedAt returns when the sync in progress started, or nil if no sync is running
+	SyncStartedAt() *time.Time
 }
@@ -1,24 +1,65 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // DataType represents the type of WaniKani data being synced
 type DataType string
 
 const (
-	DataTypeSubjects    DataType = "subjects"
-	DataTypeAssignments DataType = "assignments"
-	DataTypeReviews     DataType = "reviews"
-	DataTypeStatistics  DataType = "statistics"
+	DataTypeSubjects          DataType = "subjects"
+	DataTypeLevelProgressions DataType = "level_progressions"
+	DataTypeAssignments       DataType = "assignments"
+	DataTypeReviews           DataType = "reviews"
+	DataTypeStatistics        DataType = "statistics"
 )
 
+// DataTypes lists every synced data type in the order SyncAll syncs them
+var DataTypes = []DataType{
+	DataTypeSubjects,
+	DataTypeLevelProgressions,
+	DataTypeAssignments,
+	DataTypeReviews,
+	DataTypeStatistics,
+}
+
 // Subject represents a WaniKani learning item
 type Subject struct {
-	ID            int         `json:"id"`
+	ID            int64       `json:"id"`
 	Object        string      `json:"object"`
 	URL           string      `json:"url"`
 	DataUpdatedAt time.Time   `json:"data_updated_at"`
 	Data          SubjectData `json:"data"`
+	// Raw holds the original, unparsed data object. It is captured when a
+	// subject is decoded from the WaniKani API so that fields not modelled by
+	// SubjectData are persisted, and is only returned by the store on request.
+	Raw json.RawMessage `json:"raw,omitempty"`
+}
+
+// UnmarshalJSON decodes a subject while retaining its original data object in Raw
+func (s *Subject) UnmarshalJSON(b []byte) error {
+	type subjectAlias Subject
+	aux := struct {
+		*subjectAlias
+		Data json.RawMessage `json:"data"`
+	}{subjectAlias: (*subjectAlias)(s)}
+
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	if len(aux.Data) == 0 || string(aux.Data) == "null" {
+		return nil
+	}
+
+	if err := json.Unmarshal(aux.Data, &s.Data); err != nil {
+		return err
+	}
+	s.Raw = aux.Data
+
+	return nil
 }
 
 type SubjectData struct {
@@ -26,22 +67,65 @@ type SubjectData struct {
 	Characters string    `json:"characters"`
 	Meanings   []Meaning `json:"meanings"`
 	Readings   []Reading `json:"readings,omitempty"`
+
+	// LessonPosition is the order WaniKani presents the subject in within its level
+	LessonPosition int `json:"lesson_position"`
+
+	// CharacterImages renders radicals that have no Unicode character; Characters is
+	// empty for those
+	CharacterImages []CharacterImage `json:"character_images,omitempty"`
+
+	AuxiliaryMeanings []AuxiliaryMeaning `json:"auxiliary_meanings,omitempty"`
+
+	// HiddenAt is when WaniKani retired the subject from lessons and reviews, nil while
+	// it is still in use
+	HiddenAt *time.Time `json:"hidden_at,omitempty"`
+}
+
+// CharacterImage is one rendition of a subject's characters as an image
+type CharacterImage struct {
+	URL         string                 `json:"url"`
+	ContentType string                 `json:"content_type"`
+	Metadata    CharacterImageMetadata `json:"metadata"`
+}
+
+// CharacterImageMetadata describes a character image. PNG images carry color, dimensions
+// and style_name; SVG images carry inline_styles.
+type CharacterImageMetadata struct {
+	Color        string `json:"color,omitempty"`
+	Dimensions   string `json:"dimensions,omitempty"`
+	StyleName    string `json:"style_name,omitempty"`
+	InlineStyles *bool  `json:"inline_styles,omitempty"`
 }
 
 type Meaning struct {
+	Meaning        string `json:"meaning"`
+	Primary        bool   `json:"primary"`
+	AcceptedAnswer bool   `json:"accepted_answer"`
+}
+
+// Auxiliary meaning types
+const (
+	AuxiliaryMeaningWhitelist = "whitelist"
+	AuxiliaryMeaningBlacklist = "blacklist"
+)
+
+// AuxiliaryMeaning is an extra answer WaniKani accepts (whitelist) or rejects (blacklist)
+type AuxiliaryMeaning struct {
 	Meaning string `json:"meaning"`
-	Primary bool   `json:"primary"`
+	Type    string `json:"type"`
 }
 
 type Reading struct {
-	Reading string `json:"reading"`
-	Primary bool   `json:"primary"`
-	Type    string `json:"type"`
+	Reading        string `json:"reading"`
+	Primary        bool   `json:"primary"`
+	AcceptedAnswer bool   `json:"accepted_answer"`
+	Type           string `json:"type,omitempty"` // onyomi, kunyomi or nanori; kanji only
 }
 
 // Assignment represents a user's progress on a subject
 type Assignment struct {
-	ID            int            `json:"id"`
+	ID            int64          `json:"id"`
 	Object        string         `json:"object"`
 	URL           string         `json:"url"`
 	DataUpdatedAt time.Time      `json:"data_updated_at"`
@@ -49,17 +133,37 @@ type Assignment struct {
 }
 
 type AssignmentData struct {
-	SubjectID   int        `json:"subject_id"`
+	SubjectID   int64      `json:"subject_id"`
 	SubjectType string     `json:"subject_type"`
 	SRSStage    int        `json:"srs_stage"`
 	UnlockedAt  *time.Time `json:"unlocked_at"`
 	StartedAt   *time.Time `json:"started_at"`
 	PassedAt    *time.Time `json:"passed_at"`
+	AvailableAt *time.Time `json:"available_at"`
+}
+
+// LevelProgression records when a user unlocked, started and passed a level
+type LevelProgression struct {
+	ID            int64                `json:"id"`
+	Object        string               `json:"object"`
+	URL           string               `json:"url"`
+	DataUpdatedAt time.Time            `json:"data_updated_at"`
+	Data          LevelProgressionData `json:"data"`
+}
+
+type LevelProgressionData struct {
+	Level       int        `json:"level"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UnlockedAt  *time.Time `json:"unlocked_at"`
+	StartedAt   *time.Time `json:"started_at"`
+	PassedAt    *time.Time `json:"passed_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	AbandonedAt *time.Time `json:"abandoned_at"`
 }
 
 // Review represents a user's answer to a quiz question
 type Review struct {
-	ID            int        `json:"id"`
+	ID            int64      `json:"id"`
 	Object        string     `json:"object"`
 	URL           string     `json:"url"`
 	DataUpdatedAt time.Time  `json:"data_updated_at"`
@@ -67,8 +171,8 @@ type Review struct {
 }
 
 type ReviewData struct {
-	AssignmentID            int       `json:"assignment_id"`
-	SubjectID               int       `json:"subject_id"`
+	AssignmentID            int64     `json:"assignment_id"`
+	SubjectID               int64     `json:"subject_id"`
 	CreatedAt               time.Time `json:"created_at"`
 	IncorrectMeaningAnswers int       `json:"incorrect_meaning_answers"`
 	IncorrectReadingAnswers int       `json:"incorrect_reading_answers"`
@@ -89,16 +193,16 @@ type StatisticsData struct {
 
 type LessonStatistics struct {
 	AvailableAt time.Time `json:"available_at"`
-	SubjectIDs  []int     `json:"subject_ids"`
+	SubjectIDs  []int64   `json:"subject_ids"`
 }
 
 type ReviewStatistics struct {
 	AvailableAt time.Time `json:"available_at"`
-	SubjectIDs  []int     `json:"subject_ids"`
+	SubjectIDs  []int64   `json:"subject_ids"`
 }
 
 type StatisticsSnapshot struct {
-	ID         int        `json:"id"`
+	ID         int64      `json:"id"`
 	Timestamp  time.Time  `json:"timestamp"`
 	Statistics Statistics `json:"statistics"`
 }
@@ -113,24 +217,61 @@ type RateLimitInfo struct {
 type SyncResult struct {
 	DataType       DataType
 	RecordsUpdated int
+	TotalRecords   int // stored row count after the sync; 0 for data types that are not counted
+	InvalidRecords int // fetched records that failed validation and were not stored
 	Success        bool
+	Skipped        bool // true when the data type was intentionally not synced
 	Error          string
+	Err            error `json:"-"` // underlying failure, for classifying it with errors.As
 	Timestamp      time.Time
 }
 
+// SetError records a failed sync, keeping err so callers can tell upstream failures apart
+func (r *SyncResult) SetError(err error) {
+	r.Err = err
+	r.Error = err.Error()
+}
+
 // Filter types for querying
 type SubjectFilters struct {
-	Type  string
-	Level *int
+	Type       string
+	Level      *int
+	LevelMin   *int
+	LevelMax   *int
+	Meaning    string // matched against meanings ignoring case and accents; see NormalizeMeaning
+	IncludeRaw bool
+	Sort       SubjectSort
+	Limit      int // maximum number of subjects to return, in sort order; 0 means no limit
+	// DataUpdatedAfter keeps only subjects whose data_updated_at is after this time
+	DataUpdatedAfter *time.Time
 }
 
+// SubjectSort is the ordering of subject query results
+type SubjectSort string
+
+const (
+	// SubjectSortID orders subjects by ID; it is the default
+	SubjectSortID SubjectSort = "id"
+	// SubjectSortPosition orders subjects by level, then by lesson position within the level
+	SubjectSortPosition SubjectSort = "position"
+	// SubjectSortDataUpdated orders subjects by data_updated_at, then by ID
+	SubjectSortDataUpdated SubjectSort = "data_updated_at"
+)
+
 type AssignmentFilters struct {
 	SRSStage *int
+	Limit    int // maximum number of assignments to return, in ID order; 0 means no limit
 }
 
 type ReviewFilters struct {
-	From *time.Time
-	To   *time.Time
+	From  *time.Time
+	To    *time.Time
+	Limit int // maximum number of reviews to return, in ID order; 0 means no limit
+	// Passed keeps only reviews answered without mistakes (true) or with at least one
+	// incorrect meaning or reading answer (false); nil keeps both. Only GetReviews applies it.
+	Passed *bool
+	// SubjectType keeps only reviews of subjects of this type (radical, kanji, vocabulary)
+	SubjectType string
 }
 
 type DateRange struct {
@@ -138,6 +279,23 @@ type DateRange struct {
 	To   time.Time
 }
 
+// SortOrder is the direction results are ordered in
+type SortOrder string
+
+const (
+	SortDesc SortOrder = "desc"
+	SortAsc  SortOrder = "asc"
+)
+
+// StatisticsOptions controls the size and ordering of statistics queries.
+// The zero value returns every matching snapshot, newest first.
+type StatisticsOptions struct {
+	// Limit caps the result to the most recent snapshots; 0 means no limit
+	Limit int
+	// Order is the timestamp ordering of the returned snapshots; empty means SortDesc
+	Order SortOrder
+}
+
 // SRS Stage constants
 const (
 	SRSStageInitiate    = 0
@@ -152,6 +310,90 @@ const (
 	SRSStageBurned      = 9
 )
 
+// LevelProgress summarizes the learner's progress on the subjects of a single level
+type LevelProgress struct {
+	Level        int                            `json:"level"`
+	SubjectTypes map[string]LevelProgressCounts `json:"subject_types"` // subject type -> counts
+	Total        LevelProgressCounts            `json:"total"`
+}
+
+// LevelProgressCounts splits a level's subjects by progress: passed (SRS stage 5 or
+// higher), in progress (unlocked but not yet passed) and locked (not yet unlocked)
+type LevelProgressCounts struct {
+	Total      int `json:"total"`
+	Passed     int `json:"passed"`
+	InProgress int `json:"in_progress"`
+	Locked     int `json:"locked"`
+}
+
+// AssignmentCounts counts current assignments by state (see Assignment.State), in total
+// and per subject type
+type AssignmentCounts struct {
+	SubjectTypes map[string]AssignmentStateCounts `json:"subject_types"` // subject type -> counts
+	Total        AssignmentStateCounts            `json:"total"`
+}
+
+// AssignmentStateCounts splits assignments by state. Started counts every assignment past
+// lessons, so it is InProgress plus Burned.
+type AssignmentStateCounts struct {
+	Total      int `json:"total"`
+	Locked     int `json:"locked"`
+	Lessons    int `json:"lessons"`
+	Started    int `json:"started"`
+	InProgress int `json:"in_progress"`
+	Burned     int `json:"burned"`
+}
+
+// Add counts one assignment in the given state
+func (c *AssignmentStateCounts) Add(state AssignmentState) {
+	c.Total++
+	switch state {
+	case AssignmentStateLocked:
+		c.Locked++
+	case AssignmentStateLesson:
+		c.Lessons++
+	case AssignmentStateInProgress:
+		c.InProgress++
+		c.Started++
+	case AssignmentStateBurned:
+		c.Burned++
+		c.Started++
+	}
+}
+
+// SubjectAccuracy sums the answers given across every review of a subject. Each review
+// has one correct meaning answer, plus one correct reading answer for subjects with
+// readings, after any incorrect ones.
+type SubjectAccuracy struct {
+	SubjectID        int64
+	Reviews          int
+	CorrectAnswers   int
+	IncorrectAnswers int
+}
+
+// PercentageCorrect returns the share of correct answers as a percentage, or 100 if no
+// answers were given
+func (a SubjectAccuracy) PercentageCorrect() float64 {
+	total := a.CorrectAnswers + a.IncorrectAnswers
+	if total == 0 {
+		return 100
+	}
+	return float64(a.CorrectAnswers) * 100 / float64(total)
+}
+
+// IntegrityReport lists rows whose parent records are missing
+type IntegrityReport struct {
+	OrphanedAssignments IntegrityIssue `json:"orphaned_assignments"` // assignments without a subject
+	OrphanedReviews     IntegrityIssue `json:"orphaned_reviews"`     // reviews without an assignment or subject
+	Repaired            bool           `json:"repaired"`             // true if the orphans were deleted
+}
+
+// IntegrityIssue counts the rows affected by an integrity problem, with a sample of their IDs
+type IntegrityIssue struct {
+	Count     int     `json:"count"`
+	SampleIDs []int64 `json:"sample_ids"`
+}
+
 // AssignmentSnapshot represents a daily snapshot of assignment counts
 type AssignmentSnapshot struct {
 	Date        time.Time `json:"date"`
@@ -166,9 +408,104 @@ type AssignmentSnapshotSummary struct {
 	Data map[string]map[string]int `json:"data"` // SRS stage name -> subject type -> count
 }
 
+// SRSStageGroupOrder lists the SRS stage group names in progression order
+var SRSStageGroupOrder = []string{"apprentice", "guru", "master", "enlightened", "burned"}
+
+// SRSStage is a WaniKani SRS stage number, from 0 (lessons) to 9 (burned)
+type SRSStage int
+
+// Bounds of the WaniKani SRS stage range
+const (
+	SRSStageMin SRSStage = 0
+	SRSStageMax SRSStage = 9
+)
+
+// srsStageNames holds the display name of each SRS stage, indexed by stage number
+var srsStageNames = [...]string{
+	"Initiate",
+	"Apprentice I", "Apprentice II", "Apprentice III", "Apprentice IV",
+	"Guru I", "Guru II",
+	"Master",
+	"Enlightened",
+	"Burned",
+}
+
+// SRSStages returns every SRS stage in progression order
+func SRSStages() []SRSStage {
+	stages := make([]SRSStage, 0, SRSStageMax-SRSStageMin+1)
+	for stage := SRSStageMin; stage <= SRSStageMax; stage++ {
+		stages = append(stages, stage)
+	}
+	return stages
+}
+
+// Name returns the display name of the stage (e.g. "Apprentice II")
+func (s SRSStage) Name() string {
+	if s < SRSStageMin || s > SRSStageMax {
+		return "Unknown"
+	}
+	return srsStageNames[s]
+}
+
+// Group returns the name of the stage group the stage belongs to (e.g. "apprentice")
+func (s SRSStage) Group() string {
+	return GetSRSStageName(int(s))
+}
+
+// SRSStageGroup is a named group of consecutive SRS stages
+type SRSStageGroup struct {
+	Name   string     `json:"name"`
+	Stages []SRSStage `json:"stages"`
+}
+
+// SRSStageGroups returns the stage groups in progression order, derived from GetSRSStageName
+func SRSStageGroups() []SRSStageGroup {
+	var groups []SRSStageGroup
+	for _, stage := range SRSStages() {
+		group := stage.Group()
+		if len(groups) == 0 || groups[len(groups)-1].Name != group {
+			groups = append(groups, SRSStageGroup{Name: group})
+		}
+		groups[len(groups)-1].Stages = append(groups[len(groups)-1].Stages, stage)
+	}
+	return groups
+}
+
+// AssignmentState is a coarse summary of where an assignment stands, as shown on a dashboard
+type AssignmentState string
+
+const (
+	// AssignmentStateLocked is an assignment whose subject has not been unlocked yet
+	AssignmentStateLocked AssignmentState = "locked"
+	// AssignmentStateLesson is an unlocked assignment still waiting in lessons (stage 0)
+	AssignmentStateLesson AssignmentState = "lesson"
+	// AssignmentStateInProgress is a started assignment that is not burned (stages 1-8)
+	AssignmentStateInProgress AssignmentState = "in_progress"
+	// AssignmentStateBurned is a burned assignment (stage 9)
+	AssignmentStateBurned AssignmentState = "burned"
+)
+
+// State classifies the assignment by its SRS stage and unlock time. Stages up to
+// SRSStageInitiate are not started yet and stages from SRSStageBurned on are burned; the
+// store counts assignments in SQL with the same thresholds.
+func (a Assignment) State() AssignmentState {
+	switch stage := a.Data.SRSStage; {
+	case stage >= SRSStageBurned:
+		return AssignmentStateBurned
+	case stage > SRSStageInitiate:
+		return AssignmentStateInProgress
+	case a.Data.UnlockedAt == nil:
+		return AssignmentStateLocked
+	default:
+		return AssignmentStateLesson
+	}
+}
+
 // GetSRSStageName returns the human-readable name for an SRS stage
 func GetSRSStageName(stage int) string {
 	switch {
+	case stage == 0:
+		return "initiate"
 	case stage >= 1 && stage <= 4:
 		return "apprentice"
 	case stage >= 5 && stage <= 6:
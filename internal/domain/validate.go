@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidRecord is wrapped by the errors returned from the Validate methods
+var ErrInvalidRecord = errors.New("invalid record")
+
+// Bounds of the WaniKani subject level range
+const (
+	MinLevel = 1
+	MaxLevel = 60
+)
+
+// invalidRecord returns an error wrapping ErrInvalidRecord for the given record
+func invalidRecord(kind string, id int64, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s %d: %s", ErrInvalidRecord, kind, id, fmt.Sprintf(format, args...))
+}
+
+// Validate checks the invariants a subject must satisfy to be stored
+func (s Subject) Validate() error {
+	if s.ID <= 0 {
+		return invalidRecord("subject", s.ID, "id must be positive")
+	}
+	if s.Object == "" {
+		return invalidRecord("subject", s.ID, "object is empty")
+	}
+	if s.Data.Level < MinLevel || s.Data.Level > MaxLevel {
+		return invalidRecord("subject", s.ID, "level %d is outside %d-%d", s.Data.Level, MinLevel, MaxLevel)
+	}
+	return nil
+}
+
+// Validate checks the invariants a level progression must satisfy to be stored
+func (p LevelProgression) Validate() error {
+	if p.ID <= 0 {
+		return invalidRecord("level progression", p.ID, "id must be positive")
+	}
+	if p.Object == "" {
+		return invalidRecord("level progression", p.ID, "object is empty")
+	}
+	if p.Data.Level < MinLevel || p.Data.Level > MaxLevel {
+		return invalidRecord("level progression", p.ID, "level %d is outside %d-%d", p.Data.Level, MinLevel, MaxLevel)
+	}
+	return nil
+}
+
+// Validate checks the invariants an assignment must satisfy to be stored
+func (a Assignment) Validate() error {
+	if a.ID <= 0 {
+		return invalidRecord("assignment", a.ID, "id must be positive")
+	}
+	if a.Object == "" {
+		return invalidRecord("assignment", a.ID, "object is empty")
+	}
+	if a.Data.SubjectID <= 0 {
+		return invalidRecord("assignment", a.ID, "subject_id must be positive")
+	}
+	if stage := SRSStage(a.Data.SRSStage); stage < SRSStageMin || stage > SRSStageMax {
+		return invalidRecord("assignment", a.ID, "srs_stage %d is outside %d-%d", stage, SRSStageMin, SRSStageMax)
+	}
+	return nil
+}
+
+// Validate checks the invariants a review must satisfy to be stored
+func (r Review) Validate() error {
+	if r.ID <= 0 {
+		return invalidRecord("review", r.ID, "id must be positive")
+	}
+	if r.Object == "" {
+		return invalidRecord("review", r.ID, "object is empty")
+	}
+	if r.Data.AssignmentID <= 0 {
+		return invalidRecord("review", r.ID, "assignment_id must be positive")
+	}
+	if r.Data.SubjectID <= 0 {
+		return invalidRecord("review", r.ID, "subject_id must be positive")
+	}
+	return nil
+}
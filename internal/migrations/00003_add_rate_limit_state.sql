@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE rate_limit_state (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	remaining INTEGER NOT NULL,
+	reset_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS rate_limit_state;
+-- +goose StatementEnd
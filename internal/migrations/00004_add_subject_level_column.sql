@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Compressed data rows can't be read by json_extract, so the level gets its own column
+ALTER TABLE subjects ADD COLUMN level INTEGER;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE subjects SET level = json_extract(data, '$.level');
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_subjects_level ON subjects(level);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_subjects_level;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE subjects DROP COLUMN level;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE level_progressions (
+	id INTEGER PRIMARY KEY,
+	object TEXT NOT NULL,
+	url TEXT NOT NULL,
+	data_updated_at TEXT NOT NULL,
+	level INTEGER NOT NULL,
+	data TEXT NOT NULL
+);
+
+CREATE INDEX idx_level_progressions_level ON level_progressions(level);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_level_progressions_level;
+DROP TABLE IF EXISTS level_progressions;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+DELETE FROM statistics_snapshots
+WHERE id NOT IN (SELECT MAX(id) FROM statistics_snapshots GROUP BY timestamp);
+
+DROP INDEX IF EXISTS idx_statistics_snapshots_timestamp;
+CREATE UNIQUE INDEX idx_statistics_snapshots_timestamp ON statistics_snapshots(timestamp);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_statistics_snapshots_timestamp;
+CREATE INDEX idx_statistics_snapshots_timestamp ON statistics_snapshots(timestamp);
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Reviews only reference their subject, so the subject type is copied in for filtering
+ALTER TABLE reviews ADD COLUMN subject_type TEXT;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE reviews SET subject_type = (SELECT object FROM subjects WHERE subjects.id = reviews.subject_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_reviews_subject_type ON reviews(subject_type);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_reviews_subject_type;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE reviews DROP COLUMN subject_type;
+-- +goose StatementEnd
@@ -3,7 +3,9 @@ package migrations
 import (
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/pressly/goose/v3"
 )
@@ -11,7 +13,46 @@ import (
 //go:embed *.sql
 var embedMigrations embed.FS
 
-// Run executes all pending database migrations
+// MaxSupportedVersion is the newest schema version this binary knows how to use. It must
+// match the highest embedded migration.
+const MaxSupportedVersion int64 = 7
+
+// ErrSchemaTooNew is returned by Run when the database was migrated by a newer binary
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary supports")
+
+// ErrSchemaIncomplete is returned by Verify when expected tables or indexes are missing
+var ErrSchemaIncomplete = errors.New("database schema is incomplete")
+
+// expectedTables and expectedIndexes are the schema objects the application relies on
+// after all migrations have been applied
+var (
+	expectedTables = []string{
+		"subjects",
+		"assignments",
+		"reviews",
+		"statistics_snapshots",
+		"sync_metadata",
+		"assignment_snapshots",
+		"rate_limit_state",
+		"level_progressions",
+	}
+	expectedIndexes = []string{
+		"idx_subjects_data_updated_at",
+		"idx_subjects_level",
+		"idx_assignments_subject_id",
+		"idx_assignments_data_updated_at",
+		"idx_reviews_assignment_id",
+		"idx_reviews_subject_id",
+		"idx_reviews_data_updated_at",
+		"idx_reviews_subject_type",
+		"idx_statistics_snapshots_timestamp",
+		"idx_assignment_snapshots_date",
+		"idx_level_progressions_level",
+	}
+)
+
+// Run executes all pending database migrations. It refuses to touch a database whose
+// schema version is above MaxSupportedVersion, e.g. after rolling back to an older binary.
 func Run(db *sql.DB) error {
 	goose.SetBaseFS(embedMigrations)
 
@@ -19,6 +60,15 @@ func Run(db *sql.DB) error {
 		return fmt.Errorf("failed to set goose dialect: %w", err)
 	}
 
+	version, err := goose.GetDBVersion(db)
+	if err != nil {
+		return fmt.Errorf("failed to get migration version: %w", err)
+	}
+	if version > MaxSupportedVersion {
+		return fmt.Errorf("%w: database is at version %d, this binary supports up to version %d; upgrade the binary or restore a backup",
+			ErrSchemaTooNew, version, MaxSupportedVersion)
+	}
+
 	if err := goose.Up(db, "."); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
@@ -26,6 +76,37 @@ func Run(db *sql.DB) error {
 	return nil
 }
 
+// Verify checks that the core tables and key indexes exist, so that a database left
+// incomplete by a failed or corrupted migration is caught at startup rather than on the
+// first query. It only reads the schema and is safe to call any number of times.
+func Verify(db *sql.DB) error {
+	var missing []string
+	for _, object := range []struct {
+		kind  string
+		names []string
+	}{
+		{"table", expectedTables},
+		{"index", expectedIndexes},
+	} {
+		for _, name := range object.names {
+			var count int
+			err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = ? AND name = ?`, object.kind, name).Scan(&count)
+			if err != nil {
+				return fmt.Errorf("failed to check %s %s: %w", object.kind, name, err)
+			}
+			if count == 0 {
+				missing = append(missing, object.kind+" "+name)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %s", ErrSchemaIncomplete, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
 // Version returns the current migration version
 func Version(db *sql.DB) (int64, error) {
 	if err := goose.SetDialect("sqlite3"); err != nil {
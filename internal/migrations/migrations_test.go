@@ -2,10 +2,13 @@ package migrations
 
 import (
 	"database/sql"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/pressly/goose/v3"
 )
 
 func TestMigrations(t *testing.T) {
@@ -31,8 +34,8 @@ func TestMigrations(t *testing.T) {
 		t.Fatalf("Failed to get migration version: %v", err)
 	}
 
-	if version != 2 {
-		t.Errorf("Expected migration version 2, got %d", version)
+	if version != 7 {
+		t.Errorf("Expected migration version 7, got %d", version)
 	}
 
 	// Verify tables exist
@@ -43,6 +46,7 @@ func TestMigrations(t *testing.T) {
 		"statistics_snapshots",
 		"sync_metadata",
 		"assignment_snapshots",
+		"rate_limit_state",
 	}
 
 	for _, table := range tables {
@@ -65,6 +69,8 @@ func TestMigrations(t *testing.T) {
 		"idx_reviews_data_updated_at",
 		"idx_statistics_snapshots_timestamp",
 		"idx_assignment_snapshots_date",
+		"idx_subjects_level",
+		"idx_reviews_subject_type",
 	}
 
 	for _, index := range indexes {
@@ -114,7 +120,90 @@ func TestMigrationsIdempotent(t *testing.T) {
 		t.Errorf("Migration version changed on second run: %d -> %d", version1, version2)
 	}
 
-	if version2 != 2 {
-		t.Errorf("Expected migration version 2, got %d", version2)
+	if version2 != 7 {
+		t.Errorf("Expected migration version 7, got %d", version2)
+	}
+}
+
+func TestMaxSupportedVersionMatchesLatestMigration(t *testing.T) {
+	goose.SetBaseFS(embedMigrations)
+	defer goose.SetBaseFS(nil)
+
+	migrations, err := goose.CollectMigrations(".", 0, goose.MaxVersion)
+	if err != nil {
+		t.Fatalf("Failed to collect migrations: %v", err)
+	}
+	latest, err := migrations.Last()
+	if err != nil {
+		t.Fatalf("Failed to find latest migration: %v", err)
+	}
+
+	if latest.Version != MaxSupportedVersion {
+		t.Errorf("Expected MaxSupportedVersion %d to match the latest migration %d", MaxSupportedVersion, latest.Version)
+	}
+}
+
+func TestRunRefusesNewerSchema(t *testing.T) {
+	tmpDB := "test_migrations_newer.db"
+	defer os.Remove(tmpDB)
+
+	db, err := sql.Open("sqlite3", tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := Run(db); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// Simulate a migration applied by a newer binary
+	if _, err := db.Exec(`INSERT INTO goose_db_version (version_id, is_applied) VALUES (?, 1)`, MaxSupportedVersion+1); err != nil {
+		t.Fatalf("Failed to record future migration: %v", err)
+	}
+
+	err = Run(db)
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("Expected ErrSchemaTooNew, got %v", err)
+	}
+
+	version, err := Version(db)
+	if err != nil {
+		t.Fatalf("Failed to get migration version: %v", err)
+	}
+	if version != MaxSupportedVersion+1 {
+		t.Errorf("Expected the database to be left at version %d, got %d", MaxSupportedVersion+1, version)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tmpDB := "test_migrations_verify.db"
+	defer os.Remove(tmpDB)
+
+	db, err := sql.Open("sqlite3", tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := Run(db); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	if err := Verify(db); err != nil {
+		t.Fatalf("Expected a fully migrated database to verify, got %v", err)
+	}
+
+	// Simulate a migration that did not create everything it should have
+	if _, err := db.Exec(`DROP TABLE level_progressions`); err != nil {
+		t.Fatalf("Failed to drop table: %v", err)
+	}
+
+	err = Verify(db)
+	if !errors.Is(err, ErrSchemaIncomplete) {
+		t.Fatalf("Expected ErrSchemaIncomplete, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "table level_progressions") || !strings.Contains(err.Error(), "index idx_level_progressions_level") {
+		t.Errorf("Expected the error to name the missing table and index, got %q", err.Error())
 	}
 }
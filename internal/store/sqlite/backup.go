@@ -0,0 +1,335 @@
+package sqlite
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+	"wanikani-api/internal/domain"
+)
+
+// backupFormatVersion identifies the layout of the export document
+const backupFormatVersion = 1
+
+// columnKind describes how a column is represented in the export document
+type columnKind int
+
+const (
+	columnInt  columnKind = iota // JSON number
+	columnText                   // JSON string
+	columnJSON                   // embedded JSON value, stored as text
+)
+
+type backupColumn struct {
+	name string
+	kind columnKind
+}
+
+// backupTable describes a table included in exports
+type backupTable struct {
+	name    string
+	orderBy string
+	columns []backupColumn
+}
+
+// backupTables lists the exported tables in foreign key order, parents first
+var backupTables = []backupTable{
+	{name: "subjects", orderBy: "id", columns: []backupColumn{
+		{"id", columnInt}, {"object", columnText}, {"url", columnText}, {"data_updated_at", columnText}, {"data", columnJSON},
+	}},
+	{name: "level_progressions", orderBy: "id", columns: []backupColumn{
+		{"id", columnInt}, {"object", columnText}, {"url", columnText}, {"data_updated_at", columnText}, {"level", columnInt}, {"data", columnJSON},
+	}},
+	{name: "assignments", orderBy: "id", columns: []backupColumn{
+		{"id", columnInt}, {"object", columnText}, {"url", columnText}, {"data_updated_at", columnText}, {"subject_id", columnInt}, {"data", columnJSON},
+	}},
+	{name: "reviews", orderBy: "id", columns: []backupColumn{
+		{"id", columnInt}, {"object", columnText}, {"url", columnText}, {"data_updated_at", columnText}, {"assignment_id", columnInt}, {"subject_id", columnInt}, {"data", columnJSON},
+	}},
+	{name: "statistics_snapshots", orderBy: "id", columns: []backupColumn{
+		{"id", columnInt}, {"timestamp", columnText}, {"data", columnJSON},
+	}},
+	{name: "assignment_snapshots", orderBy: "date, srs_stage, subject_type", columns: []backupColumn{
+		{"date", columnText}, {"srs_stage", columnInt}, {"subject_type", columnText}, {"count", columnInt},
+	}},
+	{name: "sync_metadata", orderBy: "data_type", columns: []backupColumn{
+		{"data_type", columnText}, {"last_sync_time", columnText},
+	}},
+}
+
+func (t backupTable) columnNames() string {
+	names := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		names[i] = c.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Export writes every stored table as a single JSON document, streaming rows so that
+// large databases are never held in memory. The tables are read in one transaction,
+// so the export is a consistent snapshot.
+func (s *Store) Export(ctx context.Context, w io.Writer) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		bw := bufio.NewWriter(w)
+
+		fmt.Fprintf(bw, `{"version":%d,"exported_at":%q`, backupFormatVersion, s.now().UTC().Format(time.RFC3339))
+		for _, table := range backupTables {
+			fmt.Fprintf(bw, `,%q:[`, table.name)
+			if err := exportTable(ctx, tx, table, bw); err != nil {
+				return err
+			}
+			bw.WriteString("]")
+		}
+		bw.WriteString("}\n")
+
+		if err := bw.Flush(); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		return nil
+	})
+}
+
+// exportTable writes the rows of table as comma-separated JSON objects
+func exportTable(ctx context.Context, tx *sql.Tx, table backupTable, w *bufio.Writer) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT %s FROM %s ORDER BY %s`, table.columnNames(), table.name, table.orderBy))
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", table.name, err)
+	}
+	defer rows.Close()
+
+	values := make([]interface{}, len(table.columns))
+	for i, c := range table.columns {
+		switch c.kind {
+		case columnInt:
+			values[i] = new(int64)
+		case columnJSON:
+			values[i] = new([]byte)
+		default:
+			values[i] = new(string)
+		}
+	}
+
+	first := true
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return fmt.Errorf("failed to scan %s: %w", table.name, err)
+		}
+
+		if !first {
+			w.WriteByte(',')
+		}
+		first = false
+
+		w.WriteByte('{')
+		for i, c := range table.columns {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%q:", c.name)
+
+			switch c.kind {
+			case columnInt:
+				fmt.Fprintf(w, "%d", *values[i].(*int64))
+			case columnJSON:
+				// Backups always hold plain JSON, whatever the stored encoding
+				data, err := decodeData(*values[i].(*[]byte))
+				if err != nil {
+					return fmt.Errorf("failed to decode %s.%s: %w", table.name, c.name, err)
+				}
+				w.Write(data)
+			default:
+				encoded, err := json.Marshal(*values[i].(*string))
+				if err != nil {
+					return fmt.Errorf("failed to encode %s.%s: %w", table.name, c.name, err)
+				}
+				w.Write(encoded)
+			}
+		}
+		w.WriteByte('}')
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating %s: %w", table.name, err)
+	}
+
+	return nil
+}
+
+// Import replaces all stored data with the contents of an export document. The document
+// is decoded as a stream and applied in a single transaction, so a malformed or
+// inconsistent document leaves the existing data untouched. It returns the number of
+// rows imported per table.
+func (s *Store) Import(ctx context.Context, r io.Reader) (map[string]int, error) {
+	tables := make(map[string]backupTable, len(backupTables))
+	for _, table := range backupTables {
+		tables[table.name] = table
+	}
+
+	counts := make(map[string]int, len(backupTables))
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		// Check foreign keys at commit so sections may arrive in any order
+		if _, err := tx.ExecContext(ctx, `PRAGMA defer_foreign_keys = ON`); err != nil {
+			return fmt.Errorf("failed to defer foreign keys: %w", err)
+		}
+
+		for i := len(backupTables) - 1; i >= 0; i-- {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM `+backupTables[i].name); err != nil {
+				return fmt.Errorf("failed to clear %s: %w", backupTables[i].name, err)
+			}
+		}
+
+		dec := json.NewDecoder(r)
+		if err := expectDelim(dec, '{'); err != nil {
+			return err
+		}
+
+		versionSeen := false
+		for dec.More() {
+			token, err := dec.Token()
+			if err != nil {
+				return invalidBackup("%v", err)
+			}
+			key, _ := token.(string)
+
+			switch key {
+			case "version":
+				var version int
+				if err := dec.Decode(&version); err != nil {
+					return invalidBackup("invalid version: %v", err)
+				}
+				if version != backupFormatVersion {
+					return invalidBackup("unsupported version %d", version)
+				}
+				versionSeen = true
+			case "exported_at":
+				var skip json.RawMessage
+				if err := dec.Decode(&skip); err != nil {
+					return invalidBackup("%v", err)
+				}
+			default:
+				table, ok := tables[key]
+				if !ok {
+					return invalidBackup("unknown section %q", key)
+				}
+				n, err := s.importTable(ctx, tx, table, dec)
+				if err != nil {
+					return err
+				}
+				counts[table.name] = n
+			}
+		}
+
+		if err := expectDelim(dec, '}'); err != nil {
+			return err
+		}
+		if !versionSeen {
+			return invalidBackup("missing version")
+		}
+
+		// The subject level and review subject type columns are derived rather than exported
+		if _, err := tx.ExecContext(ctx, `UPDATE subjects SET level = json_extract(`+dataJSON("data")+`, '$.level')`); err != nil {
+			return fmt.Errorf("failed to derive subject levels: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE reviews SET subject_type = (SELECT object FROM subjects WHERE subjects.id = reviews.subject_id)`); err != nil {
+			return fmt.Errorf("failed to derive review subject types: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		// Duplicate keys and dangling references are problems with the document
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidBackup, err)
+		}
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// invalidBackup returns an error wrapping domain.ErrInvalidBackup
+func invalidBackup(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", domain.ErrInvalidBackup, fmt.Sprintf(format, args...))
+}
+
+// importTable inserts the rows of a JSON array section into table
+func (s *Store) importTable(ctx context.Context, tx *sql.Tx, table backupTable, dec *json.Decoder) (int, error) {
+	if err := expectDelim(dec, '['); err != nil {
+		return 0, fmt.Errorf("%s: %w", table.name, err)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(table.columns)), ", ")
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, table.name, table.columnNames(), placeholders))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	count := 0
+	for dec.More() {
+		var row map[string]json.RawMessage
+		if err := dec.Decode(&row); err != nil {
+			return 0, invalidBackup("invalid %s row: %v", table.name, err)
+		}
+
+		args := make([]interface{}, len(table.columns))
+		for i, c := range table.columns {
+			raw, ok := row[c.name]
+			if !ok {
+				return 0, invalidBackup("%s row %d is missing %s", table.name, count, c.name)
+			}
+
+			switch c.kind {
+			case columnInt:
+				var v int64
+				if err := json.Unmarshal(raw, &v); err != nil {
+					return 0, invalidBackup("%s row %d has invalid %s: %v", table.name, count, c.name, err)
+				}
+				args[i] = v
+			case columnJSON:
+				data, err := s.encodeData(raw)
+				if err != nil {
+					return 0, err
+				}
+				args[i] = data
+			default:
+				var v string
+				if err := json.Unmarshal(raw, &v); err != nil {
+					return 0, invalidBackup("%s row %d has invalid %s: %v", table.name, count, c.name, err)
+				}
+				args[i] = v
+			}
+		}
+
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return 0, fmt.Errorf("failed to import %s row %d: %w", table.name, count, err)
+		}
+		count++
+	}
+
+	if err := expectDelim(dec, ']'); err != nil {
+		return 0, fmt.Errorf("%s: %w", table.name, err)
+	}
+
+	return count, nil
+}
+
+// expectDelim reads the next token and checks that it is the given delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return invalidBackup("%v", err)
+	}
+	if d, ok := token.(json.Delim); !ok || d != delim {
+		return invalidBackup("expected %q, got %v", delim, token)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Stored data columns hold either plain JSON text or, when compression is enabled, a
+// gzip stream stored as a BLOB. The gzip magic number is the per-row marker: JSON text
+// can never start with it, so rows written before compression was enabled (or after it
+// was disabled) keep reading correctly alongside compressed ones.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// dataJSONFunc is the SQL function that returns a data column as JSON text, decompressing
+// gzip rows
+const dataJSONFunc = "data_json"
+
+// dataJSON returns an SQL expression for column as JSON text. Queries that inspect stored
+// JSON must use json_extract(dataJSON("data"), ...) rather than reading the column
+// directly, since compressed rows are not valid JSON. Plain rows are TEXT and are used as
+// they are, so only compressed BLOB rows pay for the call into Go.
+func dataJSON(column string) string {
+	return "CASE WHEN typeof(" + column + ") = 'blob' THEN " + dataJSONFunc + "(" + column + ") ELSE " + column + " END"
+}
+
+// encodeData returns the value to store in a data column for the given JSON document
+func (s *Store) encodeData(data []byte) (interface{}, error) {
+	if !s.compressData {
+		return string(data), nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress data: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress data: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeData returns the JSON document held in a data column value, decompressing it if
+// it carries the gzip marker
+func decodeData(raw []byte) ([]byte, error) {
+	if !bytes.HasPrefix(raw, gzipMagic) {
+		return raw, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress data: %w", err)
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress data: %w", err)
+	}
+	return data, nil
+}
+
+// sqlDataJSON implements the data_json SQL function. NULL (e.g. from an outer join),
+// which the driver passes as a nil slice, stays NULL.
+func sqlDataJSON(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		if v == nil {
+			return nil, nil
+		}
+		data, err := decodeData(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	case string:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported argument type %T", dataJSONFunc, value)
+	}
+}
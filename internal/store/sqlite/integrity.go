@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"wanikani-api/internal/domain"
+)
+
+// integritySampleSize caps how many orphan IDs are reported per issue
+const integritySampleSize = 10
+
+// orphanedAssignmentsQuery selects assignments whose subject is missing
+const orphanedAssignmentsQuery = `
+	SELECT a.id
+	FROM assignments a
+	LEFT JOIN subjects s ON s.id = a.subject_id
+	WHERE s.id IS NULL
+	ORDER BY a.id`
+
+// orphanedReviewsQuery selects reviews whose assignment or subject is missing. Reviews of
+// an orphaned assignment are included so that repairing never leaves a dangling reference.
+const orphanedReviewsQuery = `
+	SELECT r.id
+	FROM reviews r
+	LEFT JOIN assignments a ON a.id = r.assignment_id
+	LEFT JOIN subjects s ON s.id = r.subject_id
+	LEFT JOIN subjects sa ON sa.id = a.subject_id
+	WHERE a.id IS NULL OR s.id IS NULL OR sa.id IS NULL
+	ORDER BY r.id`
+
+// CheckIntegrity finds assignments without a subject and reviews without an assignment or
+// subject. Foreign keys should prevent these, but they are only enforced on connections
+// that enabled them. If repair is true the orphans are deleted, reviews first, in the same
+// transaction that found them.
+func (s *Store) CheckIntegrity(ctx context.Context, repair bool) (*domain.IntegrityReport, error) {
+	report := &domain.IntegrityReport{}
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		reviewIDs, err := queryIDs(ctx, tx, orphanedReviewsQuery)
+		if err != nil {
+			return fmt.Errorf("failed to find orphaned reviews: %w", err)
+		}
+		assignmentIDs, err := queryIDs(ctx, tx, orphanedAssignmentsQuery)
+		if err != nil {
+			return fmt.Errorf("failed to find orphaned assignments: %w", err)
+		}
+
+		report.OrphanedReviews = integrityIssue(reviewIDs)
+		report.OrphanedAssignments = integrityIssue(assignmentIDs)
+
+		if !repair {
+			return nil
+		}
+
+		if err := deleteIDs(ctx, tx, "reviews", reviewIDs); err != nil {
+			return err
+		}
+		if err := deleteIDs(ctx, tx, "assignments", assignmentIDs); err != nil {
+			return err
+		}
+		report.Repaired = true
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// integrityIssue summarizes a list of orphan IDs
+func integrityIssue(ids []int64) domain.IntegrityIssue {
+	sample := ids
+	if len(sample) > integritySampleSize {
+		sample = sample[:integritySampleSize]
+	}
+	return domain.IntegrityIssue{
+		Count:     len(ids),
+		SampleIDs: append([]int64{}, sample...),
+	}
+}
+
+// queryIDs runs a query selecting a single integer column
+func queryIDs(ctx context.Context, tx *sql.Tx, query string) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// deleteIDs deletes the rows of table with the given IDs
+func deleteIDs(ctx context.Context, tx *sql.Tx, table string, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `DELETE FROM `+table+` WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, id := range ids {
+		if _, err := stmt.ExecContext(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete orphaned %s %d: %w", table, id, err)
+		}
+	}
+
+	return nil
+}
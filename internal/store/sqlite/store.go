@@ -3,37 +3,195 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	sqlite3 "github.com/mattn/go-sqlite3"
 	"wanikani-api/internal/domain"
 )
 
 // Store implements the DataStore interface using SQLite
 type Store struct {
-	db *sql.DB
+	db       *sql.DB
+	location *time.Location
+	now      func() time.Time
+
+	cacheSizeKB int   // page cache size per connection in KiB; 0 keeps SQLite's default
+	mmapSize    int64 // memory-mapped I/O size in bytes; 0 disables it
+
+	compressData bool // gzip data columns on write
+}
+
+// Option configures optional Store behavior
+type Option func(*Store)
+
+// WithLocation sets the timezone used to group timestamps into calendar days (defaults to UTC)
+func WithLocation(location *time.Location) Option {
+	return func(s *Store) {
+		if location != nil {
+			s.location = location
+		}
+	}
+}
+
+// WithCacheSize sets SQLite's page cache size per connection in KiB (0 keeps SQLite's default)
+func WithCacheSize(kb int) Option {
+	return func(s *Store) {
+		s.cacheSizeKB = kb
+	}
+}
+
+// WithMmapSize sets how many bytes of the database file SQLite may memory-map for reads
+// (0 disables memory-mapped I/O). It has no effect on in-memory databases.
+func WithMmapSize(bytes int64) Option {
+	return func(s *Store) {
+		s.mmapSize = bytes
+	}
+}
+
+// WithDataCompression gzips the JSON data columns on write. Rows are marked individually,
+// so existing uncompressed rows stay readable and the option can be toggled at any time.
+func WithDataCompression(enabled bool) Option {
+	return func(s *Store) {
+		s.compressData = enabled
+	}
 }
 
 // New creates a new SQLite store
 // Note: Migrations should be run separately before creating the store
-func New(dbPath string) (*Store, error) {
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+func New(dbPath string, opts ...Option) (*Store, error) {
+	store := &Store{
+		location: time.UTC,
+		now:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	if isMemoryPath(dbPath) {
+		store.mmapSize = 0
 	}
 
-	// Enable foreign keys
-	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	// Pragmas and SQL functions are per connection, so set them up on every connection the pool opens
+	pragmas := store.connectionPragmas()
+	driver := &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc(dataJSONFunc, sqlDataJSON, true); err != nil {
+				return fmt.Errorf("failed to register %s: %w", dataJSONFunc, err)
+			}
+			for _, pragma := range pragmas {
+				if _, err := conn.Exec(pragma, nil); err != nil {
+					return fmt.Errorf("failed to apply %q: %w", pragma, err)
+				}
+			}
+			return nil
+		},
 	}
+	store.db = sql.OpenDB(&connector{driver: driver, dsn: dbPath})
 
-	store := &Store{db: db}
+	if err := store.verifyPragmas(); err != nil {
+		store.db.Close()
+		return nil, err
+	}
 
 	return store, nil
 }
 
+// connector opens connections through a driver configured for this store
+type connector struct {
+	driver *sqlite3.SQLiteDriver
+	dsn    string
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// isMemoryPath reports whether dbPath names an in-memory database
+func isMemoryPath(dbPath string) bool {
+	return dbPath == ":memory:" || strings.Contains(dbPath, "mode=memory")
+}
+
+// EnsureDir creates the directory that will hold the database file at dbPath, along with
+// any missing parents. SQLite opens databases lazily, so without it a path in a fresh
+// volume only fails at the first write, with an unhelpful "unable to open database file".
+func EnsureDir(dbPath string) error {
+	if isMemoryPath(dbPath) {
+		return nil
+	}
+
+	// Accept file: URIs as well as plain paths
+	path := strings.TrimPrefix(dbPath, "file:")
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create database directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// connectionPragmas returns the pragmas run on every new connection
+func (s *Store) connectionPragmas() []string {
+	pragmas := []string{"PRAGMA foreign_keys = ON"}
+	if s.cacheSizeKB > 0 {
+		// A negative cache_size is a size in KiB rather than a page count
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA cache_size = -%d", s.cacheSizeKB))
+	}
+	if s.mmapSize > 0 {
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA mmap_size = %d", s.mmapSize))
+	}
+	return pragmas
+}
+
+// verifyPragmas opens a connection and reads the configured pragmas back, failing if
+// SQLite did not apply them
+func (s *Store) verifyPragmas() error {
+	var foreignKeys int
+	if err := s.db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	if foreignKeys != 1 {
+		return fmt.Errorf("failed to enable foreign keys")
+	}
+
+	if s.cacheSizeKB > 0 {
+		var cacheSize int
+		if err := s.db.QueryRow("PRAGMA cache_size").Scan(&cacheSize); err != nil {
+			return fmt.Errorf("failed to read cache_size: %w", err)
+		}
+		if cacheSize != -s.cacheSizeKB {
+			return fmt.Errorf("cache_size not applied: want %d KiB, got %d", s.cacheSizeKB, cacheSize)
+		}
+	}
+
+	if s.mmapSize > 0 {
+		// SQLite silently caps mmap_size at its compile-time maximum, so only require that it is on
+		var mmapSize int64
+		if err := s.db.QueryRow("PRAGMA mmap_size").Scan(&mmapSize); err != nil {
+			return fmt.Errorf("failed to read mmap_size: %w", err)
+		}
+		if mmapSize <= 0 {
+			return fmt.Errorf("mmap_size not applied: want %d bytes, got %d", s.mmapSize, mmapSize)
+		}
+	}
+
+	return nil
+}
+
 // Close closes the database connection
 func (s *Store) Close() error {
 	return s.db.Close()
@@ -44,59 +202,118 @@ func (s *Store) BeginTx(ctx context.Context) (*sql.Tx, error) {
 	return s.db.BeginTx(ctx, nil)
 }
 
-// UpsertSubjects inserts or updates subjects
-func (s *Store) UpsertSubjects(ctx context.Context, subjects []domain.Subject) error {
-	if len(subjects) == 0 {
-		return nil
-	}
-
+// withTx runs fn in a transaction, committing if it returns nil and rolling back otherwise
+func (s *Store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO subjects (id, object, url, data_updated_at, data)
-		VALUES (?, ?, ?, ?, ?)
-		ON CONFLICT(id) DO UPDATE SET
-			object = excluded.object,
-			url = excluded.url,
-			data_updated_at = excluded.data_updated_at,
-			data = excluded.data
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
+	if err := fn(tx); err != nil {
+		return err
 	}
-	defer stmt.Close()
 
-	for _, subject := range subjects {
-		dataJSON, err := json.Marshal(subject.Data)
-		if err != nil {
-			return fmt.Errorf("failed to marshal subject data: %w", err)
-		}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 
-		_, err = stmt.ExecContext(ctx,
-			subject.ID,
-			subject.Object,
-			subject.URL,
-			subject.DataUpdatedAt.Format(time.RFC3339),
-			string(dataJSON),
-		)
-		if err != nil {
-			return fmt.Errorf("failed to upsert subject: %w", err)
+	return nil
+}
+
+// latestByID drops records whose ID repeats within a batch, keeping the one with the newest
+// data_updated_at (the later one on a tie, as it was fetched later). A record can appear
+// twice when WaniKani updates it while a sync is paging through a collection.
+func latestByID[T any](records []T, key func(T) (int64, time.Time)) []T {
+	index := make(map[int64]int, len(records))
+	deduped := make([]T, 0, len(records))
+	for _, record := range records {
+		id, updatedAt := key(record)
+		i, seen := index[id]
+		if !seen {
+			index[id] = len(deduped)
+			deduped = append(deduped, record)
+			continue
+		}
+		if _, keptAt := key(deduped[i]); !updatedAt.Before(keptAt) {
+			deduped[i] = record
 		}
 	}
+	return deduped
+}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+// UpsertSubjects inserts or updates subjects
+func (s *Store) UpsertSubjects(ctx context.Context, subjects []domain.Subject) error {
+	if len(subjects) == 0 {
+		return nil
 	}
+	subjects = latestByID(subjects, func(subject domain.Subject) (int64, time.Time) { return subject.ID, subject.DataUpdatedAt })
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO subjects (id, object, url, data_updated_at, level, data)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				object = excluded.object,
+				url = excluded.url,
+				data_updated_at = excluded.data_updated_at,
+				level = excluded.level,
+				data = excluded.data
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, subject := range subjects {
+			// Prefer the original API payload so fields not modelled by SubjectData are kept
+			dataJSON := []byte(subject.Raw)
+			if len(dataJSON) == 0 {
+				dataJSON, err = json.Marshal(subject.Data)
+				if err != nil {
+					return fmt.Errorf("failed to marshal subject data: %w", err)
+				}
+			}
+
+			data, err := s.encodeData(dataJSON)
+			if err != nil {
+				return err
+			}
+
+			_, err = stmt.ExecContext(ctx,
+				subject.ID,
+				subject.Object,
+				subject.URL,
+				subject.DataUpdatedAt.UTC().Format(time.RFC3339),
+				subject.Data.Level,
+				data,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to upsert subject: %w", err)
+			}
+		}
 
-	return nil
+		return nil
+	})
 }
 
 // GetSubjects retrieves subjects matching the provided filters
 func (s *Store) GetSubjects(ctx context.Context, filters domain.SubjectFilters) ([]domain.Subject, error) {
+	var subjects []domain.Subject
+	err := s.StreamSubjects(ctx, filters, func(subject domain.Subject) error {
+		subjects = append(subjects, subject)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subjects, nil
+}
+
+// StreamSubjects calls fn with each subject matching the provided filters as its row is
+// read, without holding the whole result in memory. An error from fn stops the iteration
+// and is returned as is.
+func (s *Store) StreamSubjects(ctx context.Context, filters domain.SubjectFilters, fn func(domain.Subject) error) error {
 	query := `SELECT id, object, url, data_updated_at, data FROM subjects WHERE 1=1`
 	args := []interface{}{}
 
@@ -106,110 +323,316 @@ func (s *Store) GetSubjects(ctx context.Context, filters domain.SubjectFilters)
 	}
 
 	if filters.Level != nil {
-		query += ` AND json_extract(data, '$.level') = ?`
+		query += ` AND level = ?`
 		args = append(args, *filters.Level)
 	}
 
+	if filters.LevelMin != nil {
+		query += ` AND level >= ?`
+		args = append(args, *filters.LevelMin)
+	}
+
+	if filters.LevelMax != nil {
+		query += ` AND level <= ?`
+		args = append(args, *filters.LevelMax)
+	}
+
+	// data_updated_at is stored as UTC RFC 3339 text, so comparing it as text uses the index
+	if filters.DataUpdatedAfter != nil {
+		query += ` AND data_updated_at > ?`
+		args = append(args, filters.DataUpdatedAfter.UTC().Format(time.RFC3339))
+	}
+
+	// The meaning filter runs in Go, so with it the limit is applied while scanning
+	if filters.Sort == domain.SubjectSortPosition {
+		query += ` ORDER BY level, json_extract(` + dataJSON("data") + `, '$.lesson_position'), id`
+	} else if filters.Sort == domain.SubjectSortDataUpdated {
+		query += ` ORDER BY data_updated_at, id`
+	} else if filters.Limit > 0 {
+		query += ` ORDER BY id`
+	}
+	if filters.Limit > 0 && filters.Meaning == "" {
+		query += ` LIMIT ?`
+		args = append(args, filters.Limit)
+	}
+
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query subjects: %w", err)
+		return fmt.Errorf("failed to query subjects: %w", err)
 	}
 	defer rows.Close()
 
-	var subjects []domain.Subject
-	for rows.Next() {
-		var subject domain.Subject
-		var dataUpdatedAtStr string
-		var dataJSON string
+	emitted := 0
+	for scanned := 0; rows.Next(); scanned++ {
+		if err := checkScanContext(ctx, scanned); err != nil {
+			return err
+		}
 
-		err := rows.Scan(
-			&subject.ID,
-			&subject.Object,
-			&subject.URL,
-			&dataUpdatedAtStr,
-			&dataJSON,
-		)
+		subject, dataJSON, err := scanSubject(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan subject: %w", err)
+			return err
 		}
 
-		subject.DataUpdatedAt, err = time.Parse(time.RFC3339, dataUpdatedAtStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse data_updated_at: %w", err)
+		// Meanings are normalized in Go since SQLite can't fold accents
+		if filters.Meaning != "" && !subject.Data.MatchesMeaning(filters.Meaning) {
+			continue
 		}
 
-		if err := json.Unmarshal([]byte(dataJSON), &subject.Data); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal subject data: %w", err)
+		if filters.IncludeRaw {
+			subject.Raw = json.RawMessage(dataJSON)
 		}
 
-		subjects = append(subjects, subject)
+		if err := fn(subject); err != nil {
+			return err
+		}
+		emitted++
+		if filters.Limit > 0 && emitted == filters.Limit {
+			break
+		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating subjects: %w", err)
+		return fmt.Errorf("error iterating subjects: %w", err)
 	}
 
-	return subjects, nil
+	return nil
 }
 
-// UpsertAssignments inserts or updates assignments
-func (s *Store) UpsertAssignments(ctx context.Context, assignments []domain.Assignment) error {
-	if len(assignments) == 0 {
-		return nil
+// scanSubject reads a subject from a row of id, object, url, data_updated_at and data,
+// also returning the decoded data JSON
+func scanSubject(rows *sql.Rows) (domain.Subject, []byte, error) {
+	var subject domain.Subject
+	var dataUpdatedAtStr string
+	var rawData []byte
+
+	err := rows.Scan(
+		&subject.ID,
+		&subject.Object,
+		&subject.URL,
+		&dataUpdatedAtStr,
+		&rawData,
+	)
+	if err != nil {
+		return subject, nil, fmt.Errorf("failed to scan subject: %w", err)
 	}
 
-	tx, err := s.db.BeginTx(ctx, nil)
+	subject.DataUpdatedAt, err = time.Parse(time.RFC3339, dataUpdatedAtStr)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return subject, nil, fmt.Errorf("failed to parse data_updated_at: %w", err)
 	}
-	defer tx.Rollback()
 
-	// Validate that all referenced subjects exist
-	for _, assignment := range assignments {
-		if err := s.validateSubjectExists(ctx, tx, assignment.Data.SubjectID); err != nil {
-			return fmt.Errorf("assignment %d references invalid subject %d: %w", assignment.ID, assignment.Data.SubjectID, err)
+	dataJSON, err := decodeData(rawData)
+	if err != nil {
+		return subject, nil, err
+	}
+
+	if err := json.Unmarshal(dataJSON, &subject.Data); err != nil {
+		return subject, nil, fmt.Errorf("failed to unmarshal subject data: %w", err)
+	}
+
+	return subject, dataJSON, nil
+}
+
+// GetSubjectsByIDs retrieves the subjects with the given IDs, querying them in batches of
+// maxIDsPerQuery. IDs without a stored subject are skipped.
+func (s *Store) GetSubjectsByIDs(ctx context.Context, ids []int64) ([]domain.Subject, error) {
+	subjects := make([]domain.Subject, 0, len(ids))
+	err := forEachIDBatch(ids, func(placeholders string, args []interface{}) error {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT id, object, url, data_updated_at, data
+			FROM subjects
+			WHERE id IN (`+placeholders+`)
+			ORDER BY id
+		`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query subjects: %w", err)
 		}
+		defer rows.Close()
+
+		for scanned := 0; rows.Next(); scanned++ {
+			if err := checkScanContext(ctx, scanned); err != nil {
+				return err
+			}
+			subject, _, err := scanSubject(rows)
+			if err != nil {
+				return err
+			}
+			subjects = append(subjects, subject)
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating subjects: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO assignments (id, object, url, data_updated_at, subject_id, data)
-		VALUES (?, ?, ?, ?, ?, ?)
-		ON CONFLICT(id) DO UPDATE SET
-			object = excluded.object,
-			url = excluded.url,
-			data_updated_at = excluded.data_updated_at,
-			subject_id = excluded.subject_id,
-			data = excluded.data
+	return subjects, nil
+}
+
+// UpsertLevelProgressions inserts or updates level progressions
+func (s *Store) UpsertLevelProgressions(ctx context.Context, progressions []domain.LevelProgression) error {
+	if len(progressions) == 0 {
+		return nil
+	}
+	progressions = latestByID(progressions, func(p domain.LevelProgression) (int64, time.Time) { return p.ID, p.DataUpdatedAt })
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO level_progressions (id, object, url, data_updated_at, level, data)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				object = excluded.object,
+				url = excluded.url,
+				data_updated_at = excluded.data_updated_at,
+				level = excluded.level,
+				data = excluded.data
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, progression := range progressions {
+			dataJSON, err := json.Marshal(progression.Data)
+			if err != nil {
+				return fmt.Errorf("failed to marshal level progression data: %w", err)
+			}
+
+			data, err := s.encodeData(dataJSON)
+			if err != nil {
+				return err
+			}
+
+			_, err = stmt.ExecContext(ctx,
+				progression.ID,
+				progression.Object,
+				progression.URL,
+				progression.DataUpdatedAt.Format(time.RFC3339),
+				progression.Data.Level,
+				data,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to upsert level progression: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetLevelProgressions retrieves all level progressions ordered by level. A level that was
+// reset and reached again has one progression per attempt, oldest first.
+func (s *Store) GetLevelProgressions(ctx context.Context) ([]domain.LevelProgression, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, object, url, data_updated_at, data
+		FROM level_progressions
+		ORDER BY level, id
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
+		return nil, fmt.Errorf("failed to query level progressions: %w", err)
 	}
-	defer stmt.Close()
+	defer rows.Close()
+
+	var progressions []domain.LevelProgression
+	for rows.Next() {
+		var progression domain.LevelProgression
+		var dataUpdatedAtStr string
+		var rawData []byte
+
+		err := rows.Scan(
+			&progression.ID,
+			&progression.Object,
+			&progression.URL,
+			&dataUpdatedAtStr,
+			&rawData,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan level progression: %w", err)
+		}
 
-	for _, assignment := range assignments {
-		dataJSON, err := json.Marshal(assignment.Data)
+		progression.DataUpdatedAt, err = time.Parse(time.RFC3339, dataUpdatedAtStr)
 		if err != nil {
-			return fmt.Errorf("failed to marshal assignment data: %w", err)
+			return nil, fmt.Errorf("failed to parse data_updated_at: %w", err)
 		}
 
-		_, err = stmt.ExecContext(ctx,
-			assignment.ID,
-			assignment.Object,
-			assignment.URL,
-			assignment.DataUpdatedAt.Format(time.RFC3339),
-			assignment.Data.SubjectID,
-			string(dataJSON),
-		)
+		dataJSON, err := decodeData(rawData)
 		if err != nil {
-			return fmt.Errorf("failed to upsert assignment: %w", err)
+			return nil, err
+		}
+
+		if err := json.Unmarshal(dataJSON, &progression.Data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal level progression data: %w", err)
 		}
+
+		progressions = append(progressions, progression)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating level progressions: %w", err)
 	}
 
-	return nil
+	return progressions, nil
+}
+
+// UpsertAssignments inserts or updates assignments
+func (s *Store) UpsertAssignments(ctx context.Context, assignments []domain.Assignment) error {
+	if len(assignments) == 0 {
+		return nil
+	}
+	assignments = latestByID(assignments, func(a domain.Assignment) (int64, time.Time) { return a.ID, a.DataUpdatedAt })
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		// Validate that all referenced subjects exist
+		for _, assignment := range assignments {
+			if err := s.validateSubjectExists(ctx, tx, assignment.Data.SubjectID); err != nil {
+				return fmt.Errorf("assignment %d references invalid subject %d: %w", assignment.ID, assignment.Data.SubjectID, err)
+			}
+		}
+
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO assignments (id, object, url, data_updated_at, subject_id, data)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				object = excluded.object,
+				url = excluded.url,
+				data_updated_at = excluded.data_updated_at,
+				subject_id = excluded.subject_id,
+				data = excluded.data
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, assignment := range assignments {
+			dataJSON, err := json.Marshal(assignment.Data)
+			if err != nil {
+				return fmt.Errorf("failed to marshal assignment data: %w", err)
+			}
+
+			data, err := s.encodeData(dataJSON)
+			if err != nil {
+				return err
+			}
+
+			_, err = stmt.ExecContext(ctx,
+				assignment.ID,
+				assignment.Object,
+				assignment.URL,
+				assignment.DataUpdatedAt.Format(time.RFC3339),
+				assignment.Data.SubjectID,
+				data,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to upsert assignment: %w", err)
+			}
+		}
+
+		return nil
+	})
 }
 
 // GetAssignments retrieves assignments matching the provided filters
@@ -218,22 +641,107 @@ func (s *Store) GetAssignments(ctx context.Context, filters domain.AssignmentFil
 	args := []interface{}{}
 
 	if filters.SRSStage != nil {
-		query += ` AND json_extract(data, '$.srs_stage') = ?`
+		query += ` AND json_extract(` + dataJSON("data") + `, '$.srs_stage') = ?`
 		args = append(args, *filters.SRSStage)
 	}
 
+	if filters.Limit > 0 {
+		query += ` ORDER BY id LIMIT ?`
+		args = append(args, filters.Limit)
+	}
+
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query assignments: %w", err)
 	}
 	defer rows.Close()
 
+	return scanAssignments(ctx, rows)
+}
+
+// GetAssignmentsByIDs retrieves the assignments with the given IDs, querying them in
+// batches of maxIDsPerQuery. IDs without a stored assignment are skipped.
+func (s *Store) GetAssignmentsByIDs(ctx context.Context, ids []int64) ([]domain.Assignment, error) {
+	assignments := make([]domain.Assignment, 0, len(ids))
+	err := forEachIDBatch(ids, func(placeholders string, args []interface{}) error {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT id, object, url, data_updated_at, subject_id, data
+			FROM assignments
+			WHERE id IN (`+placeholders+`)
+			ORDER BY id
+		`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query assignments: %w", err)
+		}
+		defer rows.Close()
+
+		batch, err := scanAssignments(ctx, rows)
+		if err != nil {
+			return err
+		}
+		assignments = append(assignments, batch...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+// GetAssignmentsPassedOn retrieves the assignments whose passed_at falls on the calendar
+// day of date in the store's timezone, in ID order. Assignments never passed are excluded.
+func (s *Store) GetAssignmentsPassedOn(ctx context.Context, date time.Time) ([]domain.Assignment, error) {
+	start := s.calendarDay(date)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, object, url, data_updated_at, subject_id, data
+		FROM assignments
+		WHERE julianday(json_extract(`+dataJSON("data")+`, '$.passed_at')) >= julianday(?)
+			AND julianday(json_extract(`+dataJSON("data")+`, '$.passed_at')) < julianday(?)
+		ORDER BY id
+	`, start.UTC().Format(time.RFC3339), start.AddDate(0, 0, 1).UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query passed assignments: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAssignments(ctx, rows)
+}
+
+// GetAvailableLessons retrieves the assignments that are unlocked but not yet started,
+// ordered like WaniKani's lesson queue: by subject level, then lesson position. Subjects
+// hidden by WaniKani are skipped, since they are no longer offered as lessons.
+func (s *Store) GetAvailableLessons(ctx context.Context) ([]domain.Assignment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT a.id, a.object, a.url, a.data_updated_at, a.subject_id, a.data
+		FROM assignments a
+		JOIN subjects s ON s.id = a.subject_id
+		WHERE json_extract(`+dataJSON("a.data")+`, '$.unlocked_at') IS NOT NULL
+			AND json_extract(`+dataJSON("a.data")+`, '$.started_at') IS NULL
+			AND json_extract(`+dataJSON("s.data")+`, '$.hidden_at') IS NULL
+		ORDER BY s.level, json_extract(`+dataJSON("s.data")+`, '$.lesson_position'), a.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query available lessons: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAssignments(ctx, rows)
+}
+
+// scanAssignments reads assignment rows selected as id, object, url, data_updated_at,
+// subject_id, data
+func scanAssignments(ctx context.Context, rows *sql.Rows) ([]domain.Assignment, error) {
 	var assignments []domain.Assignment
-	for rows.Next() {
+	for scanned := 0; rows.Next(); scanned++ {
+		if err := checkScanContext(ctx, scanned); err != nil {
+			return nil, err
+		}
+
 		var assignment domain.Assignment
 		var dataUpdatedAtStr string
-		var dataJSON string
-		var subjectID int
+		var rawData []byte
+		var subjectID int64
 
 		err := rows.Scan(
 			&assignment.ID,
@@ -241,7 +749,7 @@ func (s *Store) GetAssignments(ctx context.Context, filters domain.AssignmentFil
 			&assignment.URL,
 			&dataUpdatedAtStr,
 			&subjectID,
-			&dataJSON,
+			&rawData,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan assignment: %w", err)
@@ -252,7 +760,12 @@ func (s *Store) GetAssignments(ctx context.Context, filters domain.AssignmentFil
 			return nil, fmt.Errorf("failed to parse data_updated_at: %w", err)
 		}
 
-		if err := json.Unmarshal([]byte(dataJSON), &assignment.Data); err != nil {
+		dataJSON, err := decodeData(rawData)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(dataJSON, &assignment.Data); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal assignment data: %w", err)
 		}
 
@@ -271,64 +784,64 @@ func (s *Store) UpsertReviews(ctx context.Context, reviews []domain.Review) erro
 	if len(reviews) == 0 {
 		return nil
 	}
-
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	// Validate that all referenced assignments and subjects exist
-	for _, review := range reviews {
-		if err := s.validateAssignmentExists(ctx, tx, review.Data.AssignmentID); err != nil {
-			return fmt.Errorf("review %d references invalid assignment %d: %w", review.ID, review.Data.AssignmentID, err)
+	reviews = latestByID(reviews, func(r domain.Review) (int64, time.Time) { return r.ID, r.DataUpdatedAt })
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		// Validate that all referenced assignments and subjects exist
+		for _, review := range reviews {
+			if err := s.validateAssignmentExists(ctx, tx, review.Data.AssignmentID); err != nil {
+				return fmt.Errorf("review %d references invalid assignment %d: %w", review.ID, review.Data.AssignmentID, err)
+			}
+			if err := s.validateSubjectExists(ctx, tx, review.Data.SubjectID); err != nil {
+				return fmt.Errorf("review %d references invalid subject %d: %w", review.ID, review.Data.SubjectID, err)
+			}
 		}
-		if err := s.validateSubjectExists(ctx, tx, review.Data.SubjectID); err != nil {
-			return fmt.Errorf("review %d references invalid subject %d: %w", review.ID, review.Data.SubjectID, err)
-		}
-	}
 
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO reviews (id, object, url, data_updated_at, assignment_id, subject_id, data)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(id) DO UPDATE SET
-			object = excluded.object,
-			url = excluded.url,
-			data_updated_at = excluded.data_updated_at,
-			assignment_id = excluded.assignment_id,
-			subject_id = excluded.subject_id,
-			data = excluded.data
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
-	}
-	defer stmt.Close()
-
-	for _, review := range reviews {
-		dataJSON, err := json.Marshal(review.Data)
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO reviews (id, object, url, data_updated_at, assignment_id, subject_id, subject_type, data)
+			VALUES (?, ?, ?, ?, ?, ?, (SELECT object FROM subjects WHERE id = ?), ?)
+			ON CONFLICT(id) DO UPDATE SET
+				object = excluded.object,
+				url = excluded.url,
+				data_updated_at = excluded.data_updated_at,
+				assignment_id = excluded.assignment_id,
+				subject_id = excluded.subject_id,
+				subject_type = excluded.subject_type,
+				data = excluded.data
+		`)
 		if err != nil {
-			return fmt.Errorf("failed to marshal review data: %w", err)
+			return fmt.Errorf("failed to prepare statement: %w", err)
 		}
-
-		_, err = stmt.ExecContext(ctx,
-			review.ID,
-			review.Object,
-			review.URL,
-			review.DataUpdatedAt.Format(time.RFC3339),
-			review.Data.AssignmentID,
-			review.Data.SubjectID,
-			string(dataJSON),
-		)
-		if err != nil {
-			return fmt.Errorf("failed to upsert review: %w", err)
+		defer stmt.Close()
+
+		for _, review := range reviews {
+			dataJSON, err := json.Marshal(review.Data)
+			if err != nil {
+				return fmt.Errorf("failed to marshal review data: %w", err)
+			}
+
+			data, err := s.encodeData(dataJSON)
+			if err != nil {
+				return err
+			}
+
+			_, err = stmt.ExecContext(ctx,
+				review.ID,
+				review.Object,
+				review.URL,
+				review.DataUpdatedAt.Format(time.RFC3339),
+				review.Data.AssignmentID,
+				review.Data.SubjectID,
+				review.Data.SubjectID,
+				data,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to upsert review: %w", err)
+			}
 		}
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
 
-	return nil
+		return nil
+	})
 }
 
 // GetReviews retrieves reviews matching the provided filters
@@ -337,15 +850,34 @@ func (s *Store) GetReviews(ctx context.Context, filters domain.ReviewFilters) ([
 	args := []interface{}{}
 
 	if filters.From != nil {
-		query += ` AND json_extract(data, '$.created_at') >= ?`
+		query += ` AND json_extract(` + dataJSON("data") + `, '$.created_at') >= ?`
 		args = append(args, filters.From.Format(time.RFC3339))
 	}
 
 	if filters.To != nil {
-		query += ` AND json_extract(data, '$.created_at') <= ?`
+		query += ` AND json_extract(` + dataJSON("data") + `, '$.created_at') <= ?`
 		args = append(args, filters.To.Format(time.RFC3339))
 	}
 
+	if filters.SubjectType != "" {
+		query += ` AND subject_type = ?`
+		args = append(args, filters.SubjectType)
+	}
+
+	if filters.Passed != nil {
+		passed := `json_extract(` + dataJSON("data") + `, '$.incorrect_meaning_answers') = 0 AND json_extract(` + dataJSON("data") + `, '$.incorrect_reading_answers') = 0`
+		if *filters.Passed {
+			query += ` AND ` + passed
+		} else {
+			query += ` AND NOT (` + passed + `)`
+		}
+	}
+
+	if filters.Limit > 0 {
+		query += ` ORDER BY id LIMIT ?`
+		args = append(args, filters.Limit)
+	}
+
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query reviews: %w", err)
@@ -353,11 +885,15 @@ func (s *Store) GetReviews(ctx context.Context, filters domain.ReviewFilters) ([
 	defer rows.Close()
 
 	var reviews []domain.Review
-	for rows.Next() {
-		var review domain.Review
+	for scanned := 0; rows.Next(); scanned++ {
+		if err := checkScanContext(ctx, scanned); err != nil {
+			return nil, err
+		}
+
+		var review domain.Review
 		var dataUpdatedAtStr string
-		var dataJSON string
-		var assignmentID, subjectID int
+		var rawData []byte
+		var assignmentID, subjectID int64
 
 		err := rows.Scan(
 			&review.ID,
@@ -366,7 +902,7 @@ func (s *Store) GetReviews(ctx context.Context, filters domain.ReviewFilters) ([
 			&dataUpdatedAtStr,
 			&assignmentID,
 			&subjectID,
-			&dataJSON,
+			&rawData,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan review: %w", err)
@@ -377,7 +913,12 @@ func (s *Store) GetReviews(ctx context.Context, filters domain.ReviewFilters) ([
 			return nil, fmt.Errorf("failed to parse data_updated_at: %w", err)
 		}
 
-		if err := json.Unmarshal([]byte(dataJSON), &review.Data); err != nil {
+		dataJSON, err := decodeData(rawData)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(dataJSON, &review.Data); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal review data: %w", err)
 		}
 
@@ -391,17 +932,24 @@ func (s *Store) GetReviews(ctx context.Context, filters domain.ReviewFilters) ([
 	return reviews, nil
 }
 
-// InsertStatistics inserts a new statistics snapshot
+// InsertStatistics inserts a new statistics snapshot. Snapshots are unique per second, so
+// a second insert at the same timestamp replaces the data of the first.
 func (s *Store) InsertStatistics(ctx context.Context, stats domain.Statistics, timestamp time.Time) error {
 	dataJSON, err := json.Marshal(stats)
 	if err != nil {
 		return fmt.Errorf("failed to marshal statistics: %w", err)
 	}
 
+	data, err := s.encodeData(dataJSON)
+	if err != nil {
+		return err
+	}
+
 	_, err = s.db.ExecContext(ctx, `
 		INSERT INTO statistics_snapshots (timestamp, data)
 		VALUES (?, ?)
-	`, timestamp.Format(time.RFC3339), string(dataJSON))
+		ON CONFLICT(timestamp) DO UPDATE SET data = excluded.data
+	`, timestamp.Format(time.RFC3339), data)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert statistics: %w", err)
@@ -410,8 +958,9 @@ func (s *Store) InsertStatistics(ctx context.Context, stats domain.Statistics, t
 	return nil
 }
 
-// GetStatistics retrieves statistics snapshots within the provided date range
-func (s *Store) GetStatistics(ctx context.Context, dateRange *domain.DateRange) ([]domain.StatisticsSnapshot, error) {
+// GetStatistics retrieves statistics snapshots within the provided date range.
+// A limit always selects the most recent snapshots, which are then returned in the requested order.
+func (s *Store) GetStatistics(ctx context.Context, dateRange *domain.DateRange, opts domain.StatisticsOptions) ([]domain.StatisticsSnapshot, error) {
 	query := `SELECT id, timestamp, data FROM statistics_snapshots WHERE 1=1`
 	args := []interface{}{}
 
@@ -420,7 +969,16 @@ func (s *Store) GetStatistics(ctx context.Context, dateRange *domain.DateRange)
 		args = append(args, dateRange.From.Format(time.RFC3339), dateRange.To.Format(time.RFC3339))
 	}
 
-	query += ` ORDER BY timestamp DESC`
+	query += ` ORDER BY timestamp DESC, id DESC`
+
+	if opts.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, opts.Limit)
+	}
+
+	if opts.Order == domain.SortAsc {
+		query = `SELECT id, timestamp, data FROM (` + query + `) ORDER BY timestamp ASC, id ASC`
+	}
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -432,9 +990,9 @@ func (s *Store) GetStatistics(ctx context.Context, dateRange *domain.DateRange)
 	for rows.Next() {
 		var snapshot domain.StatisticsSnapshot
 		var timestampStr string
-		var dataJSON string
+		var rawData []byte
 
-		err := rows.Scan(&snapshot.ID, &timestampStr, &dataJSON)
+		err := rows.Scan(&snapshot.ID, &timestampStr, &rawData)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan statistics snapshot: %w", err)
 		}
@@ -444,7 +1002,12 @@ func (s *Store) GetStatistics(ctx context.Context, dateRange *domain.DateRange)
 			return nil, fmt.Errorf("failed to parse timestamp: %w", err)
 		}
 
-		if err := json.Unmarshal([]byte(dataJSON), &snapshot.Statistics); err != nil {
+		dataJSON, err := decodeData(rawData)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(dataJSON, &snapshot.Statistics); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal statistics: %w", err)
 		}
 
@@ -462,13 +1025,13 @@ func (s *Store) GetStatistics(ctx context.Context, dateRange *domain.DateRange)
 func (s *Store) GetLatestStatistics(ctx context.Context) (*domain.StatisticsSnapshot, error) {
 	var snapshot domain.StatisticsSnapshot
 	var timestampStr string
-	var dataJSON string
+	var rawData []byte
 
 	err := s.db.QueryRowContext(ctx, `
 		SELECT id, timestamp, data FROM statistics_snapshots
-		ORDER BY timestamp DESC
+		ORDER BY timestamp DESC, id DESC
 		LIMIT 1
-	`).Scan(&snapshot.ID, &timestampStr, &dataJSON)
+	`).Scan(&snapshot.ID, &timestampStr, &rawData)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -482,13 +1045,82 @@ func (s *Store) GetLatestStatistics(ctx context.Context) (*domain.StatisticsSnap
 		return nil, fmt.Errorf("failed to parse timestamp: %w", err)
 	}
 
-	if err := json.Unmarshal([]byte(dataJSON), &snapshot.Statistics); err != nil {
+	dataJSON, err := decodeData(rawData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(dataJSON, &snapshot.Statistics); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal statistics: %w", err)
 	}
 
 	return &snapshot, nil
 }
 
+// PruneStatistics applies the statistics retention policy: the keepRecent most recent
+// snapshots are kept, and older snapshots are collapsed to the latest one per calendar
+// day in the store's timezone. It returns the number of snapshots deleted.
+func (s *Store) PruneStatistics(ctx context.Context, keepRecent int) (int, error) {
+	var deleted int
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT id, timestamp FROM statistics_snapshots ORDER BY timestamp DESC, id DESC`)
+		if err != nil {
+			return fmt.Errorf("failed to query statistics snapshots: %w", err)
+		}
+
+		var toDelete []int64
+		seenDays := make(map[time.Time]bool)
+		position := 0
+		for rows.Next() {
+			var id int64
+			var timestampStr string
+			if err := rows.Scan(&id, &timestampStr); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan statistics snapshot: %w", err)
+			}
+
+			timestamp, err := time.Parse(time.RFC3339, timestampStr)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to parse timestamp: %w", err)
+			}
+
+			// Rows are newest first, so the first row seen for a day is that day's latest
+			day := s.startOfDay(timestamp)
+			if position >= keepRecent && seenDays[day] {
+				toDelete = append(toDelete, id)
+			}
+			seenDays[day] = true
+			position++
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating statistics snapshots: %w", err)
+		}
+
+		stmt, err := tx.PrepareContext(ctx, `DELETE FROM statistics_snapshots WHERE id = ?`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, id := range toDelete {
+			if _, err := stmt.ExecContext(ctx, id); err != nil {
+				return fmt.Errorf("failed to delete statistics snapshot: %w", err)
+			}
+		}
+
+		deleted = len(toDelete)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
 // UpsertAssignmentSnapshot inserts or updates an assignment snapshot
 func (s *Store) UpsertAssignmentSnapshot(ctx context.Context, snapshot domain.AssignmentSnapshot) error {
 	_, err := s.db.ExecContext(ctx, `
@@ -554,11 +1186,11 @@ func (s *Store) CalculateAssignmentSnapshot(ctx context.Context, date time.Time)
 	// Exclude SRS stage 0 (unstarted assignments) as per requirement 12.2
 	query := `
 		SELECT 
-			json_extract(data, '$.srs_stage') as srs_stage,
-			json_extract(data, '$.subject_type') as subject_type,
+			json_extract(` + dataJSON("data") + `, '$.srs_stage') as srs_stage,
+			json_extract(` + dataJSON("data") + `, '$.subject_type') as subject_type,
 			COUNT(*) as count
 		FROM assignments
-		WHERE json_extract(data, '$.srs_stage') > 0
+		WHERE json_extract(` + dataJSON("data") + `, '$.srs_stage') > 0
 		GROUP BY srs_stage, subject_type
 		ORDER BY srs_stage, subject_type
 	`
@@ -596,6 +1228,240 @@ func (s *Store) CalculateAssignmentSnapshot(ctx context.Context, date time.Time)
 	return snapshots, nil
 }
 
+// GetCurrentSRSGroupTotals counts current assignments by SRS stage group and subject type.
+// The grouping is done in SQL so no assignment rows are loaded; the CASE expression mirrors
+// domain.GetSRSStageName. Stage 0 (lessons) is excluded, as in snapshots.
+func (s *Store) GetCurrentSRSGroupTotals(ctx context.Context) (map[string]map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			CASE
+				WHEN srs_stage BETWEEN 1 AND 4 THEN 'apprentice'
+				WHEN srs_stage BETWEEN 5 AND 6 THEN 'guru'
+				WHEN srs_stage = 7 THEN 'master'
+				WHEN srs_stage = 8 THEN 'enlightened'
+				WHEN srs_stage = 9 THEN 'burned'
+				ELSE 'unknown'
+			END AS srs_group,
+			subject_type,
+			COUNT(*) AS count
+		FROM (
+			SELECT
+				json_extract(`+dataJSON("data")+`, '$.srs_stage') AS srs_stage,
+				json_extract(`+dataJSON("data")+`, '$.subject_type') AS subject_type
+			FROM assignments
+		)
+		WHERE srs_stage > 0
+		GROUP BY srs_group, subject_type
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SRS group totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[string]map[string]int)
+	for rows.Next() {
+		var group, subjectType string
+		var count int
+		if err := rows.Scan(&group, &subjectType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan SRS group total: %w", err)
+		}
+
+		if totals[group] == nil {
+			totals[group] = make(map[string]int)
+		}
+		totals[group][subjectType] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating SRS group totals: %w", err)
+	}
+
+	return totals, nil
+}
+
+// GetAssignmentCounts counts assignments by subject type with conditional aggregation,
+// classifying them as domain.Assignment.State does with the same stage thresholds, and
+// sums the types for the total
+func (s *Store) GetAssignmentCounts(ctx context.Context) (domain.AssignmentCounts, error) {
+	counts := domain.AssignmentCounts{SubjectTypes: make(map[string]domain.AssignmentStateCounts)}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			subject_type,
+			COUNT(*),
+			SUM(CASE WHEN srs_stage <= ? AND unlocked_at IS NULL THEN 1 ELSE 0 END),
+			SUM(CASE WHEN srs_stage <= ? AND unlocked_at IS NOT NULL THEN 1 ELSE 0 END),
+			SUM(CASE WHEN srs_stage > ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN srs_stage > ? AND srs_stage < ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN srs_stage >= ? THEN 1 ELSE 0 END)
+		FROM (
+			SELECT
+				json_extract(`+dataJSON("data")+`, '$.subject_type') AS subject_type,
+				json_extract(`+dataJSON("data")+`, '$.srs_stage') AS srs_stage,
+				json_extract(`+dataJSON("data")+`, '$.unlocked_at') AS unlocked_at
+			FROM assignments
+		)
+		GROUP BY subject_type
+	`, domain.SRSStageInitiate, domain.SRSStageInitiate, domain.SRSStageInitiate,
+		domain.SRSStageInitiate, domain.SRSStageBurned, domain.SRSStageBurned)
+	if err != nil {
+		return counts, fmt.Errorf("failed to query assignment counts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subjectType string
+		var c domain.AssignmentStateCounts
+		if err := rows.Scan(&subjectType, &c.Total, &c.Locked, &c.Lessons, &c.Started, &c.InProgress, &c.Burned); err != nil {
+			return counts, fmt.Errorf("failed to scan assignment counts: %w", err)
+		}
+		counts.SubjectTypes[subjectType] = c
+
+		counts.Total.Total += c.Total
+		counts.Total.Locked += c.Locked
+		counts.Total.Lessons += c.Lessons
+		counts.Total.Started += c.Started
+		counts.Total.InProgress += c.InProgress
+		counts.Total.Burned += c.Burned
+	}
+
+	if err := rows.Err(); err != nil {
+		return counts, fmt.Errorf("error iterating assignment counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetSubjectCountsByLevel counts subjects by level and type using the indexed level column
+func (s *Store) GetSubjectCountsByLevel(ctx context.Context) (map[int]map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT level, object, COUNT(*)
+		FROM subjects
+		WHERE level IS NOT NULL
+		GROUP BY level, object
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subject counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int]map[string]int)
+	for rows.Next() {
+		var level, count int
+		var subjectType string
+		if err := rows.Scan(&level, &subjectType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan subject count: %w", err)
+		}
+
+		if counts[level] == nil {
+			counts[level] = make(map[string]int)
+		}
+		counts[level][subjectType] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subject counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetDistinctLevels returns the levels that have at least one subject, in ascending order
+func (s *Store) GetDistinctLevels(ctx context.Context) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT level
+		FROM subjects
+		WHERE level IS NOT NULL
+		ORDER BY level
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subject levels: %w", err)
+	}
+	defer rows.Close()
+
+	levels := []int{}
+	for rows.Next() {
+		var level int
+		if err := rows.Scan(&level); err != nil {
+			return nil, fmt.Errorf("failed to scan subject level: %w", err)
+		}
+		levels = append(levels, level)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subject levels: %w", err)
+	}
+
+	return levels, nil
+}
+
+// maxIDsPerQuery bounds the number of IDs bound into a single IN (...) lookup, keeping
+// it under SQLite's host parameter limit
+const maxIDsPerQuery = 500
+
+// forEachIDBatch calls fn with batches of at most maxIDsPerQuery IDs, passing the
+// placeholder list and arguments to bind for each
+func forEachIDBatch(ids []int64, fn func(placeholders string, args []interface{}) error) error {
+	for start := 0; start < len(ids); start += maxIDsPerQuery {
+		batch := ids[start:min(start+maxIDsPerQuery, len(ids))]
+
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		if err := fn(strings.TrimSuffix(strings.Repeat("?,", len(batch)), ","), args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetLastReviewPerSubject returns the latest review created_at of each given subject,
+// querying the IDs in batches of maxIDsPerQuery. Timestamps are normalized to
+// millisecond precision so MAX compares them in time order.
+func (s *Store) GetLastReviewPerSubject(ctx context.Context, subjectIDs []int64) (map[int64]time.Time, error) {
+	lastReviews := make(map[int64]time.Time, len(subjectIDs))
+	err := forEachIDBatch(subjectIDs, func(placeholders string, args []interface{}) error {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT subject_id, MAX(strftime('%Y-%m-%dT%H:%M:%fZ', json_extract(`+dataJSON("data")+`, '$.created_at')))
+			FROM reviews
+			WHERE subject_id IN (`+placeholders+`)
+			GROUP BY subject_id
+		`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query last reviews: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var subjectID int64
+			var createdAtStr sql.NullString
+			if err := rows.Scan(&subjectID, &createdAtStr); err != nil {
+				return fmt.Errorf("failed to scan last review: %w", err)
+			}
+			if !createdAtStr.Valid {
+				continue
+			}
+
+			createdAt, err := time.Parse("2006-01-02T15:04:05.000Z", createdAtStr.String)
+			if err != nil {
+				return fmt.Errorf("failed to parse created_at: %w", err)
+			}
+			lastReviews[subjectID] = createdAt
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating last reviews: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lastReviews, nil
+}
+
 // GetLastSyncTime retrieves the last successful sync timestamp for a data type
 func (s *Store) GetLastSyncTime(ctx context.Context, dataType domain.DataType) (*time.Time, error) {
 	var lastSyncTimeStr string
@@ -634,8 +1500,420 @@ func (s *Store) SetLastSyncTime(ctx context.Context, dataType domain.DataType, t
 	return nil
 }
 
+// ClearSyncTimes removes the last sync timestamp of dataType, or of every data type if
+// dataType is nil, so the next sync fetches everything
+func (s *Store) ClearSyncTimes(ctx context.Context, dataType *domain.DataType) (int, error) {
+	query := `DELETE FROM sync_metadata`
+	var args []interface{}
+	if dataType != nil {
+		query += ` WHERE data_type = ?`
+		args = append(args, string(*dataType))
+	}
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear sync times: %w", err)
+	}
+
+	cleared, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count cleared sync times: %w", err)
+	}
+
+	return int(cleared), nil
+}
+
+// GetLevelProgress counts the subjects of a level by type, splitting them by the state
+// of their assignment. Subjects without an unlocked assignment count as locked.
+func (s *Store) GetLevelProgress(ctx context.Context, level int) (*domain.LevelProgress, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			s.object,
+			COUNT(*) AS total,
+			SUM(CASE WHEN json_extract(`+dataJSON("a.data")+`, '$.srs_stage') >= 5 THEN 1 ELSE 0 END) AS passed,
+			SUM(CASE
+				WHEN json_extract(`+dataJSON("a.data")+`, '$.unlocked_at') IS NOT NULL
+					AND json_extract(`+dataJSON("a.data")+`, '$.srs_stage') < 5 THEN 1
+				ELSE 0
+			END) AS in_progress
+		FROM subjects s
+		LEFT JOIN assignments a ON a.subject_id = s.id
+		WHERE s.level = ?
+		GROUP BY s.object
+		ORDER BY s.object
+	`, level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query level progress: %w", err)
+	}
+	defer rows.Close()
+
+	progress := &domain.LevelProgress{
+		Level:        level,
+		SubjectTypes: make(map[string]domain.LevelProgressCounts),
+	}
+	for rows.Next() {
+		var subjectType string
+		var counts domain.LevelProgressCounts
+		if err := rows.Scan(&subjectType, &counts.Total, &counts.Passed, &counts.InProgress); err != nil {
+			return nil, fmt.Errorf("failed to scan level progress: %w", err)
+		}
+		counts.Locked = counts.Total - counts.Passed - counts.InProgress
+
+		progress.SubjectTypes[subjectType] = counts
+		progress.Total.Total += counts.Total
+		progress.Total.Passed += counts.Passed
+		progress.Total.InProgress += counts.InProgress
+		progress.Total.Locked += counts.Locked
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating level progress: %w", err)
+	}
+
+	return progress, nil
+}
+
+// CountSubjects returns the number of stored subjects
+func (s *Store) CountSubjects(ctx context.Context) (int, error) {
+	return s.countRows(ctx, "subjects")
+}
+
+// CountAssignments returns the number of stored assignments
+func (s *Store) CountAssignments(ctx context.Context) (int, error) {
+	return s.countRows(ctx, "assignments")
+}
+
+// CountReviews returns the number of stored reviews
+func (s *Store) CountReviews(ctx context.Context) (int, error) {
+	return s.countRows(ctx, "reviews")
+}
+
+// CountAssignmentSnapshots returns the number of stored assignment snapshot rows
+func (s *Store) CountAssignmentSnapshots(ctx context.Context) (int, error) {
+	return s.countRows(ctx, "assignment_snapshots")
+}
+
+// scanContextCheckInterval is how many rows the list queries read between checks for a
+// cancelled context, so an abandoned request stops decoding a large result early
+const scanContextCheckInterval = 256
+
+// checkScanContext returns the context's error on every scanContextCheckInterval-th row
+func checkScanContext(ctx context.Context, scanned int) error {
+	if scanned%scanContextCheckInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// countRows returns the number of rows in table
+func (s *Store) countRows(ctx context.Context, table string) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", table, err)
+	}
+	return count, nil
+}
+
+// GetCurrentLevel derives the learner's level as the highest level among subjects with an
+// unlocked assignment, since WaniKani unlocks a level's subjects when the learner reaches it
+func (s *Store) GetCurrentLevel(ctx context.Context) (int, error) {
+	var level int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(s.level), 0)
+		FROM assignments a
+		JOIN subjects s ON s.id = a.subject_id
+		WHERE json_extract(`+dataJSON("a.data")+`, '$.unlocked_at') IS NOT NULL
+	`).Scan(&level)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query current level: %w", err)
+	}
+
+	return level, nil
+}
+
+// GetReviewStreak computes review streaks from the distinct calendar days, in the store's
+// timezone, on which reviews were created. The current streak counts consecutive days ending
+// today, or yesterday if there are no reviews yet today; lastDay is zero if there are no reviews.
+func (s *Store) GetReviewStreak(ctx context.Context) (current int, longest int, lastDay time.Time, err error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT json_extract(`+dataJSON("data")+`, '$.created_at') FROM reviews`)
+	if err != nil {
+		return 0, 0, time.Time{}, fmt.Errorf("failed to query review dates: %w", err)
+	}
+	defer rows.Close()
+
+	seen := make(map[time.Time]struct{})
+	for rows.Next() {
+		var createdAtStr string
+		if err := rows.Scan(&createdAtStr); err != nil {
+			return 0, 0, time.Time{}, fmt.Errorf("failed to scan review date: %w", err)
+		}
+
+		createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+		if err != nil {
+			return 0, 0, time.Time{}, fmt.Errorf("failed to parse created_at: %w", err)
+		}
+
+		seen[s.startOfDay(createdAt)] = struct{}{}
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, 0, time.Time{}, fmt.Errorf("error iterating review dates: %w", err)
+	}
+
+	if len(seen) == 0 {
+		return 0, 0, time.Time{}, nil
+	}
+
+	days := make([]time.Time, 0, len(seen))
+	for day := range seen {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	run := 0
+	for i, day := range days {
+		if i > 0 && days[i-1].AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+
+	lastDay = days[len(days)-1]
+	today := s.startOfDay(s.now())
+	if lastDay.Equal(today) || lastDay.AddDate(0, 0, 1).Equal(today) {
+		current = run
+	}
+
+	return current, longest, lastDay, nil
+}
+
+// GetReviewDateRange returns the earliest and latest created_at across all reviews
+func (s *Store) GetReviewDateRange(ctx context.Context) (*time.Time, *time.Time, error) {
+	var minStr, maxStr sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT MIN(created_at), MAX(created_at)
+		FROM (SELECT json_extract(`+dataJSON("data")+`, '$.created_at') AS created_at FROM reviews)
+	`).Scan(&minStr, &maxStr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query review date range: %w", err)
+	}
+	if !minStr.Valid || !maxStr.Valid {
+		return nil, nil, nil
+	}
+
+	first, err := time.Parse(time.RFC3339Nano, minStr.String)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+	last, err := time.Parse(time.RFC3339Nano, maxStr.String)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+
+	return &first, &last, nil
+}
+
+// GetSubjectAccuracy sums the correct and incorrect answers of every reviewed subject, in
+// subject ID order. Radicals and kana-only vocabulary have no reading, so each of their
+// reviews has one correct answer rather than two.
+func (s *Store) GetSubjectAccuracy(ctx context.Context) ([]domain.SubjectAccuracy, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			r.subject_id,
+			COUNT(*),
+			COUNT(*) * CASE WHEN sub.object IN ('radical', 'kana_vocabulary') THEN 1 ELSE 2 END,
+			COALESCE(SUM(json_extract(`+dataJSON("r.data")+`, '$.incorrect_meaning_answers')), 0)
+				+ COALESCE(SUM(json_extract(`+dataJSON("r.data")+`, '$.incorrect_reading_answers')), 0)
+		FROM reviews r
+		JOIN subjects sub ON sub.id = r.subject_id
+		GROUP BY r.subject_id, sub.object
+		ORDER BY r.subject_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subject accuracy: %w", err)
+	}
+	defer rows.Close()
+
+	var accuracy []domain.SubjectAccuracy
+	for rows.Next() {
+		var a domain.SubjectAccuracy
+		if err := rows.Scan(&a.SubjectID, &a.Reviews, &a.CorrectAnswers, &a.IncorrectAnswers); err != nil {
+			return nil, fmt.Errorf("failed to scan subject accuracy: %w", err)
+		}
+		accuracy = append(accuracy, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subject accuracy: %w", err)
+	}
+
+	return accuracy, nil
+}
+
+// GetReviewCountsByHour counts reviews by the hour of day they were created in, in the
+// store's timezone
+func (s *Store) GetReviewCountsByHour(ctx context.Context, filters domain.ReviewFilters) ([24]int, error) {
+	var counts [24]int
+	err := s.countReviewsByQuarterHour(ctx, filters, func(quarter time.Time, count int) {
+		counts[quarter.In(s.location).Hour()] += count
+	})
+	if err != nil {
+		return counts, fmt.Errorf("failed to count review hours: %w", err)
+	}
+	return counts, nil
+}
+
+// GetReviewCountsByWeek counts reviews by the ISO week (starting Monday) they were created
+// in, in the store's timezone. Keys are the Monday of each week as a UTC-midnight calendar
+// date; weeks without reviews are absent.
+func (s *Store) GetReviewCountsByWeek(ctx context.Context, filters domain.ReviewFilters) (map[time.Time]int, error) {
+	counts := make(map[time.Time]int)
+	err := s.countReviewsByQuarterHour(ctx, filters, func(quarter time.Time, count int) {
+		day := quarter.In(s.location)
+		monday := time.Date(day.Year(), day.Month(), day.Day()-(int(day.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+		counts[monday] += count
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count review weeks: %w", err)
+	}
+	return counts, nil
+}
+
+// GetReviewCountsByDay counts reviews by the calendar day they were created on, in the
+// store's timezone. Keys are UTC-midnight calendar dates; days without reviews are absent.
+func (s *Store) GetReviewCountsByDay(ctx context.Context, filters domain.ReviewFilters) (map[time.Time]int, error) {
+	counts := make(map[time.Time]int)
+	err := s.countReviewsByQuarterHour(ctx, filters, func(quarter time.Time, count int) {
+		day := quarter.In(s.location)
+		counts[time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)] += count
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count review days: %w", err)
+	}
+	return counts, nil
+}
+
+// GetReviewCountToday counts the reviews created since midnight in the store's timezone
+func (s *Store) GetReviewCountToday(ctx context.Context) (time.Time, int, error) {
+	today := s.startOfDay(s.now())
+	count := 0
+	err := s.countReviewsByQuarterHour(ctx, domain.ReviewFilters{From: &today, To: &today}, func(_ time.Time, n int) {
+		count += n
+	})
+	if err != nil {
+		return today, 0, fmt.Errorf("failed to count today's reviews: %w", err)
+	}
+	return today, count, nil
+}
+
+// countReviewsByQuarterHour calls add with the number of reviews created in each UTC
+// quarter hour that has any. SQLite only knows UTC, so local-time buckets are built by
+// mapping each quarter to the store's timezone in Go; every timezone offset and DST
+// transition falls on a quarter hour, so the mapping is exact. From and To in filters are
+// inclusive calendar days in the store's timezone.
+func (s *Store) countReviewsByQuarterHour(ctx context.Context, filters domain.ReviewFilters, add func(quarter time.Time, count int)) error {
+	where := ``
+	args := []interface{}{}
+	if filters.From != nil {
+		where += ` AND created_at >= ?`
+		args = append(args, s.calendarDay(*filters.From).UTC().Format(time.RFC3339))
+	}
+	if filters.To != nil {
+		where += ` AND created_at < ?`
+		args = append(args, s.calendarDay(*filters.To).AddDate(0, 0, 1).UTC().Format(time.RFC3339))
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT strftime('%Y-%m-%dT%H:', created_at) ||
+			printf('%02d', CAST(strftime('%M', created_at) AS INTEGER) / 15 * 15) AS quarter,
+			COUNT(*)
+		FROM (SELECT json_extract(`+dataJSON("data")+`, '$.created_at') AS created_at FROM reviews)
+		WHERE created_at IS NOT NULL`+where+`
+		GROUP BY quarter
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query reviews: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var quarterStr string
+		var count int
+		if err := rows.Scan(&quarterStr, &count); err != nil {
+			return fmt.Errorf("failed to scan review count: %w", err)
+		}
+
+		quarter, err := time.Parse("2006-01-02T15:04", quarterStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse review time: %w", err)
+		}
+
+		add(quarter, count)
+	}
+
+	return rows.Err()
+}
+
+// calendarDay reinterprets the date of t as a calendar day in the store's timezone,
+// e.g. for dates parsed from YYYY-MM-DD query parameters, which come out in UTC
+func (s *Store) calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.location)
+}
+
+// startOfDay truncates a timestamp to midnight in the store's timezone
+func (s *Store) startOfDay(t time.Time) time.Time {
+	t = t.In(s.location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.location)
+}
+
+// SaveRateLimitState stores the most recently observed WaniKani rate limit information
+func (s *Store) SaveRateLimitState(ctx context.Context, info domain.RateLimitInfo) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rate_limit_state (id, remaining, reset_at, updated_at)
+		VALUES (1, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			remaining = excluded.remaining,
+			reset_at = excluded.reset_at,
+			updated_at = excluded.updated_at
+	`, info.Remaining, info.ResetAt.Format(time.RFC3339), time.Now().Format(time.RFC3339))
+
+	if err != nil {
+		return fmt.Errorf("failed to save rate limit state: %w", err)
+	}
+
+	return nil
+}
+
+// LoadRateLimitState retrieves the persisted WaniKani rate limit information
+func (s *Store) LoadRateLimitState(ctx context.Context) (*domain.RateLimitInfo, error) {
+	var info domain.RateLimitInfo
+	var resetAtStr string
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT remaining, reset_at FROM rate_limit_state WHERE id = 1
+	`).Scan(&info.Remaining, &resetAtStr)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rate limit state: %w", err)
+	}
+
+	info.ResetAt, err = time.Parse(time.RFC3339, resetAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse reset_at: %w", err)
+	}
+
+	return &info, nil
+}
+
 // validateSubjectExists checks if a subject with the given ID exists in the database
-func (s *Store) validateSubjectExists(ctx context.Context, tx *sql.Tx, subjectID int) error {
+func (s *Store) validateSubjectExists(ctx context.Context, tx *sql.Tx, subjectID int64) error {
 	var exists bool
 	var query string
 	var err error
@@ -660,7 +1938,7 @@ func (s *Store) validateSubjectExists(ctx context.Context, tx *sql.Tx, subjectID
 }
 
 // validateAssignmentExists checks if an assignment with the given ID exists in the database
-func (s *Store) validateAssignmentExists(ctx context.Context, tx *sql.Tx, assignmentID int) error {
+func (s *Store) validateAssignmentExists(ctx context.Context, tx *sql.Tx, assignmentID int64) error {
 	var exists bool
 	var query string
 	var err error
@@ -1,9 +1,17 @@
 package sqlite
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -40,6 +48,55 @@ func setupTestStore(t *testing.T, dbPath string) *Store {
 	return store
 }
 
+func TestEnsureDir(t *testing.T) {
+	base := t.TempDir()
+
+	tests := []struct {
+		name   string
+		dbPath string
+		dir    string
+	}{
+		{name: "nested path", dbPath: filepath.Join(base, "data", "db", "wanikani.db"), dir: filepath.Join(base, "data", "db")},
+		{name: "file URI", dbPath: "file:" + filepath.Join(base, "uri", "wanikani.db") + "?_busy_timeout=5000", dir: filepath.Join(base, "uri")},
+		{name: "existing directory", dbPath: filepath.Join(base, "wanikani.db"), dir: base},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := EnsureDir(tt.dbPath); err != nil {
+				t.Fatalf("failed to ensure database directory: %v", err)
+			}
+			if info, err := os.Stat(tt.dir); err != nil || !info.IsDir() {
+				t.Fatalf("expected directory %s to exist, got %v", tt.dir, err)
+			}
+		})
+	}
+
+	// The created directory holds a working database
+	dbPath := filepath.Join(base, "fresh", "volume", "wanikani.db")
+	if err := EnsureDir(dbPath); err != nil {
+		t.Fatalf("failed to ensure database directory: %v", err)
+	}
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+	if err := store.UpsertSubjects(context.Background(), []domain.Subject{{ID: 1, Object: "kanji", DataUpdatedAt: time.Now()}}); err != nil {
+		t.Errorf("failed to write to the database: %v", err)
+	}
+
+	if err := EnsureDir(":memory:"); err != nil {
+		t.Errorf("expected in-memory databases to be skipped, got %v", err)
+	}
+
+	// A file in the way of the directory is reported
+	blocker := filepath.Join(base, "blocker")
+	if err := os.WriteFile(blocker, nil, 0o600); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := EnsureDir(filepath.Join(blocker, "wanikani.db")); err == nil {
+		t.Error("expected an error when the directory cannot be created")
+	}
+}
+
 func TestStore_UpsertAndGetSubjects(t *testing.T) {
 	// Create temporary database
 	dbPath := "test_subjects.db"
@@ -128,8 +185,8 @@ func TestStore_UpsertAndGetSubjects(t *testing.T) {
 	}
 }
 
-func TestStore_UpsertAndGetAssignments(t *testing.T) {
-	dbPath := "test_assignments.db"
+func TestStore_StreamSubjects(t *testing.T) {
+	dbPath := "test_stream_subjects.db"
 	defer os.Remove(dbPath)
 
 	store := setupTestStore(t, dbPath)
@@ -137,70 +194,83 @@ func TestStore_UpsertAndGetAssignments(t *testing.T) {
 
 	ctx := context.Background()
 
-	// First create a subject (for foreign key constraint)
-	subjects := []domain.Subject{
-		{
-			ID:            1,
-			Object:        "kanji",
-			URL:           "https://api.wanikani.com/v2/subjects/1",
-			DataUpdatedAt: time.Now(),
-			Data: domain.SubjectData{
-				Level:      5,
-				Characters: "一",
-			},
-		},
+	var subjects []domain.Subject
+	for i := 1; i <= 4; i++ {
+		subjects = append(subjects, domain.Subject{ID: int64(i), Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}})
 	}
-	err := store.UpsertSubjects(ctx, subjects)
-	if err != nil {
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
 		t.Fatalf("failed to upsert subjects: %v", err)
 	}
 
-	// Create test assignments
-	now := time.Now()
-	assignments := []domain.Assignment{
-		{
-			ID:            100,
-			Object:        "assignment",
-			URL:           "https://api.wanikani.com/v2/assignments/100",
-			DataUpdatedAt: now,
-			Data: domain.AssignmentData{
-				SubjectID:   1,
-				SubjectType: "kanji",
-				SRSStage:    3,
-				UnlockedAt:  &now,
-			},
-		},
-	}
-
-	err = store.UpsertAssignments(ctx, assignments)
+	var ids []int64
+	err := store.StreamSubjects(ctx, domain.SubjectFilters{Limit: 3}, func(subject domain.Subject) error {
+		ids = append(ids, subject.ID)
+		return nil
+	})
 	if err != nil {
-		t.Fatalf("failed to upsert assignments: %v", err)
+		t.Fatalf("failed to stream subjects: %v", err)
+	}
+	if fmt.Sprint(ids) != "[1 2 3]" {
+		t.Errorf("expected subjects [1 2 3] within the limit, got %v", ids)
 	}
 
-	// Test get assignments
-	retrieved, err := store.GetAssignments(ctx, domain.AssignmentFilters{})
-	if err != nil {
-		t.Fatalf("failed to get assignments: %v", err)
+	// An error from the callback stops the stream and is returned
+	stop := errors.New("client went away")
+	calls := 0
+	err = store.StreamSubjects(ctx, domain.SubjectFilters{}, func(subject domain.Subject) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("expected the callback error after 1 call, got %v after %d", err, calls)
 	}
+}
 
-	if len(retrieved) != 1 {
-		t.Errorf("expected 1 assignment, got %d", len(retrieved))
+func TestStore_SubjectAnswerFieldsRoundTrip(t *testing.T) {
+	dbPath := "test_subject_answer_fields.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// No Raw payload, so the stored JSON comes from marshalling SubjectData
+	data := domain.SubjectData{
+		Level:      1,
+		Characters: "人",
+		Meanings: []domain.Meaning{
+			{Meaning: "Person", Primary: true, AcceptedAnswer: true},
+		},
+		AuxiliaryMeanings: []domain.AuxiliaryMeaning{
+			{Meaning: "People", Type: domain.AuxiliaryMeaningWhitelist},
+			{Meaning: "Enter", Type: domain.AuxiliaryMeaningBlacklist},
+		},
+		Readings: []domain.Reading{
+			{Reading: "じん", Primary: true, AcceptedAnswer: true, Type: "onyomi"},
+			{Reading: "ひと", Primary: false, AcceptedAnswer: false, Type: "kunyomi"},
+		},
+	}
+	subject := domain.Subject{ID: 1, Object: "kanji", URL: "https://api.wanikani.com/v2/subjects/1", DataUpdatedAt: time.Now(), Data: data}
+	if err := store.UpsertSubjects(ctx, []domain.Subject{subject}); err != nil {
+		t.Fatalf("failed to upsert subject: %v", err)
 	}
 
-	// Test filter by SRS stage
-	srsStage := 3
-	filtered, err := store.GetAssignments(ctx, domain.AssignmentFilters{SRSStage: &srsStage})
+	retrieved, err := store.GetSubjects(ctx, domain.SubjectFilters{})
 	if err != nil {
-		t.Fatalf("failed to get filtered assignments: %v", err)
+		t.Fatalf("failed to get subjects: %v", err)
+	}
+	if len(retrieved) != 1 {
+		t.Fatalf("expected 1 subject, got %d", len(retrieved))
 	}
 
-	if len(filtered) != 1 {
-		t.Errorf("expected 1 assignment with SRS stage 3, got %d", len(filtered))
+	if fmt.Sprintf("%+v", retrieved[0].Data) != fmt.Sprintf("%+v", data) {
+		t.Errorf("expected subject data %+v, got %+v", data, retrieved[0].Data)
 	}
 }
 
-func TestStore_TransactionRollback(t *testing.T) {
-	dbPath := "test_transaction.db"
+func TestStore_ImageOnlyRadical(t *testing.T) {
+	dbPath := "test_subject_image_radical.db"
 	defer os.Remove(dbPath)
 
 	store := setupTestStore(t, dbPath)
@@ -208,40 +278,106 @@ func TestStore_TransactionRollback(t *testing.T) {
 
 	ctx := context.Background()
 
-	// Start a transaction
-	tx, err := store.BeginTx(ctx)
-	if err != nil {
-		t.Fatalf("failed to begin transaction: %v", err)
+	// WaniKani sends characters as null for radicals that only exist as images
+	payload := `{
+		"id": 8761,
+		"object": "radical",
+		"url": "https://api.wanikani.com/v2/subjects/8761",
+		"data_updated_at": "2024-01-01T00:00:00Z",
+		"data": {
+			"level": 1,
+			"characters": null,
+			"character_images": [
+				{"url": "https://files.wanikani.com/barb.svg", "content_type": "image/svg+xml", "metadata": {"inline_styles": true}},
+				{"url": "https://files.wanikani.com/barb.png", "content_type": "image/png", "metadata": {"color": "#000000", "dimensions": "32x32", "style_name": "32px"}}
+			],
+			"meanings": [{"meaning": "Barb", "primary": true, "accepted_answer": true}]
+		}
+	}`
+	var subject domain.Subject
+	if err := json.Unmarshal([]byte(payload), &subject); err != nil {
+		t.Fatalf("failed to decode subject: %v", err)
 	}
-
-	// Insert a subject within the transaction
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO subjects (id, object, url, data_updated_at, data)
-		VALUES (?, ?, ?, ?, ?)
-	`, 1, "kanji", "https://test.com", time.Now().Format(time.RFC3339), `{"level": 1}`)
-	if err != nil {
-		t.Fatalf("failed to insert in transaction: %v", err)
+	if err := store.UpsertSubjects(ctx, []domain.Subject{subject}); err != nil {
+		t.Fatalf("failed to upsert subject: %v", err)
 	}
 
-	// Rollback the transaction
-	err = tx.Rollback()
-	if err != nil {
-		t.Fatalf("failed to rollback transaction: %v", err)
+	level := 1
+	for name, filters := range map[string]domain.SubjectFilters{
+		"by level":   {Level: &level},
+		"by type":    {Type: "radical"},
+		"by meaning": {Meaning: "barb"},
+	} {
+		retrieved, err := store.GetSubjects(ctx, filters)
+		if err != nil {
+			t.Fatalf("%s: failed to get subjects: %v", name, err)
+		}
+		if len(retrieved) != 1 {
+			t.Fatalf("%s: expected the image-only radical, got %d subjects", name, len(retrieved))
+		}
+
+		data := retrieved[0].Data
+		if data.Characters != "" {
+			t.Errorf("%s: expected empty characters, got %q", name, data.Characters)
+		}
+		if len(data.CharacterImages) != 2 {
+			t.Fatalf("%s: expected 2 character images, got %d", name, len(data.CharacterImages))
+		}
+		svg, png := data.CharacterImages[0], data.CharacterImages[1]
+		if svg.ContentType != "image/svg+xml" || svg.Metadata.InlineStyles == nil || !*svg.Metadata.InlineStyles {
+			t.Errorf("%s: unexpected SVG image %+v", name, svg)
+		}
+		if png.URL != "https://files.wanikani.com/barb.png" || png.Metadata.Dimensions != "32x32" || png.Metadata.StyleName != "32px" {
+			t.Errorf("%s: unexpected PNG image %+v", name, png)
+		}
 	}
+}
 
-	// Verify the subject was not persisted
-	subjects, err := store.GetSubjects(ctx, domain.SubjectFilters{})
-	if err != nil {
-		t.Fatalf("failed to get subjects: %v", err)
+func TestStore_SubjectBecomesHidden(t *testing.T) {
+	dbPath := "test_subject_hidden.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// The same subject as fetched before and after WaniKani hid it
+	payloads := []string{
+		`{"id": 1, "object": "vocabulary", "data_updated_at": "2024-01-01T00:00:00Z",
+			"data": {"level": 1, "characters": "一つ", "hidden_at": null}}`,
+		`{"id": 1, "object": "vocabulary", "data_updated_at": "2024-06-01T00:00:00Z",
+			"data": {"level": 1, "characters": "一つ", "hidden_at": "2024-06-01T00:00:00.000000Z"}}`,
 	}
+	for i, payload := range payloads {
+		var subject domain.Subject
+		if err := json.Unmarshal([]byte(payload), &subject); err != nil {
+			t.Fatalf("failed to decode subject: %v", err)
+		}
+		if err := store.UpsertSubjects(ctx, []domain.Subject{subject}); err != nil {
+			t.Fatalf("failed to upsert subject: %v", err)
+		}
 
-	if len(subjects) != 0 {
-		t.Errorf("expected 0 subjects after rollback, got %d", len(subjects))
+		retrieved, err := store.GetSubjects(ctx, domain.SubjectFilters{})
+		if err != nil {
+			t.Fatalf("failed to get subjects: %v", err)
+		}
+		if len(retrieved) != 1 {
+			t.Fatalf("expected 1 subject, got %d", len(retrieved))
+		}
+
+		hiddenAt := retrieved[0].Data.HiddenAt
+		if i == 0 && hiddenAt != nil {
+			t.Errorf("expected a visible subject, got hidden_at %v", *hiddenAt)
+		}
+		if i == 1 && (hiddenAt == nil || !hiddenAt.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))) {
+			t.Errorf("expected the subject to be marked hidden on 2024-06-01, got %v", hiddenAt)
+		}
 	}
 }
 
-func TestStore_SyncMetadata(t *testing.T) {
-	dbPath := "test_sync.db"
+func TestStore_GetSubjectsByLevelRange(t *testing.T) {
+	dbPath := "test_subjects_level_range.db"
 	defer os.Remove(dbPath)
 
 	store := setupTestStore(t, dbPath)
@@ -249,58 +385,112 @@ func TestStore_SyncMetadata(t *testing.T) {
 
 	ctx := context.Background()
 
-	// Test getting sync time when none exists
-	syncTime, err := store.GetLastSyncTime(ctx, domain.DataTypeSubjects)
-	if err != nil {
-		t.Fatalf("failed to get last sync time: %v", err)
+	var subjects []domain.Subject
+	for _, level := range []int{1, 3, 5, 10, 11, 60} {
+		subjects = append(subjects, domain.Subject{
+			ID:            int64(level),
+			Object:        "kanji",
+			URL:           fmt.Sprintf("https://api.wanikani.com/v2/subjects/%d", level),
+			DataUpdatedAt: time.Now(),
+			Data:          domain.SubjectData{Level: level, Characters: "一"},
+		})
 	}
-
-	if syncTime != nil {
-		t.Errorf("expected nil sync time, got %v", syncTime)
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
 	}
 
-	// Set sync time
-	now := time.Now()
-	err = store.SetLastSyncTime(ctx, domain.DataTypeSubjects, now)
-	if err != nil {
-		t.Fatalf("failed to set last sync time: %v", err)
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name           string
+		filters        domain.SubjectFilters
+		expectedLevels []int
+	}{
+		{name: "range", filters: domain.SubjectFilters{LevelMin: intPtr(1), LevelMax: intPtr(10)}, expectedLevels: []int{1, 3, 5, 10}},
+		{name: "single-value range", filters: domain.SubjectFilters{LevelMin: intPtr(5), LevelMax: intPtr(5)}, expectedLevels: []int{5}},
+		{name: "min only", filters: domain.SubjectFilters{LevelMin: intPtr(11)}, expectedLevels: []int{11, 60}},
+		{name: "max only", filters: domain.SubjectFilters{LevelMax: intPtr(3)}, expectedLevels: []int{1, 3}},
+		{name: "range combined with exact level", filters: domain.SubjectFilters{Level: intPtr(3), LevelMin: intPtr(1), LevelMax: intPtr(10)}, expectedLevels: []int{3}},
+		{name: "empty range", filters: domain.SubjectFilters{LevelMin: intPtr(20), LevelMax: intPtr(30)}, expectedLevels: nil},
 	}
 
-	// Get sync time
-	syncTime, err = store.GetLastSyncTime(ctx, domain.DataTypeSubjects)
-	if err != nil {
-		t.Fatalf("failed to get last sync time: %v", err)
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retrieved, err := store.GetSubjects(ctx, tt.filters)
+			if err != nil {
+				t.Fatalf("failed to get subjects: %v", err)
+			}
 
-	if syncTime == nil {
-		t.Fatal("expected sync time, got nil")
-	}
+			var levels []int
+			for _, subject := range retrieved {
+				levels = append(levels, subject.Data.Level)
+			}
+			sort.Ints(levels)
 
-	// Compare times (allowing for small differences due to formatting)
-	if syncTime.Unix() != now.Unix() {
-		t.Errorf("expected sync time %v, got %v", now, syncTime)
+			if fmt.Sprint(levels) != fmt.Sprint(tt.expectedLevels) {
+				t.Errorf("expected levels %v, got %v", tt.expectedLevels, levels)
+			}
+		})
 	}
+}
 
-	// Update sync time
-	later := now.Add(1 * time.Hour)
-	err = store.SetLastSyncTime(ctx, domain.DataTypeSubjects, later)
-	if err != nil {
-		t.Fatalf("failed to update last sync time: %v", err)
+func TestStore_GetSubjectsSortedByPosition(t *testing.T) {
+	dbPath := "test_subjects_position.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// IDs deliberately disagree with lesson positions
+	subjects := []domain.Subject{
+		{ID: 1, Object: "radical", Data: domain.SubjectData{Level: 2, LessonPosition: 0}},
+		{ID: 2, Object: "kanji", Data: domain.SubjectData{Level: 1, LessonPosition: 2}},
+		{ID: 3, Object: "radical", Data: domain.SubjectData{Level: 1, LessonPosition: 1}},
+		{ID: 4, Object: "vocabulary", Data: domain.SubjectData{Level: 1, LessonPosition: 0}},
+		{ID: 5, Object: "kanji", Data: domain.SubjectData{Level: 1, LessonPosition: 10}},
+	}
+	for i := range subjects {
+		subjects[i].URL = fmt.Sprintf("https://api.wanikani.com/v2/subjects/%d", subjects[i].ID)
+		subjects[i].DataUpdatedAt = time.Now()
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
 	}
 
-	// Verify update
-	syncTime, err = store.GetLastSyncTime(ctx, domain.DataTypeSubjects)
-	if err != nil {
-		t.Fatalf("failed to get updated sync time: %v", err)
+	level := 1
+	tests := []struct {
+		name        string
+		filters     domain.SubjectFilters
+		expectedIDs []int
+	}{
+		{name: "within a level", filters: domain.SubjectFilters{Level: &level, Sort: domain.SubjectSortPosition}, expectedIDs: []int{4, 3, 2, 5}},
+		{name: "across levels", filters: domain.SubjectFilters{Sort: domain.SubjectSortPosition}, expectedIDs: []int{4, 3, 2, 5, 1}},
+		{name: "with limit", filters: domain.SubjectFilters{Level: &level, Sort: domain.SubjectSortPosition, Limit: 2}, expectedIDs: []int{4, 3}},
+		{name: "by id", filters: domain.SubjectFilters{Level: &level, Sort: domain.SubjectSortID, Limit: 10}, expectedIDs: []int{2, 3, 4, 5}},
 	}
 
-	if syncTime.Unix() != later.Unix() {
-		t.Errorf("expected updated sync time %v, got %v", later, syncTime)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retrieved, err := store.GetSubjects(ctx, tt.filters)
+			if err != nil {
+				t.Fatalf("failed to get subjects: %v", err)
+			}
+
+			var ids []int64
+			for _, subject := range retrieved {
+				ids = append(ids, subject.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.expectedIDs) {
+				t.Errorf("expected IDs %v, got %v", tt.expectedIDs, ids)
+			}
+		})
 	}
 }
 
-func TestStore_Statistics(t *testing.T) {
-	dbPath := "test_statistics.db"
+func TestStore_GetSubjectsByMeaning(t *testing.T) {
+	dbPath := "test_subjects_meaning.db"
 	defer os.Remove(dbPath)
 
 	store := setupTestStore(t, dbPath)
@@ -308,64 +498,65 @@ func TestStore_Statistics(t *testing.T) {
 
 	ctx := context.Background()
 
-	// Create test statistics
-	stats := domain.Statistics{
-		Object:        "report",
-		URL:           "https://api.wanikani.com/v2/summary",
-		DataUpdatedAt: time.Now(),
-		Data: domain.StatisticsData{
-			Lessons: []domain.LessonStatistics{
-				{
-					AvailableAt: time.Now(),
-					SubjectIDs:  []int{1, 2, 3},
-				},
+	subject := func(id int64, meaning string, auxiliary ...domain.AuxiliaryMeaning) domain.Subject {
+		return domain.Subject{
+			ID:            id,
+			Object:        "vocabulary",
+			URL:           fmt.Sprintf("https://api.wanikani.com/v2/subjects/%d", id),
+			DataUpdatedAt: time.Now(),
+			Data: domain.SubjectData{
+				Level:             1,
+				Meanings:          []domain.Meaning{{Meaning: meaning, Primary: true}},
+				AuxiliaryMeanings: auxiliary,
 			},
-		},
-	}
-
-	// Insert first snapshot
-	timestamp1 := time.Now().Add(-2 * time.Hour)
-	err := store.InsertStatistics(ctx, stats, timestamp1)
-	if err != nil {
-		t.Fatalf("failed to insert statistics: %v", err)
+		}
 	}
-
-	// Insert second snapshot
-	timestamp2 := time.Now().Add(-1 * time.Hour)
-	err = store.InsertStatistics(ctx, stats, timestamp2)
-	if err != nil {
-		t.Fatalf("failed to insert second statistics: %v", err)
+	subjects := []domain.Subject{
+		subject(1, "Über"),
+		subject(2, "Café"),
+		subject(3, "Big", domain.AuxiliaryMeaning{Meaning: "Large", Type: domain.AuxiliaryMeaningWhitelist}),
+		subject(4, "Small", domain.AuxiliaryMeaning{Meaning: "Large", Type: domain.AuxiliaryMeaningBlacklist}),
+		subject(5, "Mountain"),
 	}
-
-	// Get latest statistics
-	latest, err := store.GetLatestStatistics(ctx)
-	if err != nil {
-		t.Fatalf("failed to get latest statistics: %v", err)
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
 	}
 
-	if latest == nil {
-		t.Fatal("expected latest statistics, got nil")
+	tests := []struct {
+		name        string
+		meaning     string
+		expectedIDs []int
+	}{
+		{name: "case-insensitive", meaning: "MOUNTAIN", expectedIDs: []int{5}},
+		{name: "plain query matches accented meaning", meaning: "uber", expectedIDs: []int{1}},
+		{name: "accented query matches", meaning: "  CAFÉ ", expectedIDs: []int{2}},
+		{name: "substring", meaning: "oun", expectedIDs: []int{5}},
+		{name: "whitelisted auxiliary meaning only", meaning: "large", expectedIDs: []int{3}},
+		{name: "no match", meaning: "river", expectedIDs: nil},
 	}
 
-	// Verify it's the most recent one
-	if latest.Timestamp.Unix() != timestamp2.Unix() {
-		t.Errorf("expected timestamp %v, got %v", timestamp2, latest.Timestamp)
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retrieved, err := store.GetSubjects(ctx, domain.SubjectFilters{Meaning: tt.meaning})
+			if err != nil {
+				t.Fatalf("failed to get subjects: %v", err)
+			}
 
-	// Get all statistics
-	allStats, err := store.GetStatistics(ctx, nil)
-	if err != nil {
-		t.Fatalf("failed to get all statistics: %v", err)
-	}
+			var ids []int64
+			for _, s := range retrieved {
+				ids = append(ids, s.ID)
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
 
-	if len(allStats) != 2 {
-		t.Errorf("expected 2 statistics snapshots, got %d", len(allStats))
+			if fmt.Sprint(ids) != fmt.Sprint(tt.expectedIDs) {
+				t.Errorf("expected subjects %v, got %v", tt.expectedIDs, ids)
+			}
+		})
 	}
 }
 
-// TestStore_StatisticsHistoricalTracking tests comprehensive historical tracking of statistics
-func TestStore_StatisticsHistoricalTracking(t *testing.T) {
-	dbPath := "test_statistics_historical.db"
+func TestStore_GetCurrentLevel(t *testing.T) {
+	dbPath := "test_current_level.db"
 	defer os.Remove(dbPath)
 
 	store := setupTestStore(t, dbPath)
@@ -373,239 +564,279 @@ func TestStore_StatisticsHistoricalTracking(t *testing.T) {
 
 	ctx := context.Background()
 
-	t.Run("snapshots are stored with timestamps", func(t *testing.T) {
-		// Create multiple statistics snapshots with different timestamps
-		baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
-
-		for i := 0; i < 5; i++ {
-			stats := domain.Statistics{
-				Object:        "report",
-				URL:           "https://api.wanikani.com/v2/summary",
-				DataUpdatedAt: baseTime.Add(time.Duration(i) * 24 * time.Hour),
-				Data: domain.StatisticsData{
-					Lessons: []domain.LessonStatistics{
-						{
-							AvailableAt: baseTime.Add(time.Duration(i) * 24 * time.Hour),
-							SubjectIDs:  []int{i + 1, i + 2, i + 3},
-						},
-					},
-					Reviews: []domain.ReviewStatistics{
-						{
-							AvailableAt: baseTime.Add(time.Duration(i) * 24 * time.Hour),
-							SubjectIDs:  []int{i * 10, i*10 + 1},
-						},
-					},
-				},
-			}
+	level, err := store.GetCurrentLevel(ctx)
+	if err != nil {
+		t.Fatalf("failed to get current level: %v", err)
+	}
+	if level != 0 {
+		t.Errorf("expected level 0 with no assignments, got %d", level)
+	}
 
-			timestamp := baseTime.Add(time.Duration(i) * 24 * time.Hour)
-			err := store.InsertStatistics(ctx, stats, timestamp)
-			if err != nil {
-				t.Fatalf("failed to insert statistics snapshot %d: %v", i, err)
-			}
-		}
+	var subjects []domain.Subject
+	for _, l := range []int{1, 2, 3} {
+		subjects = append(subjects, domain.Subject{
+			ID:            int64(l),
+			Object:        "kanji",
+			URL:           fmt.Sprintf("https://api.wanikani.com/v2/subjects/%d", l),
+			DataUpdatedAt: time.Now(),
+			Data:          domain.SubjectData{Level: l},
+		})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
 
-		// Verify all snapshots were stored
-		allSnapshots, err := store.GetStatistics(ctx, nil)
-		if err != nil {
-			t.Fatalf("failed to get all statistics: %v", err)
-		}
+	// Level 3 has an assignment, but it isn't unlocked yet
+	unlockedAt := time.Now()
+	assignments := []domain.Assignment{
+		{ID: 1, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 1, UnlockedAt: &unlockedAt}},
+		{ID: 2, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 2, UnlockedAt: &unlockedAt}},
+		{ID: 3, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 3}},
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("failed to upsert assignments: %v", err)
+	}
 
-		if len(allSnapshots) != 5 {
-			t.Errorf("expected 5 snapshots, got %d", len(allSnapshots))
-		}
+	level, err = store.GetCurrentLevel(ctx)
+	if err != nil {
+		t.Fatalf("failed to get current level: %v", err)
+	}
+	if level != 2 {
+		t.Errorf("expected level 2, got %d", level)
+	}
+}
 
-		// Verify each snapshot has the correct timestamp
-		for i, snapshot := range allSnapshots {
-			expectedTime := baseTime.Add(time.Duration(4-i) * 24 * time.Hour) // Reversed order (DESC)
-			if snapshot.Timestamp.Unix() != expectedTime.Unix() {
-				t.Errorf("snapshot %d: expected timestamp %v, got %v", i, expectedTime, snapshot.Timestamp)
-			}
-		}
-	})
+func TestStore_GetLevelProgress(t *testing.T) {
+	dbPath := "test_level_progress.db"
+	defer os.Remove(dbPath)
 
-	t.Run("date range filtering works correctly", func(t *testing.T) {
-		// Query with date range
-		baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
-		dateRange := &domain.DateRange{
-			From: baseTime.Add(1 * 24 * time.Hour),
-			To:   baseTime.Add(3 * 24 * time.Hour),
-		}
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
 
-		filtered, err := store.GetStatistics(ctx, dateRange)
-		if err != nil {
-			t.Fatalf("failed to get filtered statistics: %v", err)
-		}
+	ctx := context.Background()
 
-		// Should return snapshots from day 1, 2, and 3 (3 snapshots)
-		if len(filtered) != 3 {
-			t.Errorf("expected 3 snapshots in date range, got %d", len(filtered))
+	subject := func(id int64, object string, level int) domain.Subject {
+		return domain.Subject{
+			ID:            id,
+			Object:        object,
+			URL:           fmt.Sprintf("https://api.wanikani.com/v2/subjects/%d", id),
+			DataUpdatedAt: time.Now(),
+			Data:          domain.SubjectData{Level: level},
 		}
+	}
+	subjects := []domain.Subject{
+		subject(1, "radical", 5),
+		subject(2, "radical", 5),
+		subject(3, "kanji", 5),
+		subject(4, "kanji", 5),
+		subject(5, "kanji", 5),
+		subject(6, "kanji", 5),
+		subject(7, "kanji", 6),
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
 
-		// Verify all returned snapshots are within the date range
-		for _, snapshot := range filtered {
-			if snapshot.Timestamp.Before(dateRange.From) || snapshot.Timestamp.After(dateRange.To) {
-				t.Errorf("snapshot timestamp %v is outside date range [%v, %v]",
-					snapshot.Timestamp, dateRange.From, dateRange.To)
-			}
+	unlockedAt := time.Now()
+	assignment := func(id, subjectID int64, stage int, unlocked bool) domain.Assignment {
+		a := domain.Assignment{ID: id, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: subjectID, SRSStage: stage}}
+		if unlocked {
+			a.Data.UnlockedAt = &unlockedAt
 		}
-	})
+		return a
+	}
+	assignments := []domain.Assignment{
+		assignment(1, 1, 9, true), // radical burned
+		assignment(2, 2, 5, true), // radical guru
+		assignment(3, 3, 7, true), // kanji master
+		assignment(4, 4, 2, true), // kanji apprentice
+		assignment(5, 5, 0, true), // kanji waiting in lessons
+		// subject 6 has no assignment yet
+		assignment(6, 7, 9, true), // other level
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("failed to upsert assignments: %v", err)
+	}
 
-	t.Run("all historical snapshots are preserved", func(t *testing.T) {
-		// Insert more snapshots to verify preservation
-		baseTime := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	progress, err := store.GetLevelProgress(ctx, 5)
+	if err != nil {
+		t.Fatalf("failed to get level progress: %v", err)
+	}
 
-		for i := 0; i < 10; i++ {
-			stats := domain.Statistics{
-				Object:        "report",
-				URL:           "https://api.wanikani.com/v2/summary",
-				DataUpdatedAt: baseTime.Add(time.Duration(i) * time.Hour),
-				Data: domain.StatisticsData{
-					Lessons: []domain.LessonStatistics{
-						{
-							AvailableAt: baseTime.Add(time.Duration(i) * time.Hour),
-							SubjectIDs:  []int{100 + i},
-						},
-					},
-				},
-			}
+	if progress.Level != 5 {
+		t.Errorf("expected level 5, got %d", progress.Level)
+	}
 
-			timestamp := baseTime.Add(time.Duration(i) * time.Hour)
-			err := store.InsertStatistics(ctx, stats, timestamp)
-			if err != nil {
-				t.Fatalf("failed to insert statistics snapshot: %v", err)
-			}
+	expected := map[string]domain.LevelProgressCounts{
+		"radical": {Total: 2, Passed: 2, InProgress: 0, Locked: 0},
+		"kanji":   {Total: 4, Passed: 1, InProgress: 2, Locked: 1},
+	}
+	if len(progress.SubjectTypes) != len(expected) {
+		t.Errorf("expected %d subject types, got %v", len(expected), progress.SubjectTypes)
+	}
+	for subjectType, want := range expected {
+		if got := progress.SubjectTypes[subjectType]; got != want {
+			t.Errorf("%s: expected %+v, got %+v", subjectType, want, got)
 		}
+	}
 
-		// Get all snapshots (should include previous 5 + new 10 = 15 total)
-		allSnapshots, err := store.GetStatistics(ctx, nil)
-		if err != nil {
-			t.Fatalf("failed to get all statistics: %v", err)
-		}
+	wantTotal := domain.LevelProgressCounts{Total: 6, Passed: 3, InProgress: 2, Locked: 1}
+	if progress.Total != wantTotal {
+		t.Errorf("expected total %+v, got %+v", wantTotal, progress.Total)
+	}
 
-		if len(allSnapshots) != 15 {
-			t.Errorf("expected 15 total snapshots, got %d", len(allSnapshots))
-		}
+	// A level without subjects has empty counts
+	empty, err := store.GetLevelProgress(ctx, 60)
+	if err != nil {
+		t.Fatalf("failed to get level progress: %v", err)
+	}
+	if len(empty.SubjectTypes) != 0 || empty.Total.Total != 0 {
+		t.Errorf("expected no subjects at level 60, got %+v", empty)
+	}
+}
 
-		// Verify snapshots are ordered by timestamp descending
-		for i := 1; i < len(allSnapshots); i++ {
-			if allSnapshots[i].Timestamp.After(allSnapshots[i-1].Timestamp) {
-				t.Errorf("snapshots not ordered correctly: snapshot %d (%v) is after snapshot %d (%v)",
-					i, allSnapshots[i].Timestamp, i-1, allSnapshots[i-1].Timestamp)
-			}
-		}
-	})
+func TestStore_GetAssignmentCounts(t *testing.T) {
+	dbPath := "test_assignment_counts.db"
+	defer os.Remove(dbPath)
 
-	t.Run("latest statistics returns most recent snapshot", func(t *testing.T) {
-		latest, err := store.GetLatestStatistics(ctx)
-		if err != nil {
-			t.Fatalf("failed to get latest statistics: %v", err)
-		}
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
 
-		if latest == nil {
-			t.Fatal("expected latest statistics, got nil")
-		}
+	ctx := context.Background()
 
-		// Get all snapshots to verify latest is actually the most recent
-		allSnapshots, err := store.GetStatistics(ctx, nil)
-		if err != nil {
-			t.Fatalf("failed to get all statistics: %v", err)
-		}
+	counts, err := store.GetAssignmentCounts(ctx)
+	if err != nil {
+		t.Fatalf("failed to count assignments: %v", err)
+	}
+	if len(counts.SubjectTypes) != 0 || counts.Total != (domain.AssignmentStateCounts{}) {
+		t.Errorf("expected no counts in an empty store, got %+v", counts)
+	}
 
-		// The latest should match the first in the list (DESC order)
-		if latest.ID != allSnapshots[0].ID {
-			t.Errorf("latest statistics ID %d doesn't match most recent snapshot ID %d",
-				latest.ID, allSnapshots[0].ID)
-		}
+	var subjects []domain.Subject
+	for id := int64(1); id <= 9; id++ {
+		subjects = append(subjects, domain.Subject{ID: id, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
 
-		if latest.Timestamp.Unix() != allSnapshots[0].Timestamp.Unix() {
-			t.Errorf("latest statistics timestamp %v doesn't match most recent snapshot timestamp %v",
-				latest.Timestamp, allSnapshots[0].Timestamp)
+	unlockedAt := time.Now()
+	assignment := func(id int64, subjectType string, stage int, unlocked bool) domain.Assignment {
+		a := domain.Assignment{ID: id, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: id, SubjectType: subjectType, SRSStage: stage}}
+		if unlocked {
+			a.Data.UnlockedAt = &unlockedAt
 		}
-	})
+		return a
+	}
+	assignments := []domain.Assignment{
+		assignment(1, "radical", 0, false),    // locked
+		assignment(2, "radical", 0, true),     // lesson
+		assignment(3, "radical", 9, true),     // burned
+		assignment(4, "kanji", 0, true),       // lesson
+		assignment(5, "kanji", 1, true),       // in progress
+		assignment(6, "kanji", 8, true),       // in progress
+		assignment(7, "vocabulary", 4, true),  // in progress
+		assignment(8, "vocabulary", 9, true),  // burned
+		assignment(9, "vocabulary", 0, false), // locked
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("failed to upsert assignments: %v", err)
+	}
 
-	t.Run("empty date range returns all snapshots", func(t *testing.T) {
-		allSnapshots, err := store.GetStatistics(ctx, nil)
-		if err != nil {
-			t.Fatalf("failed to get statistics with nil date range: %v", err)
-		}
+	counts, err = store.GetAssignmentCounts(ctx)
+	if err != nil {
+		t.Fatalf("failed to count assignments: %v", err)
+	}
 
-		if len(allSnapshots) == 0 {
-			t.Error("expected snapshots with nil date range, got 0")
+	expected := map[string]domain.AssignmentStateCounts{
+		"radical":    {Total: 3, Locked: 1, Lessons: 1, Started: 1, Burned: 1},
+		"kanji":      {Total: 3, Lessons: 1, Started: 2, InProgress: 2},
+		"vocabulary": {Total: 3, Locked: 1, Started: 2, InProgress: 1, Burned: 1},
+	}
+	if len(counts.SubjectTypes) != len(expected) {
+		t.Errorf("expected %d subject types, got %+v", len(expected), counts.SubjectTypes)
+	}
+	for subjectType, want := range expected {
+		if got := counts.SubjectTypes[subjectType]; got != want {
+			t.Errorf("%s: expected %+v, got %+v", subjectType, want, got)
 		}
-	})
+	}
 
-	t.Run("statistics data integrity is preserved", func(t *testing.T) {
-		// Insert a snapshot with complex data
-		baseTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
-		stats := domain.Statistics{
-			Object:        "report",
-			URL:           "https://api.wanikani.com/v2/summary",
-			DataUpdatedAt: baseTime,
-			Data: domain.StatisticsData{
-				Lessons: []domain.LessonStatistics{
-					{
-						AvailableAt: baseTime,
-						SubjectIDs:  []int{1, 2, 3, 4, 5},
-					},
-					{
-						AvailableAt: baseTime.Add(1 * time.Hour),
-						SubjectIDs:  []int{6, 7, 8},
-					},
-				},
-				Reviews: []domain.ReviewStatistics{
-					{
-						AvailableAt: baseTime,
-						SubjectIDs:  []int{10, 20, 30},
-					},
-				},
-			},
-		}
+	// The totals agree with classifying each assignment in Go
+	var total domain.AssignmentStateCounts
+	for _, a := range assignments {
+		total.Add(a.State())
+	}
+	if counts.Total != total {
+		t.Errorf("expected total %+v, got %+v", total, counts.Total)
+	}
+}
 
-		err := store.InsertStatistics(ctx, stats, baseTime)
-		if err != nil {
-			t.Fatalf("failed to insert complex statistics: %v", err)
-		}
+func TestStore_UpsertAndGetLevelProgressions(t *testing.T) {
+	dbPath := "test_level_progressions.db"
+	defer os.Remove(dbPath)
 
-		// Retrieve and verify data integrity
-		retrieved, err := store.GetStatistics(ctx, &domain.DateRange{
-			From: baseTime.Add(-1 * time.Minute),
-			To:   baseTime.Add(1 * time.Minute),
-		})
-		if err != nil {
-			t.Fatalf("failed to retrieve statistics: %v", err)
-		}
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
 
-		if len(retrieved) != 1 {
-			t.Fatalf("expected 1 snapshot, got %d", len(retrieved))
+	ctx := context.Background()
+
+	unlockedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	progression := func(id int64, level int, updatedAt time.Time, passedAt *time.Time) domain.LevelProgression {
+		return domain.LevelProgression{
+			ID:            id,
+			Object:        "level_progression",
+			URL:           fmt.Sprintf("https://api.wanikani.com/v2/level_progressions/%d", id),
+			DataUpdatedAt: updatedAt,
+			Data:          domain.LevelProgressionData{Level: level, CreatedAt: unlockedAt, UnlockedAt: &unlockedAt, PassedAt: passedAt},
 		}
+	}
 
-		snapshot := retrieved[0]
+	// IDs deliberately out of level order
+	first := []domain.LevelProgression{
+		progression(30, 3, unlockedAt, nil),
+		progression(10, 1, unlockedAt, nil),
+		progression(20, 2, unlockedAt, nil),
+	}
+	if err := store.UpsertLevelProgressions(ctx, first); err != nil {
+		t.Fatalf("failed to upsert level progressions: %v", err)
+	}
 
-		// Verify lessons data
-		if len(snapshot.Statistics.Data.Lessons) != 2 {
-			t.Errorf("expected 2 lesson statistics, got %d", len(snapshot.Statistics.Data.Lessons))
-		}
+	// Upserting again with level 1 passed updates it in place instead of adding a row
+	passedAt := unlockedAt.Add(7 * 24 * time.Hour)
+	second := append([]domain.LevelProgression{progression(10, 1, passedAt, &passedAt)}, first[0], first[2])
+	if err := store.UpsertLevelProgressions(ctx, second); err != nil {
+		t.Fatalf("failed to upsert level progressions again: %v", err)
+	}
 
-		if len(snapshot.Statistics.Data.Lessons[0].SubjectIDs) != 5 {
-			t.Errorf("expected 5 subject IDs in first lesson, got %d",
-				len(snapshot.Statistics.Data.Lessons[0].SubjectIDs))
-		}
+	progressions, err := store.GetLevelProgressions(ctx)
+	if err != nil {
+		t.Fatalf("failed to get level progressions: %v", err)
+	}
+	if len(progressions) != 3 {
+		t.Fatalf("expected 3 level progressions, got %d", len(progressions))
+	}
 
-		// Verify reviews data
-		if len(snapshot.Statistics.Data.Reviews) != 1 {
-			t.Errorf("expected 1 review statistics, got %d", len(snapshot.Statistics.Data.Reviews))
+	for i, expectedLevel := range []int{1, 2, 3} {
+		if progressions[i].Data.Level != expectedLevel {
+			t.Errorf("expected level %d at position %d, got %d", expectedLevel, i, progressions[i].Data.Level)
 		}
+	}
 
-		if len(snapshot.Statistics.Data.Reviews[0].SubjectIDs) != 3 {
-			t.Errorf("expected 3 subject IDs in review, got %d",
-				len(snapshot.Statistics.Data.Reviews[0].SubjectIDs))
-		}
-	})
+	levelOne := progressions[0]
+	if levelOne.ID != 10 || !levelOne.DataUpdatedAt.Equal(passedAt) {
+		t.Errorf("expected level 1 progression 10 updated at %v, got %d updated at %v", passedAt, levelOne.ID, levelOne.DataUpdatedAt)
+	}
+	if levelOne.Data.PassedAt == nil || !levelOne.Data.PassedAt.Equal(passedAt) {
+		t.Errorf("expected level 1 passed at %v, got %v", passedAt, levelOne.Data.PassedAt)
+	}
+	if progressions[1].Data.PassedAt != nil {
+		t.Errorf("expected level 2 not passed, got %v", progressions[1].Data.PassedAt)
+	}
 }
 
-func TestStore_ReferentialIntegrity(t *testing.T) {
-	dbPath := "test_referential.db"
+func TestStore_UpsertAndGetAssignments(t *testing.T) {
+	dbPath := "test_assignments.db"
 	defer os.Remove(dbPath)
 
 	store := setupTestStore(t, dbPath)
@@ -613,144 +844,159 @@ func TestStore_ReferentialIntegrity(t *testing.T) {
 
 	ctx := context.Background()
 
-	t.Run("assignment with non-existent subject fails", func(t *testing.T) {
-		var err error
-		// Try to insert an assignment without a subject (should fail)
-		assignments := []domain.Assignment{
-			{
-				ID:            100,
-				Object:        "assignment",
-				URL:           "https://api.wanikani.com/v2/assignments/100",
-				DataUpdatedAt: time.Now(),
-				Data: domain.AssignmentData{
-					SubjectID:   999, // Non-existent subject
-					SubjectType: "kanji",
-					SRSStage:    3,
-				},
+	// First create a subject (for foreign key constraint)
+	subjects := []domain.Subject{
+		{
+			ID:            1,
+			Object:        "kanji",
+			URL:           "https://api.wanikani.com/v2/subjects/1",
+			DataUpdatedAt: time.Now(),
+			Data: domain.SubjectData{
+				Level:      5,
+				Characters: "一",
 			},
-		}
-
-		err = store.UpsertAssignments(ctx, assignments)
-		if err == nil {
-			t.Error("expected error when inserting assignment with non-existent subject, got nil")
-		}
-	})
+		},
+	}
+	err := store.UpsertSubjects(ctx, subjects)
+	if err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
 
-	t.Run("assignment with valid subject succeeds", func(t *testing.T) {
-		var err error
-		// First create a subject
-		subjects := []domain.Subject{
-			{
-				ID:            1,
-				Object:        "kanji",
-				URL:           "https://api.wanikani.com/v2/subjects/1",
-				DataUpdatedAt: time.Now(),
-				Data: domain.SubjectData{
-					Level:      5,
-					Characters: "一",
-				},
+	// Create test assignments
+	now := time.Now()
+	assignments := []domain.Assignment{
+		{
+			ID:            100,
+			Object:        "assignment",
+			URL:           "https://api.wanikani.com/v2/assignments/100",
+			DataUpdatedAt: now,
+			Data: domain.AssignmentData{
+				SubjectID:   1,
+				SubjectType: "kanji",
+				SRSStage:    3,
+				UnlockedAt:  &now,
 			},
-		}
-		err = store.UpsertSubjects(ctx, subjects)
-		if err != nil {
-			t.Fatalf("failed to upsert subjects: %v", err)
-		}
+		},
+	}
 
-		// Now insert assignment with valid subject
-		assignments := []domain.Assignment{
-			{
-				ID:            100,
-				Object:        "assignment",
-				URL:           "https://api.wanikani.com/v2/assignments/100",
-				DataUpdatedAt: time.Now(),
-				Data: domain.AssignmentData{
-					SubjectID:   1,
-					SubjectType: "kanji",
-					SRSStage:    3,
-				},
-			},
-		}
+	err = store.UpsertAssignments(ctx, assignments)
+	if err != nil {
+		t.Fatalf("failed to upsert assignments: %v", err)
+	}
 
-		err = store.UpsertAssignments(ctx, assignments)
-		if err != nil {
-			t.Errorf("expected no error when inserting assignment with valid subject, got: %v", err)
-		}
-	})
+	// Test get assignments
+	retrieved, err := store.GetAssignments(ctx, domain.AssignmentFilters{})
+	if err != nil {
+		t.Fatalf("failed to get assignments: %v", err)
+	}
 
-	t.Run("review with non-existent assignment fails", func(t *testing.T) {
-		var err error
-		// Try to insert a review without an assignment (should fail)
-		reviews := []domain.Review{
-			{
-				ID:            200,
-				Object:        "review",
-				URL:           "https://api.wanikani.com/v2/reviews/200",
-				DataUpdatedAt: time.Now(),
-				Data: domain.ReviewData{
-					AssignmentID: 999, // Non-existent assignment
-					SubjectID:    1,
-					CreatedAt:    time.Now(),
-				},
-			},
-		}
+	if len(retrieved) != 1 {
+		t.Errorf("expected 1 assignment, got %d", len(retrieved))
+	}
 
-		err = store.UpsertReviews(ctx, reviews)
-		if err == nil {
-			t.Error("expected error when inserting review with non-existent assignment, got nil")
-		}
-	})
+	// Test filter by SRS stage
+	srsStage := 3
+	filtered, err := store.GetAssignments(ctx, domain.AssignmentFilters{SRSStage: &srsStage})
+	if err != nil {
+		t.Fatalf("failed to get filtered assignments: %v", err)
+	}
 
-	t.Run("review with non-existent subject fails", func(t *testing.T) {
-		var err error
-		// Try to insert a review with non-existent subject (should fail)
-		reviews := []domain.Review{
-			{
-				ID:            201,
-				Object:        "review",
-				URL:           "https://api.wanikani.com/v2/reviews/201",
-				DataUpdatedAt: time.Now(),
-				Data: domain.ReviewData{
-					AssignmentID: 100, // Valid assignment
-					SubjectID:    999, // Non-existent subject
-					CreatedAt:    time.Now(),
-				},
-			},
-		}
+	if len(filtered) != 1 {
+		t.Errorf("expected 1 assignment with SRS stage 3, got %d", len(filtered))
+	}
+}
 
-		err = store.UpsertReviews(ctx, reviews)
-		if err == nil {
-			t.Error("expected error when inserting review with non-existent subject, got nil")
+func TestStore_TransactionRollback(t *testing.T) {
+	dbPath := "test_transaction.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// Start a transaction
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+
+	// Insert a subject within the transaction
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO subjects (id, object, url, data_updated_at, data)
+		VALUES (?, ?, ?, ?, ?)
+	`, 1, "kanji", "https://test.com", time.Now().Format(time.RFC3339), `{"level": 1}`)
+	if err != nil {
+		t.Fatalf("failed to insert in transaction: %v", err)
+	}
+
+	// Rollback the transaction
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatalf("failed to rollback transaction: %v", err)
+	}
+
+	// Verify the subject was not persisted
+	subjects, err := store.GetSubjects(ctx, domain.SubjectFilters{})
+	if err != nil {
+		t.Fatalf("failed to get subjects: %v", err)
+	}
+
+	if len(subjects) != 0 {
+		t.Errorf("expected 0 subjects after rollback, got %d", len(subjects))
+	}
+}
+
+func TestStore_WithTxRollsBackOnError(t *testing.T) {
+	dbPath := "test_with_tx.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// Writes made before the callback fails are rolled back
+	sentinel := fmt.Errorf("abort")
+	err := store.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO subjects (id, object, url, data_updated_at, data)
+			VALUES (?, ?, ?, ?, ?)
+		`, 1, "kanji", "https://test.com", time.Now().Format(time.RFC3339), `{"level": 1}`); err != nil {
+			return err
 		}
+		return sentinel
 	})
+	if err != sentinel {
+		t.Fatalf("expected the callback error to be returned unwrapped, got %v", err)
+	}
 
-	t.Run("review with valid assignment and subject succeeds", func(t *testing.T) {
-		var err error
-		// Insert a review with valid references
-		reviews := []domain.Review{
-			{
-				ID:            202,
-				Object:        "review",
-				URL:           "https://api.wanikani.com/v2/reviews/202",
-				DataUpdatedAt: time.Now(),
-				Data: domain.ReviewData{
-					AssignmentID:            100,
-					SubjectID:               1,
-					CreatedAt:               time.Now(),
-					IncorrectMeaningAnswers: 0,
-					IncorrectReadingAnswers: 1,
-				},
-			},
-		}
+	subjects, err := store.GetSubjects(ctx, domain.SubjectFilters{})
+	if err != nil {
+		t.Fatalf("failed to get subjects: %v", err)
+	}
+	if len(subjects) != 0 {
+		t.Errorf("expected 0 subjects after rollback, got %d", len(subjects))
+	}
 
-		err = store.UpsertReviews(ctx, reviews)
-		if err != nil {
-			t.Errorf("expected no error when inserting review with valid references, got: %v", err)
-		}
+	// A failed upsert leaves no partial batch behind
+	err = store.UpsertAssignments(ctx, []domain.Assignment{
+		{ID: 1, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 999}},
 	})
+	if err == nil {
+		t.Fatal("expected upsert with a missing subject to fail")
+	}
+	assignments, err := store.GetAssignments(ctx, domain.AssignmentFilters{})
+	if err != nil {
+		t.Fatalf("failed to get assignments: %v", err)
+	}
+	if len(assignments) != 0 {
+		t.Errorf("expected 0 assignments after failed upsert, got %d", len(assignments))
+	}
 }
 
-func TestStore_AssignmentSnapshots(t *testing.T) {
-	dbPath := "test_assignment_snapshots.db"
+func TestStore_SyncMetadata(t *testing.T) {
+	dbPath := "test_sync.db"
 	defer os.Remove(dbPath)
 
 	store := setupTestStore(t, dbPath)
@@ -758,262 +1004,2680 @@ func TestStore_AssignmentSnapshots(t *testing.T) {
 
 	ctx := context.Background()
 
-	t.Run("upsert and get assignment snapshots", func(t *testing.T) {
-		// Create test snapshots
-		date1 := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
-		snapshots := []domain.AssignmentSnapshot{
-			{
-				Date:        date1,
-				SRSStage:    1,
-				SubjectType: "kanji",
-				Count:       10,
-			},
-			{
-				Date:        date1,
-				SRSStage:    1,
-				SubjectType: "vocabulary",
-				Count:       15,
-			},
-			{
-				Date:        date1,
-				SRSStage:    5,
-				SubjectType: "kanji",
-				Count:       20,
+	// Test getting sync time when none exists
+	syncTime, err := store.GetLastSyncTime(ctx, domain.DataTypeSubjects)
+	if err != nil {
+		t.Fatalf("failed to get last sync time: %v", err)
+	}
+
+	if syncTime != nil {
+		t.Errorf("expected nil sync time, got %v", syncTime)
+	}
+
+	// Set sync time
+	now := time.Now()
+	err = store.SetLastSyncTime(ctx, domain.DataTypeSubjects, now)
+	if err != nil {
+		t.Fatalf("failed to set last sync time: %v", err)
+	}
+
+	// Get sync time
+	syncTime, err = store.GetLastSyncTime(ctx, domain.DataTypeSubjects)
+	if err != nil {
+		t.Fatalf("failed to get last sync time: %v", err)
+	}
+
+	if syncTime == nil {
+		t.Fatal("expected sync time, got nil")
+	}
+
+	// Compare times (allowing for small differences due to formatting)
+	if syncTime.Unix() != now.Unix() {
+		t.Errorf("expected sync time %v, got %v", now, syncTime)
+	}
+
+	// Update sync time
+	later := now.Add(1 * time.Hour)
+	err = store.SetLastSyncTime(ctx, domain.DataTypeSubjects, later)
+	if err != nil {
+		t.Fatalf("failed to update last sync time: %v", err)
+	}
+
+	// Verify update
+	syncTime, err = store.GetLastSyncTime(ctx, domain.DataTypeSubjects)
+	if err != nil {
+		t.Fatalf("failed to get updated sync time: %v", err)
+	}
+
+	if syncTime.Unix() != later.Unix() {
+		t.Errorf("expected updated sync time %v, got %v", later, syncTime)
+	}
+}
+
+func TestStore_Statistics(t *testing.T) {
+	dbPath := "test_statistics.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// Create test statistics
+	stats := domain.Statistics{
+		Object:        "report",
+		URL:           "https://api.wanikani.com/v2/summary",
+		DataUpdatedAt: time.Now(),
+		Data: domain.StatisticsData{
+			Lessons: []domain.LessonStatistics{
+				{
+					AvailableAt: time.Now(),
+					SubjectIDs:  []int64{1, 2, 3},
+				},
 			},
+		},
+	}
+
+	// Insert first snapshot
+	timestamp1 := time.Now().Add(-2 * time.Hour)
+	err := store.InsertStatistics(ctx, stats, timestamp1)
+	if err != nil {
+		t.Fatalf("failed to insert statistics: %v", err)
+	}
+
+	// Insert second snapshot
+	timestamp2 := time.Now().Add(-1 * time.Hour)
+	err = store.InsertStatistics(ctx, stats, timestamp2)
+	if err != nil {
+		t.Fatalf("failed to insert second statistics: %v", err)
+	}
+
+	// Get latest statistics
+	latest, err := store.GetLatestStatistics(ctx)
+	if err != nil {
+		t.Fatalf("failed to get latest statistics: %v", err)
+	}
+
+	if latest == nil {
+		t.Fatal("expected latest statistics, got nil")
+	}
+
+	// Verify it's the most recent one
+	if latest.Timestamp.Unix() != timestamp2.Unix() {
+		t.Errorf("expected timestamp %v, got %v", timestamp2, latest.Timestamp)
+	}
+
+	// Get all statistics
+	allStats, err := store.GetStatistics(ctx, nil, domain.StatisticsOptions{})
+	if err != nil {
+		t.Fatalf("failed to get all statistics: %v", err)
+	}
+
+	if len(allStats) != 2 {
+		t.Errorf("expected 2 statistics snapshots, got %d", len(allStats))
+	}
+}
+
+func TestStore_StatisticsSameSecond(t *testing.T) {
+	dbPath := "test_statistics_same_second.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+	timestamp := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// Two syncs firing within the same RFC3339 second
+	first := domain.Statistics{Object: "report", URL: "first"}
+	second := domain.Statistics{Object: "report", URL: "second"}
+	if err := store.InsertStatistics(ctx, first, timestamp.Add(100*time.Millisecond)); err != nil {
+		t.Fatalf("failed to insert first statistics: %v", err)
+	}
+	if err := store.InsertStatistics(ctx, second, timestamp.Add(900*time.Millisecond)); err != nil {
+		t.Fatalf("failed to insert second statistics: %v", err)
+	}
+
+	snapshots, err := store.GetStatistics(ctx, nil, domain.StatisticsOptions{})
+	if err != nil {
+		t.Fatalf("failed to get statistics: %v", err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("expected same-second inserts to collapse to 1 snapshot, got %d", len(snapshots))
+	}
+
+	for i := 0; i < 3; i++ {
+		latest, err := store.GetLatestStatistics(ctx)
+		if err != nil {
+			t.Fatalf("failed to get latest statistics: %v", err)
+		}
+		if latest == nil {
+			t.Fatal("expected latest statistics, got nil")
+		}
+		if latest.Statistics.URL != "second" {
+			t.Errorf("expected the last insert to win, got URL %q", latest.Statistics.URL)
 		}
+	}
+}
 
-		// Upsert snapshots
-		for _, snapshot := range snapshots {
-			err := store.UpsertAssignmentSnapshot(ctx, snapshot)
+// TestStore_StatisticsHistoricalTracking tests comprehensive historical tracking of statistics
+func TestStore_StatisticsHistoricalTracking(t *testing.T) {
+	dbPath := "test_statistics_historical.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	t.Run("snapshots are stored with timestamps", func(t *testing.T) {
+		// Create multiple statistics snapshots with different timestamps
+		baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		for i := 0; i < 5; i++ {
+			stats := domain.Statistics{
+				Object:        "report",
+				URL:           "https://api.wanikani.com/v2/summary",
+				DataUpdatedAt: baseTime.Add(time.Duration(i) * 24 * time.Hour),
+				Data: domain.StatisticsData{
+					Lessons: []domain.LessonStatistics{
+						{
+							AvailableAt: baseTime.Add(time.Duration(i) * 24 * time.Hour),
+							SubjectIDs:  []int64{int64(i) + 1, int64(i) + 2, int64(i) + 3},
+						},
+					},
+					Reviews: []domain.ReviewStatistics{
+						{
+							AvailableAt: baseTime.Add(time.Duration(i) * 24 * time.Hour),
+							SubjectIDs:  []int64{int64(i) * 10, int64(i)*10 + 1},
+						},
+					},
+				},
+			}
+
+			timestamp := baseTime.Add(time.Duration(i) * 24 * time.Hour)
+			err := store.InsertStatistics(ctx, stats, timestamp)
 			if err != nil {
-				t.Fatalf("failed to upsert snapshot: %v", err)
+				t.Fatalf("failed to insert statistics snapshot %d: %v", i, err)
+			}
+		}
+
+		// Verify all snapshots were stored
+		allSnapshots, err := store.GetStatistics(ctx, nil, domain.StatisticsOptions{})
+		if err != nil {
+			t.Fatalf("failed to get all statistics: %v", err)
+		}
+
+		if len(allSnapshots) != 5 {
+			t.Errorf("expected 5 snapshots, got %d", len(allSnapshots))
+		}
+
+		// Verify each snapshot has the correct timestamp
+		for i, snapshot := range allSnapshots {
+			expectedTime := baseTime.Add(time.Duration(4-i) * 24 * time.Hour) // Reversed order (DESC)
+			if snapshot.Timestamp.Unix() != expectedTime.Unix() {
+				t.Errorf("snapshot %d: expected timestamp %v, got %v", i, expectedTime, snapshot.Timestamp)
+			}
+		}
+	})
+
+	t.Run("date range filtering works correctly", func(t *testing.T) {
+		// Query with date range
+		baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		dateRange := &domain.DateRange{
+			From: baseTime.Add(1 * 24 * time.Hour),
+			To:   baseTime.Add(3 * 24 * time.Hour),
+		}
+
+		filtered, err := store.GetStatistics(ctx, dateRange, domain.StatisticsOptions{})
+		if err != nil {
+			t.Fatalf("failed to get filtered statistics: %v", err)
+		}
+
+		// Should return snapshots from day 1, 2, and 3 (3 snapshots)
+		if len(filtered) != 3 {
+			t.Errorf("expected 3 snapshots in date range, got %d", len(filtered))
+		}
+
+		// Verify all returned snapshots are within the date range
+		for _, snapshot := range filtered {
+			if snapshot.Timestamp.Before(dateRange.From) || snapshot.Timestamp.After(dateRange.To) {
+				t.Errorf("snapshot timestamp %v is outside date range [%v, %v]",
+					snapshot.Timestamp, dateRange.From, dateRange.To)
+			}
+		}
+	})
+
+	t.Run("all historical snapshots are preserved", func(t *testing.T) {
+		// Insert more snapshots to verify preservation
+		baseTime := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+		for i := 0; i < 10; i++ {
+			stats := domain.Statistics{
+				Object:        "report",
+				URL:           "https://api.wanikani.com/v2/summary",
+				DataUpdatedAt: baseTime.Add(time.Duration(i) * time.Hour),
+				Data: domain.StatisticsData{
+					Lessons: []domain.LessonStatistics{
+						{
+							AvailableAt: baseTime.Add(time.Duration(i) * time.Hour),
+							SubjectIDs:  []int64{100 + int64(i)},
+						},
+					},
+				},
+			}
+
+			timestamp := baseTime.Add(time.Duration(i) * time.Hour)
+			err := store.InsertStatistics(ctx, stats, timestamp)
+			if err != nil {
+				t.Fatalf("failed to insert statistics snapshot: %v", err)
 			}
 		}
 
-		// Get all snapshots
-		retrieved, err := store.GetAssignmentSnapshots(ctx, nil)
-		if err != nil {
-			t.Fatalf("failed to get snapshots: %v", err)
-		}
+		// Get all snapshots (should include previous 5 + new 10 = 15 total)
+		allSnapshots, err := store.GetStatistics(ctx, nil, domain.StatisticsOptions{})
+		if err != nil {
+			t.Fatalf("failed to get all statistics: %v", err)
+		}
+
+		if len(allSnapshots) != 15 {
+			t.Errorf("expected 15 total snapshots, got %d", len(allSnapshots))
+		}
+
+		// Verify snapshots are ordered by timestamp descending
+		for i := 1; i < len(allSnapshots); i++ {
+			if allSnapshots[i].Timestamp.After(allSnapshots[i-1].Timestamp) {
+				t.Errorf("snapshots not ordered correctly: snapshot %d (%v) is after snapshot %d (%v)",
+					i, allSnapshots[i].Timestamp, i-1, allSnapshots[i-1].Timestamp)
+			}
+		}
+	})
+
+	t.Run("latest statistics returns most recent snapshot", func(t *testing.T) {
+		latest, err := store.GetLatestStatistics(ctx)
+		if err != nil {
+			t.Fatalf("failed to get latest statistics: %v", err)
+		}
+
+		if latest == nil {
+			t.Fatal("expected latest statistics, got nil")
+		}
+
+		// Get all snapshots to verify latest is actually the most recent
+		allSnapshots, err := store.GetStatistics(ctx, nil, domain.StatisticsOptions{})
+		if err != nil {
+			t.Fatalf("failed to get all statistics: %v", err)
+		}
+
+		// The latest should match the first in the list (DESC order)
+		if latest.ID != allSnapshots[0].ID {
+			t.Errorf("latest statistics ID %d doesn't match most recent snapshot ID %d",
+				latest.ID, allSnapshots[0].ID)
+		}
+
+		if latest.Timestamp.Unix() != allSnapshots[0].Timestamp.Unix() {
+			t.Errorf("latest statistics timestamp %v doesn't match most recent snapshot timestamp %v",
+				latest.Timestamp, allSnapshots[0].Timestamp)
+		}
+	})
+
+	t.Run("empty date range returns all snapshots", func(t *testing.T) {
+		allSnapshots, err := store.GetStatistics(ctx, nil, domain.StatisticsOptions{})
+		if err != nil {
+			t.Fatalf("failed to get statistics with nil date range: %v", err)
+		}
+
+		if len(allSnapshots) == 0 {
+			t.Error("expected snapshots with nil date range, got 0")
+		}
+	})
+
+	t.Run("statistics data integrity is preserved", func(t *testing.T) {
+		// Insert a snapshot with complex data
+		baseTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		stats := domain.Statistics{
+			Object:        "report",
+			URL:           "https://api.wanikani.com/v2/summary",
+			DataUpdatedAt: baseTime,
+			Data: domain.StatisticsData{
+				Lessons: []domain.LessonStatistics{
+					{
+						AvailableAt: baseTime,
+						SubjectIDs:  []int64{1, 2, 3, 4, 5},
+					},
+					{
+						AvailableAt: baseTime.Add(1 * time.Hour),
+						SubjectIDs:  []int64{6, 7, 8},
+					},
+				},
+				Reviews: []domain.ReviewStatistics{
+					{
+						AvailableAt: baseTime,
+						SubjectIDs:  []int64{10, 20, 30},
+					},
+				},
+			},
+		}
+
+		err := store.InsertStatistics(ctx, stats, baseTime)
+		if err != nil {
+			t.Fatalf("failed to insert complex statistics: %v", err)
+		}
+
+		// Retrieve and verify data integrity
+		retrieved, err := store.GetStatistics(ctx, &domain.DateRange{
+			From: baseTime.Add(-1 * time.Minute),
+			To:   baseTime.Add(1 * time.Minute),
+		}, domain.StatisticsOptions{})
+		if err != nil {
+			t.Fatalf("failed to retrieve statistics: %v", err)
+		}
+
+		if len(retrieved) != 1 {
+			t.Fatalf("expected 1 snapshot, got %d", len(retrieved))
+		}
+
+		snapshot := retrieved[0]
+
+		// Verify lessons data
+		if len(snapshot.Statistics.Data.Lessons) != 2 {
+			t.Errorf("expected 2 lesson statistics, got %d", len(snapshot.Statistics.Data.Lessons))
+		}
+
+		if len(snapshot.Statistics.Data.Lessons[0].SubjectIDs) != 5 {
+			t.Errorf("expected 5 subject IDs in first lesson, got %d",
+				len(snapshot.Statistics.Data.Lessons[0].SubjectIDs))
+		}
+
+		// Verify reviews data
+		if len(snapshot.Statistics.Data.Reviews) != 1 {
+			t.Errorf("expected 1 review statistics, got %d", len(snapshot.Statistics.Data.Reviews))
+		}
+
+		if len(snapshot.Statistics.Data.Reviews[0].SubjectIDs) != 3 {
+			t.Errorf("expected 3 subject IDs in review, got %d",
+				len(snapshot.Statistics.Data.Reviews[0].SubjectIDs))
+		}
+	})
+}
+
+func TestStore_ReferentialIntegrity(t *testing.T) {
+	dbPath := "test_referential.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	t.Run("assignment with non-existent subject fails", func(t *testing.T) {
+		var err error
+		// Try to insert an assignment without a subject (should fail)
+		assignments := []domain.Assignment{
+			{
+				ID:            100,
+				Object:        "assignment",
+				URL:           "https://api.wanikani.com/v2/assignments/100",
+				DataUpdatedAt: time.Now(),
+				Data: domain.AssignmentData{
+					SubjectID:   999, // Non-existent subject
+					SubjectType: "kanji",
+					SRSStage:    3,
+				},
+			},
+		}
+
+		err = store.UpsertAssignments(ctx, assignments)
+		if err == nil {
+			t.Error("expected error when inserting assignment with non-existent subject, got nil")
+		}
+	})
+
+	t.Run("assignment with valid subject succeeds", func(t *testing.T) {
+		var err error
+		// First create a subject
+		subjects := []domain.Subject{
+			{
+				ID:            1,
+				Object:        "kanji",
+				URL:           "https://api.wanikani.com/v2/subjects/1",
+				DataUpdatedAt: time.Now(),
+				Data: domain.SubjectData{
+					Level:      5,
+					Characters: "一",
+				},
+			},
+		}
+		err = store.UpsertSubjects(ctx, subjects)
+		if err != nil {
+			t.Fatalf("failed to upsert subjects: %v", err)
+		}
+
+		// Now insert assignment with valid subject
+		assignments := []domain.Assignment{
+			{
+				ID:            100,
+				Object:        "assignment",
+				URL:           "https://api.wanikani.com/v2/assignments/100",
+				DataUpdatedAt: time.Now(),
+				Data: domain.AssignmentData{
+					SubjectID:   1,
+					SubjectType: "kanji",
+					SRSStage:    3,
+				},
+			},
+		}
+
+		err = store.UpsertAssignments(ctx, assignments)
+		if err != nil {
+			t.Errorf("expected no error when inserting assignment with valid subject, got: %v", err)
+		}
+	})
+
+	t.Run("review with non-existent assignment fails", func(t *testing.T) {
+		var err error
+		// Try to insert a review without an assignment (should fail)
+		reviews := []domain.Review{
+			{
+				ID:            200,
+				Object:        "review",
+				URL:           "https://api.wanikani.com/v2/reviews/200",
+				DataUpdatedAt: time.Now(),
+				Data: domain.ReviewData{
+					AssignmentID: 999, // Non-existent assignment
+					SubjectID:    1,
+					CreatedAt:    time.Now(),
+				},
+			},
+		}
+
+		err = store.UpsertReviews(ctx, reviews)
+		if err == nil {
+			t.Error("expected error when inserting review with non-existent assignment, got nil")
+		}
+	})
+
+	t.Run("review with non-existent subject fails", func(t *testing.T) {
+		var err error
+		// Try to insert a review with non-existent subject (should fail)
+		reviews := []domain.Review{
+			{
+				ID:            201,
+				Object:        "review",
+				URL:           "https://api.wanikani.com/v2/reviews/201",
+				DataUpdatedAt: time.Now(),
+				Data: domain.ReviewData{
+					AssignmentID: 100, // Valid assignment
+					SubjectID:    999, // Non-existent subject
+					CreatedAt:    time.Now(),
+				},
+			},
+		}
+
+		err = store.UpsertReviews(ctx, reviews)
+		if err == nil {
+			t.Error("expected error when inserting review with non-existent subject, got nil")
+		}
+	})
+
+	t.Run("review with valid assignment and subject succeeds", func(t *testing.T) {
+		var err error
+		// Insert a review with valid references
+		reviews := []domain.Review{
+			{
+				ID:            202,
+				Object:        "review",
+				URL:           "https://api.wanikani.com/v2/reviews/202",
+				DataUpdatedAt: time.Now(),
+				Data: domain.ReviewData{
+					AssignmentID:            100,
+					SubjectID:               1,
+					CreatedAt:               time.Now(),
+					IncorrectMeaningAnswers: 0,
+					IncorrectReadingAnswers: 1,
+				},
+			},
+		}
+
+		err = store.UpsertReviews(ctx, reviews)
+		if err != nil {
+			t.Errorf("expected no error when inserting review with valid references, got: %v", err)
+		}
+	})
+}
+
+func TestStore_AssignmentSnapshots(t *testing.T) {
+	dbPath := "test_assignment_snapshots.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	t.Run("upsert and get assignment snapshots", func(t *testing.T) {
+		// Create test snapshots
+		date1 := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+		snapshots := []domain.AssignmentSnapshot{
+			{
+				Date:        date1,
+				SRSStage:    1,
+				SubjectType: "kanji",
+				Count:       10,
+			},
+			{
+				Date:        date1,
+				SRSStage:    1,
+				SubjectType: "vocabulary",
+				Count:       15,
+			},
+			{
+				Date:        date1,
+				SRSStage:    5,
+				SubjectType: "kanji",
+				Count:       20,
+			},
+		}
+
+		// Upsert snapshots
+		for _, snapshot := range snapshots {
+			err := store.UpsertAssignmentSnapshot(ctx, snapshot)
+			if err != nil {
+				t.Fatalf("failed to upsert snapshot: %v", err)
+			}
+		}
+
+		// Get all snapshots
+		retrieved, err := store.GetAssignmentSnapshots(ctx, nil)
+		if err != nil {
+			t.Fatalf("failed to get snapshots: %v", err)
+		}
+
+		if len(retrieved) != 3 {
+			t.Errorf("expected 3 snapshots, got %d", len(retrieved))
+		}
+
+		// Verify data
+		if retrieved[0].Count != 10 {
+			t.Errorf("expected count 10, got %d", retrieved[0].Count)
+		}
+	})
+
+	t.Run("upsert idempotence", func(t *testing.T) {
+		// Upsert the same snapshot twice with different counts
+		date := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
+		snapshot := domain.AssignmentSnapshot{
+			Date:        date,
+			SRSStage:    2,
+			SubjectType: "radical",
+			Count:       5,
+		}
+
+		err := store.UpsertAssignmentSnapshot(ctx, snapshot)
+		if err != nil {
+			t.Fatalf("failed to upsert snapshot: %v", err)
+		}
+
+		// Update with new count
+		snapshot.Count = 8
+		err = store.UpsertAssignmentSnapshot(ctx, snapshot)
+		if err != nil {
+			t.Fatalf("failed to update snapshot: %v", err)
+		}
+
+		// Verify only one record exists with updated count
+		dateRange := &domain.DateRange{
+			From: date,
+			To:   date,
+		}
+		retrieved, err := store.GetAssignmentSnapshots(ctx, dateRange)
+		if err != nil {
+			t.Fatalf("failed to get snapshots: %v", err)
+		}
+
+		count := 0
+		for _, s := range retrieved {
+			if s.SRSStage == 2 && s.SubjectType == "radical" {
+				count++
+				if s.Count != 8 {
+					t.Errorf("expected count 8, got %d", s.Count)
+				}
+			}
+		}
+
+		if count != 1 {
+			t.Errorf("expected 1 snapshot with SRS stage 2 and type radical, got %d", count)
+		}
+	})
+
+	t.Run("date range filtering", func(t *testing.T) {
+		// Create snapshots for multiple dates
+		date1 := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+		date2 := time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)
+		date3 := time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)
+
+		snapshots := []domain.AssignmentSnapshot{
+			{Date: date1, SRSStage: 1, SubjectType: "kanji", Count: 10},
+			{Date: date2, SRSStage: 1, SubjectType: "kanji", Count: 12},
+			{Date: date3, SRSStage: 1, SubjectType: "kanji", Count: 15},
+		}
+
+		for _, snapshot := range snapshots {
+			err := store.UpsertAssignmentSnapshot(ctx, snapshot)
+			if err != nil {
+				t.Fatalf("failed to upsert snapshot: %v", err)
+			}
+		}
+
+		// Query with date range
+		dateRange := &domain.DateRange{
+			From: date1,
+			To:   date2,
+		}
+
+		filtered, err := store.GetAssignmentSnapshots(ctx, dateRange)
+		if err != nil {
+			t.Fatalf("failed to get filtered snapshots: %v", err)
+		}
+
+		// Count snapshots within the date range
+		count := 0
+		for _, s := range filtered {
+			if !s.Date.Before(date1) && !s.Date.After(date2) {
+				count++
+			}
+		}
+
+		if count < 2 {
+			t.Errorf("expected at least 2 snapshots in date range, got %d", count)
+		}
+	})
+
+	t.Run("calculate assignment snapshot", func(t *testing.T) {
+		// First create subjects
+		subjects := []domain.Subject{
+			{
+				ID:            1,
+				Object:        "kanji",
+				URL:           "https://api.wanikani.com/v2/subjects/1",
+				DataUpdatedAt: time.Now(),
+				Data:          domain.SubjectData{Level: 5, Characters: "一"},
+			},
+			{
+				ID:            2,
+				Object:        "vocabulary",
+				URL:           "https://api.wanikani.com/v2/subjects/2",
+				DataUpdatedAt: time.Now(),
+				Data:          domain.SubjectData{Level: 5, Characters: "一つ"},
+			},
+			{
+				ID:            3,
+				Object:        "radical",
+				URL:           "https://api.wanikani.com/v2/subjects/3",
+				DataUpdatedAt: time.Now(),
+				Data:          domain.SubjectData{Level: 1, Characters: "丨"},
+			},
+		}
+		err := store.UpsertSubjects(ctx, subjects)
+		if err != nil {
+			t.Fatalf("failed to upsert subjects: %v", err)
+		}
+
+		// Create assignments with various SRS stages
+		now := time.Now()
+		assignments := []domain.Assignment{
+			{
+				ID:            100,
+				Object:        "assignment",
+				URL:           "https://api.wanikani.com/v2/assignments/100",
+				DataUpdatedAt: now,
+				Data: domain.AssignmentData{
+					SubjectID:   1,
+					SubjectType: "kanji",
+					SRSStage:    1, // Apprentice
+					StartedAt:   &now,
+				},
+			},
+			{
+				ID:            101,
+				Object:        "assignment",
+				URL:           "https://api.wanikani.com/v2/assignments/101",
+				DataUpdatedAt: now,
+				Data: domain.AssignmentData{
+					SubjectID:   2,
+					SubjectType: "vocabulary",
+					SRSStage:    5, // Guru
+					StartedAt:   &now,
+				},
+			},
+			{
+				ID:            102,
+				Object:        "assignment",
+				URL:           "https://api.wanikani.com/v2/assignments/102",
+				DataUpdatedAt: now,
+				Data: domain.AssignmentData{
+					SubjectID:   3,
+					SubjectType: "radical",
+					SRSStage:    0, // Unstarted - should be excluded
+					StartedAt:   nil,
+				},
+			},
+		}
+
+		err = store.UpsertAssignments(ctx, assignments)
+		if err != nil {
+			t.Fatalf("failed to upsert assignments: %v", err)
+		}
+
+		// Calculate snapshot
+		date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		calculated, err := store.CalculateAssignmentSnapshot(ctx, date)
+		if err != nil {
+			t.Fatalf("failed to calculate snapshot: %v", err)
+		}
+
+		// Verify results
+		if len(calculated) == 0 {
+			t.Fatal("expected calculated snapshots, got none")
+		}
+
+		// Verify SRS stage 0 is excluded
+		for _, snapshot := range calculated {
+			if snapshot.SRSStage == 0 {
+				t.Error("SRS stage 0 should be excluded from snapshot")
+			}
+		}
+
+		// Verify we have snapshots for SRS stages 1 and 5
+		foundStage1 := false
+		foundStage5 := false
+		for _, snapshot := range calculated {
+			if snapshot.SRSStage == 1 && snapshot.SubjectType == "kanji" {
+				foundStage1 = true
+				if snapshot.Count != 1 {
+					t.Errorf("expected count 1 for stage 1 kanji, got %d", snapshot.Count)
+				}
+			}
+			if snapshot.SRSStage == 5 && snapshot.SubjectType == "vocabulary" {
+				foundStage5 = true
+				if snapshot.Count != 1 {
+					t.Errorf("expected count 1 for stage 5 vocabulary, got %d", snapshot.Count)
+				}
+			}
+		}
+
+		if !foundStage1 {
+			t.Error("expected snapshot for SRS stage 1 kanji")
+		}
+		if !foundStage5 {
+			t.Error("expected snapshot for SRS stage 5 vocabulary")
+		}
+	})
+}
+
+func TestStore_RateLimitState(t *testing.T) {
+	dbPath := "test_rate_limit_state.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// No state persisted yet
+	info, err := store.LoadRateLimitState(ctx)
+	if err != nil {
+		t.Fatalf("failed to load rate limit state: %v", err)
+	}
+	if info != nil {
+		t.Errorf("expected nil rate limit state, got %+v", info)
+	}
+
+	// Save and reload
+	resetAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	if err := store.SaveRateLimitState(ctx, domain.RateLimitInfo{Remaining: 3, ResetAt: resetAt}); err != nil {
+		t.Fatalf("failed to save rate limit state: %v", err)
+	}
+
+	// Overwrite to verify the single-row upsert
+	if err := store.SaveRateLimitState(ctx, domain.RateLimitInfo{Remaining: 1, ResetAt: resetAt}); err != nil {
+		t.Fatalf("failed to update rate limit state: %v", err)
+	}
+
+	info, err = store.LoadRateLimitState(ctx)
+	if err != nil {
+		t.Fatalf("failed to load rate limit state: %v", err)
+	}
+	if info == nil {
+		t.Fatal("expected rate limit state, got nil")
+	}
+	if info.Remaining != 1 {
+		t.Errorf("expected remaining 1, got %d", info.Remaining)
+	}
+	if !info.ResetAt.Equal(resetAt) {
+		t.Errorf("expected reset at %v, got %v", resetAt, info.ResetAt)
+	}
+}
+
+func TestStore_StatisticsLimitAndOrder(t *testing.T) {
+	dbPath := "test_statistics_limit_order.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		stats := domain.Statistics{Object: "report", DataUpdatedAt: baseTime}
+		if err := store.InsertStatistics(ctx, stats, baseTime.Add(time.Duration(i)*24*time.Hour)); err != nil {
+			t.Fatalf("failed to insert statistics snapshot %d: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		opts     domain.StatisticsOptions
+		expected []int // day offsets from baseTime, in result order
+	}{
+		{name: "default is newest first", opts: domain.StatisticsOptions{}, expected: []int{4, 3, 2, 1, 0}},
+		{name: "ascending", opts: domain.StatisticsOptions{Order: domain.SortAsc}, expected: []int{0, 1, 2, 3, 4}},
+		{name: "limit keeps most recent", opts: domain.StatisticsOptions{Limit: 2}, expected: []int{4, 3}},
+		{name: "limit ascending keeps most recent", opts: domain.StatisticsOptions{Limit: 3, Order: domain.SortAsc}, expected: []int{2, 3, 4}},
+		{name: "limit larger than result", opts: domain.StatisticsOptions{Limit: 10, Order: domain.SortDesc}, expected: []int{4, 3, 2, 1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshots, err := store.GetStatistics(ctx, nil, tt.opts)
+			if err != nil {
+				t.Fatalf("failed to get statistics: %v", err)
+			}
+
+			if len(snapshots) != len(tt.expected) {
+				t.Fatalf("expected %d snapshots, got %d", len(tt.expected), len(snapshots))
+			}
+
+			for i, day := range tt.expected {
+				expectedTime := baseTime.Add(time.Duration(day) * 24 * time.Hour)
+				if !snapshots[i].Timestamp.Equal(expectedTime) {
+					t.Errorf("snapshot %d: expected timestamp %v, got %v", i, expectedTime, snapshots[i].Timestamp)
+				}
+			}
+		})
+	}
+}
+
+func TestStore_GetReviewStreak(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+
+	// day returns a review timestamp at noon UTC the given number of days before now
+	day := func(daysAgo int) time.Time {
+		return time.Date(2024, 3, 10-daysAgo, 12, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name            string
+		location        *time.Location
+		reviewTimes     []time.Time
+		expectedCurrent int
+		expectedLongest int
+		expectedLastDay string
+	}{
+		{
+			name: "empty history",
+		},
+		{
+			name:            "contiguous days including today",
+			reviewTimes:     []time.Time{day(0), day(1), day(1), day(2)},
+			expectedCurrent: 3,
+			expectedLongest: 3,
+			expectedLastDay: "2024-03-10",
+		},
+		{
+			name:            "no reviews yet today keeps streak",
+			reviewTimes:     []time.Time{day(1), day(2)},
+			expectedCurrent: 2,
+			expectedLongest: 2,
+			expectedLastDay: "2024-03-09",
+		},
+		{
+			name:            "gap resets current streak",
+			reviewTimes:     []time.Time{day(0), day(2), day(3), day(4), day(5)},
+			expectedCurrent: 1,
+			expectedLongest: 4,
+			expectedLastDay: "2024-03-10",
+		},
+		{
+			name:            "stale history has no current streak",
+			reviewTimes:     []time.Time{day(5), day(6)},
+			expectedCurrent: 0,
+			expectedLongest: 2,
+			expectedLastDay: "2024-03-05",
+		},
+		{
+			// 23:30 UTC on the 8th is already the 9th in Tokyo, bridging the gap to the 10th
+			name:            "days are bucketed in the configured timezone",
+			location:        time.FixedZone("JST", 9*60*60),
+			reviewTimes:     []time.Time{time.Date(2024, 3, 8, 23, 30, 0, 0, time.UTC), day(0)},
+			expectedCurrent: 2,
+			expectedLongest: 2,
+			expectedLastDay: "2024-03-10",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbPath := fmt.Sprintf("test_review_streak_%d.db", i)
+			defer os.Remove(dbPath)
+
+			store := setupTestStore(t, dbPath)
+			defer store.Close()
+
+			if tt.location != nil {
+				WithLocation(tt.location)(store)
+			}
+			store.now = func() time.Time { return now }
+
+			seedReviewsAt(t, store, tt.reviewTimes)
+
+			current, longest, lastDay, err := store.GetReviewStreak(ctx)
+			if err != nil {
+				t.Fatalf("failed to get review streak: %v", err)
+			}
+
+			if current != tt.expectedCurrent {
+				t.Errorf("expected current streak %d, got %d", tt.expectedCurrent, current)
+			}
+			if longest != tt.expectedLongest {
+				t.Errorf("expected longest streak %d, got %d", tt.expectedLongest, longest)
+			}
+
+			if tt.expectedLastDay == "" {
+				if !lastDay.IsZero() {
+					t.Errorf("expected zero last day, got %v", lastDay)
+				}
+			} else if got := lastDay.Format("2006-01-02"); got != tt.expectedLastDay {
+				t.Errorf("expected last day %s, got %s", tt.expectedLastDay, got)
+			}
+		})
+	}
+}
+
+// seedReviewsAt inserts one subject and assignment plus a review created at each of the given times
+func TestStore_PruneStatistics(t *testing.T) {
+	dbPath := "test_prune_statistics.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Three snapshots per day (06:00, 12:00, 18:00) over three days
+	var timestamps []time.Time
+	for day := 0; day < 3; day++ {
+		for _, hour := range []int{6, 12, 18} {
+			timestamps = append(timestamps, baseTime.Add(time.Duration(day*24+hour)*time.Hour))
+		}
+	}
+	for _, ts := range timestamps {
+		if err := store.InsertStatistics(ctx, domain.Statistics{Object: "report"}, ts); err != nil {
+			t.Fatalf("failed to insert statistics snapshot: %v", err)
+		}
+	}
+
+	deleted, err := store.PruneStatistics(ctx, 2)
+	if err != nil {
+		t.Fatalf("failed to prune statistics: %v", err)
+	}
+	if deleted != 5 {
+		t.Errorf("expected 5 snapshots deleted, got %d", deleted)
+	}
+
+	snapshots, err := store.GetStatistics(ctx, nil, domain.StatisticsOptions{})
+	if err != nil {
+		t.Fatalf("failed to get statistics: %v", err)
+	}
+
+	// Two most recent are kept, older days collapse to their latest snapshot
+	expected := []time.Time{timestamps[8], timestamps[7], timestamps[5], timestamps[2]}
+	if len(snapshots) != len(expected) {
+		t.Fatalf("expected %d snapshots after pruning, got %d", len(expected), len(snapshots))
+	}
+	for i, ts := range expected {
+		if !snapshots[i].Timestamp.Equal(ts) {
+			t.Errorf("snapshot %d: expected timestamp %v, got %v", i, ts, snapshots[i].Timestamp)
+		}
+	}
+
+	// Pruning again is a no-op
+	deleted, err = store.PruneStatistics(ctx, 2)
+	if err != nil {
+		t.Fatalf("failed to prune statistics: %v", err)
+	}
+	if deleted != 0 {
+		t.Errorf("expected second prune to delete nothing, got %d", deleted)
+	}
+}
+
+func seedReviewsAt(t *testing.T, store *Store, createdAt []time.Time) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := store.UpsertSubjects(ctx, []domain.Subject{{
+		ID:            1,
+		Object:        "kanji",
+		URL:           "https://api.wanikani.com/v2/subjects/1",
+		DataUpdatedAt: time.Now(),
+		Data:          domain.SubjectData{Level: 1, Characters: "一"},
+	}}); err != nil {
+		t.Fatalf("failed to upsert subject: %v", err)
+	}
+
+	if err := store.UpsertAssignments(ctx, []domain.Assignment{{
+		ID:            1,
+		Object:        "assignment",
+		URL:           "https://api.wanikani.com/v2/assignments/1",
+		DataUpdatedAt: time.Now(),
+		Data:          domain.AssignmentData{SubjectID: 1, SubjectType: "kanji", SRSStage: 1},
+	}}); err != nil {
+		t.Fatalf("failed to upsert assignment: %v", err)
+	}
+
+	reviews := make([]domain.Review, 0, len(createdAt))
+	for i, ts := range createdAt {
+		reviews = append(reviews, domain.Review{
+			ID:            int64(i + 1),
+			Object:        "review",
+			URL:           fmt.Sprintf("https://api.wanikani.com/v2/reviews/%d", i+1),
+			DataUpdatedAt: ts,
+			Data:          domain.ReviewData{AssignmentID: 1, SubjectID: 1, CreatedAt: ts},
+		})
+	}
+
+	if err := store.UpsertReviews(ctx, reviews); err != nil {
+		t.Fatalf("failed to upsert reviews: %v", err)
+	}
+}
+
+// seedBackupData populates every exported table with a little data
+func seedBackupData(t *testing.T, store *Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	updatedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	subjects := []domain.Subject{
+		{ID: 1, Object: "radical", URL: "https://api.wanikani.com/v2/subjects/1", DataUpdatedAt: updatedAt, Data: domain.SubjectData{Level: 1, Characters: "一"}},
+		{ID: 2, Object: "kanji", URL: "https://api.wanikani.com/v2/subjects/2", DataUpdatedAt: updatedAt, Data: domain.SubjectData{Level: 1, Characters: "人"}},
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
+
+	progressions := []domain.LevelProgression{
+		{ID: 50, Object: "level_progression", DataUpdatedAt: updatedAt, Data: domain.LevelProgressionData{Level: 1, CreatedAt: updatedAt, UnlockedAt: &updatedAt}},
+	}
+	if err := store.UpsertLevelProgressions(ctx, progressions); err != nil {
+		t.Fatalf("failed to upsert level progressions: %v", err)
+	}
+
+	assignments := []domain.Assignment{
+		{ID: 10, Object: "assignment", DataUpdatedAt: updatedAt, Data: domain.AssignmentData{SubjectID: 1, SRSStage: 5}},
+		{ID: 11, Object: "assignment", DataUpdatedAt: updatedAt, Data: domain.AssignmentData{SubjectID: 2, SRSStage: 1}},
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("failed to upsert assignments: %v", err)
+	}
+
+	reviews := []domain.Review{
+		{ID: 100, Object: "review", DataUpdatedAt: updatedAt, Data: domain.ReviewData{AssignmentID: 10, SubjectID: 1, CreatedAt: updatedAt, IncorrectMeaningAnswers: 1}},
+	}
+	if err := store.UpsertReviews(ctx, reviews); err != nil {
+		t.Fatalf("failed to upsert reviews: %v", err)
+	}
+
+	stats := domain.Statistics{Object: "report", DataUpdatedAt: updatedAt, Data: domain.StatisticsData{Lessons: []domain.LessonStatistics{}}}
+	if err := store.InsertStatistics(ctx, stats, updatedAt); err != nil {
+		t.Fatalf("failed to insert statistics: %v", err)
+	}
+
+	snapshot := domain.AssignmentSnapshot{Date: updatedAt, SRSStage: 5, SubjectType: "radical", Count: 1}
+	if err := store.UpsertAssignmentSnapshot(ctx, snapshot); err != nil {
+		t.Fatalf("failed to upsert assignment snapshot: %v", err)
+	}
+
+	if err := store.SetLastSyncTime(ctx, domain.DataTypeSubjects, updatedAt); err != nil {
+		t.Fatalf("failed to set sync time: %v", err)
+	}
+}
+
+func TestStore_ExportImportRoundTrip(t *testing.T) {
+	sourcePath := "test_export_source.db"
+	targetPath := "test_export_target.db"
+	defer os.Remove(sourcePath)
+	defer os.Remove(targetPath)
+
+	exportedAt := func() time.Time { return time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC) }
+
+	source := setupTestStore(t, sourcePath)
+	defer source.Close()
+	source.now = exportedAt
+	seedBackupData(t, source)
+
+	ctx := context.Background()
+
+	var exported bytes.Buffer
+	if err := source.Export(ctx, &exported); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	target := setupTestStore(t, targetPath)
+	defer target.Close()
+	target.now = exportedAt
+
+	counts, err := target.Import(ctx, bytes.NewReader(exported.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+
+	expectedCounts := map[string]int{
+		"subjects":             2,
+		"level_progressions":   1,
+		"assignments":          2,
+		"reviews":              1,
+		"statistics_snapshots": 1,
+		"assignment_snapshots": 1,
+		"sync_metadata":        1,
+	}
+	for table, expected := range expectedCounts {
+		if counts[table] != expected {
+			t.Errorf("expected %d %s imported, got %d", expected, table, counts[table])
+		}
+	}
+
+	var reexported bytes.Buffer
+	if err := target.Export(ctx, &reexported); err != nil {
+		t.Fatalf("failed to re-export: %v", err)
+	}
+	if exported.String() != reexported.String() {
+		t.Errorf("re-exported document differs from original\noriginal: %s\nre-exported: %s", exported.String(), reexported.String())
+	}
+
+	reviews, err := target.GetReviews(ctx, domain.ReviewFilters{})
+	if err != nil {
+		t.Fatalf("failed to get reviews: %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].Data.IncorrectMeaningAnswers != 1 {
+		t.Errorf("expected imported review to be readable, got %+v", reviews)
+	}
+
+	// The level column isn't part of the backup and must be derived on import
+	level := 1
+	subjects, err := target.GetSubjects(ctx, domain.SubjectFilters{Level: &level})
+	if err != nil {
+		t.Fatalf("failed to get subjects by level: %v", err)
+	}
+	if len(subjects) != 2 {
+		t.Errorf("expected 2 imported level 1 subjects, got %d", len(subjects))
+	}
+}
+
+func TestStore_ImportInvalidDocumentKeepsExistingData(t *testing.T) {
+	dbPath := "test_import_invalid.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+	seedBackupData(t, store)
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"not json", `not a backup`},
+		{"truncated", `{"version":1,"subjects":[{"id":1`},
+		{"missing version", `{"subjects":[]}`},
+		{"unsupported version", `{"version":99}`},
+		{"unknown section", `{"version":1,"widgets":[]}`},
+		{"missing column", `{"version":1,"subjects":[{"id":1,"object":"kanji"}]}`},
+		{"dangling reference", `{"version":1,"assignments":[{"id":1,"object":"assignment","url":"","data_updated_at":"","subject_id":999,"data":{}}]}`},
+		{"duplicate key", `{"version":1,"sync_metadata":[{"data_type":"subjects","last_sync_time":""},{"data_type":"subjects","last_sync_time":""}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Import(ctx, strings.NewReader(tt.doc))
+			if !errors.Is(err, domain.ErrInvalidBackup) {
+				t.Fatalf("expected ErrInvalidBackup, got %v", err)
+			}
+
+			subjects, err := store.GetSubjects(ctx, domain.SubjectFilters{})
+			if err != nil {
+				t.Fatalf("failed to get subjects: %v", err)
+			}
+			if len(subjects) != 2 {
+				t.Errorf("expected existing 2 subjects to survive failed import, got %d", len(subjects))
+			}
+		})
+	}
+}
+
+func TestStore_CountRecords(t *testing.T) {
+	dbPath := "test_count_records.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	if count, err := store.CountSubjects(ctx); err != nil || count != 0 {
+		t.Fatalf("expected 0 subjects in empty store, got %d (err: %v)", count, err)
+	}
+
+	seedBackupData(t, store)
+
+	counts := []struct {
+		name     string
+		count    func(context.Context) (int, error)
+		expected int
+	}{
+		{"subjects", store.CountSubjects, 2},
+		{"assignments", store.CountAssignments, 2},
+		{"reviews", store.CountReviews, 1},
+		{"assignment snapshots", store.CountAssignmentSnapshots, 1},
+	}
+	for _, c := range counts {
+		got, err := c.count(ctx)
+		if err != nil {
+			t.Fatalf("failed to count %s: %v", c.name, err)
+		}
+		if got != c.expected {
+			t.Errorf("expected %d %s, got %d", c.expected, c.name, got)
+		}
+	}
+}
+
+func TestStore_CheckIntegrity(t *testing.T) {
+	dbPath := "test_integrity.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+	seedBackupData(t, store)
+
+	ctx := context.Background()
+
+	// A connection without the foreign_keys pragma, as a buggy code path might use
+	raw, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open raw connection: %v", err)
+	}
+	defer raw.Close()
+
+	tx, err := raw.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin raw transaction: %v", err)
+	}
+	orphans := []string{
+		// Assignment for a subject that does not exist
+		`INSERT INTO assignments (id, object, url, data_updated_at, subject_id, data) VALUES (20, 'assignment', '', '', 999, '{"subject_id":999}')`,
+		// Review for an assignment that does not exist
+		`INSERT INTO reviews (id, object, url, data_updated_at, assignment_id, subject_id, data) VALUES (200, 'review', '', '', 998, 1, '{}')`,
+		// Review of the orphaned assignment
+		`INSERT INTO reviews (id, object, url, data_updated_at, assignment_id, subject_id, data) VALUES (201, 'review', '', '', 20, 999, '{}')`,
+	}
+	for _, stmt := range orphans {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			t.Fatalf("failed to insert orphan: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit orphans: %v", err)
+	}
+
+	report, err := store.CheckIntegrity(ctx, false)
+	if err != nil {
+		t.Fatalf("failed to check integrity: %v", err)
+	}
+	if report.Repaired {
+		t.Error("expected check without repair to leave data alone")
+	}
+	if report.OrphanedAssignments.Count != 1 || len(report.OrphanedAssignments.SampleIDs) != 1 || report.OrphanedAssignments.SampleIDs[0] != 20 {
+		t.Errorf("expected orphaned assignment 20, got %+v", report.OrphanedAssignments)
+	}
+	if report.OrphanedReviews.Count != 2 || len(report.OrphanedReviews.SampleIDs) != 2 ||
+		report.OrphanedReviews.SampleIDs[0] != 200 || report.OrphanedReviews.SampleIDs[1] != 201 {
+		t.Errorf("expected orphaned reviews 200 and 201, got %+v", report.OrphanedReviews)
+	}
+
+	report, err = store.CheckIntegrity(ctx, true)
+	if err != nil {
+		t.Fatalf("failed to repair: %v", err)
+	}
+	if !report.Repaired || report.OrphanedAssignments.Count != 1 || report.OrphanedReviews.Count != 2 {
+		t.Errorf("expected repair to report the orphans it removed, got %+v", report)
+	}
+
+	report, err = store.CheckIntegrity(ctx, false)
+	if err != nil {
+		t.Fatalf("failed to re-check integrity: %v", err)
+	}
+	if report.OrphanedAssignments.Count != 0 || report.OrphanedReviews.Count != 0 {
+		t.Errorf("expected no orphans after repair, got %+v", report)
+	}
+
+	// Valid rows are untouched
+	if count, _ := store.CountAssignments(ctx); count != 2 {
+		t.Errorf("expected 2 assignments to remain, got %d", count)
+	}
+	if count, _ := store.CountReviews(ctx); count != 1 {
+		t.Errorf("expected 1 review to remain, got %d", count)
+	}
+}
+
+func TestStore_GetSubjectsLimit(t *testing.T) {
+	dbPath := "test_subjects_limit.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	var subjects []domain.Subject
+	for id := int64(5); id >= 1; id-- {
+		meaning := "Other"
+		if id%2 == 1 {
+			meaning = "Match"
+		}
+		subjects = append(subjects, domain.Subject{
+			ID: id, Object: "kanji", URL: fmt.Sprintf("https://api.wanikani.com/v2/subjects/%d", id), DataUpdatedAt: time.Now(),
+			Data: domain.SubjectData{Level: 1, Meanings: []domain.Meaning{{Meaning: meaning, Primary: true}}},
+		})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
+
+	limited, err := store.GetSubjects(ctx, domain.SubjectFilters{Limit: 2})
+	if err != nil {
+		t.Fatalf("failed to get subjects: %v", err)
+	}
+	if len(limited) != 2 || limited[0].ID != 1 || limited[1].ID != 2 {
+		t.Errorf("expected the first 2 subjects by ID, got %+v", limited)
+	}
+
+	// The limit applies after the meaning filter
+	matched, err := store.GetSubjects(ctx, domain.SubjectFilters{Meaning: "match", Limit: 2})
+	if err != nil {
+		t.Fatalf("failed to get subjects by meaning: %v", err)
+	}
+	if len(matched) != 2 || matched[0].ID != 1 || matched[1].ID != 3 {
+		t.Errorf("expected subjects 1 and 3, got %+v", matched)
+	}
+}
+
+func TestStore_ConnectionPragmas(t *testing.T) {
+	dbPath := "test_pragmas.db"
+	defer os.Remove(dbPath)
+
+	setupTestStore(t, dbPath).Close()
+
+	store, err := New(dbPath, WithCacheSize(4096), WithMmapSize(1<<20))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// Hold two connections at once so both come from the pool's connect path
+	conns := make([]*sql.Conn, 2)
+	for i := range conns {
+		conn, err := store.db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("failed to get connection: %v", err)
+		}
+		defer conn.Close()
+		conns[i] = conn
+	}
+
+	for i, conn := range conns {
+		var cacheSize, mmapSize, foreignKeys int64
+		if err := conn.QueryRowContext(ctx, "PRAGMA cache_size").Scan(&cacheSize); err != nil {
+			t.Fatalf("failed to read cache_size: %v", err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA mmap_size").Scan(&mmapSize); err != nil {
+			t.Fatalf("failed to read mmap_size: %v", err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+			t.Fatalf("failed to read foreign_keys: %v", err)
+		}
+
+		if cacheSize != -4096 {
+			t.Errorf("connection %d: expected cache_size -4096, got %d", i, cacheSize)
+		}
+		if mmapSize != 1<<20 {
+			t.Errorf("connection %d: expected mmap_size %d, got %d", i, 1<<20, mmapSize)
+		}
+		if foreignKeys != 1 {
+			t.Errorf("connection %d: expected foreign keys enabled, got %d", i, foreignKeys)
+		}
+	}
+}
+
+func TestStore_MmapSkippedForMemoryDatabase(t *testing.T) {
+	// In-memory databases have no file to map; requesting mmap must not fail verification
+	store, err := New(":memory:", WithMmapSize(1<<20))
+	if err != nil {
+		t.Fatalf("failed to create in-memory store: %v", err)
+	}
+	store.Close()
+}
+
+// columnType returns the SQLite storage class of a row's data column
+func columnType(t *testing.T, store *Store, table string, id int) string {
+	t.Helper()
+	var typ string
+	if err := store.db.QueryRow(`SELECT typeof(data) FROM `+table+` WHERE id = ?`, id).Scan(&typ); err != nil {
+		t.Fatalf("failed to read %s %d storage type: %v", table, id, err)
+	}
+	return typ
+}
+
+func TestStore_CompressedDataRoundTrip(t *testing.T) {
+	dbPath := "test_compressed.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+	store.compressData = true
+	seedBackupData(t, store)
+
+	ctx := context.Background()
+
+	for table, id := range map[string]int{"subjects": 1, "assignments": 10, "reviews": 100} {
+		if typ := columnType(t, store, table, id); typ != "blob" {
+			t.Errorf("expected compressed %s data stored as blob, got %s", table, typ)
+		}
+	}
+
+	level, stage := 1, 5
+	subjects, err := store.GetSubjects(ctx, domain.SubjectFilters{Level: &level})
+	if err != nil {
+		t.Fatalf("failed to get subjects: %v", err)
+	}
+	if len(subjects) != 2 || subjects[1].Data.Characters != "人" {
+		t.Errorf("expected both level 1 subjects with data, got %+v", subjects)
+	}
+
+	assignments, err := store.GetAssignments(ctx, domain.AssignmentFilters{SRSStage: &stage})
+	if err != nil {
+		t.Fatalf("failed to get assignments: %v", err)
+	}
+	if len(assignments) != 1 || assignments[0].Data.SubjectID != 1 {
+		t.Errorf("expected assignment 10 from SRS stage filter, got %+v", assignments)
+	}
+
+	reviews, err := store.GetReviews(ctx, domain.ReviewFilters{})
+	if err != nil {
+		t.Fatalf("failed to get reviews: %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].Data.IncorrectMeaningAnswers != 1 {
+		t.Errorf("expected review data to round-trip, got %+v", reviews)
+	}
+
+	latest, err := store.GetLatestStatistics(ctx)
+	if err != nil {
+		t.Fatalf("failed to get latest statistics: %v", err)
+	}
+	if latest == nil || latest.Statistics.Object != "report" {
+		t.Errorf("expected statistics to round-trip, got %+v", latest)
+	}
+
+	progress, err := store.GetLevelProgress(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to get level progress: %v", err)
+	}
+	if progress.Total.Passed != 1 {
+		t.Errorf("expected 1 passed subject from compressed assignments, got %d", progress.Total.Passed)
+	}
+}
+
+func TestStore_MixedCompressedAndPlainData(t *testing.T) {
+	dbPath := "test_mixed_compression.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+	updatedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	plain := domain.Subject{ID: 1, Object: "radical", DataUpdatedAt: updatedAt, Data: domain.SubjectData{Level: 1, Characters: "一"}}
+	if err := store.UpsertSubjects(ctx, []domain.Subject{plain}); err != nil {
+		t.Fatalf("failed to upsert plain subject: %v", err)
+	}
+
+	store.compressData = true
+	compressed := domain.Subject{ID: 2, Object: "kanji", DataUpdatedAt: updatedAt, Data: domain.SubjectData{Level: 2, Characters: "人"}}
+	if err := store.UpsertSubjects(ctx, []domain.Subject{compressed}); err != nil {
+		t.Fatalf("failed to upsert compressed subject: %v", err)
+	}
+
+	if typ := columnType(t, store, "subjects", 1); typ != "text" {
+		t.Errorf("expected subject written before compression to stay text, got %s", typ)
+	}
+	if typ := columnType(t, store, "subjects", 2); typ != "blob" {
+		t.Errorf("expected subject written with compression to be blob, got %s", typ)
+	}
+
+	subjects, err := store.GetSubjects(ctx, domain.SubjectFilters{IncludeRaw: true})
+	if err != nil {
+		t.Fatalf("failed to get subjects: %v", err)
+	}
+	if len(subjects) != 2 {
+		t.Fatalf("expected 2 subjects, got %d", len(subjects))
+	}
+	for i, want := range []string{"一", "人"} {
+		if subjects[i].Data.Characters != want {
+			t.Errorf("subject %d: expected characters %q, got %q", subjects[i].ID, want, subjects[i].Data.Characters)
+		}
+		if !json.Valid(subjects[i].Raw) {
+			t.Errorf("subject %d: expected raw data to be plain JSON, got %q", subjects[i].ID, subjects[i].Raw)
+		}
+	}
+
+	maxLevel := 1
+	levelOne, err := store.GetSubjects(ctx, domain.SubjectFilters{LevelMax: &maxLevel})
+	if err != nil {
+		t.Fatalf("failed to filter subjects: %v", err)
+	}
+	if len(levelOne) != 1 || levelOne[0].ID != 1 {
+		t.Errorf("expected level filter to match only subject 1, got %+v", levelOne)
+	}
+
+	// Turning compression off again must not affect reading compressed rows
+	store.compressData = false
+	var exported bytes.Buffer
+	if err := store.Export(ctx, &exported); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if !json.Valid(exported.Bytes()) || !strings.Contains(exported.String(), `"characters":"人"`) {
+		t.Errorf("expected export to contain decompressed subject data, got %s", exported.String())
+	}
+}
+
+// cancelAfterChecksContext reports itself cancelled once Err has been called more than
+// checks times. Its Done channel stays nil, so database/sql does not close the rows on
+// its own and only the store's own checks can stop the scan.
+type cancelAfterChecksContext struct {
+	context.Context
+	checks int
+}
+
+func (c *cancelAfterChecksContext) Err() error {
+	if c.checks == 0 {
+		return context.Canceled
+	}
+	c.checks--
+	return nil
+}
+
+func TestStore_GetReviewsByPassed(t *testing.T) {
+	dbPath := "test_reviews_passed.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+	seedReviewsAt(t, store, nil)
+
+	jan10 := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	jan20 := time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC)
+	review := func(id int64, createdAt time.Time, incorrectMeaning, incorrectReading int) domain.Review {
+		return domain.Review{ID: id, Object: "review", DataUpdatedAt: createdAt, Data: domain.ReviewData{
+			AssignmentID: 1, SubjectID: 1, CreatedAt: createdAt,
+			IncorrectMeaningAnswers: incorrectMeaning, IncorrectReadingAnswers: incorrectReading,
+		}}
+	}
+	if err := store.UpsertReviews(ctx, []domain.Review{
+		review(1, jan10, 0, 0),
+		review(2, jan10, 1, 0),
+		review(3, jan20, 0, 2),
+		review(4, jan20, 0, 0),
+	}); err != nil {
+		t.Fatalf("failed to upsert reviews: %v", err)
+	}
+
+	passed, failed := true, false
+	from := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		filters     domain.ReviewFilters
+		expectedIDs []int64
+	}{
+		{name: "unset", filters: domain.ReviewFilters{}, expectedIDs: []int64{1, 2, 3, 4}},
+		{name: "passed only", filters: domain.ReviewFilters{Passed: &passed}, expectedIDs: []int64{1, 4}},
+		{name: "failed only", filters: domain.ReviewFilters{Passed: &failed}, expectedIDs: []int64{2, 3}},
+		{name: "failed within dates", filters: domain.ReviewFilters{Passed: &failed, From: &from}, expectedIDs: []int64{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviews, err := store.GetReviews(ctx, tt.filters)
+			if err != nil {
+				t.Fatalf("failed to get reviews: %v", err)
+			}
+
+			var ids []int64
+			for _, r := range reviews {
+				ids = append(ids, r.ID)
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			if fmt.Sprint(ids) != fmt.Sprint(tt.expectedIDs) {
+				t.Errorf("expected reviews %v, got %v", tt.expectedIDs, ids)
+			}
+		})
+	}
+}
+
+func TestStore_GetReviewsBySubjectType(t *testing.T) {
+	dbPath := "test_reviews_subject_type.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+	seedReviewsAt(t, store, nil)
+
+	if err := store.UpsertSubjects(ctx, []domain.Subject{
+		{ID: 2, Object: "radical", URL: "https://api.wanikani.com/v2/subjects/2", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}},
+	}); err != nil {
+		t.Fatalf("failed to upsert subject: %v", err)
+	}
+	if err := store.UpsertAssignments(ctx, []domain.Assignment{
+		{ID: 2, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: 2, SubjectType: "radical", SRSStage: 1}},
+	}); err != nil {
+		t.Fatalf("failed to upsert assignment: %v", err)
+	}
+
+	createdAt := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	review := func(id, subjectID int64) domain.Review {
+		return domain.Review{ID: id, Object: "review", DataUpdatedAt: createdAt, Data: domain.ReviewData{
+			AssignmentID: subjectID, SubjectID: subjectID, CreatedAt: createdAt,
+		}}
+	}
+	if err := store.UpsertReviews(ctx, []domain.Review{review(1, 1), review(2, 2), review(3, 1), review(4, 2)}); err != nil {
+		t.Fatalf("failed to upsert reviews: %v", err)
+	}
+
+	tests := []struct {
+		subjectType string
+		expectedIDs []int64
+	}{
+		{subjectType: "", expectedIDs: []int64{1, 2, 3, 4}},
+		{subjectType: "kanji", expectedIDs: []int64{1, 3}},
+		{subjectType: "radical", expectedIDs: []int64{2, 4}},
+		{subjectType: "vocabulary", expectedIDs: nil},
+	}
+
+	for _, tt := range tests {
+		reviews, err := store.GetReviews(ctx, domain.ReviewFilters{SubjectType: tt.subjectType})
+		if err != nil {
+			t.Fatalf("failed to get reviews: %v", err)
+		}
+
+		var ids []int64
+		for _, r := range reviews {
+			ids = append(ids, r.ID)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		if fmt.Sprint(ids) != fmt.Sprint(tt.expectedIDs) {
+			t.Errorf("subject type %q: expected reviews %v, got %v", tt.subjectType, tt.expectedIDs, ids)
+		}
+	}
+}
+
+func TestStore_UpsertDeduplicatesBatch(t *testing.T) {
+	dbPath := "test_upsert_dedup.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	// The newer copy of subject 1 comes first, so keeping the last one would be wrong
+	subjects := []domain.Subject{
+		{ID: 1, Object: "kanji", DataUpdatedAt: newer, Data: domain.SubjectData{Level: 2, Characters: "new"}},
+		{ID: 2, Object: "kanji", DataUpdatedAt: older, Data: domain.SubjectData{Level: 1, Characters: "other"}},
+		{ID: 1, Object: "kanji", DataUpdatedAt: older, Data: domain.SubjectData{Level: 1, Characters: "old"}},
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
+
+	assignments := []domain.Assignment{
+		{ID: 10, Object: "assignment", DataUpdatedAt: older, Data: domain.AssignmentData{SubjectID: 1, SRSStage: 1}},
+		{ID: 10, Object: "assignment", DataUpdatedAt: newer, Data: domain.AssignmentData{SubjectID: 1, SRSStage: 5}},
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("failed to upsert assignments: %v", err)
+	}
+
+	reviews := []domain.Review{
+		{ID: 100, Object: "review", DataUpdatedAt: newer, Data: domain.ReviewData{AssignmentID: 10, SubjectID: 1, CreatedAt: newer, IncorrectMeaningAnswers: 2}},
+		{ID: 100, Object: "review", DataUpdatedAt: older, Data: domain.ReviewData{AssignmentID: 10, SubjectID: 1, CreatedAt: older}},
+	}
+	if err := store.UpsertReviews(ctx, reviews); err != nil {
+		t.Fatalf("failed to upsert reviews: %v", err)
+	}
+
+	storedSubjects, err := store.GetSubjects(ctx, domain.SubjectFilters{})
+	if err != nil {
+		t.Fatalf("failed to get subjects: %v", err)
+	}
+	if len(storedSubjects) != 2 {
+		t.Fatalf("expected 2 subjects, got %d", len(storedSubjects))
+	}
+	if got := storedSubjects[0]; got.ID != 1 || got.Data.Characters != "new" || got.Data.Level != 2 || !got.DataUpdatedAt.Equal(newer) {
+		t.Errorf("expected the newest copy of subject 1, got %+v", got)
+	}
+
+	storedAssignments, err := store.GetAssignments(ctx, domain.AssignmentFilters{})
+	if err != nil {
+		t.Fatalf("failed to get assignments: %v", err)
+	}
+	if len(storedAssignments) != 1 || storedAssignments[0].Data.SRSStage != 5 {
+		t.Errorf("expected a single assignment at stage 5, got %+v", storedAssignments)
+	}
+
+	storedReviews, err := store.GetReviews(ctx, domain.ReviewFilters{})
+	if err != nil {
+		t.Fatalf("failed to get reviews: %v", err)
+	}
+	if len(storedReviews) != 1 || storedReviews[0].Data.IncorrectMeaningAnswers != 2 {
+		t.Errorf("expected a single review with the newest data, got %+v", storedReviews)
+	}
+}
+
+func TestStore_Int64IDsRoundTrip(t *testing.T) {
+	dbPath := "test_int64_ids.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// IDs beyond the range of a 32-bit int
+	const subjectID, assignmentID, reviewID = int64(1) << 33, int64(1)<<33 + 1, int64(1)<<40 + 7
+
+	if err := store.UpsertSubjects(ctx, []domain.Subject{
+		{ID: subjectID, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}},
+	}); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
+	if err := store.UpsertAssignments(ctx, []domain.Assignment{
+		{ID: assignmentID, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: subjectID}},
+	}); err != nil {
+		t.Fatalf("failed to upsert assignments: %v", err)
+	}
+	if err := store.UpsertReviews(ctx, []domain.Review{
+		{ID: reviewID, Object: "review", DataUpdatedAt: time.Now(), Data: domain.ReviewData{AssignmentID: assignmentID, SubjectID: subjectID, CreatedAt: time.Now()}},
+	}); err != nil {
+		t.Fatalf("failed to upsert reviews: %v", err)
+	}
+
+	reviews, err := store.GetReviews(ctx, domain.ReviewFilters{})
+	if err != nil {
+		t.Fatalf("failed to get reviews: %v", err)
+	}
+	if len(reviews) != 1 {
+		t.Fatalf("expected 1 review, got %d", len(reviews))
+	}
+	got := reviews[0]
+	if got.ID != reviewID || got.Data.AssignmentID != assignmentID || got.Data.SubjectID != subjectID {
+		t.Errorf("expected IDs %d/%d/%d, got %d/%d/%d", reviewID, assignmentID, subjectID, got.ID, got.Data.AssignmentID, got.Data.SubjectID)
+	}
+}
+
+func TestStore_GetReviewsStopsWhenContextCancelled(t *testing.T) {
+	dbPath := "test_reviews_cancel.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	createdAt := make([]time.Time, 3*scanContextCheckInterval)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range createdAt {
+		createdAt[i] = base.Add(time.Duration(i) * time.Minute)
+	}
+	seedReviewsAt(t, store, createdAt)
+
+	// The first check passes, so the cancellation is seen part way through the rows
+	ctx := &cancelAfterChecksContext{Context: context.Background(), checks: 1}
+
+	reviews, err := store.GetReviews(ctx, domain.ReviewFilters{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if reviews != nil {
+		t.Errorf("expected no reviews on cancellation, got %d", len(reviews))
+	}
+
+	// The same query runs to completion with a live context
+	reviews, err = store.GetReviews(context.Background(), domain.ReviewFilters{})
+	if err != nil {
+		t.Fatalf("failed to get reviews: %v", err)
+	}
+	if len(reviews) != len(createdAt) {
+		t.Errorf("expected %d reviews, got %d", len(createdAt), len(reviews))
+	}
+}
+
+func TestStore_GetReviewCountsByWeek(t *testing.T) {
+	dbPath := "test_review_weeks.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	seedReviewsAt(t, store, []time.Time{
+		time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),   // Monday, week 1
+		time.Date(2024, 1, 7, 23, 30, 0, 0, time.UTC),  // Sunday in UTC, but Monday of week 2 in Stockholm
+		time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC),  // week 2
+		time.Date(2024, 1, 23, 12, 0, 0, 0, time.UTC),  // week 4; week 3 has no reviews
+		time.Date(2023, 12, 31, 12, 0, 0, 0, time.UTC), // Sunday, ISO week 52 of 2023
+	})
+
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	WithLocation(stockholm)(store)
+
+	monday := func(day int) time.Time {
+		return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)
+	}
+	from, to := monday(1), monday(21)
+
+	tests := []struct {
+		name     string
+		filters  domain.ReviewFilters
+		expected map[time.Time]int
+	}{
+		{
+			name:    "all",
+			filters: domain.ReviewFilters{},
+			expected: map[time.Time]int{
+				time.Date(2023, 12, 25, 0, 0, 0, 0, time.UTC): 1,
+				monday(1): 1, monday(8): 2, monday(22): 1,
+			},
+		},
+		{
+			name:     "date range",
+			filters:  domain.ReviewFilters{From: &from, To: &to},
+			expected: map[time.Time]int{monday(1): 1, monday(8): 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts, err := store.GetReviewCountsByWeek(context.Background(), tt.filters)
+			if err != nil {
+				t.Fatalf("failed to get review weeks: %v", err)
+			}
+			if len(counts) != len(tt.expected) {
+				t.Fatalf("expected %d weeks, got %v", len(tt.expected), counts)
+			}
+			for week, count := range tt.expected {
+				if counts[week] != count {
+					t.Errorf("week of %s: expected %d reviews, got %d", week.Format("2006-01-02"), count, counts[week])
+				}
+			}
+		})
+	}
+}
+
+func TestStore_ClearSyncTimes(t *testing.T) {
+	dbPath := "test_clear_sync_times.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, dataType := range []domain.DataType{domain.DataTypeSubjects, domain.DataTypeReviews} {
+		if err := store.SetLastSyncTime(ctx, dataType, now); err != nil {
+			t.Fatalf("Failed to set sync time: %v", err)
+		}
+	}
+
+	subjects := domain.DataTypeSubjects
+	cleared, err := store.ClearSyncTimes(ctx, &subjects)
+	if err != nil {
+		t.Fatalf("Failed to clear sync times: %v", err)
+	}
+	if cleared != 1 {
+		t.Errorf("Expected 1 cleared, got %d", cleared)
+	}
+	if ts, _ := store.GetLastSyncTime(ctx, domain.DataTypeSubjects); ts != nil {
+		t.Errorf("Expected subjects sync time to be cleared, got %v", ts)
+	}
+	if ts, _ := store.GetLastSyncTime(ctx, domain.DataTypeReviews); ts == nil {
+		t.Error("Expected reviews sync time to be kept")
+	}
+
+	cleared, err = store.ClearSyncTimes(ctx, nil)
+	if err != nil {
+		t.Fatalf("Failed to clear sync times: %v", err)
+	}
+	if cleared != 1 {
+		t.Errorf("Expected 1 cleared, got %d", cleared)
+	}
+	if ts, _ := store.GetLastSyncTime(ctx, domain.DataTypeReviews); ts != nil {
+		t.Errorf("Expected reviews sync time to be cleared, got %v", ts)
+	}
+}
+
+func TestStore_GetSubjectAccuracy(t *testing.T) {
+	dbPath := "test_subject_accuracy.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	// Subject 3 has no reviews and is left out
+	objects := map[int64]string{1: "radical", 2: "kanji", 3: "kanji"}
+	for id := int64(1); id <= 3; id++ {
+		if err := store.UpsertSubjects(ctx, []domain.Subject{{ID: id, Object: objects[id], DataUpdatedAt: now, Data: domain.SubjectData{Level: 1}}}); err != nil {
+			t.Fatalf("failed to upsert subject: %v", err)
+		}
+		if err := store.UpsertAssignments(ctx, []domain.Assignment{{ID: id, Object: "assignment", DataUpdatedAt: now, Data: domain.AssignmentData{SubjectID: id}}}); err != nil {
+			t.Fatalf("failed to upsert assignment: %v", err)
+		}
+	}
+	review := func(id, subjectID int64, incorrectMeaning, incorrectReading int) domain.Review {
+		return domain.Review{ID: id, Object: "review", DataUpdatedAt: now, Data: domain.ReviewData{
+			AssignmentID: subjectID, SubjectID: subjectID, CreatedAt: now,
+			IncorrectMeaningAnswers: incorrectMeaning, IncorrectReadingAnswers: incorrectReading,
+		}}
+	}
+	if err := store.UpsertReviews(ctx, []domain.Review{
+		review(1, 1, 2, 0),
+		review(2, 1, 0, 0),
+		review(3, 2, 1, 1),
+		review(4, 2, 0, 2),
+		review(5, 2, 0, 0),
+	}); err != nil {
+		t.Fatalf("failed to upsert reviews: %v", err)
+	}
+
+	accuracy, err := store.GetSubjectAccuracy(ctx)
+	if err != nil {
+		t.Fatalf("failed to get subject accuracy: %v", err)
+	}
+
+	expected := []domain.SubjectAccuracy{
+		{SubjectID: 1, Reviews: 2, CorrectAnswers: 2, IncorrectAnswers: 2},
+		{SubjectID: 2, Reviews: 3, CorrectAnswers: 6, IncorrectAnswers: 4},
+	}
+	if !reflect.DeepEqual(accuracy, expected) {
+		t.Errorf("expected %+v, got %+v", expected, accuracy)
+	}
+	if got := accuracy[1].PercentageCorrect(); got != 60 {
+		t.Errorf("expected 60%% correct, got %v", got)
+	}
+}
+
+func TestStore_GetReviewDateRange(t *testing.T) {
+	dbPath := "test_review_date_range.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	earliest, latest, err := store.GetReviewDateRange(ctx)
+	if err != nil {
+		t.Fatalf("failed to get review date range: %v", err)
+	}
+	if earliest != nil || latest != nil {
+		t.Errorf("expected no range without reviews, got %v to %v", earliest, latest)
+	}
+
+	first := time.Date(2023, 12, 31, 23, 59, 0, 0, time.UTC)
+	last := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	seedReviewsAt(t, store, []time.Time{
+		time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		last,
+		first,
+	})
+
+	earliest, latest, err = store.GetReviewDateRange(ctx)
+	if err != nil {
+		t.Fatalf("failed to get review date range: %v", err)
+	}
+	if earliest == nil || !earliest.Equal(first) {
+		t.Errorf("expected earliest review %v, got %v", first, earliest)
+	}
+	if latest == nil || !latest.Equal(last) {
+		t.Errorf("expected latest review %v, got %v", last, latest)
+	}
+}
+
+func TestStore_GetReviewCountsByDay(t *testing.T) {
+	dbPath := "test_review_days.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	seedReviewsAt(t, store, []time.Time{
+		time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC), // January 2nd in Stockholm
+		time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 4, 12, 0, 0, 0, time.UTC), // January 3rd has no reviews
+	})
+
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	WithLocation(stockholm)(store)
+
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	from, to := day(2), day(3)
+
+	tests := []struct {
+		name     string
+		filters  domain.ReviewFilters
+		expected map[time.Time]int
+	}{
+		{
+			name:     "all",
+			filters:  domain.ReviewFilters{},
+			expected: map[time.Time]int{day(1): 1, day(2): 2, day(4): 1},
+		},
+		{
+			name:     "date range",
+			filters:  domain.ReviewFilters{From: &from, To: &to},
+			expected: map[time.Time]int{day(2): 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts, err := store.GetReviewCountsByDay(context.Background(), tt.filters)
+			if err != nil {
+				t.Fatalf("failed to get review days: %v", err)
+			}
+			if len(counts) != len(tt.expected) {
+				t.Fatalf("expected %d days, got %v", len(tt.expected), counts)
+			}
+			for d, count := range tt.expected {
+				if counts[d] != count {
+					t.Errorf("%s: expected %d reviews, got %d", d.Format("2006-01-02"), count, counts[d])
+				}
+			}
+		})
+	}
+}
+
+func TestStore_GetReviewCountsByHour(t *testing.T) {
+	dbPath := "test_review_hours.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	seedReviewsAt(t, store, []time.Time{
+		time.Date(2024, 1, 10, 0, 10, 0, 0, time.UTC),
+		time.Date(2024, 1, 10, 0, 40, 0, 0, time.UTC),
+		time.Date(2024, 1, 10, 9, 20, 0, 0, time.UTC),
+		time.Date(2024, 1, 10, 23, 50, 0, 0, time.UTC),
+		time.Date(2024, 1, 11, 9, 5, 0, 0, time.UTC),
+		time.Date(2024, 1, 12, 14, 0, 0, 0, time.UTC),
+	})
+
+	// A half-hour offset checks that reviews are not bucketed by UTC hour
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+	day := time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		location *time.Location
+		filters  domain.ReviewFilters
+		expected map[int]int
+	}{
+		{name: "utc", expected: map[int]int{0: 2, 9: 2, 14: 1, 23: 1}},
+		{name: "half-hour offset", location: ist, expected: map[int]int{5: 2, 6: 1, 14: 2, 19: 1}},
+		{name: "utc single day", filters: domain.ReviewFilters{From: &day, To: &day}, expected: map[int]int{9: 1}},
+		{name: "local single day", location: ist, filters: domain.ReviewFilters{From: &day, To: &day}, expected: map[int]int{5: 1, 14: 1}},
+		{name: "from only", filters: domain.ReviewFilters{From: &day}, expected: map[int]int{9: 1, 14: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			WithLocation(time.UTC)(store)
+			if tt.location != nil {
+				WithLocation(tt.location)(store)
+			}
+
+			counts, err := store.GetReviewCountsByHour(context.Background(), tt.filters)
+			if err != nil {
+				t.Fatalf("failed to get review hours: %v", err)
+			}
+
+			for hour, count := range counts {
+				if count != tt.expected[hour] {
+					t.Errorf("hour %d: expected %d reviews, got %d", hour, tt.expected[hour], count)
+				}
+			}
+		})
+	}
+}
+
+func TestStore_GetCurrentSRSGroupTotals(t *testing.T) {
+	dbPath := "test_srs_group_totals.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	types := []string{"radical", "kanji", "vocabulary"}
+	var subjects []domain.Subject
+	var assignments []domain.Assignment
+	for i := int64(1); i <= 200; i++ {
+		subjectType := types[i%int64(len(types))]
+		subjects = append(subjects, domain.Subject{
+			ID:            i,
+			Object:        subjectType,
+			URL:           fmt.Sprintf("https://api.wanikani.com/v2/subjects/%d", i),
+			DataUpdatedAt: time.Now(),
+			Data:          domain.SubjectData{Level: 1},
+		})
+		assignments = append(assignments, domain.Assignment{
+			ID:            i,
+			Object:        "assignment",
+			URL:           fmt.Sprintf("https://api.wanikani.com/v2/assignments/%d", i),
+			DataUpdatedAt: time.Now(),
+			Data:          domain.AssignmentData{SubjectID: i, SubjectType: subjectType, SRSStage: int(i*7) % 10},
+		})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("failed to upsert assignments: %v", err)
+	}
+
+	totals, err := store.GetCurrentSRSGroupTotals(ctx)
+	if err != nil {
+		t.Fatalf("failed to get SRS group totals: %v", err)
+	}
+
+	// The same aggregation done in Go over the loaded assignments
+	stored, err := store.GetAssignments(ctx, domain.AssignmentFilters{})
+	if err != nil {
+		t.Fatalf("failed to get assignments: %v", err)
+	}
+	expected := make(map[string]map[string]int)
+	for _, assignment := range stored {
+		if assignment.Data.SRSStage == 0 {
+			continue
+		}
+		group := domain.GetSRSStageName(assignment.Data.SRSStage)
+		if expected[group] == nil {
+			expected[group] = make(map[string]int)
+		}
+		expected[group][assignment.Data.SubjectType]++
+	}
+
+	if fmt.Sprint(totals) != fmt.Sprint(expected) {
+		t.Errorf("expected totals %v, got %v", expected, totals)
+	}
+	if _, ok := totals["initiate"]; ok {
+		t.Error("expected assignments in lessons to be excluded")
+	}
+}
+
+func TestStore_GetSubjectCountsByLevel(t *testing.T) {
+	dbPath := "test_subject_counts_by_level.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	seed := []struct {
+		level       int
+		subjectType string
+	}{
+		{1, "radical"}, {1, "radical"}, {1, "kanji"}, {1, "vocabulary"},
+		{2, "kanji"}, {2, "vocabulary"}, {2, "vocabulary"},
+		{5, "radical"},
+	}
+	var subjects []domain.Subject
+	for i, s := range seed {
+		subjects = append(subjects, domain.Subject{
+			ID:            int64(i + 1),
+			Object:        s.subjectType,
+			URL:           fmt.Sprintf("https://api.wanikani.com/v2/subjects/%d", i+1),
+			DataUpdatedAt: time.Now(),
+			Data:          domain.SubjectData{Level: s.level},
+		})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
+
+	counts, err := store.GetSubjectCountsByLevel(ctx)
+	if err != nil {
+		t.Fatalf("failed to get subject counts: %v", err)
+	}
+
+	expected := map[int]map[string]int{
+		1: {"radical": 2, "kanji": 1, "vocabulary": 1},
+		2: {"kanji": 1, "vocabulary": 2},
+		5: {"radical": 1},
+	}
+	if fmt.Sprint(counts) != fmt.Sprint(expected) {
+		t.Errorf("expected counts %v, got %v", expected, counts)
+	}
+}
+
+func TestStore_GetDistinctLevels(t *testing.T) {
+	dbPath := "test_distinct_levels.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	levels, err := store.GetDistinctLevels(ctx)
+	if err != nil {
+		t.Fatalf("failed to get levels: %v", err)
+	}
+	if len(levels) != 0 {
+		t.Errorf("expected no levels in an empty store, got %v", levels)
+	}
+
+	// Non-contiguous levels, inserted out of order and with duplicates
+	var subjects []domain.Subject
+	for i, level := range []int{30, 2, 7, 2, 30, 1} {
+		subjects = append(subjects, domain.Subject{
+			ID:            int64(i + 1),
+			Object:        "kanji",
+			URL:           fmt.Sprintf("https://api.wanikani.com/v2/subjects/%d", i+1),
+			DataUpdatedAt: time.Now(),
+			Data:          domain.SubjectData{Level: level},
+		})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
+
+	levels, err = store.GetDistinctLevels(ctx)
+	if err != nil {
+		t.Fatalf("failed to get levels: %v", err)
+	}
+
+	expected := []int{1, 2, 7, 30}
+	if fmt.Sprint(levels) != fmt.Sprint(expected) {
+		t.Errorf("expected levels %v, got %v", expected, levels)
+	}
+}
+
+func TestStore_GetSubjects_DataUpdatedAfter(t *testing.T) {
+	dbPath := "test_subjects_updated_after.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+	since := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cet := time.FixedZone("CET", 3600)
+	subjects := []domain.Subject{
+		{ID: 1, Object: "kanji", DataUpdatedAt: since.Add(-time.Hour), Data: domain.SubjectData{Level: 1}},
+		{ID: 2, Object: "kanji", DataUpdatedAt: since, Data: domain.SubjectData{Level: 1}},
+		{ID: 3, Object: "kanji", DataUpdatedAt: since.Add(time.Minute), Data: domain.SubjectData{Level: 1}},
+		// 12:30 UTC, written in another zone: compared in UTC, not as local text
+		{ID: 4, Object: "kanji", DataUpdatedAt: since.Add(30 * time.Minute).In(cet), Data: domain.SubjectData{Level: 1}},
+		// 11:30 UTC, which would sort after since as local text
+		{ID: 5, Object: "kanji", DataUpdatedAt: since.Add(-30 * time.Minute).In(cet), Data: domain.SubjectData{Level: 1}},
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
+
+	got, err := store.GetSubjects(ctx, domain.SubjectFilters{DataUpdatedAfter: &since})
+	if err != nil {
+		t.Fatalf("failed to get subjects: %v", err)
+	}
+	var ids []int64
+	for _, subject := range got {
+		ids = append(ids, subject.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if fmt.Sprint(ids) != "[3 4]" {
+		t.Errorf("expected subjects [3 4] updated after %v, got %v", since, ids)
+	}
+}
+
+func TestStore_GetSubjectsAndAssignmentsByIDs(t *testing.T) {
+	dbPath := "test_get_by_ids.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// ID 700 lands in a second batch of IDs
+	var subjects []domain.Subject
+	var assignments []domain.Assignment
+	for _, id := range []int64{1, 2, 700} {
+		subjects = append(subjects, domain.Subject{ID: id, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}})
+		assignments = append(assignments, domain.Assignment{ID: id + 10000, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: id, SRSStage: 1}})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("failed to upsert assignments: %v", err)
+	}
+
+	ids := make([]int64, 0, 1000)
+	for id := int64(1); id <= 1000; id++ {
+		ids = append(ids, id)
+	}
+
+	gotSubjects, err := store.GetSubjectsByIDs(ctx, ids)
+	if err != nil {
+		t.Fatalf("failed to get subjects: %v", err)
+	}
+	var subjectIDs []int64
+	for _, subject := range gotSubjects {
+		subjectIDs = append(subjectIDs, subject.ID)
+		if subject.Data.Level != 1 {
+			t.Errorf("expected subject %d to be fully loaded, got %+v", subject.ID, subject)
+		}
+	}
+	if fmt.Sprint(subjectIDs) != "[1 2 700]" {
+		t.Errorf("expected subjects [1 2 700], got %v", subjectIDs)
+	}
+
+	gotAssignments, err := store.GetAssignmentsByIDs(ctx, []int64{10001, 10700, 3})
+	if err != nil {
+		t.Fatalf("failed to get assignments: %v", err)
+	}
+	if len(gotAssignments) != 2 {
+		t.Fatalf("expected 2 assignments, got %d", len(gotAssignments))
+	}
+	for _, assignment := range gotAssignments {
+		if assignment.Data.SubjectID != assignment.ID-10000 {
+			t.Errorf("expected assignment %d to belong to subject %d, got %d", assignment.ID, assignment.ID-10000, assignment.Data.SubjectID)
+		}
+	}
+
+	if none, err := store.GetSubjectsByIDs(ctx, nil); err != nil || len(none) != 0 {
+		t.Errorf("expected no subjects for no IDs, got %v (err %v)", none, err)
+	}
+}
+
+func TestStore_GetLastReviewPerSubject(t *testing.T) {
+	dbPath := "test_last_review_per_subject.db"
+	defer os.Remove(dbPath)
+
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
+
+	ctx := context.Background()
+
+	// Subject 700 lands in a second batch of IDs; subject 3 has no reviews
+	subjectIDs := []int64{1, 2, 3, 700}
+	var subjects []domain.Subject
+	var assignments []domain.Assignment
+	for _, id := range subjectIDs {
+		subjects = append(subjects, domain.Subject{ID: id, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}})
+		assignments = append(assignments, domain.Assignment{ID: id, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: id, SRSStage: 1}})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("failed to upsert assignments: %v", err)
+	}
+
+	jan5 := time.Date(2024, 1, 5, 8, 0, 0, 0, time.UTC)
+	jan10 := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	review := func(id, subjectID int64, createdAt time.Time) domain.Review {
+		return domain.Review{ID: id, Object: "review", DataUpdatedAt: createdAt, Data: domain.ReviewData{
+			AssignmentID: subjectID, SubjectID: subjectID, CreatedAt: createdAt,
+		}}
+	}
+	if err := store.UpsertReviews(ctx, []domain.Review{
+		review(1, 1, jan10),
+		// A fractional second sorts before "Z" as text, so this checks the latest wins by time
+		review(2, 1, jan10.Add(500*time.Millisecond)),
+		review(3, 1, jan5),
+		review(4, 2, jan5),
+		review(5, 700, jan5),
+		review(6, 700, jan10),
+	}); err != nil {
+		t.Fatalf("failed to upsert reviews: %v", err)
+	}
+
+	ids := make([]int64, 0, 1000)
+	for id := int64(1); id <= 1000; id++ {
+		ids = append(ids, id)
+	}
 
-		if len(retrieved) != 3 {
-			t.Errorf("expected 3 snapshots, got %d", len(retrieved))
-		}
+	lastReviews, err := store.GetLastReviewPerSubject(ctx, ids)
+	if err != nil {
+		t.Fatalf("failed to get last reviews: %v", err)
+	}
 
-		// Verify data
-		if retrieved[0].Count != 10 {
-			t.Errorf("expected count 10, got %d", retrieved[0].Count)
+	expected := map[int64]time.Time{
+		1:   jan10.Add(500 * time.Millisecond),
+		2:   jan5,
+		700: jan10,
+	}
+	if len(lastReviews) != len(expected) {
+		t.Fatalf("expected last reviews for %d subjects, got %v", len(expected), lastReviews)
+	}
+	for subjectID, want := range expected {
+		if got, ok := lastReviews[subjectID]; !ok || !got.Equal(want) {
+			t.Errorf("subject %d: expected last review at %v, got %v", subjectID, want, got)
 		}
-	})
+	}
 
-	t.Run("upsert idempotence", func(t *testing.T) {
-		// Upsert the same snapshot twice with different counts
-		date := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
-		snapshot := domain.AssignmentSnapshot{
-			Date:        date,
-			SRSStage:    2,
-			SubjectType: "radical",
-			Count:       5,
-		}
+	// No IDs means no query at all
+	lastReviews, err = store.GetLastReviewPerSubject(ctx, nil)
+	if err != nil || len(lastReviews) != 0 {
+		t.Errorf("expected no last reviews for no subjects, got %v (err %v)", lastReviews, err)
+	}
+}
 
-		err := store.UpsertAssignmentSnapshot(ctx, snapshot)
-		if err != nil {
-			t.Fatalf("failed to upsert snapshot: %v", err)
-		}
+func TestStore_GetAssignmentsPassedOn(t *testing.T) {
+	dbPath := "test_assignments_passed_on.db"
+	defer os.Remove(dbPath)
 
-		// Update with new count
-		snapshot.Count = 8
-		err = store.UpsertAssignmentSnapshot(ctx, snapshot)
-		if err != nil {
-			t.Fatalf("failed to update snapshot: %v", err)
-		}
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
 
-		// Verify only one record exists with updated count
-		dateRange := &domain.DateRange{
-			From: date,
-			To:   date,
-		}
-		retrieved, err := store.GetAssignmentSnapshots(ctx, dateRange)
-		if err != nil {
-			t.Fatalf("failed to get snapshots: %v", err)
-		}
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	WithLocation(stockholm)(store)
 
-		count := 0
-		for _, s := range retrieved {
-			if s.SRSStage == 2 && s.SubjectType == "radical" {
-				count++
-				if s.Count != 8 {
-					t.Errorf("expected count 8, got %d", s.Count)
-				}
-			}
-		}
+	ctx := context.Background()
 
-		if count != 1 {
-			t.Errorf("expected 1 snapshot with SRS stage 2 and type radical, got %d", count)
-		}
-	})
+	ptr := func(t time.Time) *time.Time { return &t }
+	passedAt := []*time.Time{
+		ptr(time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)),            // on the day
+		ptr(time.Date(2024, 3, 9, 23, 30, 0, 0, time.UTC)),           // 00:30 on the day in Stockholm
+		ptr(time.Date(2024, 3, 10, 23, 0, 0, 500000000, time.UTC)),   // midnight after the day in Stockholm
+		ptr(time.Date(2024, 3, 10, 22, 59, 59, 500000000, time.UTC)), // last second of the day in Stockholm
+		ptr(time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)),            // the next day
+		nil, // never passed
+	}
 
-	t.Run("date range filtering", func(t *testing.T) {
-		// Create snapshots for multiple dates
-		date1 := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
-		date2 := time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)
-		date3 := time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)
+	var subjects []domain.Subject
+	var assignments []domain.Assignment
+	for i, at := range passedAt {
+		id := int64(i + 1)
+		subjects = append(subjects, domain.Subject{ID: id, Object: "kanji", DataUpdatedAt: time.Now(), Data: domain.SubjectData{Level: 1}})
+		assignments = append(assignments, domain.Assignment{ID: id, Object: "assignment", DataUpdatedAt: time.Now(), Data: domain.AssignmentData{SubjectID: id, SRSStage: 5, PassedAt: at}})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("failed to upsert assignments: %v", err)
+	}
 
-		snapshots := []domain.AssignmentSnapshot{
-			{Date: date1, SRSStage: 1, SubjectType: "kanji", Count: 10},
-			{Date: date2, SRSStage: 1, SubjectType: "kanji", Count: 12},
-			{Date: date3, SRSStage: 1, SubjectType: "kanji", Count: 15},
-		}
+	passed, err := store.GetAssignmentsPassedOn(ctx, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("failed to get passed assignments: %v", err)
+	}
 
-		for _, snapshot := range snapshots {
-			err := store.UpsertAssignmentSnapshot(ctx, snapshot)
-			if err != nil {
-				t.Fatalf("failed to upsert snapshot: %v", err)
-			}
-		}
+	var ids []int64
+	for _, a := range passed {
+		ids = append(ids, a.ID)
+	}
+	if fmt.Sprint(ids) != "[1 2 4]" {
+		t.Errorf("expected assignments [1 2 4] passed on 2024-03-10, got %v", ids)
+	}
 
-		// Query with date range
-		dateRange := &domain.DateRange{
-			From: date1,
-			To:   date2,
-		}
+	passed, err = store.GetAssignmentsPassedOn(ctx, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("failed to get passed assignments: %v", err)
+	}
+	if len(passed) != 0 {
+		t.Errorf("expected no assignments passed on 2024-01-01, got %d", len(passed))
+	}
+}
 
-		filtered, err := store.GetAssignmentSnapshots(ctx, dateRange)
-		if err != nil {
-			t.Fatalf("failed to get filtered snapshots: %v", err)
-		}
+func TestStore_GetReviewCountToday(t *testing.T) {
+	dbPath := "test_review_count_today.db"
+	defer os.Remove(dbPath)
 
-		// Count snapshots within the date range
-		count := 0
-		for _, s := range filtered {
-			if !s.Date.Before(date1) && !s.Date.After(date2) {
-				count++
-			}
-		}
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
 
-		if count < 2 {
-			t.Errorf("expected at least 2 snapshots in date range, got %d", count)
-		}
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	WithLocation(stockholm)(store)
+
+	// 2024-03-10 runs from 23:00 UTC on the 9th to 23:00 UTC on the 10th in Stockholm
+	store.now = func() time.Time { return time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC) }
+
+	seedReviewsAt(t, store, []time.Time{
+		time.Date(2024, 3, 9, 22, 59, 0, 0, time.UTC),  // yesterday
+		time.Date(2024, 3, 9, 23, 0, 0, 0, time.UTC),   // midnight, today
+		time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC),  // today
+		time.Date(2024, 3, 10, 22, 59, 0, 0, time.UTC), // just before midnight, today
+		time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC),  // tomorrow
 	})
 
-	t.Run("calculate assignment snapshot", func(t *testing.T) {
-		// First create subjects
-		subjects := []domain.Subject{
-			{
-				ID:            1,
-				Object:        "kanji",
-				URL:           "https://api.wanikani.com/v2/subjects/1",
-				DataUpdatedAt: time.Now(),
-				Data:          domain.SubjectData{Level: 5, Characters: "一"},
-			},
-			{
-				ID:            2,
-				Object:        "vocabulary",
-				URL:           "https://api.wanikani.com/v2/subjects/2",
-				DataUpdatedAt: time.Now(),
-				Data:          domain.SubjectData{Level: 5, Characters: "一つ"},
-			},
-			{
-				ID:            3,
-				Object:        "radical",
-				URL:           "https://api.wanikani.com/v2/subjects/3",
-				DataUpdatedAt: time.Now(),
-				Data:          domain.SubjectData{Level: 1, Characters: "丨"},
-			},
-		}
-		err := store.UpsertSubjects(ctx, subjects)
-		if err != nil {
-			t.Fatalf("failed to upsert subjects: %v", err)
-		}
+	day, count, err := store.GetReviewCountToday(context.Background())
+	if err != nil {
+		t.Fatalf("failed to count today's reviews: %v", err)
+	}
 
-		// Create assignments with various SRS stages
-		now := time.Now()
-		assignments := []domain.Assignment{
-			{
-				ID:            100,
-				Object:        "assignment",
-				URL:           "https://api.wanikani.com/v2/assignments/100",
-				DataUpdatedAt: now,
-				Data: domain.AssignmentData{
-					SubjectID:   1,
-					SubjectType: "kanji",
-					SRSStage:    1, // Apprentice
-					StartedAt:   &now,
-				},
-			},
-			{
-				ID:            101,
-				Object:        "assignment",
-				URL:           "https://api.wanikani.com/v2/assignments/101",
-				DataUpdatedAt: now,
-				Data: domain.AssignmentData{
-					SubjectID:   2,
-					SubjectType: "vocabulary",
-					SRSStage:    5, // Guru
-					StartedAt:   &now,
-				},
-			},
-			{
-				ID:            102,
-				Object:        "assignment",
-				URL:           "https://api.wanikani.com/v2/assignments/102",
-				DataUpdatedAt: now,
-				Data: domain.AssignmentData{
-					SubjectID:   3,
-					SubjectType: "radical",
-					SRSStage:    0, // Unstarted - should be excluded
-					StartedAt:   nil,
-				},
-			},
-		}
+	if got := day.Format("2006-01-02"); got != "2024-03-10" {
+		t.Errorf("expected today to be 2024-03-10, got %s", got)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 reviews today, got %d", count)
+	}
+}
 
-		err = store.UpsertAssignments(ctx, assignments)
-		if err != nil {
-			t.Fatalf("failed to upsert assignments: %v", err)
-		}
+func TestStore_GetAvailableLessons(t *testing.T) {
+	dbPath := "test_available_lessons.db"
+	defer os.Remove(dbPath)
 
-		// Calculate snapshot
-		date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
-		calculated, err := store.CalculateAssignmentSnapshot(ctx, date)
-		if err != nil {
-			t.Fatalf("failed to calculate snapshot: %v", err)
-		}
+	store := setupTestStore(t, dbPath)
+	defer store.Close()
 
-		// Verify results
-		if len(calculated) == 0 {
-			t.Fatal("expected calculated snapshots, got none")
-		}
+	ctx := context.Background()
 
-		// Verify SRS stage 0 is excluded
-		for _, snapshot := range calculated {
-			if snapshot.SRSStage == 0 {
-				t.Error("SRS stage 0 should be excluded from snapshot")
-			}
-		}
+	unlocked := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	started := unlocked.Add(time.Hour)
+	hidden := unlocked.Add(-time.Hour)
+	tests := []struct {
+		level      int
+		position   int
+		unlockedAt *time.Time
+		startedAt  *time.Time
+		hiddenAt   *time.Time
+	}{
+		{level: 2, position: 0, unlockedAt: &unlocked},                      // 1: available, later level
+		{level: 1, position: 5, unlockedAt: &unlocked},                      // 2: available
+		{level: 1, position: 2, unlockedAt: &unlocked},                      // 3: available, earlier position
+		{level: 1, position: 1, unlockedAt: &unlocked, startedAt: &started}, // 4: already lessoned
+		{level: 1, position: 0},                                             // 5: still locked
+		{level: 1, position: 3, unlockedAt: &unlocked, hiddenAt: &hidden},   // 6: hidden by WaniKani
+	}
 
-		// Verify we have snapshots for SRS stages 1 and 5
-		foundStage1 := false
-		foundStage5 := false
-		for _, snapshot := range calculated {
-			if snapshot.SRSStage == 1 && snapshot.SubjectType == "kanji" {
-				foundStage1 = true
-				if snapshot.Count != 1 {
-					t.Errorf("expected count 1 for stage 1 kanji, got %d", snapshot.Count)
-				}
-			}
-			if snapshot.SRSStage == 5 && snapshot.SubjectType == "vocabulary" {
-				foundStage5 = true
-				if snapshot.Count != 1 {
-					t.Errorf("expected count 1 for stage 5 vocabulary, got %d", snapshot.Count)
-				}
-			}
-		}
+	var subjects []domain.Subject
+	var assignments []domain.Assignment
+	for i, tt := range tests {
+		id := int64(i + 1)
+		subjects = append(subjects, domain.Subject{ID: id, Object: "kanji", DataUpdatedAt: time.Now(),
+			Data: domain.SubjectData{Level: tt.level, LessonPosition: tt.position, HiddenAt: tt.hiddenAt}})
+		assignments = append(assignments, domain.Assignment{ID: id, Object: "assignment", DataUpdatedAt: time.Now(),
+			Data: domain.AssignmentData{SubjectID: id, UnlockedAt: tt.unlockedAt, StartedAt: tt.startedAt}})
+	}
+	if err := store.UpsertSubjects(ctx, subjects); err != nil {
+		t.Fatalf("failed to upsert subjects: %v", err)
+	}
+	if err := store.UpsertAssignments(ctx, assignments); err != nil {
+		t.Fatalf("failed to upsert assignments: %v", err)
+	}
 
-		if !foundStage1 {
-			t.Error("expected snapshot for SRS stage 1 kanji")
-		}
-		if !foundStage5 {
-			t.Error("expected snapshot for SRS stage 5 vocabulary")
-		}
-	})
+	lessons, err := store.GetAvailableLessons(ctx)
+	if err != nil {
+		t.Fatalf("failed to get available lessons: %v", err)
+	}
+
+	var ids []int64
+	for _, a := range lessons {
+		ids = append(ids, a.ID)
+	}
+	if fmt.Sprint(ids) != "[3 2 1]" {
+		t.Errorf("expected available lessons [3 2 1] in level and position order, got %v", ids)
+	}
 }
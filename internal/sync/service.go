@@ -1,7 +1,9 @@
 package sync
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -17,16 +19,162 @@ type Service struct {
 	logger  *logrus.Logger
 	mu      sync.Mutex
 	syncing bool
+	// syncStartedAt is when the sync in progress started; zero when not syncing
+	syncStartedAt time.Time
+
+	// dedupStatistics skips storing a statistics snapshot identical to the latest one
+	dedupStatistics bool
+	// statisticsKeepRecent enables statistics retention when positive; see PruneStatistics
+	statisticsKeepRecent int
+	// skipStatistics makes SyncAll skip the statistics (/summary) phase
+	skipStatistics bool
+	// statisticsSource selects where statistics snapshots come from
+	statisticsSource StatisticsSource
+	// conditionalSync makes SyncAll skip the sync when the summary reports no changes
+	conditionalSync bool
+	// validation controls how fetched records that fail Validate are handled
+	validation ValidationMode
+	// initialSnapshot creates an assignment snapshot as soon as assignments are synced into
+	// a database that has none, instead of waiting for the end of the sync
+	initialSnapshot bool
+	// syncOverlap is subtracted from the last sync time when fetching incrementally
+	syncOverlap time.Duration
+	// initialReviewWindow bounds the first reviews sync to this recent period when positive
+	initialReviewWindow time.Duration
+	// levelUpWebhook is notified when an assignments sync raises the current level
+	levelUpWebhook *webhook
+	// syncWebhook receives the results of every SyncAll run
+	syncWebhook *webhook
+	// webhooks tracks in-flight background webhook deliveries
+	webhooks sync.WaitGroup
+}
+
+// Option configures optional sync Service behavior
+type Option func(*Service)
+
+// ValidationMode controls what a sync does with fetched records that fail validation
+type ValidationMode string
+
+const (
+	// ValidationOff stores fetched records without validating them
+	ValidationOff ValidationMode = "off"
+	// ValidationSkip drops invalid records, logging them and counting them in the result
+	ValidationSkip ValidationMode = "skip"
+	// ValidationStrict fails the sync of a data type if any fetched record is invalid
+	ValidationStrict ValidationMode = "strict"
+)
+
+// WithValidation validates fetched subjects, assignments and reviews before they are
+// stored, handling invalid records according to mode. Validation is off by default.
+func WithValidation(mode ValidationMode) Option {
+	return func(s *Service) {
+		s.validation = mode
+	}
+}
+
+// WithStatisticsDedup skips storing a statistics snapshot whose data is identical to the most recent one
+func WithStatisticsDedup(enabled bool) Option {
+	return func(s *Service) {
+		s.dedupStatistics = enabled
+	}
+}
+
+// WithStatisticsRetention keeps the keepRecent most recent statistics snapshots plus the
+// latest snapshot of each earlier day, pruning the rest after each sync. Zero disables pruning.
+func WithStatisticsRetention(keepRecent int) Option {
+	return func(s *Service) {
+		s.statisticsKeepRecent = keepRecent
+	}
+}
+
+// WithStatisticsSync controls whether SyncAll fetches statistics. When disabled the
+// statistics phase is reported as skipped rather than failed.
+func WithStatisticsSync(enabled bool) Option {
+	return func(s *Service) {
+		s.skipStatistics = !enabled
+	}
+}
+
+// WithStatisticsSource selects where SyncStatistics gets its report: the live /summary
+// endpoint (the default) or the synced assignments
+func WithStatisticsSource(source StatisticsSource) Option {
+	return func(s *Service) {
+		s.statisticsSource = source
+	}
+}
+
+// WithConditionalSync makes SyncAll start with a pre-flight fetch of the cheap /summary
+// endpoint and skip the subject, assignment, review and statistics fetches when its
+// data_updated_at is no newer than the last sync of every data type
+func WithConditionalSync(enabled bool) Option {
+	return func(s *Service) {
+		s.conditionalSync = enabled
+	}
+}
+
+// WithInitialSnapshot makes SyncAll create today's assignment snapshot right after the
+// assignments phase when no snapshots exist yet, so a fresh deployment has chart data
+// even if the later phases fail or take long
+func WithInitialSnapshot(enabled bool) Option {
+	return func(s *Service) {
+		s.initialSnapshot = enabled
+	}
+}
+
+// WithInitialReviewSyncDays limits the first reviews sync (no previous sync time) to
+// reviews updated in the last days days, so a new install does not page through years of
+// history. Later syncs are incremental from that sync, so older reviews are never fetched.
+// Zero or less fetches the full history.
+func WithInitialReviewSyncDays(days int) Option {
+	return func(s *Service) {
+		if days > 0 {
+			s.initialReviewWindow = time.Duration(days) * 24 * time.Hour
+		}
+	}
+}
+
+// WithSyncOverlap re-fetches records updated up to overlap before the last sync time on
+// incremental syncs, catching updates that WaniKani made visible late or that were missed
+// through clock skew. Upserts are idempotent, so the re-fetched records are harmless.
+func WithSyncOverlap(overlap time.Duration) Option {
+	return func(s *Service) {
+		if overlap > 0 {
+			s.syncOverlap = overlap
+		}
+	}
+}
+
+// WithLevelUpWebhook POSTs a LevelUpEvent to url whenever a sync detects that the
+// learner's level increased. An empty url disables the notification.
+func WithLevelUpWebhook(url string) Option {
+	return func(s *Service) {
+		s.levelUpWebhook = newWebhook(url, s.logger)
+	}
+}
+
+// WithSyncWebhook POSTs a SyncCompletedEvent to url in the background after every
+// SyncAll run, successful or not. An empty url disables the notification.
+func WithSyncWebhook(url string) Option {
+	return func(s *Service) {
+		s.syncWebhook = newWebhook(url, s.logger)
+	}
 }
 
 // NewService creates a new sync service
-func NewService(client domain.WaniKaniClient, store domain.DataStore, logger *logrus.Logger) *Service {
-	return &Service{
-		client:  client,
-		store:   store,
-		logger:  logger,
-		syncing: false,
+func NewService(client domain.WaniKaniClient, store domain.DataStore, logger *logrus.Logger, opts ...Option) *Service {
+	s := &Service{
+		client:           client,
+		store:            store,
+		logger:           logger,
+		syncing:          false,
+		statisticsSource: StatisticsFromSummary,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 // IsSyncing returns true if a sync operation is currently in progress
@@ -36,28 +184,69 @@ func (s *Service) IsSyncing() bool {
 	return s.syncing
 }
 
-// setSyncing sets the syncing flag
+// SyncStartedAt returns when the sync in progress started, or nil if no sync is running
+func (s *Service) SyncStartedAt() *time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.syncing {
+		return nil
+	}
+	startedAt := s.syncStartedAt
+	return &startedAt
+}
+
+// setSyncing sets the syncing flag, recording the start time when a sync begins
 func (s *Service) setSyncing(syncing bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.syncing = syncing
+	if syncing {
+		s.syncStartedAt = time.Now().UTC()
+	} else {
+		s.syncStartedAt = time.Time{}
+	}
+}
+
+// updatedAfter returns the time to fetch dataType changes after: its last sync time
+// moved back by the configured overlap, or nil if it was never synced
+func (s *Service) updatedAfter(ctx context.Context, dataType domain.DataType) (*time.Time, error) {
+	lastSyncTime, err := s.store.GetLastSyncTime(ctx, dataType)
+	if err != nil || lastSyncTime == nil {
+		return lastSyncTime, err
+	}
+	after := lastSyncTime.Add(-s.syncOverlap)
+	return &after, nil
 }
 
 // SyncAll performs a full sync of all data types in the correct order
-func (s *Service) SyncAll(ctx context.Context) ([]domain.SyncResult, error) {
+func (s *Service) SyncAll(ctx context.Context) (results []domain.SyncResult, err error) {
 	// Prevent concurrent syncs
 	if s.IsSyncing() {
 		s.logger.Warn("Sync already in progress, rejecting concurrent sync request")
-		return nil, fmt.Errorf("sync already in progress")
+		return nil, domain.ErrSyncInProgress
 	}
 
 	s.logger.Info("Starting full sync operation")
 	s.setSyncing(true)
 	defer s.setSyncing(false)
+	defer func() {
+		s.notifySyncCompleted(results, err)
+	}()
 
-	var results []domain.SyncResult
+	// 0. Skip everything if WaniKani has nothing new since the last sync
+	if s.conditionalSync {
+		unchanged, err := s.unchangedSinceLastSync(ctx)
+		if err != nil {
+			s.logger.WithError(err).Warn("Pre-flight change check failed, running full sync")
+		} else if unchanged {
+			s.logger.Info("No changes on WaniKani since the last sync, skipping fetches")
+			results = unchangedResults()
+			s.createAssignmentSnapshotAfterSync(ctx)
+			return results, nil
+		}
+	}
 
-	// Sync in order: subjects → assignments → reviews → statistics
+	// Sync in order: subjects → level progressions → assignments → reviews → statistics
 	// This maintains referential integrity
 
 	// 1. Sync subjects
@@ -69,12 +258,37 @@ func (s *Service) SyncAll(ctx context.Context) ([]domain.SyncResult, error) {
 			"data_type": subjectsResult.DataType,
 			"error":     subjectsResult.Error,
 		}).Error("Subjects sync failed")
-		return results, fmt.Errorf("subjects sync failed: %s", subjectsResult.Error)
+		return results, fmt.Errorf("subjects sync failed: %w", subjectsResult.Err)
+	}
+	s.logger.WithFields(logrus.Fields{
+		"records_updated": subjectsResult.RecordsUpdated,
+		"total_records":   subjectsResult.TotalRecords,
+	}).Info("Subjects sync completed successfully")
+
+	if err := s.stopIfCancelled(ctx, domain.DataTypeLevelProgressions); err != nil {
+		return results, err
+	}
+
+	// 2. Sync level progressions
+	s.logger.Info("Syncing level progressions...")
+	levelProgressionsResult := s.SyncLevelProgressions(ctx)
+	results = append(results, levelProgressionsResult)
+	if !levelProgressionsResult.Success {
+		s.logger.WithFields(logrus.Fields{
+			"data_type": levelProgressionsResult.DataType,
+			"error":     levelProgressionsResult.Error,
+		}).Error("Level progressions sync failed")
+		return results, fmt.Errorf("level progressions sync failed: %w", levelProgressionsResult.Err)
+	}
+	s.logger.WithField("records_updated", levelProgressionsResult.RecordsUpdated).Info("Level progressions sync completed successfully")
+
+	if err := s.stopIfCancelled(ctx, domain.DataTypeAssignments); err != nil {
+		return results, err
 	}
-	s.logger.WithField("records_updated", subjectsResult.RecordsUpdated).Info("Subjects sync completed successfully")
 
-	// 2. Sync assignments
+	// 3. Sync assignments
 	s.logger.Info("Syncing assignments...")
+	previousLevel := s.currentLevel(ctx)
 	assignmentsResult := s.SyncAssignments(ctx)
 	results = append(results, assignmentsResult)
 	if !assignmentsResult.Success {
@@ -82,11 +296,20 @@ func (s *Service) SyncAll(ctx context.Context) ([]domain.SyncResult, error) {
 			"data_type": assignmentsResult.DataType,
 			"error":     assignmentsResult.Error,
 		}).Error("Assignments sync failed")
-		return results, fmt.Errorf("assignments sync failed: %s", assignmentsResult.Error)
+		return results, fmt.Errorf("assignments sync failed: %w", assignmentsResult.Err)
 	}
-	s.logger.WithField("records_updated", assignmentsResult.RecordsUpdated).Info("Assignments sync completed successfully")
+	s.logger.WithFields(logrus.Fields{
+		"records_updated": assignmentsResult.RecordsUpdated,
+		"total_records":   assignmentsResult.TotalRecords,
+	}).Info("Assignments sync completed successfully")
+	s.notifyLevelUp(ctx, previousLevel)
+	s.createInitialSnapshot(ctx)
 
-	// 3. Sync reviews
+	if err := s.stopIfCancelled(ctx, domain.DataTypeReviews); err != nil {
+		return results, err
+	}
+
+	// 4. Sync reviews
 	s.logger.Info("Syncing reviews...")
 	reviewsResult := s.SyncReviews(ctx)
 	results = append(results, reviewsResult)
@@ -95,35 +318,119 @@ func (s *Service) SyncAll(ctx context.Context) ([]domain.SyncResult, error) {
 			"data_type": reviewsResult.DataType,
 			"error":     reviewsResult.Error,
 		}).Error("Reviews sync failed")
-		return results, fmt.Errorf("reviews sync failed: %s", reviewsResult.Error)
+		return results, fmt.Errorf("reviews sync failed: %w", reviewsResult.Err)
 	}
-	s.logger.WithField("records_updated", reviewsResult.RecordsUpdated).Info("Reviews sync completed successfully")
+	s.logger.WithFields(logrus.Fields{
+		"records_updated": reviewsResult.RecordsUpdated,
+		"total_records":   reviewsResult.TotalRecords,
+	}).Info("Reviews sync completed successfully")
 
-	// 4. Sync statistics
-	s.logger.Info("Syncing statistics...")
-	statisticsResult := s.SyncStatistics(ctx)
-	results = append(results, statisticsResult)
-	if !statisticsResult.Success {
-		s.logger.WithFields(logrus.Fields{
-			"data_type": statisticsResult.DataType,
-			"error":     statisticsResult.Error,
-		}).Error("Statistics sync failed")
-		return results, fmt.Errorf("statistics sync failed: %s", statisticsResult.Error)
+	if err := s.stopIfCancelled(ctx, domain.DataTypeStatistics); err != nil {
+		return results, err
+	}
+
+	// 5. Sync statistics
+	if s.skipStatistics {
+		s.logger.Info("Statistics sync disabled, skipping")
+		results = append(results, domain.SyncResult{
+			DataType:  domain.DataTypeStatistics,
+			Success:   true,
+			Skipped:   true,
+			Timestamp: time.Now(),
+		})
+	} else {
+		s.logger.Info("Syncing statistics...")
+		statisticsResult := s.SyncStatistics(ctx)
+		results = append(results, statisticsResult)
+		if !statisticsResult.Success {
+			s.logger.WithFields(logrus.Fields{
+				"data_type": statisticsResult.DataType,
+				"error":     statisticsResult.Error,
+			}).Error("Statistics sync failed")
+			return results, fmt.Errorf("statistics sync failed: %w", statisticsResult.Err)
+		}
+		s.logger.WithField("records_updated", statisticsResult.RecordsUpdated).Info("Statistics sync completed successfully")
+	}
+
+	// A sync cancelled during the last phase did not complete, so it gets no snapshot
+	if err := ctx.Err(); err != nil {
+		return results, fmt.Errorf("sync cancelled: %w", err)
 	}
-	s.logger.WithField("records_updated", statisticsResult.RecordsUpdated).Info("Statistics sync completed successfully")
 
 	s.logger.WithField("total_results", len(results)).Info("Full sync operation completed successfully")
 
-	// 5. Create assignment snapshot after successful sync
+	// 6. Create assignment snapshot after successful sync
+	s.createAssignmentSnapshotAfterSync(ctx)
+
+	return results, nil
+}
+
+// stopIfCancelled returns an error if ctx was cancelled before SyncAll starts the next
+// phase, so that an interrupted sync stops instead of running the remaining phases
+func (s *Service) stopIfCancelled(ctx context.Context, next domain.DataType) error {
+	if err := ctx.Err(); err != nil {
+		s.logger.WithField("next_data_type", next).Warn("Sync cancelled, skipping remaining phases")
+		return fmt.Errorf("sync cancelled before %s: %w", next, err)
+	}
+	return nil
+}
+
+// cancelled records a cancelled ctx as the failure of result, so that a phase interrupted
+// between fetching and storing neither stores partial data nor advances its last sync time
+func (s *Service) cancelled(ctx context.Context, result *domain.SyncResult) bool {
+	if err := ctx.Err(); err != nil {
+		result.SetError(fmt.Errorf("%s sync cancelled: %w", result.DataType, err))
+		s.logger.WithField("data_type", result.DataType).Warn("Sync cancelled, last sync time not advanced")
+		return true
+	}
+	return false
+}
+
+// createAssignmentSnapshotAfterSync creates today's assignment snapshot at the end of a
+// successful SyncAll. A failure is logged but doesn't fail the sync.
+func (s *Service) createAssignmentSnapshotAfterSync(ctx context.Context) {
 	s.logger.Info("Creating assignment snapshot...")
 	if err := s.CreateAssignmentSnapshot(ctx); err != nil {
-		// Log the error but don't fail the entire sync
 		s.logger.WithError(err).Warn("Failed to create assignment snapshot, but sync completed successfully")
 	} else {
 		s.logger.Info("Assignment snapshot created successfully")
 	}
+}
 
-	return results, nil
+// unchangedSinceLastSync fetches the summary and reports whether its data_updated_at is
+// no later than the last sync of subjects, level progressions, assignments and reviews. A
+// data type that has never been synced always counts as changed.
+func (s *Service) unchangedSinceLastSync(ctx context.Context) (bool, error) {
+	statistics, err := s.client.FetchStatistics(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch summary: %w", err)
+	}
+	if statistics == nil || statistics.DataUpdatedAt.IsZero() {
+		return false, nil
+	}
+
+	for _, dataType := range []domain.DataType{domain.DataTypeSubjects, domain.DataTypeLevelProgressions, domain.DataTypeAssignments, domain.DataTypeReviews} {
+		lastSync, err := s.store.GetLastSyncTime(ctx, dataType)
+		if err != nil {
+			return false, fmt.Errorf("failed to get last sync time for %s: %w", dataType, err)
+		}
+		if lastSync == nil || statistics.DataUpdatedAt.After(*lastSync) {
+			return false, nil
+		}
+	}
+
+	s.logger.WithField("data_updated_at", statistics.DataUpdatedAt.Format(time.RFC3339)).Debug("Summary unchanged since last sync")
+	return true, nil
+}
+
+// unchangedResults returns the SyncAll results for a sync skipped by the pre-flight check
+func unchangedResults() []domain.SyncResult {
+	now := time.Now()
+	results := make([]domain.SyncResult, len(domain.DataTypes))
+	for i, dataType := range domain.DataTypes {
+		results[i] = domain.SyncResult{DataType: dataType, Success: true, Skipped: true, Timestamp: now}
+	}
+	return results
 }
 
 // SyncSubjects syncs only subjects
@@ -135,9 +442,9 @@ func (s *Service) SyncSubjects(ctx context.Context) domain.SyncResult {
 	}
 
 	// Get last sync time for incremental updates
-	lastSyncTime, err := s.store.GetLastSyncTime(ctx, domain.DataTypeSubjects)
+	lastSyncTime, err := s.updatedAfter(ctx, domain.DataTypeSubjects)
 	if err != nil {
-		result.Error = fmt.Sprintf("failed to get last sync time: %v", err)
+		result.SetError(fmt.Errorf("failed to get last sync time: %w", err))
 		s.logger.WithError(err).Error("Failed to get last sync time for subjects")
 		return result
 	}
@@ -148,33 +455,116 @@ func (s *Service) SyncSubjects(ctx context.Context) domain.SyncResult {
 		s.logger.Debug("Performing full sync for subjects (no previous sync time)")
 	}
 
-	// Fetch subjects from API
-	subjects, err := s.client.FetchSubjects(ctx, lastSyncTime)
+	// Fetch subjects from API. Hidden subjects are included so that a subject WaniKani
+	// hides after it was stored gets its hidden_at instead of keeping a stale copy.
+	subjects, err := s.client.FetchSubjectsWithOptions(ctx, domain.FetchOptions{UpdatedAfter: lastSyncTime, IncludeHidden: true})
 	if err != nil {
-		result.Error = fmt.Sprintf("failed to fetch subjects: %v", err)
+		result.SetError(fmt.Errorf("failed to fetch subjects: %w", err))
 		s.logger.WithError(err).Error("Failed to fetch subjects from API")
 		return result
 	}
 
 	s.logger.WithField("count", len(subjects)).Debug("Fetched subjects from API")
 
+	subjects, result.InvalidRecords, err = validRecords(s, domain.DataTypeSubjects, subjects)
+	if err != nil {
+		result.SetError(err)
+		return result
+	}
+
+	if s.cancelled(ctx, &result) {
+		return result
+	}
+
 	// Store subjects
 	if len(subjects) > 0 {
 		if err := s.store.UpsertSubjects(ctx, subjects); err != nil {
-			result.Error = fmt.Sprintf("failed to store subjects: %v", err)
+			result.SetError(fmt.Errorf("failed to store subjects: %w", err))
 			s.logger.WithError(err).Error("Failed to store subjects in database")
 			return result
 		}
 	}
 
+	if s.cancelled(ctx, &result) {
+		return result
+	}
+
 	// Update last sync time
 	if err := s.store.SetLastSyncTime(ctx, domain.DataTypeSubjects, result.Timestamp); err != nil {
-		result.Error = fmt.Sprintf("failed to update sync time: %v", err)
+		result.SetError(fmt.Errorf("failed to update sync time: %w", err))
 		s.logger.WithError(err).Error("Failed to update last sync time for subjects")
 		return result
 	}
 
 	result.RecordsUpdated = len(subjects)
+	result.TotalRecords = s.countRecords(ctx, domain.DataTypeSubjects, s.store.CountSubjects)
+	result.Success = true
+	return result
+}
+
+// SyncLevelProgressions syncs only level progressions
+func (s *Service) SyncLevelProgressions(ctx context.Context) domain.SyncResult {
+	result := domain.SyncResult{
+		DataType:  domain.DataTypeLevelProgressions,
+		Timestamp: time.Now(),
+		Success:   false,
+	}
+
+	// Get last sync time for incremental updates
+	lastSyncTime, err := s.updatedAfter(ctx, domain.DataTypeLevelProgressions)
+	if err != nil {
+		result.SetError(fmt.Errorf("failed to get last sync time: %w", err))
+		s.logger.WithError(err).Error("Failed to get last sync time for level progressions")
+		return result
+	}
+
+	if lastSyncTime != nil {
+		s.logger.WithField("updated_after", lastSyncTime.Format(time.RFC3339)).Debug("Performing incremental sync for level progressions")
+	} else {
+		s.logger.Debug("Performing full sync for level progressions (no previous sync time)")
+	}
+
+	// Fetch level progressions from API
+	progressions, err := s.client.FetchLevelProgressions(ctx, lastSyncTime)
+	if err != nil {
+		result.SetError(fmt.Errorf("failed to fetch level progressions: %w", err))
+		s.logger.WithError(err).Error("Failed to fetch level progressions from API")
+		return result
+	}
+
+	s.logger.WithField("count", len(progressions)).Debug("Fetched level progressions from API")
+
+	progressions, result.InvalidRecords, err = validRecords(s, domain.DataTypeLevelProgressions, progressions)
+	if err != nil {
+		result.SetError(err)
+		return result
+	}
+
+	if s.cancelled(ctx, &result) {
+		return result
+	}
+
+	// Store level progressions
+	if len(progressions) > 0 {
+		if err := s.store.UpsertLevelProgressions(ctx, progressions); err != nil {
+			result.SetError(fmt.Errorf("failed to store level progressions: %w", err))
+			s.logger.WithError(err).Error("Failed to store level progressions in database")
+			return result
+		}
+	}
+
+	if s.cancelled(ctx, &result) {
+		return result
+	}
+
+	// Update last sync time
+	if err := s.store.SetLastSyncTime(ctx, domain.DataTypeLevelProgressions, result.Timestamp); err != nil {
+		result.SetError(fmt.Errorf("failed to update sync time: %w", err))
+		s.logger.WithError(err).Error("Failed to update last sync time for level progressions")
+		return result
+	}
+
+	result.RecordsUpdated = len(progressions)
 	result.Success = true
 	return result
 }
@@ -188,9 +578,9 @@ func (s *Service) SyncAssignments(ctx context.Context) domain.SyncResult {
 	}
 
 	// Get last sync time for incremental updates
-	lastSyncTime, err := s.store.GetLastSyncTime(ctx, domain.DataTypeAssignments)
+	lastSyncTime, err := s.updatedAfter(ctx, domain.DataTypeAssignments)
 	if err != nil {
-		result.Error = fmt.Sprintf("failed to get last sync time: %v", err)
+		result.SetError(fmt.Errorf("failed to get last sync time: %w", err))
 		s.logger.WithError(err).Error("Failed to get last sync time for assignments")
 		return result
 	}
@@ -204,30 +594,89 @@ func (s *Service) SyncAssignments(ctx context.Context) domain.SyncResult {
 	// Fetch assignments from API
 	assignments, err := s.client.FetchAssignments(ctx, lastSyncTime)
 	if err != nil {
-		result.Error = fmt.Sprintf("failed to fetch assignments: %v", err)
+		result.SetError(fmt.Errorf("failed to fetch assignments: %w", err))
 		s.logger.WithError(err).Error("Failed to fetch assignments from API")
 		return result
 	}
 
 	s.logger.WithField("count", len(assignments)).Debug("Fetched assignments from API")
 
+	assignments, result.InvalidRecords, err = validRecords(s, domain.DataTypeAssignments, assignments)
+	if err != nil {
+		result.SetError(err)
+		return result
+	}
+
+	if s.cancelled(ctx, &result) {
+		return result
+	}
+
 	// Store assignments
 	if len(assignments) > 0 {
 		if err := s.store.UpsertAssignments(ctx, assignments); err != nil {
-			result.Error = fmt.Sprintf("failed to store assignments: %v", err)
+			result.SetError(fmt.Errorf("failed to store assignments: %w", err))
 			s.logger.WithError(err).Error("Failed to store assignments in database")
 			return result
 		}
 	}
 
+	if s.cancelled(ctx, &result) {
+		return result
+	}
+
 	// Update last sync time
 	if err := s.store.SetLastSyncTime(ctx, domain.DataTypeAssignments, result.Timestamp); err != nil {
-		result.Error = fmt.Sprintf("failed to update sync time: %v", err)
+		result.SetError(fmt.Errorf("failed to update sync time: %w", err))
 		s.logger.WithError(err).Error("Failed to update last sync time for assignments")
 		return result
 	}
 
 	result.RecordsUpdated = len(assignments)
+	result.TotalRecords = s.countRecords(ctx, domain.DataTypeAssignments, s.store.CountAssignments)
+	result.Success = true
+	return result
+}
+
+// SyncAvailableAssignments is a lightweight refresh of just the assignments that are
+// immediately available for review. Becoming reviewable does not change an assignment's
+// data_updated_at, so the fetch ignores the last sync time; it also leaves the last sync
+// time alone, since the other assignments were not checked.
+func (s *Service) SyncAvailableAssignments(ctx context.Context) domain.SyncResult {
+	result := domain.SyncResult{
+		DataType:  domain.DataTypeAssignments,
+		Timestamp: time.Now(),
+		Success:   false,
+	}
+
+	assignments, err := s.client.FetchAssignmentsWithOptions(ctx, domain.FetchOptions{ImmediatelyAvailableForReview: true})
+	if err != nil {
+		result.SetError(fmt.Errorf("failed to fetch available assignments: %w", err))
+		s.logger.WithError(err).Error("Failed to fetch available assignments from API")
+		return result
+	}
+
+	s.logger.WithField("count", len(assignments)).Debug("Fetched available assignments from API")
+
+	assignments, result.InvalidRecords, err = validRecords(s, domain.DataTypeAssignments, assignments)
+	if err != nil {
+		result.SetError(err)
+		return result
+	}
+
+	if s.cancelled(ctx, &result) {
+		return result
+	}
+
+	if len(assignments) > 0 {
+		if err := s.store.UpsertAssignments(ctx, assignments); err != nil {
+			result.SetError(fmt.Errorf("failed to store assignments: %w", err))
+			s.logger.WithError(err).Error("Failed to store available assignments in database")
+			return result
+		}
+	}
+
+	result.RecordsUpdated = len(assignments)
+	result.TotalRecords = s.countRecords(ctx, domain.DataTypeAssignments, s.store.CountAssignments)
 	result.Success = true
 	return result
 }
@@ -241,50 +690,115 @@ func (s *Service) SyncReviews(ctx context.Context) domain.SyncResult {
 	}
 
 	// Get last sync time for incremental updates
-	lastSyncTime, err := s.store.GetLastSyncTime(ctx, domain.DataTypeReviews)
+	lastSyncTime, err := s.updatedAfter(ctx, domain.DataTypeReviews)
 	if err != nil {
-		result.Error = fmt.Sprintf("failed to get last sync time: %v", err)
+		result.SetError(fmt.Errorf("failed to get last sync time: %w", err))
 		s.logger.WithError(err).Error("Failed to get last sync time for reviews")
 		return result
 	}
 
+	updatedAfter := lastSyncTime
 	if lastSyncTime != nil {
 		s.logger.WithField("updated_after", lastSyncTime.Format(time.RFC3339)).Debug("Performing incremental sync for reviews")
+	} else if s.initialReviewWindow > 0 {
+		windowStart := result.Timestamp.Add(-s.initialReviewWindow)
+		updatedAfter = &windowStart
+		s.logger.WithField("updated_after", windowStart.Format(time.RFC3339)).Info("Performing initial reviews sync bounded to the configured window")
 	} else {
 		s.logger.Debug("Performing full sync for reviews (no previous sync time)")
 	}
 
 	// Fetch reviews from API
-	reviews, err := s.client.FetchReviews(ctx, lastSyncTime)
+	reviews, err := s.client.FetchReviews(ctx, updatedAfter)
 	if err != nil {
-		result.Error = fmt.Sprintf("failed to fetch reviews: %v", err)
+		result.SetError(fmt.Errorf("failed to fetch reviews: %w", err))
 		s.logger.WithError(err).Error("Failed to fetch reviews from API")
 		return result
 	}
 
 	s.logger.WithField("count", len(reviews)).Debug("Fetched reviews from API")
 
+	reviews, result.InvalidRecords, err = validRecords(s, domain.DataTypeReviews, reviews)
+	if err != nil {
+		result.SetError(err)
+		return result
+	}
+
+	if s.cancelled(ctx, &result) {
+		return result
+	}
+
 	// Store reviews
 	if len(reviews) > 0 {
 		if err := s.store.UpsertReviews(ctx, reviews); err != nil {
-			result.Error = fmt.Sprintf("failed to store reviews: %v", err)
+			result.SetError(fmt.Errorf("failed to store reviews: %w", err))
 			s.logger.WithError(err).Error("Failed to store reviews in database")
 			return result
 		}
 	}
 
+	if s.cancelled(ctx, &result) {
+		return result
+	}
+
 	// Update last sync time
 	if err := s.store.SetLastSyncTime(ctx, domain.DataTypeReviews, result.Timestamp); err != nil {
-		result.Error = fmt.Sprintf("failed to update sync time: %v", err)
+		result.SetError(fmt.Errorf("failed to update sync time: %w", err))
 		s.logger.WithError(err).Error("Failed to update last sync time for reviews")
 		return result
 	}
 
 	result.RecordsUpdated = len(reviews)
+	result.TotalRecords = s.countRecords(ctx, domain.DataTypeReviews, s.store.CountReviews)
 	result.Success = true
 	return result
 }
 
+// validRecords validates fetched records according to the service's validation mode. It
+// returns the records to store and the number of invalid records dropped; in strict mode
+// an invalid record is returned as an error instead.
+func validRecords[T interface{ Validate() error }](s *Service, dataType domain.DataType, records []T) ([]T, int, error) {
+	if s.validation == "" || s.validation == ValidationOff {
+		return records, 0, nil
+	}
+
+	valid := make([]T, 0, len(records))
+	invalid := 0
+	for _, record := range records {
+		if err := record.Validate(); err != nil {
+			if s.validation == ValidationStrict {
+				s.logger.WithError(err).WithField("data_type", dataType).Error("Fetched record failed validation")
+				return nil, 0, fmt.Errorf("failed to validate %s: %w", dataType, err)
+			}
+			s.logger.WithError(err).WithField("data_type", dataType).Warn("Skipping invalid fetched record")
+			invalid++
+			continue
+		}
+		valid = append(valid, record)
+	}
+
+	if invalid > 0 {
+		s.logger.WithFields(logrus.Fields{
+			"data_type": dataType,
+			"invalid":   invalid,
+			"stored":    len(valid),
+		}).Warn("Skipped invalid records")
+	}
+
+	return valid, invalid, nil
+}
+
+// countRecords returns the stored row count for a data type after its sync. A failed
+// count is only logged, since the count is informational and the sync itself succeeded.
+func (s *Service) countRecords(ctx context.Context, dataType domain.DataType, count func(context.Context) (int, error)) int {
+	total, err := count(ctx)
+	if err != nil {
+		s.logger.WithError(err).WithField("data_type", dataType).Warn("Failed to count stored records")
+		return 0
+	}
+	return total
+}
+
 // SyncStatistics syncs only statistics
 func (s *Service) SyncStatistics(ctx context.Context) domain.SyncResult {
 	result := domain.SyncResult{
@@ -293,38 +807,100 @@ func (s *Service) SyncStatistics(ctx context.Context) domain.SyncResult {
 		Success:   false,
 	}
 
-	s.logger.Debug("Fetching statistics snapshot from API")
+	s.logger.WithField("source", s.statisticsSource).Debug("Fetching statistics snapshot")
 
-	// Fetch statistics from API
-	statistics, err := s.client.FetchStatistics(ctx)
+	// Fetch statistics from the configured source
+	statistics, err := s.fetchStatistics(ctx, result.Timestamp)
 	if err != nil {
-		result.Error = fmt.Sprintf("failed to fetch statistics: %v", err)
-		s.logger.WithError(err).Error("Failed to fetch statistics from API")
+		result.SetError(fmt.Errorf("failed to fetch statistics: %w", err))
+		s.logger.WithError(err).Error("Failed to fetch statistics")
+		return result
+	}
+
+	// No summary at all is not a failure, but there is nothing to record either
+	if statistics == nil {
+		s.logger.Warn("Statistics summary returned no data, skipping")
+		result.Success = true
+		result.Skipped = true
+		return result
+	}
+
+	// Skip storing a snapshot that is identical to the latest one
+	if s.dedupStatistics {
+		duplicate, err := s.isDuplicateStatistics(ctx, *statistics)
+		if err != nil {
+			result.SetError(fmt.Errorf("failed to compare statistics: %w", err))
+			s.logger.WithError(err).Error("Failed to compare statistics with latest snapshot")
+			return result
+		}
+		if duplicate {
+			s.logger.Debug("Statistics unchanged since latest snapshot, skipping insert")
+			statistics = nil
+		}
+	}
+
+	if s.cancelled(ctx, &result) {
 		return result
 	}
 
 	// Store statistics snapshot
 	if statistics != nil {
 		if err := s.store.InsertStatistics(ctx, *statistics, result.Timestamp); err != nil {
-			result.Error = fmt.Sprintf("failed to store statistics: %v", err)
+			result.SetError(fmt.Errorf("failed to store statistics: %w", err))
 			s.logger.WithError(err).Error("Failed to store statistics in database")
 			return result
 		}
 		s.logger.Debug("Statistics snapshot stored successfully")
+		result.RecordsUpdated = 1
+
+		// Apply the retention policy; a failure here doesn't invalidate the sync
+		if s.statisticsKeepRecent > 0 {
+			pruned, err := s.store.PruneStatistics(ctx, s.statisticsKeepRecent)
+			if err != nil {
+				s.logger.WithError(err).Warn("Failed to prune statistics snapshots")
+			} else if pruned > 0 {
+				s.logger.WithField("pruned", pruned).Info("Pruned statistics snapshots")
+			}
+		}
+	}
+
+	if s.cancelled(ctx, &result) {
+		return result
 	}
 
 	// Update last sync time
 	if err := s.store.SetLastSyncTime(ctx, domain.DataTypeStatistics, result.Timestamp); err != nil {
-		result.Error = fmt.Sprintf("failed to update sync time: %v", err)
+		result.SetError(fmt.Errorf("failed to update sync time: %w", err))
 		s.logger.WithError(err).Error("Failed to update last sync time for statistics")
 		return result
 	}
 
-	result.RecordsUpdated = 1
 	result.Success = true
 	return result
 }
 
+// isDuplicateStatistics reports whether stats marshals to the same JSON as the latest stored snapshot
+func (s *Service) isDuplicateStatistics(ctx context.Context, stats domain.Statistics) (bool, error) {
+	latest, err := s.store.GetLatestStatistics(ctx)
+	if err != nil {
+		return false, err
+	}
+	if latest == nil {
+		return false, nil
+	}
+
+	current, err := json.Marshal(stats)
+	if err != nil {
+		return false, err
+	}
+	previous, err := json.Marshal(latest.Statistics)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(current, previous), nil
+}
+
 // CreateAssignmentSnapshot creates a daily snapshot of assignment distribution by SRS stage and subject type
 func (s *Service) CreateAssignmentSnapshot(ctx context.Context) error {
 	s.logger.Debug("Calculating assignment snapshot for today")
@@ -350,3 +926,133 @@ func (s *Service) CreateAssignmentSnapshot(ctx context.Context) error {
 	s.logger.WithField("date", today.Format("2006-01-02")).Info("Assignment snapshot created successfully")
 	return nil
 }
+
+// createInitialSnapshot creates today's assignment snapshot if initial snapshots are
+// enabled and none have been stored yet. Failures are logged, not returned.
+func (s *Service) createInitialSnapshot(ctx context.Context) {
+	if !s.initialSnapshot {
+		return
+	}
+
+	count, err := s.store.CountAssignmentSnapshots(ctx)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to count assignment snapshots, skipping initial snapshot")
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	s.logger.Info("No assignment snapshots yet, creating initial snapshot")
+	if err := s.CreateAssignmentSnapshot(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to create initial assignment snapshot")
+	}
+}
+
+// LevelUpEvent is the payload sent to the level-up webhook
+type LevelUpEvent struct {
+	Event     string    `json:"event"`
+	OldLevel  int       `json:"old_level"`
+	NewLevel  int       `json:"new_level"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// currentLevel returns the stored current level, or 0 if it is unknown or level-up
+// notifications are disabled
+func (s *Service) currentLevel(ctx context.Context) int {
+	if s.levelUpWebhook == nil {
+		return 0
+	}
+
+	level, err := s.store.GetCurrentLevel(ctx)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to read current level")
+		return 0
+	}
+	return level
+}
+
+// notifyLevelUp sends a level-up notification if the current level rose above
+// previousLevel. Nothing is sent on the initial sync, when previousLevel is 0.
+func (s *Service) notifyLevelUp(ctx context.Context, previousLevel int) {
+	if s.levelUpWebhook == nil || previousLevel == 0 {
+		return
+	}
+
+	level := s.currentLevel(ctx)
+	if level <= previousLevel {
+		return
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"old_level": previousLevel,
+		"new_level": level,
+	}).Info("Level up detected")
+
+	event := LevelUpEvent{
+		Event:     "level_up",
+		OldLevel:  previousLevel,
+		NewLevel:  level,
+		Timestamp: time.Now().UTC(),
+	}
+	if err := s.levelUpWebhook.send(ctx, event.Event, event); err != nil {
+		s.logger.WithError(err).Error("Failed to deliver level-up webhook")
+	}
+}
+
+// WaitForWebhooks blocks until background webhook deliveries have finished or ctx is
+// done, in which case it returns ctx.Err(). Call it on shutdown so a sync finishing just
+// before does not lose its notification.
+func (s *Service) WaitForWebhooks(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.webhooks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SyncCompletedEvent is the payload sent to the sync webhook
+type SyncCompletedEvent struct {
+	Event     string              `json:"event"`
+	Success   bool                `json:"success"`
+	Error     string              `json:"error,omitempty"`
+	Results   []domain.SyncResult `json:"results"`
+	Timestamp time.Time           `json:"timestamp"`
+}
+
+// notifySyncCompleted delivers the SyncAll outcome to the sync webhook in the background
+// so that a slow or unreachable receiver never delays the sync
+func (s *Service) notifySyncCompleted(results []domain.SyncResult, syncErr error) {
+	if s.syncWebhook == nil {
+		return
+	}
+
+	event := SyncCompletedEvent{
+		Event:     "sync_completed",
+		Success:   syncErr == nil,
+		Results:   results,
+		Timestamp: time.Now().UTC(),
+	}
+	if syncErr != nil {
+		event.Error = syncErr.Error()
+	}
+	if event.Results == nil {
+		event.Results = []domain.SyncResult{}
+	}
+
+	s.webhooks.Add(1)
+	go func() {
+		defer s.webhooks.Done()
+		// The sync's context may already be cancelled; delivery is bounded by the webhook timeout
+		if err := s.syncWebhook.send(context.Background(), event.Event, event); err != nil {
+			s.logger.WithError(err).Error("Failed to deliver sync webhook")
+		}
+	}()
+}
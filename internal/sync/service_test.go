@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"io"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/leanovate/gopter/prop"
 	"github.com/sirupsen/logrus"
 	"wanikani-api/internal/domain"
+	"wanikani-api/internal/wanikani"
 )
 
 // testLogger creates a logger for testing that discards output
@@ -24,17 +26,32 @@ func testLogger() *logrus.Logger {
 
 // Mock client for testing
 type mockClient struct {
-	subjects    []domain.Subject
-	assignments []domain.Assignment
-	reviews     []domain.Review
-	statistics  *domain.Statistics
-	fetchError  error
-	delay       time.Duration
+	subjects          []domain.Subject
+	levelProgressions []domain.LevelProgression
+	assignments       []domain.Assignment
+	reviews           []domain.Review
+	statistics        *domain.Statistics
+	fetchError        error
+	reviewError       error
+	delay             time.Duration
+
+	subjectOptions    []domain.FetchOptions
+	assignmentOptions []domain.FetchOptions
+	fetches           map[domain.DataType]int
+}
+
+// countFetch records a fetch of dataType
+func (m *mockClient) countFetch(dataType domain.DataType) {
+	if m.fetches == nil {
+		m.fetches = make(map[domain.DataType]int)
+	}
+	m.fetches[dataType]++
 }
 
 func (m *mockClient) SetAPIToken(token string) {}
 
 func (m *mockClient) FetchSubjects(ctx context.Context, updatedAfter *time.Time) ([]domain.Subject, error) {
+	m.countFetch(domain.DataTypeSubjects)
 	if m.delay > 0 {
 		time.Sleep(m.delay)
 	}
@@ -44,21 +61,45 @@ func (m *mockClient) FetchSubjects(ctx context.Context, updatedAfter *time.Time)
 	return m.subjects, nil
 }
 
+func (m *mockClient) FetchSubjectsWithOptions(ctx context.Context, opts domain.FetchOptions) ([]domain.Subject, error) {
+	m.subjectOptions = append(m.subjectOptions, opts)
+	return m.FetchSubjects(ctx, opts.UpdatedAfter)
+}
+
+func (m *mockClient) FetchLevelProgressions(ctx context.Context, updatedAfter *time.Time) ([]domain.LevelProgression, error) {
+	m.countFetch(domain.DataTypeLevelProgressions)
+	if m.fetchError != nil {
+		return nil, m.fetchError
+	}
+	return m.levelProgressions, nil
+}
+
 func (m *mockClient) FetchAssignments(ctx context.Context, updatedAfter *time.Time) ([]domain.Assignment, error) {
+	m.countFetch(domain.DataTypeAssignments)
 	if m.fetchError != nil {
 		return nil, m.fetchError
 	}
 	return m.assignments, nil
 }
 
+func (m *mockClient) FetchAssignmentsWithOptions(ctx context.Context, opts domain.FetchOptions) ([]domain.Assignment, error) {
+	m.assignmentOptions = append(m.assignmentOptions, opts)
+	return m.FetchAssignments(ctx, opts.UpdatedAfter)
+}
+
 func (m *mockClient) FetchReviews(ctx context.Context, updatedAfter *time.Time) ([]domain.Review, error) {
+	m.countFetch(domain.DataTypeReviews)
 	if m.fetchError != nil {
 		return nil, m.fetchError
 	}
+	if m.reviewError != nil {
+		return nil, m.reviewError
+	}
 	return m.reviews, nil
 }
 
 func (m *mockClient) FetchStatistics(ctx context.Context) (*domain.Statistics, error) {
+	m.countFetch(domain.DataTypeStatistics)
 	if m.fetchError != nil {
 		return nil, m.fetchError
 	}
@@ -77,32 +118,90 @@ type mockStore struct {
 	syncTimeError       error
 	snapshotUpsertError error
 	snapshotCalcError   error
+	statistics          []domain.Statistics
+	pruneKeepRecent     []int
+	levels              []int
+	subjectIDs          map[int64]bool
+	levelProgressionIDs map[int64]bool
+	assignmentIDs       map[int64]bool
+	reviewIDs           map[int64]bool
+	countError          error
+	snapshots           []domain.AssignmentSnapshot
+	assignments         []domain.Assignment
 }
 
 func newMockStore() *mockStore {
 	return &mockStore{
-		lastSyncTimes: make(map[domain.DataType]*time.Time),
+		lastSyncTimes:       make(map[domain.DataType]*time.Time),
+		subjectIDs:          make(map[int64]bool),
+		levelProgressionIDs: make(map[int64]bool),
+		assignmentIDs:       make(map[int64]bool),
+		reviewIDs:           make(map[int64]bool),
 	}
 }
 
 func (m *mockStore) UpsertSubjects(ctx context.Context, subjects []domain.Subject) error {
-	return m.upsertError
+	if m.upsertError != nil {
+		return m.upsertError
+	}
+	for _, subject := range subjects {
+		m.subjectIDs[subject.ID] = true
+	}
+	return nil
 }
 
 func (m *mockStore) GetSubjects(ctx context.Context, filters domain.SubjectFilters) ([]domain.Subject, error) {
 	return nil, nil
 }
 
+func (m *mockStore) StreamSubjects(ctx context.Context, filters domain.SubjectFilters, fn func(domain.Subject) error) error {
+	return nil
+}
+
+func (m *mockStore) UpsertLevelProgressions(ctx context.Context, progressions []domain.LevelProgression) error {
+	if m.upsertError != nil {
+		return m.upsertError
+	}
+	for _, progression := range progressions {
+		m.levelProgressionIDs[progression.ID] = true
+	}
+	return nil
+}
+
+func (m *mockStore) GetLevelProgressions(ctx context.Context) ([]domain.LevelProgression, error) {
+	return nil, nil
+}
+
 func (m *mockStore) UpsertAssignments(ctx context.Context, assignments []domain.Assignment) error {
-	return m.upsertError
+	if m.upsertError != nil {
+		return m.upsertError
+	}
+	for _, assignment := range assignments {
+		m.assignmentIDs[assignment.ID] = true
+	}
+	return nil
 }
 
 func (m *mockStore) GetAssignments(ctx context.Context, filters domain.AssignmentFilters) ([]domain.Assignment, error) {
+	return m.assignments, nil
+}
+
+func (m *mockStore) GetAssignmentsPassedOn(ctx context.Context, date time.Time) ([]domain.Assignment, error) {
+	return nil, nil
+}
+
+func (m *mockStore) GetAvailableLessons(ctx context.Context) ([]domain.Assignment, error) {
 	return nil, nil
 }
 
 func (m *mockStore) UpsertReviews(ctx context.Context, reviews []domain.Review) error {
-	return m.upsertError
+	if m.upsertError != nil {
+		return m.upsertError
+	}
+	for _, review := range reviews {
+		m.reviewIDs[review.ID] = true
+	}
+	return nil
 }
 
 func (m *mockStore) GetReviews(ctx context.Context, filters domain.ReviewFilters) ([]domain.Review, error) {
@@ -110,17 +209,129 @@ func (m *mockStore) GetReviews(ctx context.Context, filters domain.ReviewFilters
 }
 
 func (m *mockStore) InsertStatistics(ctx context.Context, stats domain.Statistics, timestamp time.Time) error {
-	return m.insertError
+	if m.insertError != nil {
+		return m.insertError
+	}
+	m.statistics = append(m.statistics, stats)
+	return nil
 }
 
-func (m *mockStore) GetStatistics(ctx context.Context, dateRange *domain.DateRange) ([]domain.StatisticsSnapshot, error) {
+func (m *mockStore) GetStatistics(ctx context.Context, dateRange *domain.DateRange, opts domain.StatisticsOptions) ([]domain.StatisticsSnapshot, error) {
 	return nil, nil
 }
 
 func (m *mockStore) GetLatestStatistics(ctx context.Context) (*domain.StatisticsSnapshot, error) {
+	if len(m.statistics) == 0 {
+		return nil, nil
+	}
+	return &domain.StatisticsSnapshot{Statistics: m.statistics[len(m.statistics)-1]}, nil
+}
+
+func (m *mockStore) PruneStatistics(ctx context.Context, keepRecent int) (int, error) {
+	m.pruneKeepRecent = append(m.pruneKeepRecent, keepRecent)
+	return 0, nil
+}
+
+func (m *mockStore) GetReviewStreak(ctx context.Context) (int, int, time.Time, error) {
+	return 0, 0, time.Time{}, nil
+}
+
+func (m *mockStore) GetReviewDateRange(ctx context.Context) (*time.Time, *time.Time, error) {
+	return nil, nil, nil
+}
+
+func (m *mockStore) GetSubjectAccuracy(ctx context.Context) ([]domain.SubjectAccuracy, error) {
+	return nil, nil
+}
+
+func (m *mockStore) GetReviewCountsByHour(ctx context.Context, filters domain.ReviewFilters) ([24]int, error) {
+	return [24]int{}, nil
+}
+
+func (m *mockStore) GetReviewCountsByWeek(ctx context.Context, filters domain.ReviewFilters) (map[time.Time]int, error) {
+	return map[time.Time]int{}, nil
+}
+
+func (m *mockStore) GetReviewCountsByDay(ctx context.Context, filters domain.ReviewFilters) (map[time.Time]int, error) {
+	return map[time.Time]int{}, nil
+}
+
+func (m *mockStore) GetReviewCountToday(ctx context.Context) (time.Time, int, error) {
+	return time.Time{}, 0, nil
+}
+
+func (m *mockStore) GetCurrentSRSGroupTotals(ctx context.Context) (map[string]map[string]int, error) {
+	return map[string]map[string]int{}, nil
+}
+
+func (m *mockStore) GetAssignmentCounts(ctx context.Context) (domain.AssignmentCounts, error) {
+	return domain.AssignmentCounts{}, nil
+}
+
+func (m *mockStore) GetSubjectCountsByLevel(ctx context.Context) (map[int]map[string]int, error) {
+	return map[int]map[string]int{}, nil
+}
+
+func (m *mockStore) GetDistinctLevels(ctx context.Context) ([]int, error) {
+	return []int{}, nil
+}
+
+func (m *mockStore) GetLastReviewPerSubject(ctx context.Context, subjectIDs []int64) (map[int64]time.Time, error) {
+	return map[int64]time.Time{}, nil
+}
+
+func (m *mockStore) GetSubjectsByIDs(ctx context.Context, ids []int64) ([]domain.Subject, error) {
+	return nil, nil
+}
+
+func (m *mockStore) GetAssignmentsByIDs(ctx context.Context, ids []int64) ([]domain.Assignment, error) {
 	return nil, nil
 }
 
+func (m *mockStore) Export(ctx context.Context, w io.Writer) error {
+	return nil
+}
+
+func (m *mockStore) Import(ctx context.Context, r io.Reader) (map[string]int, error) {
+	return nil, nil
+}
+
+func (m *mockStore) GetLevelProgress(ctx context.Context, level int) (*domain.LevelProgress, error) {
+	return nil, nil
+}
+
+// GetCurrentLevel returns the queued levels in order, repeating the last one
+func (m *mockStore) CountSubjects(ctx context.Context) (int, error) {
+	return len(m.subjectIDs), m.countError
+}
+
+func (m *mockStore) CountAssignments(ctx context.Context) (int, error) {
+	return len(m.assignmentIDs), m.countError
+}
+
+func (m *mockStore) CountReviews(ctx context.Context) (int, error) {
+	return len(m.reviewIDs), m.countError
+}
+
+func (m *mockStore) CountAssignmentSnapshots(ctx context.Context) (int, error) {
+	return len(m.snapshots), m.countError
+}
+
+func (m *mockStore) GetCurrentLevel(ctx context.Context) (int, error) {
+	if len(m.levels) == 0 {
+		return 0, nil
+	}
+	level := m.levels[0]
+	if len(m.levels) > 1 {
+		m.levels = m.levels[1:]
+	}
+	return level, nil
+}
+
+func (m *mockStore) CheckIntegrity(ctx context.Context, repair bool) (*domain.IntegrityReport, error) {
+	return &domain.IntegrityReport{}, nil
+}
+
 func (m *mockStore) GetLastSyncTime(ctx context.Context, dataType domain.DataType) (*time.Time, error) {
 	if m.syncTimeError != nil {
 		return nil, m.syncTimeError
@@ -136,12 +347,32 @@ func (m *mockStore) SetLastSyncTime(ctx context.Context, dataType domain.DataTyp
 	return nil
 }
 
+func (m *mockStore) ClearSyncTimes(ctx context.Context, dataType *domain.DataType) (int, error) {
+	if m.syncTimeError != nil {
+		return 0, m.syncTimeError
+	}
+	if dataType != nil {
+		if _, ok := m.lastSyncTimes[*dataType]; !ok {
+			return 0, nil
+		}
+		delete(m.lastSyncTimes, *dataType)
+		return 1, nil
+	}
+	cleared := len(m.lastSyncTimes)
+	m.lastSyncTimes = make(map[domain.DataType]*time.Time)
+	return cleared, nil
+}
+
 func (m *mockStore) BeginTx(ctx context.Context) (*sql.Tx, error) {
 	return nil, nil
 }
 
 func (m *mockStore) UpsertAssignmentSnapshot(ctx context.Context, snapshot domain.AssignmentSnapshot) error {
-	return m.snapshotUpsertError
+	if m.snapshotUpsertError != nil {
+		return m.snapshotUpsertError
+	}
+	m.snapshots = append(m.snapshots, snapshot)
+	return nil
 }
 
 func (m *mockStore) GetAssignmentSnapshots(ctx context.Context, dateRange *domain.DateRange) ([]domain.AssignmentSnapshot, error) {
@@ -179,11 +410,24 @@ func (m *mockClientWithTimestampCapture) FetchSubjects(ctx context.Context, upda
 	return m.subjects, nil
 }
 
+func (m *mockClientWithTimestampCapture) FetchSubjectsWithOptions(ctx context.Context, opts domain.FetchOptions) ([]domain.Subject, error) {
+	return m.FetchSubjects(ctx, opts.UpdatedAfter)
+}
+
+func (m *mockClientWithTimestampCapture) FetchLevelProgressions(ctx context.Context, updatedAfter *time.Time) ([]domain.LevelProgression, error) {
+	*m.capturedUpdatedAfter = updatedAfter
+	return nil, nil
+}
+
 func (m *mockClientWithTimestampCapture) FetchAssignments(ctx context.Context, updatedAfter *time.Time) ([]domain.Assignment, error) {
 	*m.capturedUpdatedAfter = updatedAfter
 	return m.assignments, nil
 }
 
+func (m *mockClientWithTimestampCapture) FetchAssignmentsWithOptions(ctx context.Context, opts domain.FetchOptions) ([]domain.Assignment, error) {
+	return m.FetchAssignments(ctx, opts.UpdatedAfter)
+}
+
 func (m *mockClientWithTimestampCapture) FetchReviews(ctx context.Context, updatedAfter *time.Time) ([]domain.Review, error) {
 	*m.capturedUpdatedAfter = updatedAfter
 	return m.reviews, nil
@@ -258,6 +502,30 @@ func TestSyncAssignments_Success(t *testing.T) {
 	}
 }
 
+func TestSyncAvailableAssignments(t *testing.T) {
+	client := &mockClient{assignments: []domain.Assignment{{ID: 1}, {ID: 2}}}
+	store := newMockStore()
+	service := NewService(client, store, testLogger())
+
+	result := service.SyncAvailableAssignments(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got error: %s", result.Error)
+	}
+	if result.RecordsUpdated != 2 {
+		t.Errorf("expected 2 records updated, got %d", result.RecordsUpdated)
+	}
+
+	if len(client.assignmentOptions) != 1 || !client.assignmentOptions[0].ImmediatelyAvailableForReview {
+		t.Errorf("expected a fetch limited to available assignments, got %+v", client.assignmentOptions)
+	}
+	if client.assignmentOptions[0].UpdatedAfter != nil {
+		t.Error("expected available assignments to be fetched regardless of the last sync time")
+	}
+	if store.lastSyncTimes[domain.DataTypeAssignments] != nil {
+		t.Error("expected last sync time for assignments to be left unchanged")
+	}
+}
+
 func TestSyncReviews_Success(t *testing.T) {
 	client := &mockClient{
 		reviews: []domain.Review{
@@ -298,6 +566,209 @@ func TestSyncStatistics_Success(t *testing.T) {
 	}
 }
 
+func TestStatisticsFromAssignments(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 25, 0, 0, time.UTC)
+	hour := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := hour.Add(d)
+		return &t
+	}
+
+	unlockedAt, startedAt := at(-48*time.Hour), at(-24*time.Hour)
+	assignment := func(id int64, data domain.AssignmentData) domain.Assignment {
+		data.SubjectID = id * 10
+		return domain.Assignment{ID: id, Object: "assignment", DataUpdatedAt: hour.Add(-time.Hour), Data: data}
+	}
+	started := func(id int64, availableAt *time.Time) domain.Assignment {
+		return assignment(id, domain.AssignmentData{SRSStage: domain.SRSStageApprentice1, UnlockedAt: unlockedAt, StartedAt: startedAt, AvailableAt: availableAt})
+	}
+
+	recent := started(5, at(3*time.Hour))
+	recent.DataUpdatedAt = hour.Add(-2 * time.Minute)
+	burned := started(8, nil)
+	burned.Data.SRSStage = domain.SRSStageBurned
+
+	assignments := []domain.Assignment{
+		assignment(1, domain.AssignmentData{UnlockedAt: unlockedAt}), // lesson
+		assignment(2, domain.AssignmentData{}),                       // locked
+		started(3, at(-5*time.Hour)),                                 // overdue
+		started(4, at(0)),                                            // due this hour
+		recent,                                                       // due in three hours
+		started(6, at(24*time.Hour)),                                 // last bucket
+		started(7, at(25*time.Hour)),                                 // beyond the report
+		burned,                                                       // burned
+		assignment(9, domain.AssignmentData{UnlockedAt: unlockedAt}), // lesson
+	}
+
+	stats := statisticsFromAssignments(assignments, now)
+
+	if stats.Object != "report" || !stats.DataUpdatedAt.Equal(hour.Add(-2*time.Minute)) {
+		t.Errorf("expected a report updated at the latest assignment update, got %q at %v", stats.Object, stats.DataUpdatedAt)
+	}
+
+	if len(stats.Data.Lessons) != 1 || !stats.Data.Lessons[0].AvailableAt.Equal(hour) {
+		t.Fatalf("expected one lesson bucket at %v, got %+v", hour, stats.Data.Lessons)
+	}
+	if got := fmt.Sprint(stats.Data.Lessons[0].SubjectIDs); got != "[10 90]" {
+		t.Errorf("expected lesson subjects [10 90], got %s", got)
+	}
+
+	if len(stats.Data.Reviews) != 25 {
+		t.Fatalf("expected 25 hourly review buckets, got %d", len(stats.Data.Reviews))
+	}
+	expected := map[int]string{0: "[30 40]", 3: "[50]", 24: "[60]"}
+	for i, bucket := range stats.Data.Reviews {
+		if !bucket.AvailableAt.Equal(hour.Add(time.Duration(i) * time.Hour)) {
+			t.Errorf("bucket %d: expected available_at %v, got %v", i, hour.Add(time.Duration(i)*time.Hour), bucket.AvailableAt)
+		}
+		want, ok := expected[i]
+		if !ok {
+			want = "[]"
+		}
+		if got := fmt.Sprint(bucket.SubjectIDs); got != want || bucket.SubjectIDs == nil {
+			t.Errorf("bucket %d: expected subjects %s, got %s", i, want, got)
+		}
+	}
+}
+
+func TestSyncStatistics_FromAssignments(t *testing.T) {
+	client := &mockClient{fetchError: errors.New("summary unavailable")}
+	store := newMockStore()
+	unlockedAt := time.Now().Add(-time.Hour)
+	store.assignments = []domain.Assignment{
+		{ID: 1, Object: "assignment", DataUpdatedAt: unlockedAt, Data: domain.AssignmentData{SubjectID: 7, UnlockedAt: &unlockedAt}},
+	}
+	service := NewService(client, store, testLogger(), WithStatisticsSource(StatisticsFromAssignments))
+
+	result := service.SyncStatistics(context.Background())
+
+	if !result.Success || result.RecordsUpdated != 1 {
+		t.Fatalf("expected a derived snapshot to be stored, got success=%v records=%d error=%q", result.Success, result.RecordsUpdated, result.Error)
+	}
+	if client.fetches[domain.DataTypeStatistics] != 0 {
+		t.Error("expected /summary not to be fetched")
+	}
+	if len(store.statistics) != 1 || fmt.Sprint(store.statistics[0].Data.Lessons[0].SubjectIDs) != "[7]" {
+		t.Errorf("expected a snapshot with lesson subject 7, got %+v", store.statistics)
+	}
+}
+
+func TestSyncStatistics_EmptySummary(t *testing.T) {
+	client := &mockClient{
+		statistics: &domain.Statistics{
+			Object: "report",
+			Data: domain.StatisticsData{
+				Lessons: []domain.LessonStatistics{},
+				Reviews: []domain.ReviewStatistics{},
+			},
+		},
+	}
+	store := newMockStore()
+	service := NewService(client, store, testLogger())
+
+	result := service.SyncStatistics(context.Background())
+
+	if !result.Success || result.Skipped {
+		t.Errorf("expected empty summary to be stored successfully, got %+v", result)
+	}
+	if result.RecordsUpdated != 1 || len(store.statistics) != 1 {
+		t.Errorf("expected one empty snapshot stored, got %d records updated and %d stored", result.RecordsUpdated, len(store.statistics))
+	}
+}
+
+func TestSyncStatistics_NilSummaryIsSkipped(t *testing.T) {
+	client := &mockClient{statistics: nil}
+	store := newMockStore()
+	service := NewService(client, store, testLogger())
+
+	result := service.SyncStatistics(context.Background())
+
+	if !result.Success || !result.Skipped {
+		t.Errorf("expected nil summary to be skipped without failing, got %+v", result)
+	}
+	if result.RecordsUpdated != 0 {
+		t.Errorf("expected 0 records updated, got %d", result.RecordsUpdated)
+	}
+	if len(store.statistics) != 0 {
+		t.Errorf("expected no snapshot stored, got %d", len(store.statistics))
+	}
+	if store.lastSyncTimes[domain.DataTypeStatistics] != nil {
+		t.Error("expected last sync time to be left untouched")
+	}
+}
+
+func TestSyncStatistics_DedupSkipsIdenticalSnapshot(t *testing.T) {
+	updatedAt := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	client := &mockClient{
+		statistics: &domain.Statistics{
+			Object:        "report",
+			DataUpdatedAt: updatedAt,
+			Data: domain.StatisticsData{
+				Reviews: []domain.ReviewStatistics{{AvailableAt: updatedAt, SubjectIDs: []int64{1, 2}}},
+			},
+		},
+	}
+	store := newMockStore()
+	service := NewService(client, store, testLogger(), WithStatisticsDedup(true))
+
+	first := service.SyncStatistics(context.Background())
+	second := service.SyncStatistics(context.Background())
+
+	if !first.Success || !second.Success {
+		t.Fatalf("expected both syncs to succeed, got %q and %q", first.Error, second.Error)
+	}
+	if len(store.statistics) != 1 {
+		t.Errorf("expected identical snapshot to be skipped, got %d stored", len(store.statistics))
+	}
+	if second.RecordsUpdated != 0 {
+		t.Errorf("expected 0 records updated for skipped snapshot, got %d", second.RecordsUpdated)
+	}
+	if store.lastSyncTimes[domain.DataTypeStatistics] == nil {
+		t.Error("expected last sync time to be updated even when the snapshot is skipped")
+	}
+
+	// A changed snapshot is stored
+	client.statistics = &domain.Statistics{
+		Object:        "report",
+		DataUpdatedAt: updatedAt,
+		Data: domain.StatisticsData{
+			Reviews: []domain.ReviewStatistics{{AvailableAt: updatedAt, SubjectIDs: []int64{1, 2, 3}}},
+		},
+	}
+	third := service.SyncStatistics(context.Background())
+	if third.RecordsUpdated != 1 || len(store.statistics) != 2 {
+		t.Errorf("expected changed snapshot to be stored, got %d records updated and %d stored", third.RecordsUpdated, len(store.statistics))
+	}
+}
+
+func TestSyncStatistics_DedupDisabledStoresEverySnapshot(t *testing.T) {
+	client := &mockClient{statistics: &domain.Statistics{Object: "report"}}
+	store := newMockStore()
+	service := NewService(client, store, testLogger())
+
+	service.SyncStatistics(context.Background())
+	service.SyncStatistics(context.Background())
+
+	if len(store.statistics) != 2 {
+		t.Errorf("expected 2 snapshots stored without dedup, got %d", len(store.statistics))
+	}
+	if len(store.pruneKeepRecent) != 0 {
+		t.Errorf("expected no pruning without retention configured, got %v", store.pruneKeepRecent)
+	}
+}
+
+func TestSyncStatistics_RetentionPrunesAfterInsert(t *testing.T) {
+	client := &mockClient{statistics: &domain.Statistics{Object: "report"}}
+	store := newMockStore()
+	service := NewService(client, store, testLogger(), WithStatisticsRetention(10))
+
+	service.SyncStatistics(context.Background())
+
+	if len(store.pruneKeepRecent) != 1 || store.pruneKeepRecent[0] != 10 {
+		t.Errorf("expected one prune keeping 10 recent snapshots, got %v", store.pruneKeepRecent)
+	}
+}
+
 func TestSyncSubjects_FetchError(t *testing.T) {
 	client := &mockClient{
 		fetchError: errors.New("network error"),
@@ -345,8 +816,8 @@ func TestSyncAll_Success(t *testing.T) {
 	if err != nil {
 		t.Errorf("expected no error, got: %v", err)
 	}
-	if len(results) != 4 {
-		t.Errorf("expected 4 results, got %d", len(results))
+	if len(results) != 5 {
+		t.Errorf("expected 5 results, got %d", len(results))
 	}
 	for _, result := range results {
 		if !result.Success {
@@ -355,6 +826,373 @@ func TestSyncAll_Success(t *testing.T) {
 	}
 }
 
+func TestSyncLevelProgressions(t *testing.T) {
+	lastSync := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	client := &mockClient{
+		levelProgressions: []domain.LevelProgression{
+			{ID: 1, Object: "level_progression", Data: domain.LevelProgressionData{Level: 1}},
+			{ID: 2, Object: "level_progression", Data: domain.LevelProgressionData{Level: 2}},
+		},
+	}
+	store := newMockStore()
+	store.lastSyncTimes[domain.DataTypeLevelProgressions] = &lastSync
+	service := NewService(client, store, testLogger())
+
+	result := service.SyncLevelProgressions(context.Background())
+
+	if !result.Success || result.RecordsUpdated != 2 {
+		t.Fatalf("expected 2 level progressions synced, got %+v", result)
+	}
+	if !store.levelProgressionIDs[1] || !store.levelProgressionIDs[2] {
+		t.Errorf("expected both level progressions stored, got %v", store.levelProgressionIDs)
+	}
+	if synced := store.lastSyncTimes[domain.DataTypeLevelProgressions]; synced == nil || !synced.Equal(result.Timestamp) {
+		t.Errorf("expected last sync time %v, got %v", result.Timestamp, synced)
+	}
+}
+
+func TestSyncAll_SyncsLevelProgressionsAfterSubjects(t *testing.T) {
+	client := &mockClient{
+		subjects:          []domain.Subject{{ID: 1}},
+		levelProgressions: []domain.LevelProgression{{ID: 1}},
+		statistics:        &domain.Statistics{Object: "report"},
+	}
+	service := NewService(client, newMockStore(), testLogger())
+
+	results, err := service.SyncAll(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	var dataTypes []domain.DataType
+	for _, result := range results {
+		dataTypes = append(dataTypes, result.DataType)
+	}
+	expected := []domain.DataType{
+		domain.DataTypeSubjects,
+		domain.DataTypeLevelProgressions,
+		domain.DataTypeAssignments,
+		domain.DataTypeReviews,
+		domain.DataTypeStatistics,
+	}
+	if fmt.Sprint(dataTypes) != fmt.Sprint(expected) {
+		t.Errorf("expected sync order %v, got %v", expected, dataTypes)
+	}
+}
+
+func TestSyncAll_TotalRecordsReflectsStoreCount(t *testing.T) {
+	store := newMockStore()
+	// Rows stored by an earlier sync
+	store.subjectIDs[1] = true
+	store.subjectIDs[2] = true
+	store.assignmentIDs[1] = true
+
+	client := &mockClient{
+		subjects:    []domain.Subject{{ID: 2}, {ID: 3}},
+		assignments: []domain.Assignment{{ID: 2}},
+		reviews:     []domain.Review{{ID: 1}},
+		statistics:  &domain.Statistics{Object: "report"},
+	}
+	service := NewService(client, store, testLogger())
+
+	results, err := service.SyncAll(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	expected := map[domain.DataType][2]int{
+		domain.DataTypeSubjects:    {2, 3},
+		domain.DataTypeAssignments: {1, 2},
+		domain.DataTypeReviews:     {1, 1},
+	}
+	for _, result := range results {
+		want, ok := expected[result.DataType]
+		if !ok {
+			continue
+		}
+		if result.RecordsUpdated != want[0] || result.TotalRecords != want[1] {
+			t.Errorf("%s: expected %d updated and %d total, got %d and %d",
+				result.DataType, want[0], want[1], result.RecordsUpdated, result.TotalRecords)
+		}
+	}
+}
+
+func TestSyncAll_InitialSnapshotOnEmptyDatabase(t *testing.T) {
+	newClient := func() *mockClient {
+		return &mockClient{
+			subjects:    []domain.Subject{{ID: 1}},
+			assignments: []domain.Assignment{{ID: 1}},
+			reviews:     []domain.Review{{ID: 1}},
+			statistics:  &domain.Statistics{Object: "report"},
+		}
+	}
+
+	t.Run("first successful sync creates a snapshot", func(t *testing.T) {
+		store := newMockStore()
+		service := NewService(newClient(), store, testLogger(), WithInitialSnapshot(true))
+
+		if _, err := service.SyncAll(context.Background()); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if len(store.snapshots) == 0 {
+			t.Error("expected at least one snapshot row after the first sync")
+		}
+	})
+
+	t.Run("snapshot survives a later phase failing", func(t *testing.T) {
+		client := newClient()
+		client.reviewError = errors.New("reviews unavailable")
+		store := newMockStore()
+		service := NewService(client, store, testLogger(), WithInitialSnapshot(true))
+
+		if _, err := service.SyncAll(context.Background()); err == nil {
+			t.Fatal("expected the reviews failure to fail the sync")
+		}
+		if len(store.snapshots) == 0 {
+			t.Error("expected the initial snapshot to be created before the reviews phase")
+		}
+	})
+
+	t.Run("disabled waits for a successful sync", func(t *testing.T) {
+		client := newClient()
+		client.reviewError = errors.New("reviews unavailable")
+		store := newMockStore()
+		service := NewService(client, store, testLogger())
+
+		service.SyncAll(context.Background())
+		if len(store.snapshots) != 0 {
+			t.Errorf("expected no snapshot without initial snapshots enabled, got %d rows", len(store.snapshots))
+		}
+	})
+
+	t.Run("existing snapshots are left to the end of the sync", func(t *testing.T) {
+		client := newClient()
+		client.reviewError = errors.New("reviews unavailable")
+		store := newMockStore()
+		store.snapshots = []domain.AssignmentSnapshot{{SRSStage: 1, SubjectType: "kanji", Count: 1}}
+		service := NewService(client, store, testLogger(), WithInitialSnapshot(true))
+
+		service.SyncAll(context.Background())
+		if len(store.snapshots) != 1 {
+			t.Errorf("expected no new snapshot rows, got %d", len(store.snapshots))
+		}
+	})
+}
+
+func TestSync_ValidationOfFetchedRecords(t *testing.T) {
+	newClient := func() *mockClient {
+		return &mockClient{
+			subjects: []domain.Subject{
+				{ID: 1, Object: "kanji", Data: domain.SubjectData{Level: 1}},
+				{ID: 2, Object: "", Data: domain.SubjectData{Level: 1}},
+				{ID: 3, Object: "kanji", Data: domain.SubjectData{Level: 0}},
+			},
+			assignments: []domain.Assignment{
+				{ID: 1, Object: "assignment", Data: domain.AssignmentData{SubjectID: 1, SRSStage: 1}},
+				{ID: 2, Object: "assignment", Data: domain.AssignmentData{SubjectID: 0}},
+				{ID: 3, Object: "assignment", Data: domain.AssignmentData{SubjectID: 1, SRSStage: 10}},
+			},
+			reviews: []domain.Review{
+				{ID: 1, Object: "review", Data: domain.ReviewData{AssignmentID: 1, SubjectID: 1}},
+				{ID: 2, Object: "review", Data: domain.ReviewData{AssignmentID: 0, SubjectID: 1}},
+			},
+		}
+	}
+
+	t.Run("skip stores only valid records", func(t *testing.T) {
+		store := newMockStore()
+		service := NewService(newClient(), store, testLogger(), WithValidation(ValidationSkip))
+		ctx := context.Background()
+
+		checks := []struct {
+			result          domain.SyncResult
+			stored          map[int64]bool
+			expectedValid   int
+			expectedInvalid int
+		}{
+			{service.SyncSubjects(ctx), store.subjectIDs, 1, 2},
+			{service.SyncAssignments(ctx), store.assignmentIDs, 1, 2},
+			{service.SyncReviews(ctx), store.reviewIDs, 1, 1},
+		}
+		for _, c := range checks {
+			if !c.result.Success {
+				t.Fatalf("%s: expected success, got error: %s", c.result.DataType, c.result.Error)
+			}
+			if c.result.RecordsUpdated != c.expectedValid || c.result.InvalidRecords != c.expectedInvalid {
+				t.Errorf("%s: expected %d updated and %d invalid, got %d and %d", c.result.DataType,
+					c.expectedValid, c.expectedInvalid, c.result.RecordsUpdated, c.result.InvalidRecords)
+			}
+			if len(c.stored) != c.expectedValid || !c.stored[1] {
+				t.Errorf("%s: expected only record 1 stored, got %v", c.result.DataType, c.stored)
+			}
+		}
+	})
+
+	t.Run("strict fails the data type", func(t *testing.T) {
+		store := newMockStore()
+		service := NewService(newClient(), store, testLogger(), WithValidation(ValidationStrict))
+
+		result := service.SyncAssignments(context.Background())
+		if result.Success {
+			t.Fatal("expected strict validation to fail the sync")
+		}
+		if !errors.Is(result.Err, domain.ErrInvalidRecord) {
+			t.Errorf("expected ErrInvalidRecord, got %v", result.Err)
+		}
+		if len(store.assignmentIDs) != 0 {
+			t.Errorf("expected nothing stored, got %v", store.assignmentIDs)
+		}
+		if store.lastSyncTimes[domain.DataTypeAssignments] != nil {
+			t.Error("expected last sync time to stay unset after a failed sync")
+		}
+	})
+
+	t.Run("off stores everything", func(t *testing.T) {
+		store := newMockStore()
+		service := NewService(newClient(), store, testLogger())
+
+		result := service.SyncSubjects(context.Background())
+		if !result.Success || result.RecordsUpdated != 3 || result.InvalidRecords != 0 {
+			t.Errorf("expected all 3 subjects stored unvalidated, got %+v", result)
+		}
+	})
+}
+
+func TestSyncAll_ConditionalSync(t *testing.T) {
+	lastSync := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	newStore := func() *mockStore {
+		store := newMockStore()
+		for _, dataType := range []domain.DataType{domain.DataTypeSubjects, domain.DataTypeLevelProgressions, domain.DataTypeAssignments, domain.DataTypeReviews} {
+			synced := lastSync
+			store.lastSyncTimes[dataType] = &synced
+		}
+		return store
+	}
+
+	t.Run("unchanged summary skips the heavy fetches", func(t *testing.T) {
+		client := &mockClient{statistics: &domain.Statistics{Object: "report", DataUpdatedAt: lastSync.Add(-time.Minute)}}
+		store := newStore()
+		service := NewService(client, store, testLogger(), WithConditionalSync(true))
+
+		results, err := service.SyncAll(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		for _, dataType := range []domain.DataType{domain.DataTypeSubjects, domain.DataTypeLevelProgressions, domain.DataTypeAssignments, domain.DataTypeReviews} {
+			if client.fetches[dataType] != 0 {
+				t.Errorf("expected no %s fetch, got %d", dataType, client.fetches[dataType])
+			}
+		}
+		if client.fetches[domain.DataTypeStatistics] != 1 {
+			t.Errorf("expected only the pre-flight summary fetch, got %d", client.fetches[domain.DataTypeStatistics])
+		}
+
+		if len(results) != 5 {
+			t.Fatalf("expected a result per data type, got %d", len(results))
+		}
+		for _, result := range results {
+			if !result.Success || !result.Skipped {
+				t.Errorf("%s: expected a successful skipped result, got %+v", result.DataType, result)
+			}
+		}
+		if len(store.statistics) != 0 {
+			t.Errorf("expected no statistics stored, got %d", len(store.statistics))
+		}
+	})
+
+	t.Run("changed summary runs the full sync", func(t *testing.T) {
+		client := &mockClient{statistics: &domain.Statistics{Object: "report", DataUpdatedAt: lastSync.Add(time.Minute)}}
+		service := NewService(client, newStore(), testLogger(), WithConditionalSync(true))
+
+		if _, err := service.SyncAll(context.Background()); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if client.fetches[domain.DataTypeSubjects] != 1 || client.fetches[domain.DataTypeReviews] != 1 {
+			t.Errorf("expected the full sync to fetch, got %v", client.fetches)
+		}
+	})
+
+	t.Run("never synced runs the full sync", func(t *testing.T) {
+		client := &mockClient{statistics: &domain.Statistics{Object: "report", DataUpdatedAt: lastSync}}
+		service := NewService(client, newMockStore(), testLogger(), WithConditionalSync(true))
+
+		if _, err := service.SyncAll(context.Background()); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if client.fetches[domain.DataTypeSubjects] != 1 {
+			t.Errorf("expected subjects to be fetched on the first sync, got %v", client.fetches)
+		}
+	})
+
+	t.Run("disabled never checks", func(t *testing.T) {
+		client := &mockClient{statistics: &domain.Statistics{Object: "report", DataUpdatedAt: lastSync.Add(-time.Minute)}}
+		service := NewService(client, newStore(), testLogger())
+
+		if _, err := service.SyncAll(context.Background()); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if client.fetches[domain.DataTypeSubjects] != 1 || client.fetches[domain.DataTypeStatistics] != 1 {
+			t.Errorf("expected a normal sync without a pre-flight fetch, got %v", client.fetches)
+		}
+	})
+}
+
+func TestSyncSubjects_CountErrorDoesNotFailSync(t *testing.T) {
+	client := &mockClient{subjects: []domain.Subject{{ID: 1}}}
+	store := newMockStore()
+	store.countError = errors.New("count failed")
+	service := NewService(client, store, testLogger())
+
+	result := service.SyncSubjects(context.Background())
+	if !result.Success {
+		t.Fatalf("expected sync to succeed despite count failure, got: %s", result.Error)
+	}
+	if result.TotalRecords != 0 {
+		t.Errorf("expected TotalRecords 0 when counting fails, got %d", result.TotalRecords)
+	}
+}
+
+func TestSyncAll_StatisticsDisabled(t *testing.T) {
+	client := &mockClient{
+		subjects:    []domain.Subject{{ID: 1}},
+		assignments: []domain.Assignment{{ID: 1}},
+		reviews:     []domain.Review{{ID: 1}},
+		statistics:  &domain.Statistics{Object: "report"},
+	}
+	store := newMockStore()
+	service := NewService(client, store, testLogger(), WithStatisticsSync(false))
+
+	results, err := service.SyncAll(context.Background())
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+
+	statisticsResult := results[4]
+	if statisticsResult.DataType != domain.DataTypeStatistics {
+		t.Errorf("expected last result to be statistics, got %s", statisticsResult.DataType)
+	}
+	if !statisticsResult.Success || !statisticsResult.Skipped {
+		t.Errorf("expected statistics to be skipped without failing, got %+v", statisticsResult)
+	}
+	for _, result := range results[:3] {
+		if result.Skipped {
+			t.Errorf("expected %s not to be skipped", result.DataType)
+		}
+	}
+	if len(store.statistics) != 0 {
+		t.Errorf("expected no statistics snapshot to be stored, got %d", len(store.statistics))
+	}
+	if store.lastSyncTimes[domain.DataTypeStatistics] != nil {
+		t.Error("expected statistics last sync time to be left untouched")
+	}
+}
+
 func TestSyncAll_StopsOnFirstFailure(t *testing.T) {
 	client := &mockClient{
 		fetchError: errors.New("api error"),
@@ -372,6 +1210,84 @@ func TestSyncAll_StopsOnFirstFailure(t *testing.T) {
 	}
 }
 
+func TestSyncAll_ErrorWrapsClientError(t *testing.T) {
+	client := &mockClient{fetchError: &wanikani.ServerError{StatusCode: 502, Body: "bad gateway"}}
+	service := NewService(client, newMockStore(), testLogger())
+
+	results, err := service.SyncAll(context.Background())
+	if err == nil {
+		t.Fatal("expected sync to fail")
+	}
+
+	var serverErr *wanikani.ServerError
+	if !errors.As(err, &serverErr) {
+		t.Errorf("expected SyncAll error to wrap the client's ServerError, got %v", err)
+	}
+	if len(results) != 1 || !errors.As(results[0].Err, &serverErr) {
+		t.Errorf("expected failed result to keep the client error, got %+v", results)
+	}
+}
+
+// cancellingClient cancels the sync's context while level progressions are being fetched
+type cancellingClient struct {
+	*mockClient
+	cancel context.CancelFunc
+}
+
+func (c *cancellingClient) FetchLevelProgressions(ctx context.Context, updatedAfter *time.Time) ([]domain.LevelProgression, error) {
+	c.cancel()
+	return c.mockClient.FetchLevelProgressions(ctx, updatedAfter)
+}
+
+func TestSyncAll_CancelledMidPhase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &cancellingClient{
+		mockClient: &mockClient{
+			subjects:          []domain.Subject{{ID: 1}},
+			levelProgressions: []domain.LevelProgression{{ID: 1}},
+			assignments:       []domain.Assignment{{ID: 1}},
+			reviews:           []domain.Review{{ID: 1}},
+			statistics:        &domain.Statistics{Object: "report"},
+		},
+		cancel: cancel,
+	}
+	store := newMockStore()
+	service := NewService(client, store, testLogger())
+
+	results, err := service.SyncAll(ctx)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a context.Canceled error, got %v", err)
+	}
+	if len(results) != 2 || !results[0].Success || results[1].Success {
+		t.Fatalf("expected subjects to succeed and level progressions to be cancelled, got %+v", results)
+	}
+
+	// The phase that completed keeps its data and last sync time
+	if !store.subjectIDs[1] || store.lastSyncTimes[domain.DataTypeSubjects] == nil {
+		t.Error("expected subjects to be stored with a last sync time")
+	}
+
+	// The cancelled phase stores nothing, and later phases don't run at all
+	if len(store.levelProgressionIDs) != 0 {
+		t.Errorf("expected no level progressions stored, got %v", store.levelProgressionIDs)
+	}
+	for _, dataType := range []domain.DataType{domain.DataTypeLevelProgressions, domain.DataTypeAssignments, domain.DataTypeReviews, domain.DataTypeStatistics} {
+		if synced := store.lastSyncTimes[dataType]; synced != nil {
+			t.Errorf("%s: expected no last sync time, got %v", dataType, synced)
+		}
+	}
+	for _, dataType := range []domain.DataType{domain.DataTypeAssignments, domain.DataTypeReviews, domain.DataTypeStatistics} {
+		if n := client.fetches[dataType]; n != 0 {
+			t.Errorf("%s: expected no fetch after cancellation, got %d", dataType, n)
+		}
+	}
+	if len(store.snapshots) != 0 {
+		t.Errorf("expected no assignment snapshot, got %d", len(store.snapshots))
+	}
+}
+
 func TestIsSyncing_ConcurrentSyncPrevention(t *testing.T) {
 	client := &mockClient{
 		subjects:    []domain.Subject{{ID: 1}},
@@ -399,8 +1315,8 @@ func TestIsSyncing_ConcurrentSyncPrevention(t *testing.T) {
 	if err == nil {
 		t.Error("expected error for concurrent sync, got nil")
 	}
-	if err != nil && err.Error() != "sync already in progress" {
-		t.Errorf("expected 'sync already in progress' error, got: %v", err)
+	if err != nil && !errors.Is(err, domain.ErrSyncInProgress) {
+		t.Errorf("expected ErrSyncInProgress, got: %v", err)
 	}
 
 	<-done
@@ -414,6 +1330,44 @@ func TestIsSyncing_ReturnsFalseWhenNotSyncing(t *testing.T) {
 	}
 }
 
+func TestSyncStartedAt_ReportedWhileSyncing(t *testing.T) {
+	client := &mockClient{
+		subjects:    []domain.Subject{{ID: 1}},
+		assignments: []domain.Assignment{{ID: 1}},
+		reviews:     []domain.Review{{ID: 1}},
+		statistics:  &domain.Statistics{Object: "report"},
+		delay:       50 * time.Millisecond,
+	}
+	service := NewService(client, newMockStore(), testLogger())
+
+	if startedAt := service.SyncStartedAt(); startedAt != nil {
+		t.Fatalf("expected no start time before a sync, got %v", startedAt)
+	}
+
+	before := time.Now()
+	done := make(chan struct{})
+	go func() {
+		service.SyncAll(context.Background())
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	startedAt := service.SyncStartedAt()
+	if startedAt == nil {
+		t.Fatal("expected a start time while syncing, got nil")
+	}
+	if startedAt.Before(before.Add(-time.Second)) || startedAt.After(time.Now()) {
+		t.Errorf("expected a recent start time, got %v (sync started after %v)", startedAt, before)
+	}
+
+	<-done
+
+	if startedAt := service.SyncStartedAt(); startedAt != nil {
+		t.Errorf("expected start time to be cleared after the sync, got %v", startedAt)
+	}
+}
+
 func TestSyncSubjects_UsesLastSyncTime(t *testing.T) {
 	lastSync := time.Now().Add(-24 * time.Hour)
 	client := &mockClient{
@@ -438,6 +1392,123 @@ func TestSyncSubjects_UsesLastSyncTime(t *testing.T) {
 	}
 }
 
+func TestSyncReviews_InitialReviewSyncDays(t *testing.T) {
+	var capturedUpdatedAfter *time.Time
+	client := &mockClientWithTimestampCapture{
+		capturedUpdatedAfter: &capturedUpdatedAfter,
+		reviews:              []domain.Review{{ID: 1}},
+	}
+	store := newMockStore()
+	service := NewService(client, store, testLogger(), WithInitialReviewSyncDays(30))
+
+	// The first sync is bounded to the window
+	before := time.Now()
+	if result := service.SyncReviews(context.Background()); !result.Success {
+		t.Fatalf("expected success, got error: %s", result.Error)
+	}
+	if capturedUpdatedAfter == nil {
+		t.Fatal("expected the initial sync to pass a bounded updatedAfter")
+	}
+	windowStart := before.Add(-30 * 24 * time.Hour)
+	if diff := capturedUpdatedAfter.Sub(windowStart); diff < 0 || diff > time.Minute {
+		t.Errorf("expected updatedAfter near %v, got %v", windowStart, *capturedUpdatedAfter)
+	}
+
+	// Later syncs continue from the last sync time
+	lastSync := *store.lastSyncTimes[domain.DataTypeReviews]
+	if result := service.SyncReviews(context.Background()); !result.Success {
+		t.Fatalf("expected success, got error: %s", result.Error)
+	}
+	if capturedUpdatedAfter == nil || !capturedUpdatedAfter.Equal(lastSync) {
+		t.Errorf("expected the second sync to use the last sync time %v, got %v", lastSync, capturedUpdatedAfter)
+	}
+
+	// Without the option the first sync fetches everything
+	service = NewService(client, newMockStore(), testLogger())
+	if result := service.SyncReviews(context.Background()); !result.Success {
+		t.Fatalf("expected success, got error: %s", result.Error)
+	}
+	if capturedUpdatedAfter != nil {
+		t.Errorf("expected a full initial sync without the option, got updatedAfter %v", *capturedUpdatedAfter)
+	}
+}
+
+func TestSyncOverlap(t *testing.T) {
+	var capturedUpdatedAfter *time.Time
+	client := &mockClientWithTimestampCapture{capturedUpdatedAfter: &capturedUpdatedAfter}
+	store := newMockStore()
+	lastSync := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	for _, dataType := range []domain.DataType{domain.DataTypeSubjects, domain.DataTypeAssignments, domain.DataTypeReviews} {
+		synced := lastSync
+		store.lastSyncTimes[dataType] = &synced
+	}
+	service := NewService(client, store, testLogger(), WithSyncOverlap(60*time.Second))
+
+	syncs := map[domain.DataType]func(context.Context) domain.SyncResult{
+		domain.DataTypeSubjects:    service.SyncSubjects,
+		domain.DataTypeAssignments: service.SyncAssignments,
+		domain.DataTypeReviews:     service.SyncReviews,
+	}
+	for dataType, sync := range syncs {
+		capturedUpdatedAfter = nil
+		if result := sync(context.Background()); !result.Success {
+			t.Fatalf("%s: expected success, got error: %s", dataType, result.Error)
+		}
+
+		expected := lastSync.Add(-60 * time.Second)
+		if capturedUpdatedAfter == nil || !capturedUpdatedAfter.Equal(expected) {
+			t.Errorf("%s: expected updatedAfter %v, got %v", dataType, expected, capturedUpdatedAfter)
+		}
+	}
+
+	// A never-synced data type is still fetched in full
+	capturedUpdatedAfter = nil
+	if result := service.SyncLevelProgressions(context.Background()); !result.Success {
+		t.Fatalf("expected success, got error: %s", result.Error)
+	}
+	if capturedUpdatedAfter != nil {
+		t.Errorf("expected a full sync without a last sync time, got updatedAfter %v", *capturedUpdatedAfter)
+	}
+}
+
+func TestSyncSubjects_IncludesHidden(t *testing.T) {
+	client := &mockClient{subjects: []domain.Subject{{ID: 1}}}
+	service := NewService(client, newMockStore(), testLogger())
+
+	if result := service.SyncSubjects(context.Background()); !result.Success {
+		t.Fatalf("expected success, got error: %s", result.Error)
+	}
+	if len(client.subjectOptions) != 1 || !client.subjectOptions[0].IncludeHidden {
+		t.Errorf("expected subjects to be fetched including hidden ones, got %+v", client.subjectOptions)
+	}
+}
+
+func TestSyncSubjects_FullFetchAfterClearSyncTimes(t *testing.T) {
+	client := &mockClient{subjects: []domain.Subject{{ID: 1}}}
+	store := newMockStore()
+	service := NewService(client, store, testLogger())
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if result := service.SyncSubjects(ctx); !result.Success {
+			t.Fatalf("expected success, got error: %s", result.Error)
+		}
+	}
+	if client.subjectOptions[1].UpdatedAfter == nil {
+		t.Fatal("expected the second sync to be incremental")
+	}
+
+	if _, err := store.ClearSyncTimes(ctx, nil); err != nil {
+		t.Fatalf("failed to clear sync times: %v", err)
+	}
+	if result := service.SyncSubjects(ctx); !result.Success {
+		t.Fatalf("expected success, got error: %s", result.Error)
+	}
+	if updatedAfter := client.subjectOptions[2].UpdatedAfter; updatedAfter != nil {
+		t.Errorf("expected a full fetch after clearing sync times, got updatedAfter %v", updatedAfter)
+	}
+}
+
 func TestSyncSubjects_EmptyResults(t *testing.T) {
 	client := &mockClient{
 		subjects: []domain.Subject{},
@@ -509,8 +1580,8 @@ func TestSyncAll_SnapshotErrorDoesNotFailSync(t *testing.T) {
 	if err != nil {
 		t.Errorf("expected no error, got: %v", err)
 	}
-	if len(results) != 4 {
-		t.Errorf("expected 4 results, got %d", len(results))
+	if len(results) != 5 {
+		t.Errorf("expected 5 results, got %d", len(results))
 	}
 
 	// Now test with snapshot error - sync should still succeed
@@ -520,8 +1591,8 @@ func TestSyncAll_SnapshotErrorDoesNotFailSync(t *testing.T) {
 	if err2 != nil {
 		t.Errorf("expected no error even with snapshot failure, got: %v", err2)
 	}
-	if len(results2) != 4 {
-		t.Errorf("expected 4 results, got %d", len(results2))
+	if len(results2) != 5 {
+		t.Errorf("expected 5 results, got %d", len(results2))
 	}
 	// All sync results should still be successful
 	for _, result := range results2 {
@@ -0,0 +1,94 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"wanikani-api/internal/domain"
+)
+
+// StatisticsSource selects where SyncStatistics gets its lesson and review availability
+type StatisticsSource string
+
+const (
+	// StatisticsFromSummary fetches the live /summary report from WaniKani
+	StatisticsFromSummary StatisticsSource = "summary"
+	// StatisticsFromAssignments derives the report from the synced assignments, so
+	// snapshots can be taken even when /summary is unavailable
+	StatisticsFromAssignments StatisticsSource = "assignments"
+)
+
+// summaryReviewHours is the number of hourly review buckets after the current hour in a
+// /summary report
+const summaryReviewHours = 24
+
+// fetchStatistics returns the statistics report from the configured source
+func (s *Service) fetchStatistics(ctx context.Context, now time.Time) (*domain.Statistics, error) {
+	if s.statisticsSource != StatisticsFromAssignments {
+		return s.client.FetchStatistics(ctx)
+	}
+
+	assignments, err := s.store.GetAssignments(ctx, domain.AssignmentFilters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load assignments: %w", err)
+	}
+
+	statistics := statisticsFromAssignments(assignments, now)
+	return &statistics, nil
+}
+
+// statisticsFromAssignments builds a report shaped like WaniKani's /summary as of now:
+// one lesson bucket at the current hour with every unlocked but unstarted subject, and
+// review buckets for the current hour and the next 24, where the first holds everything
+// already due. DataUpdatedAt is the latest assignment update.
+func statisticsFromAssignments(assignments []domain.Assignment, now time.Time) domain.Statistics {
+	hour := now.UTC().Truncate(time.Hour)
+
+	lessons := domain.LessonStatistics{AvailableAt: hour, SubjectIDs: []int64{}}
+	reviews := make([]domain.ReviewStatistics, summaryReviewHours+1)
+	for i := range reviews {
+		reviews[i] = domain.ReviewStatistics{AvailableAt: hour.Add(time.Duration(i) * time.Hour), SubjectIDs: []int64{}}
+	}
+
+	var updatedAt time.Time
+	for _, assignment := range assignments {
+		if assignment.DataUpdatedAt.After(updatedAt) {
+			updatedAt = assignment.DataUpdatedAt
+		}
+
+		data := assignment.Data
+		switch {
+		case assignment.State() == domain.AssignmentStateLesson:
+			lessons.SubjectIDs = append(lessons.SubjectIDs, data.SubjectID)
+		case data.StartedAt != nil && data.AvailableAt != nil:
+			bucket := 0
+			if data.AvailableAt.After(hour) {
+				bucket = int(data.AvailableAt.Sub(hour) / time.Hour)
+			}
+			if bucket < len(reviews) {
+				reviews[bucket].SubjectIDs = append(reviews[bucket].SubjectIDs, data.SubjectID)
+			}
+		}
+	}
+
+	sortIDs(lessons.SubjectIDs)
+	for _, bucket := range reviews {
+		sortIDs(bucket.SubjectIDs)
+	}
+
+	return domain.Statistics{
+		Object:        "report",
+		DataUpdatedAt: updatedAt.UTC(),
+		Data: domain.StatisticsData{
+			Lessons: []domain.LessonStatistics{lessons},
+			Reviews: reviews,
+		},
+	}
+}
+
+// sortIDs sorts ids in ascending order
+func sortIDs(ids []int64) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
@@ -0,0 +1,106 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// webhookTimeout bounds each webhook delivery attempt
+	webhookTimeout = 5 * time.Second
+	// webhookAttempts is the number of delivery attempts before giving up
+	webhookAttempts = 3
+	// webhookRetryDelay is the pause between delivery attempts
+	webhookRetryDelay = 500 * time.Millisecond
+)
+
+// webhook delivers JSON payloads to a configured URL on a best-effort basis
+type webhook struct {
+	url        string
+	client     *http.Client
+	attempts   int
+	retryDelay time.Duration
+	logger     *logrus.Logger
+}
+
+// newWebhook creates a webhook for url, or returns nil if url is empty
+func newWebhook(url string, logger *logrus.Logger) *webhook {
+	if url == "" {
+		return nil
+	}
+	return &webhook{
+		url:        url,
+		client:     &http.Client{Timeout: webhookTimeout},
+		attempts:   webhookAttempts,
+		retryDelay: webhookRetryDelay,
+		logger:     logger,
+	}
+}
+
+// send POSTs payload as JSON, retrying network errors and 5xx responses. Failures
+// are logged and returned; callers should never fail a sync because of them.
+func (w *webhook) send(ctx context.Context, event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= w.attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(w.retryDelay):
+			}
+		}
+
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			w.logger.WithField("event", event).Debug("Webhook delivered")
+			return nil
+		}
+		lastErr = err
+
+		w.logger.WithFields(logrus.Fields{
+			"event":   event,
+			"attempt": attempt,
+		}).WithError(err).Warn("Webhook delivery failed")
+
+		if !retry {
+			break
+		}
+	}
+
+	return lastErr
+}
+
+// post makes a single delivery attempt and reports whether a failure is worth retrying
+func (w *webhook) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("webhook request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return true, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,277 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"wanikani-api/internal/domain"
+)
+
+// webhookReceiver records the JSON bodies POSTed to it, failing the first failures requests with 500
+type webhookReceiver struct {
+	mu       gosync.Mutex
+	bodies   [][]byte
+	requests int
+	failures int
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+
+	rcv.requests++
+	if rcv.requests <= rcv.failures {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rcv.bodies = append(rcv.bodies, body)
+}
+
+func (rcv *webhookReceiver) received() [][]byte {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return append([][]byte(nil), rcv.bodies...)
+}
+
+func newLevelUpTestService(t *testing.T, url string, store *mockStore) *Service {
+	t.Helper()
+	client := &mockClient{
+		subjects:    []domain.Subject{{ID: 1}},
+		assignments: []domain.Assignment{{ID: 1}},
+		reviews:     []domain.Review{{ID: 1}},
+		statistics:  &domain.Statistics{Object: "report"},
+	}
+	service := NewService(client, store, testLogger(), WithLevelUpWebhook(url))
+	service.levelUpWebhook.retryDelay = time.Millisecond
+	return service
+}
+
+func TestLevelUpWebhook_SentOncePerLevelIncrease(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	store := newMockStore()
+	store.levels = []int{4, 5}
+	service := newLevelUpTestService(t, server.URL, store)
+
+	if _, err := service.SyncAll(context.Background()); err != nil {
+		t.Fatalf("expected sync to succeed, got: %v", err)
+	}
+
+	bodies := receiver.received()
+	if len(bodies) != 1 {
+		t.Fatalf("expected 1 level-up notification, got %d", len(bodies))
+	}
+
+	var event LevelUpEvent
+	if err := json.Unmarshal(bodies[0], &event); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if event.Event != "level_up" || event.OldLevel != 4 || event.NewLevel != 5 {
+		t.Errorf("unexpected payload: %+v", event)
+	}
+	if event.Timestamp.IsZero() {
+		t.Error("expected payload to include a timestamp")
+	}
+
+	// The level stays at 5, so a second sync sends nothing
+	if _, err := service.SyncAll(context.Background()); err != nil {
+		t.Fatalf("expected second sync to succeed, got: %v", err)
+	}
+	if got := len(receiver.received()); got != 1 {
+		t.Errorf("expected no notification without a level increase, got %d total", got)
+	}
+}
+
+func TestLevelUpWebhook_NotSentOnInitialSync(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	store := newMockStore()
+	store.levels = []int{0, 3}
+	service := newLevelUpTestService(t, server.URL, store)
+
+	if _, err := service.SyncAll(context.Background()); err != nil {
+		t.Fatalf("expected sync to succeed, got: %v", err)
+	}
+	if got := len(receiver.received()); got != 0 {
+		t.Errorf("expected no notification when there was no previous level, got %d", got)
+	}
+}
+
+func TestLevelUpWebhook_RetriesServerErrors(t *testing.T) {
+	receiver := &webhookReceiver{failures: 2}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	store := newMockStore()
+	store.levels = []int{4, 5}
+	service := newLevelUpTestService(t, server.URL, store)
+
+	if _, err := service.SyncAll(context.Background()); err != nil {
+		t.Fatalf("expected sync to succeed, got: %v", err)
+	}
+	if got := len(receiver.received()); got != 1 {
+		t.Errorf("expected notification to be delivered once after retries, got %d", got)
+	}
+}
+
+func TestLevelUpWebhook_FailureDoesNotFailSync(t *testing.T) {
+	receiver := &webhookReceiver{failures: webhookAttempts}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	store := newMockStore()
+	store.levels = []int{4, 5}
+	service := newLevelUpTestService(t, server.URL, store)
+
+	results, err := service.SyncAll(context.Background())
+	if err != nil {
+		t.Fatalf("expected sync to succeed despite webhook failure, got: %v", err)
+	}
+	if len(results) != 5 {
+		t.Errorf("expected 5 results, got %d", len(results))
+	}
+	if receiver.requests != webhookAttempts {
+		t.Errorf("expected %d delivery attempts, got %d", webhookAttempts, receiver.requests)
+	}
+}
+
+func TestSyncWebhook_ReceivesResults(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	client := &mockClient{
+		subjects:    []domain.Subject{{ID: 1}, {ID: 2}},
+		assignments: []domain.Assignment{{ID: 1}},
+		reviews:     []domain.Review{{ID: 1}},
+		statistics:  &domain.Statistics{Object: "report"},
+	}
+	service := NewService(client, newMockStore(), testLogger(), WithSyncWebhook(server.URL))
+
+	results, err := service.SyncAll(context.Background())
+	if err != nil {
+		t.Fatalf("expected sync to succeed, got: %v", err)
+	}
+	service.webhooks.Wait()
+
+	bodies := receiver.received()
+	if len(bodies) != 1 {
+		t.Fatalf("expected 1 sync notification, got %d", len(bodies))
+	}
+
+	var event SyncCompletedEvent
+	if err := json.Unmarshal(bodies[0], &event); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if event.Event != "sync_completed" || !event.Success || event.Error != "" {
+		t.Errorf("unexpected payload: %+v", event)
+	}
+	if len(event.Results) != len(results) {
+		t.Fatalf("expected %d results in payload, got %d", len(results), len(event.Results))
+	}
+	if event.Results[0].DataType != domain.DataTypeSubjects || event.Results[0].RecordsUpdated != 2 {
+		t.Errorf("expected subjects result with 2 records, got %+v", event.Results[0])
+	}
+}
+
+func TestSyncWebhook_ReceivesFailure(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	client := &mockClient{fetchError: context.DeadlineExceeded}
+	service := NewService(client, newMockStore(), testLogger(), WithSyncWebhook(server.URL))
+
+	if _, err := service.SyncAll(context.Background()); err == nil {
+		t.Fatal("expected sync to fail")
+	}
+	service.webhooks.Wait()
+
+	bodies := receiver.received()
+	if len(bodies) != 1 {
+		t.Fatalf("expected 1 sync notification, got %d", len(bodies))
+	}
+
+	var event SyncCompletedEvent
+	if err := json.Unmarshal(bodies[0], &event); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if event.Success || event.Error == "" {
+		t.Errorf("expected a failed sync payload with an error, got %+v", event)
+	}
+	if len(event.Results) != 1 || event.Results[0].Success {
+		t.Errorf("expected the failed subjects result in payload, got %+v", event.Results)
+	}
+}
+
+func TestSyncWebhook_UnreachableReceiverDoesNotFailSync(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	client := &mockClient{
+		subjects:    []domain.Subject{{ID: 1}},
+		assignments: []domain.Assignment{{ID: 1}},
+		reviews:     []domain.Review{{ID: 1}},
+		statistics:  &domain.Statistics{Object: "report"},
+	}
+	service := NewService(client, newMockStore(), testLogger(), WithSyncWebhook(url))
+	service.syncWebhook.retryDelay = time.Millisecond
+
+	if _, err := service.SyncAll(context.Background()); err != nil {
+		t.Fatalf("expected sync to succeed despite unreachable webhook, got: %v", err)
+	}
+	service.webhooks.Wait()
+}
+
+func TestWaitForWebhooks(t *testing.T) {
+	release := make(chan struct{})
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		receiver.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := &mockClient{
+		subjects:    []domain.Subject{{ID: 1}},
+		assignments: []domain.Assignment{{ID: 1}},
+		reviews:     []domain.Review{{ID: 1}},
+		statistics:  &domain.Statistics{Object: "report"},
+	}
+	service := NewService(client, newMockStore(), testLogger(), WithSyncWebhook(server.URL))
+
+	if _, err := service.SyncAll(context.Background()); err != nil {
+		t.Fatalf("expected sync to succeed, got: %v", err)
+	}
+
+	// A delivery still in flight is abandoned once the shutdown context expires
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := service.WaitForWebhooks(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded while the delivery is blocked, got %v", err)
+	}
+
+	close(release)
+	if err := service.WaitForWebhooks(context.Background()); err != nil {
+		t.Fatalf("expected waiting to succeed once the delivery completes, got %v", err)
+	}
+	if bodies := receiver.received(); len(bodies) != 1 {
+		t.Errorf("expected the notification to be delivered before returning, got %d", len(bodies))
+	}
+}
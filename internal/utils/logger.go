@@ -26,6 +26,33 @@ func Init(level string) *logrus.Logger {
 	return log
 }
 
+// fieldHook adds a fixed set of fields to every log entry
+type fieldHook struct {
+	fields logrus.Fields
+}
+
+func (h fieldHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h fieldHook) Fire(entry *logrus.Entry) error {
+	for key, value := range h.fields {
+		if _, ok := entry.Data[key]; !ok {
+			entry.Data[key] = value
+		}
+	}
+	return nil
+}
+
+// WithInstance tags every entry logged through l with the instance name, so logs from
+// several instances can be told apart. An empty name adds nothing.
+func WithInstance(l *logrus.Logger, name string) {
+	if name == "" {
+		return
+	}
+	l.AddHook(fieldHook{fields: logrus.Fields{"instance": name}})
+}
+
 // Get returns the global logger instance
 func Get() *logrus.Logger {
 	if log == nil {
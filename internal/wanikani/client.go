@@ -1,12 +1,20 @@
 package wanikani
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,17 +24,28 @@ import (
 
 const (
 	baseURL        = "https://api.wanikani.com/v2"
+	apiHost        = "api.wanikani.com"
 	maxRetries     = 3
 	initialBackoff = 1 * time.Second
+
+	// rateLimitPersistDelay debounces writes of rate limit state to the store
+	rateLimitPersistDelay = 5 * time.Second
 )
 
 // Client implements the WaniKaniClient interface
 type Client struct {
-	httpClient *http.Client
-	apiToken   string
-	logger     *logrus.Logger
-	mu         sync.RWMutex // protects apiToken and rateLimitInfo
-	rateLimit  domain.RateLimitInfo
+	httpClient     *http.Client
+	apiToken       string
+	logger         *logrus.Logger
+	mu             sync.RWMutex // protects apiToken, rateLimit and persistPending
+	rateLimit      domain.RateLimitInfo
+	rateLimitStore domain.RateLimitStore
+	persistPending bool
+
+	// strictParsing reports API fields the domain types don't model; reportedDrift holds
+	// the drift already warned about, so each one is logged once. Both are protected by mu.
+	strictParsing bool
+	reportedDrift map[string]bool
 }
 
 // NewClient creates a new WaniKani API client
@@ -47,6 +66,56 @@ func (c *Client) SetAPIToken(token string) {
 	c.logger.Debug("API token set successfully")
 }
 
+// SetRateLimitStore sets the store used to persist rate limit state across restarts
+func (c *Client) SetRateLimitStore(store domain.RateLimitStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rateLimitStore = store
+}
+
+// SetStrictParsing enables warnings about fields in API responses that the domain types
+// don't model, so maintainers notice when WaniKani extends its schema. Responses are still
+// decoded leniently either way. This is noisy, since many fields are deliberately left out.
+func (c *Client) SetStrictParsing(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.strictParsing = enabled
+}
+
+// LoadRateLimitState restores the persisted rate limit state so that the first
+// requests after a restart respect a nearly exhausted quota. State whose reset
+// time has already passed is ignored.
+func (c *Client) LoadRateLimitState(ctx context.Context) error {
+	c.mu.RLock()
+	store := c.rateLimitStore
+	c.mu.RUnlock()
+
+	if store == nil {
+		return nil
+	}
+
+	info, err := store.LoadRateLimitState(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load rate limit state: %w", err)
+	}
+
+	if info == nil || !info.ResetAt.After(time.Now()) {
+		c.logger.Debug("No current persisted rate limit state, starting fresh")
+		return nil
+	}
+
+	c.mu.Lock()
+	c.rateLimit = *info
+	c.mu.Unlock()
+
+	c.logger.WithFields(logrus.Fields{
+		"remaining": info.Remaining,
+		"reset_at":  info.ResetAt,
+	}).Info("Restored persisted rate limit state")
+
+	return nil
+}
+
 // GetRateLimitStatus returns the current rate limit information
 func (c *Client) GetRateLimitStatus() domain.RateLimitInfo {
 	c.mu.RLock()
@@ -54,15 +123,24 @@ func (c *Client) GetRateLimitStatus() domain.RateLimitInfo {
 	return c.rateLimit
 }
 
-// FetchSubjects retrieves subjects from the WaniKani API
+// FetchSubjects retrieves subjects from the WaniKani API, including hidden ones
 func (c *Client) FetchSubjects(ctx context.Context, updatedAfter *time.Time) ([]domain.Subject, error) {
+	return c.FetchSubjectsWithOptions(ctx, domain.FetchOptions{UpdatedAfter: updatedAfter, IncludeHidden: true})
+}
+
+// FetchSubjectsWithOptions retrieves subjects from the WaniKani API, narrowed by opts
+func (c *Client) FetchSubjectsWithOptions(ctx context.Context, opts domain.FetchOptions) ([]domain.Subject, error) {
 	params := url.Values{}
-	if updatedAfter != nil {
-		params.Set("updated_after", updatedAfter.Format(time.RFC3339))
-		c.logger.WithField("updated_after", updatedAfter.Format(time.RFC3339)).Debug("Fetching subjects with incremental update")
+	if opts.UpdatedAfter != nil {
+		params.Set("updated_after", opts.UpdatedAfter.Format(time.RFC3339))
+		c.logger.WithField("updated_after", opts.UpdatedAfter.Format(time.RFC3339)).Debug("Fetching subjects with incremental update")
 	} else {
 		c.logger.Debug("Fetching all subjects")
 	}
+	if !opts.IncludeHidden {
+		// Without the filter WaniKani returns hidden subjects too
+		params.Set("hidden", "false")
+	}
 
 	var allSubjects []domain.Subject
 	nextURL := fmt.Sprintf("%s/subjects?%s", baseURL, params.Encode())
@@ -91,15 +169,68 @@ func (c *Client) FetchSubjects(ctx context.Context, updatedAfter *time.Time) ([]
 	return allSubjects, nil
 }
 
-// FetchAssignments retrieves assignments from the WaniKani API
-func (c *Client) FetchAssignments(ctx context.Context, updatedAfter *time.Time) ([]domain.Assignment, error) {
+// FetchLevelProgressions retrieves level progressions from the WaniKani API
+func (c *Client) FetchLevelProgressions(ctx context.Context, updatedAfter *time.Time) ([]domain.LevelProgression, error) {
 	params := url.Values{}
 	if updatedAfter != nil {
 		params.Set("updated_after", updatedAfter.Format(time.RFC3339))
-		c.logger.WithField("updated_after", updatedAfter.Format(time.RFC3339)).Debug("Fetching assignments with incremental update")
+		c.logger.WithField("updated_after", updatedAfter.Format(time.RFC3339)).Debug("Fetching level progressions with incremental update")
+	} else {
+		c.logger.Debug("Fetching all level progressions")
+	}
+
+	var allProgressions []domain.LevelProgression
+	nextURL := fmt.Sprintf("%s/level_progressions?%s", baseURL, params.Encode())
+	pageCount := 0
+
+	for nextURL != "" {
+		var response paginatedResponse
+		var progressions []domain.LevelProgression
+
+		err := c.fetchWithRetry(ctx, nextURL, &response, &progressions)
+		if err != nil {
+			c.logger.WithError(err).Error("Failed to fetch level progressions page")
+			return nil, fmt.Errorf("failed to fetch level progressions: %w", err)
+		}
+
+		pageCount++
+		allProgressions = append(allProgressions, progressions...)
+		nextURL = response.Pages.NextURL
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"total_level_progressions": len(allProgressions),
+		"pages_fetched":            pageCount,
+	}).Info("Successfully fetched level progressions from API")
+
+	return allProgressions, nil
+}
+
+// FetchAssignments retrieves assignments from the WaniKani API
+func (c *Client) FetchAssignments(ctx context.Context, updatedAfter *time.Time) ([]domain.Assignment, error) {
+	return c.FetchAssignmentsWithOptions(ctx, domain.FetchOptions{UpdatedAfter: updatedAfter})
+}
+
+// FetchAssignmentsWithOptions retrieves assignments from the WaniKani API, narrowed by opts
+func (c *Client) FetchAssignmentsWithOptions(ctx context.Context, opts domain.FetchOptions) ([]domain.Assignment, error) {
+	params := url.Values{}
+	if opts.UpdatedAfter != nil {
+		params.Set("updated_after", opts.UpdatedAfter.Format(time.RFC3339))
+		c.logger.WithField("updated_after", opts.UpdatedAfter.Format(time.RFC3339)).Debug("Fetching assignments with incremental update")
 	} else {
 		c.logger.Debug("Fetching all assignments")
 	}
+	if opts.ImmediatelyAvailableForReview {
+		// WaniKani treats the presence of the parameter as the filter; its value is ignored
+		params.Set("immediately_available_for_review", "true")
+		c.logger.Debug("Fetching only assignments immediately available for review")
+	}
+	if opts.Started != nil {
+		params.Set("started", strconv.FormatBool(*opts.Started))
+	}
+	if opts.Unlocked != nil {
+		params.Set("unlocked", strconv.FormatBool(*opts.Unlocked))
+	}
 
 	var allAssignments []domain.Assignment
 	nextURL := fmt.Sprintf("%s/assignments?%s", baseURL, params.Encode())
@@ -178,22 +309,93 @@ func (c *Client) FetchStatistics(ctx context.Context) (*domain.Statistics, error
 		return nil, fmt.Errorf("failed to fetch statistics: %w", err)
 	}
 
+	// New accounts can return no lessons or reviews; store those as empty rather than null
+	if stats.Data.Lessons == nil {
+		stats.Data.Lessons = []domain.LessonStatistics{}
+	}
+	if stats.Data.Reviews == nil {
+		stats.Data.Reviews = []domain.ReviewStatistics{}
+	}
+
 	c.logger.Info("Successfully fetched statistics from API")
 	return &stats, nil
 }
 
+// noRetryKey marks a context whose requests should be attempted only once
+type noRetryKey struct{}
+
+// WithoutRetries returns a context under which client requests are attempted exactly
+// once, for fail-fast callers such as token validation and health probes
+func WithoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// Ping verifies that the WaniKani API is reachable and the API token is valid by
+// making a single /user request without retries
+func (c *Client) Ping(ctx context.Context) error {
+	endpoint := fmt.Sprintf("%s/user", baseURL)
+
+	var user struct {
+		Object string `json:"object"`
+	}
+	if err := c.fetchWithRetry(WithoutRetries(ctx), endpoint, nil, &user); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+
+	return nil
+}
+
+// FetchResource retrieves a single WaniKani resource by its URL (such as the URL an
+// assignment uses to reference its subject) and decodes the response into out.
+// Only HTTPS URLs on the WaniKani API host are allowed so that URLs taken from
+// API payloads can't be used to make requests to arbitrary hosts.
+func (c *Client) FetchResource(ctx context.Context, resourceURL string, out interface{}) error {
+	if err := validateResourceURL(resourceURL); err != nil {
+		c.logger.WithField("url", resourceURL).Warn("Rejected resource URL")
+		return err
+	}
+
+	c.logger.WithField("url", resourceURL).Debug("Fetching single resource from API")
+
+	if err := c.fetchWithRetry(ctx, resourceURL, nil, out); err != nil {
+		c.logger.WithError(err).Error("Failed to fetch resource")
+		return fmt.Errorf("failed to fetch resource: %w", err)
+	}
+
+	return nil
+}
+
+// validateResourceURL checks that a URL points at the WaniKani API
+func validateResourceURL(resourceURL string) error {
+	parsed, err := url.Parse(resourceURL)
+	if err != nil {
+		return fmt.Errorf("invalid resource URL: %w", err)
+	}
+
+	if parsed.Scheme != "https" || parsed.Host != apiHost {
+		return fmt.Errorf("resource URL must be an https://%s URL, got %q", apiHost, resourceURL)
+	}
+
+	return nil
+}
+
 // fetchWithRetry performs an HTTP request with retry logic and exponential backoff
 func (c *Client) fetchWithRetry(ctx context.Context, url string, paginationInfo *paginatedResponse, data interface{}) error {
 	var lastErr error
 	backoff := initialBackoff
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
+	attempts := maxRetries
+	if noRetry, _ := ctx.Value(noRetryKey{}).(bool); noRetry {
+		attempts = 1
+	}
+
+	for attempt := 0; attempt < attempts; attempt++ {
 		if attempt > 0 {
 			// Calculate wait duration based on error type
 			waitDuration := backoff
-			if rateLimitErr, ok := lastErr.(*rateLimitError); ok {
+			if rateLimitErr, ok := lastErr.(*RateLimitError); ok {
 				// For rate limit errors, wait for the specified retry-after duration
-				waitDuration = rateLimitErr.retryAfter
+				waitDuration = rateLimitErr.RetryAfter
 				c.logger.WithFields(logrus.Fields{
 					"retry_after": waitDuration,
 					"attempt":     attempt,
@@ -222,7 +424,7 @@ func (c *Client) fetchWithRetry(ctx context.Context, url string, paginationInfo
 		lastErr = err
 
 		// Check if error is retryable
-		if !isRetryableError(err) {
+		if attempts == 1 || !isRetryableError(err) {
 			c.logger.WithError(err).Error("Non-retryable error encountered")
 			return err
 		}
@@ -245,7 +447,7 @@ func (c *Client) doRequest(ctx context.Context, url string, paginationInfo *pagi
 
 	if token == "" {
 		c.logger.Error("API token not set")
-		return fmt.Errorf("API token not set")
+		return &AuthError{Message: "API token not set"}
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
@@ -261,7 +463,7 @@ func (c *Client) doRequest(ctx context.Context, url string, paginationInfo *pagi
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		c.logger.WithError(err).Error("Network error during API request")
-		return &networkError{err: err}
+		return &NetworkError{Err: err}
 	}
 	defer resp.Body.Close()
 
@@ -273,19 +475,19 @@ func (c *Client) doRequest(ctx context.Context, url string, paginationInfo *pagi
 		body, _ := io.ReadAll(resp.Body)
 		if resp.StatusCode == http.StatusUnauthorized {
 			c.logger.Error("Authentication failed: Invalid API token")
-			return &authError{message: "Invalid API token"}
+			return &AuthError{Message: "Invalid API token"}
 		}
 		if resp.StatusCode == http.StatusTooManyRequests {
 			retryAfter := parseRetryAfter(resp)
 			c.logger.WithField("retry_after", retryAfter).Warn("Rate limit exceeded")
-			return &rateLimitError{retryAfter: retryAfter}
+			return &RateLimitError{RetryAfter: retryAfter}
 		}
 		if resp.StatusCode >= 500 {
 			c.logger.WithFields(logrus.Fields{
 				"status_code": resp.StatusCode,
 				"body":        string(body),
 			}).Error("Server error from WaniKani API")
-			return &serverError{statusCode: resp.StatusCode, body: string(body)}
+			return &ServerError{StatusCode: resp.StatusCode, Body: string(body)}
 		}
 		c.logger.WithFields(logrus.Fields{
 			"status_code": resp.StatusCode,
@@ -294,39 +496,160 @@ func (c *Client) doRequest(ctx context.Context, url string, paginationInfo *pagi
 		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
 	}
 
+	c.mu.RLock()
+	strict := c.strictParsing
+	c.mu.RUnlock()
+
 	// Parse response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+	var body io.Reader = resp.Body
+	if strict {
+		// The drift check needs a second pass over the response
+		raw, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return &NetworkError{Err: err}
+		}
+		c.checkSchemaDrift(url, raw, paginationInfo != nil, data)
+		body = bytes.NewReader(raw)
 	}
 
-	// If we need pagination info, parse the full response
 	if paginationInfo != nil {
-		var fullResponse struct {
-			Data  json.RawMessage `json:"data"`
-			Pages struct {
-				NextURL string `json:"next_url"`
-			} `json:"pages"`
+		if err := decodePage(body, paginationInfo, data); err != nil {
+			return err
 		}
+	} else {
+		// For non-paginated responses (like statistics), parse the entire response directly
+		if err := json.NewDecoder(body).Decode(data); err != nil {
+			return fmt.Errorf("failed to parse data: %w", err)
+		}
+	}
 
-		if err := json.Unmarshal(body, &fullResponse); err != nil {
-			return fmt.Errorf("failed to parse response: %w", err)
+	c.logger.WithField("url", url).Debug("API request completed successfully")
+	return nil
+}
+
+// checkSchemaDrift decodes a response into a fresh value of data's type, rejecting unknown
+// fields, and logs a warning if the response has fields that the type doesn't model. For
+// collections only the data array is checked. Types with their own UnmarshalJSON, such as
+// Subject, which keeps its whole payload in Raw, are not checked below that point.
+func (c *Client) checkSchemaDrift(url string, raw []byte, paginated bool, data interface{}) {
+	if paginated {
+		var collection struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(raw, &collection); err != nil || len(collection.Data) == 0 {
+			return // malformed responses are reported by the regular decode
 		}
+		raw = collection.Data
+	}
 
-		paginationInfo.Pages.NextURL = fullResponse.Pages.NextURL
+	target := reflect.New(reflect.TypeOf(data).Elem()).Interface()
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(target)
+	if err == nil || !strings.HasPrefix(err.Error(), "json: unknown field") {
+		return
+	}
 
-		// Parse the data array
-		if err := json.Unmarshal(fullResponse.Data, data); err != nil {
-			return fmt.Errorf("failed to parse data: %w", err)
+	key := fmt.Sprintf("%T: %s", target, err)
+	c.mu.Lock()
+	reported := c.reportedDrift[key]
+	if !reported {
+		if c.reportedDrift == nil {
+			c.reportedDrift = make(map[string]bool)
 		}
-	} else {
-		// For non-paginated responses (like statistics), parse the entire response directly
-		if err := json.Unmarshal(body, data); err != nil {
-			return fmt.Errorf("failed to parse data: %w", err)
+		c.reportedDrift[key] = true
+	}
+	c.mu.Unlock()
+	if reported {
+		return
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"url":   url,
+		"type":  fmt.Sprintf("%T", target),
+		"error": err.Error(),
+	}).Warn("WaniKani API response has a field the domain types don't model")
+}
+
+// decodePage streams a paginated collection response from r, decoding the elements of
+// its data array one at a time into data (a pointer to a slice) so that a large page is
+// never held in memory as raw JSON in addition to its decoded form
+func decodePage(r io.Reader, paginationInfo *paginatedResponse, data interface{}) error {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		switch tok {
+		case "data":
+			if err := decodeArray(dec, data); err != nil {
+				return fmt.Errorf("failed to parse data: %w", err)
+			}
+		case "pages":
+			if err := dec.Decode(&paginationInfo.Pages); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
 		}
 	}
 
-	c.logger.WithField("url", url).Debug("API request completed successfully")
+	if err := expectDelim(dec, '}'); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// decodeArray decodes a JSON array from dec element by element, appending to the slice
+// out points to. Values other than a slice pointer are decoded in one go.
+func decodeArray(dec *json.Decoder, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return dec.Decode(out)
+	}
+	slice := v.Elem()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil // null leaves the slice untouched, as json.Unmarshal does
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected array, got %v", tok)
+	}
+
+	for dec.More() {
+		elem := reflect.New(slice.Type().Elem())
+		if err := dec.Decode(elem.Interface()); err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, elem.Elem()))
+	}
+
+	return expectDelim(dec, ']')
+}
+
+// expectDelim reads the next token from dec and checks that it is the given delimiter
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %v, got %v", want, tok)
+	}
 	return nil
 }
 
@@ -396,6 +719,38 @@ func (c *Client) updateRateLimitInfo(resp *http.Response) {
 			"reset_at":  c.rateLimit.ResetAt,
 		}).Debug("Rate limit status updated")
 	}
+
+	c.schedulePersistLocked()
+}
+
+// schedulePersistLocked schedules a debounced write of the rate limit state.
+// The caller must hold c.mu.
+func (c *Client) schedulePersistLocked() {
+	if c.rateLimitStore == nil || c.persistPending {
+		return
+	}
+	c.persistPending = true
+	time.AfterFunc(rateLimitPersistDelay, c.persistRateLimitState)
+}
+
+// persistRateLimitState writes the latest rate limit state to the store
+func (c *Client) persistRateLimitState() {
+	c.mu.Lock()
+	info := c.rateLimit
+	store := c.rateLimitStore
+	c.persistPending = false
+	c.mu.Unlock()
+
+	if store == nil || info.ResetAt.IsZero() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := store.SaveRateLimitState(ctx, info); err != nil {
+		c.logger.WithError(err).Warn("Failed to persist rate limit state")
+	}
 }
 
 // parseRetryAfter parses the Retry-After header
@@ -421,14 +776,40 @@ func parseRetryAfter(resp *http.Response) time.Duration {
 
 // isRetryableError determines if an error should trigger a retry
 func isRetryableError(err error) bool {
-	switch err.(type) {
-	case *networkError, *serverError, *rateLimitError:
+	switch e := err.(type) {
+	case *NetworkError:
+		return !isPermanentNetworkError(e.Err)
+	case *ServerError, *RateLimitError:
 		return true
 	default:
 		return false
 	}
 }
 
+// isPermanentNetworkError reports whether a transport error will fail the same way on
+// every attempt: an unknown host or a TLS certificate/handshake failure. Connection
+// refusals, resets and timeouts are transient. errors.As walks through the *url.Error
+// that http.Client.Do wraps around transport errors.
+func isPermanentNetworkError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsNotFound
+	}
+
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &verifyErr) ||
+		errors.As(err, &recordErr) ||
+		errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr)
+}
+
 // paginatedResponse holds pagination information
 type paginatedResponse struct {
 	Pages struct {
@@ -436,40 +817,43 @@ type paginatedResponse struct {
 	} `json:"pages"`
 }
 
-// Error types
-type networkError struct {
-	err error
+// NetworkError indicates that the WaniKani API could not be reached
+type NetworkError struct {
+	Err error
 }
 
-func (e *networkError) Error() string {
-	return fmt.Sprintf("network error: %v", e.err)
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("network error: %v", e.Err)
 }
 
-func (e *networkError) Unwrap() error {
-	return e.err
+func (e *NetworkError) Unwrap() error {
+	return e.Err
 }
 
-type authError struct {
-	message string
+// AuthError indicates that the WaniKani API token is missing or was rejected
+type AuthError struct {
+	Message string
 }
 
-func (e *authError) Error() string {
-	return e.message
+func (e *AuthError) Error() string {
+	return e.Message
 }
 
-type rateLimitError struct {
-	retryAfter time.Duration
+// RateLimitError indicates that the WaniKani API rate limit was exceeded
+type RateLimitError struct {
+	RetryAfter time.Duration
 }
 
-func (e *rateLimitError) Error() string {
-	return fmt.Sprintf("rate limit exceeded, retry after %v", e.retryAfter)
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry after %v", e.RetryAfter)
 }
 
-type serverError struct {
-	statusCode int
-	body       string
+// ServerError indicates that the WaniKani API responded with a 5xx status
+type ServerError struct {
+	StatusCode int
+	Body       string
 }
 
-func (e *serverError) Error() string {
-	return fmt.Sprintf("server error %d: %s", e.statusCode, e.body)
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("server error %d: %s", e.StatusCode, e.Body)
 }
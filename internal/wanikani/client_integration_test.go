@@ -254,7 +254,7 @@ func TestIntegration_AuthenticationError(t *testing.T) {
 	t.Logf("Got expected error: %v", err)
 
 	// Check if it's an auth error
-	if _, ok := err.(*authError); !ok {
+	if _, ok := err.(*AuthError); !ok {
 		// The error might be wrapped, check the message
 		if err.Error() == "" {
 			t.Error("Expected non-empty error message")
@@ -2,14 +2,22 @@ package wanikani
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"wanikani-api/internal/domain"
 )
 
@@ -143,6 +151,88 @@ func TestFetchSubjects_Pagination(t *testing.T) {
 	}
 }
 
+func TestDoRequest_LargePageInt64IDs(t *testing.T) {
+	const pageSize = 20000
+	const firstID = int64(1) << 40 // beyond the range of a 32-bit int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Written element by element, with pages after data and keys the client ignores
+		fmt.Fprint(w, `{"object":"collection","total_count":20000,"data":[`)
+		for i := int64(0); i < pageSize; i++ {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"id":%d,"object":"review","data":{"assignment_id":%d,"subject_id":%d}}`, firstID+i, firstID+i, i+1)
+		}
+		fmt.Fprint(w, `],"pages":{"next_url":"https://api.wanikani.com/v2/reviews?page_after_id=1"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(testLogger())
+	client.SetAPIToken("test-token")
+
+	var response paginatedResponse
+	var reviews []domain.Review
+	if err := client.doRequest(context.Background(), server.URL, &response, &reviews); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(reviews) != pageSize {
+		t.Fatalf("expected %d reviews, got %d", pageSize, len(reviews))
+	}
+	last := reviews[pageSize-1]
+	if reviews[0].ID != firstID || last.ID != firstID+pageSize-1 || last.Data.AssignmentID != firstID+pageSize-1 {
+		t.Errorf("expected IDs %d..%d, got %d..%d", firstID, firstID+pageSize-1, reviews[0].ID, last.ID)
+	}
+	if response.Pages.NextURL != "https://api.wanikani.com/v2/reviews?page_after_id=1" {
+		t.Errorf("expected next_url to be parsed after data, got %q", response.Pages.NextURL)
+	}
+}
+
+func TestDecodePage(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		expectedIDs string
+		nextURL     string
+		expectErr   bool
+	}{
+		{name: "pages first", body: `{"pages":{"next_url":"next"},"data":[{"id":1},{"id":2}]}`, expectedIDs: "[1 2]", nextURL: "next"},
+		{name: "null data", body: `{"data":null,"pages":{"next_url":null}}`, expectedIDs: "[]"},
+		{name: "empty data", body: `{"data":[]}`, expectedIDs: "[]"},
+		{name: "data not an array", body: `{"data":{"id":1}}`, expectErr: true},
+		{name: "truncated", body: `{"data":[{"id":1},`, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response paginatedResponse
+			var subjects []domain.Subject
+			err := decodePage(strings.NewReader(tt.body), &response, &subjects)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var ids []int64
+			for _, subject := range subjects {
+				ids = append(ids, subject.ID)
+			}
+			if got := fmt.Sprint(ids); got != tt.expectedIDs {
+				t.Errorf("expected IDs %s, got %s", tt.expectedIDs, got)
+			}
+			if response.Pages.NextURL != tt.nextURL {
+				t.Errorf("expected next_url %q, got %q", tt.nextURL, response.Pages.NextURL)
+			}
+		})
+	}
+}
+
 func TestFetchSubjects_WithUpdatedAfter(t *testing.T) {
 	token := "test-api-token"
 	var capturedURL string
@@ -201,8 +291,8 @@ func TestAuthError(t *testing.T) {
 		t.Fatal("expected authentication error, got nil")
 	}
 
-	if _, ok := err.(*authError); !ok {
-		t.Errorf("expected authError type, got %T", err)
+	if _, ok := err.(*AuthError); !ok {
+		t.Errorf("expected AuthError type, got %T", err)
 	}
 }
 
@@ -226,8 +316,8 @@ func TestRateLimitError(t *testing.T) {
 		t.Fatal("expected rate limit error, got nil")
 	}
 
-	if _, ok := err.(*rateLimitError); !ok {
-		t.Errorf("expected rateLimitError type, got %T", err)
+	if _, ok := err.(*RateLimitError); !ok {
+		t.Errorf("expected RateLimitError type, got %T", err)
 	}
 }
 
@@ -318,3 +408,536 @@ func TestNoAPIToken(t *testing.T) {
 		t.Errorf("expected 'API token not set' error, got: %v", err)
 	}
 }
+
+// mockRateLimitStore is an in-memory RateLimitStore for testing
+type mockRateLimitStore struct {
+	info *domain.RateLimitInfo
+}
+
+func (m *mockRateLimitStore) SaveRateLimitState(ctx context.Context, info domain.RateLimitInfo) error {
+	m.info = &info
+	return nil
+}
+
+func (m *mockRateLimitStore) LoadRateLimitState(ctx context.Context) (*domain.RateLimitInfo, error) {
+	return m.info, nil
+}
+
+func TestLoadRateLimitState_WaitsOnExhaustedQuota(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{
+			"data": []domain.Subject{},
+			"pages": map[string]interface{}{
+				"next_url": nil,
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	resetAt := time.Now().Add(1500 * time.Millisecond)
+	client := NewClient(testLogger())
+	client.SetAPIToken("test-token")
+	client.SetRateLimitStore(&mockRateLimitStore{
+		info: &domain.RateLimitInfo{Remaining: 0, ResetAt: resetAt},
+	})
+
+	ctx := context.Background()
+	if err := client.LoadRateLimitState(ctx); err != nil {
+		t.Fatalf("unexpected error loading rate limit state: %v", err)
+	}
+
+	start := time.Now()
+	var response paginatedResponse
+	var subjects []domain.Subject
+	if err := client.doRequest(ctx, server.URL, &response, &subjects); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if time.Now().Before(resetAt) {
+		t.Errorf("expected request to wait until %v, returned after %v", resetAt, time.Since(start))
+	}
+}
+
+func TestLoadRateLimitState_IgnoresExpiredState(t *testing.T) {
+	client := NewClient(testLogger())
+	client.SetRateLimitStore(&mockRateLimitStore{
+		info: &domain.RateLimitInfo{Remaining: 0, ResetAt: time.Now().Add(-time.Minute)},
+	})
+
+	if err := client.LoadRateLimitState(context.Background()); err != nil {
+		t.Fatalf("unexpected error loading rate limit state: %v", err)
+	}
+
+	if status := client.GetRateLimitStatus(); !status.ResetAt.IsZero() {
+		t.Errorf("expected expired state to be ignored, got %+v", status)
+	}
+}
+
+// rewriteTransport sends every request to a test server regardless of the requested host
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestFetchResource(t *testing.T) {
+	var capturedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedPath = r.URL.Path
+		json.NewEncoder(w).Encode(domain.Subject{ID: 440, Object: "kanji"})
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	client := NewClient(testLogger())
+	client.SetAPIToken("test-token")
+	client.httpClient = &http.Client{Transport: &rewriteTransport{target: target}}
+
+	var subject domain.Subject
+	err := client.FetchResource(context.Background(), "https://api.wanikani.com/v2/subjects/440", &subject)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if capturedPath != "/v2/subjects/440" {
+		t.Errorf("expected request path /v2/subjects/440, got %s", capturedPath)
+	}
+	if subject.ID != 440 || subject.Object != "kanji" {
+		t.Errorf("unexpected subject decoded: %+v", subject)
+	}
+}
+
+func TestFetchStatistics_EmptySummary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"object":"report","url":"https://api.wanikani.com/v2/summary","data_updated_at":"2024-01-15T10:00:00Z","data":{"lessons":null,"next_reviews_at":null}}`))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	client := NewClient(testLogger())
+	client.SetAPIToken("test-token")
+	client.httpClient = &http.Client{Transport: &rewriteTransport{target: target}}
+
+	stats, err := client.FetchStatistics(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Data.Lessons == nil || len(stats.Data.Lessons) != 0 {
+		t.Errorf("expected empty, non-nil lessons, got %#v", stats.Data.Lessons)
+	}
+	if stats.Data.Reviews == nil || len(stats.Data.Reviews) != 0 {
+		t.Errorf("expected empty, non-nil reviews, got %#v", stats.Data.Reviews)
+	}
+
+	encoded, err := json.Marshal(stats.Data)
+	if err != nil {
+		t.Fatalf("failed to marshal statistics: %v", err)
+	}
+	if string(encoded) != `{"lessons":[],"reviews":[]}` {
+		t.Errorf("expected empty arrays when stored, got %s", encoded)
+	}
+}
+
+func TestFetchAssignmentsWithOptions_ImmediatelyAvailableForReview(t *testing.T) {
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		w.Write([]byte(`{"object":"collection","pages":{"next_url":null},"data":[{"id":1,"object":"assignment","data":{"subject_id":10,"srs_stage":3}}]}`))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	client := NewClient(testLogger())
+	client.SetAPIToken("test-token")
+	client.httpClient = &http.Client{Transport: &rewriteTransport{target: target}}
+
+	assignments, err := client.FetchAssignmentsWithOptions(context.Background(), domain.FetchOptions{ImmediatelyAvailableForReview: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(assignments) != 1 || assignments[0].Data.SubjectID != 10 {
+		t.Errorf("expected the returned assignment, got %+v", assignments)
+	}
+
+	if _, err := client.FetchAssignments(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(queries) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(queries))
+	}
+	if !queries[0].Has("immediately_available_for_review") {
+		t.Errorf("expected immediately_available_for_review to be sent, got query %v", queries[0])
+	}
+	if queries[1].Has("immediately_available_for_review") {
+		t.Errorf("expected plain fetch to omit immediately_available_for_review, got query %v", queries[1])
+	}
+}
+
+func TestFetchAssignmentsWithOptions_StartedAndUnlocked(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`{"object":"collection","pages":{"next_url":null},"data":[]}`))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	client := NewClient(testLogger())
+	client.SetAPIToken("test-token")
+	client.httpClient = &http.Client{Transport: &rewriteTransport{target: target}}
+
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		opts     domain.FetchOptions
+		started  string // "" means the parameter must be absent
+		unlocked string
+	}{
+		{name: "unset", opts: domain.FetchOptions{}},
+		{name: "started only", opts: domain.FetchOptions{Started: &yes}, started: "true"},
+		{name: "not started but unlocked", opts: domain.FetchOptions{Started: &no, Unlocked: &yes}, started: "false", unlocked: "true"},
+		{name: "locked", opts: domain.FetchOptions{Unlocked: &no}, unlocked: "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.FetchAssignmentsWithOptions(context.Background(), tt.opts); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for param, expected := range map[string]string{"started": tt.started, "unlocked": tt.unlocked} {
+				if expected == "" {
+					if query.Has(param) {
+						t.Errorf("expected %s to be omitted, got query %v", param, query)
+					}
+				} else if got := query.Get(param); got != expected {
+					t.Errorf("expected %s=%s, got query %v", param, expected, query)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchSubjectsWithOptions_Hidden(t *testing.T) {
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		w.Write([]byte(`{"object":"collection","pages":{"next_url":null},"data":[{"id":1,"object":"kanji","data":{"level":1,"hidden_at":"2024-06-01T00:00:00.000000Z"}}]}`))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	client := NewClient(testLogger())
+	client.SetAPIToken("test-token")
+	client.httpClient = &http.Client{Transport: &rewriteTransport{target: target}}
+
+	if _, err := client.FetchSubjectsWithOptions(context.Background(), domain.FetchOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	subjects, err := client.FetchSubjectsWithOptions(context.Background(), domain.FetchOptions{IncludeHidden: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.FetchSubjects(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(queries) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(queries))
+	}
+	if got := queries[0].Get("hidden"); got != "false" {
+		t.Errorf("expected hidden=false without IncludeHidden, got query %v", queries[0])
+	}
+	for _, query := range queries[1:] {
+		if query.Has("hidden") {
+			t.Errorf("expected hidden to be omitted when including hidden subjects, got query %v", query)
+		}
+	}
+
+	if len(subjects) != 1 || subjects[0].Data.HiddenAt == nil {
+		t.Errorf("expected hidden_at to be decoded, got %+v", subjects)
+	}
+}
+
+func TestStrictParsing_WarnsOnUnknownFields(t *testing.T) {
+	body := `{"object":"collection","pages":{"next_url":null},"data":[{"id":1,"object":"level_progression","data":{"level":1,"skipped_at":null}}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	newClient := func(strict bool) (*Client, *logtest.Hook) {
+		logger, hook := logtest.NewNullLogger()
+		client := NewClient(logger)
+		client.SetAPIToken("test-token")
+		client.SetStrictParsing(strict)
+		client.httpClient = &http.Client{Transport: &rewriteTransport{target: target}}
+		return client, hook
+	}
+	driftWarnings := func(hook *logtest.Hook) []*logrus.Entry {
+		var warnings []*logrus.Entry
+		for _, entry := range hook.AllEntries() {
+			if entry.Level == logrus.WarnLevel && strings.Contains(entry.Message, "don't model") {
+				warnings = append(warnings, entry)
+			}
+		}
+		return warnings
+	}
+
+	client, hook := newClient(true)
+	for i := 0; i < 2; i++ {
+		progressions, err := client.FetchLevelProgressions(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(progressions) != 1 || progressions[0].Data.Level != 1 {
+			t.Fatalf("expected the response to still be decoded, got %+v", progressions)
+		}
+	}
+
+	// The same drift is only reported once
+	warnings := driftWarnings(hook)
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 drift warning, got %d", len(warnings))
+	}
+	if msg, _ := warnings[0].Data["error"].(string); !strings.Contains(msg, "skipped_at") {
+		t.Errorf("expected the warning to name the unknown field, got %v", warnings[0].Data)
+	}
+
+	client, hook = newClient(false)
+	if _, err := client.FetchLevelProgressions(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if warnings := driftWarnings(hook); len(warnings) != 0 {
+		t.Errorf("expected no drift warnings without strict parsing, got %d", len(warnings))
+	}
+}
+
+func TestFetchLevelProgressions(t *testing.T) {
+	var path string
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.Query()
+		w.Write([]byte(`{"object":"collection","pages":{"next_url":null},"data":[
+			{"id":49392,"object":"level_progression","url":"https://api.wanikani.com/v2/level_progressions/49392",
+			 "data_updated_at":"2017-03-30T11:31:20.438432Z",
+			 "data":{"level":2,"created_at":"2017-03-30T08:21:51.439918Z","unlocked_at":"2017-03-30T08:21:51.405055Z",
+			         "started_at":"2017-03-30T11:31:20.438432Z","passed_at":null,"completed_at":null,"abandoned_at":null}}]}`))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	client := NewClient(testLogger())
+	client.SetAPIToken("test-token")
+	client.httpClient = &http.Client{Transport: &rewriteTransport{target: target}}
+
+	updatedAfter := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	progressions, err := client.FetchLevelProgressions(context.Background(), &updatedAfter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if path != "/v2/level_progressions" {
+		t.Errorf("expected path /v2/level_progressions, got %s", path)
+	}
+	if got := query.Get("updated_after"); got != "2017-03-01T00:00:00Z" {
+		t.Errorf("expected updated_after=2017-03-01T00:00:00Z, got %q", got)
+	}
+	if len(progressions) != 1 {
+		t.Fatalf("expected 1 level progression, got %d", len(progressions))
+	}
+	progression := progressions[0]
+	if progression.ID != 49392 || progression.Data.Level != 2 {
+		t.Errorf("unexpected level progression: %+v", progression)
+	}
+	if progression.Data.StartedAt == nil || progression.Data.PassedAt != nil {
+		t.Errorf("expected started_at set and passed_at nil, got %+v", progression.Data)
+	}
+}
+
+func TestFetchResource_RejectsNonWaniKaniURL(t *testing.T) {
+	requestMade := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestMade = true
+	}))
+	defer server.Close()
+
+	client := NewClient(testLogger())
+	client.SetAPIToken("test-token")
+
+	urls := []string{
+		server.URL + "/v2/subjects/1",
+		"https://evil.example.com/v2/subjects/1",
+		"http://api.wanikani.com/v2/subjects/1",
+		"https://api.wanikani.com.evil.example.com/v2/subjects/1",
+	}
+
+	for _, resourceURL := range urls {
+		var out map[string]interface{}
+		if err := client.FetchResource(context.Background(), resourceURL, &out); err == nil {
+			t.Errorf("expected error for URL %s, got nil", resourceURL)
+		}
+	}
+
+	if requestMade {
+		t.Error("expected no request to be made for rejected URLs")
+	}
+}
+
+func TestWithoutRetries_MakesSingleRequest(t *testing.T) {
+	requestCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("down"))
+	}))
+	defer server.Close()
+
+	client := NewClient(testLogger())
+	client.SetAPIToken("test-token")
+
+	var out map[string]interface{}
+	err := client.fetchWithRetry(WithoutRetries(context.Background()), server.URL+"/v2/summary", nil, &out)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) {
+		t.Errorf("expected ServerError, got %T: %v", err, err)
+	}
+	if requestCount != 1 {
+		t.Errorf("expected exactly 1 request, got %d", requestCount)
+	}
+}
+
+func TestIsRetryableError_NetworkErrors(t *testing.T) {
+	wrap := func(err error) error {
+		return &NetworkError{Err: &url.Error{Op: "Get", URL: "https://api.wanikani.com/v2/user", Err: err}}
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"unknown host", wrap(&net.DNSError{Err: "no such host", Name: "api.wanikani.com", IsNotFound: true}), false},
+		{"dns timeout", wrap(&net.DNSError{Err: "i/o timeout", Name: "api.wanikani.com", IsTimeout: true}), true},
+		{"untrusted certificate", wrap(x509.UnknownAuthorityError{}), false},
+		{"hostname mismatch", wrap(x509.HostnameError{Host: "api.wanikani.com"}), false},
+		{"expired certificate", wrap(x509.CertificateInvalidError{Reason: x509.Expired}), false},
+		{"connection refused", wrap(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}), true},
+		{"timeout", wrap(context.DeadlineExceeded), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableError(tt.err); got != tt.retryable {
+				t.Errorf("isRetryableError(%v) = %v, want %v", tt.err, got, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestFetchWithRetry_CertificateErrorNotRetried(t *testing.T) {
+	// The default client does not trust the test server's self-signed certificate
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not reach the handler")
+	}))
+	defer server.Close()
+
+	client := NewClient(testLogger())
+	client.SetAPIToken("test-token")
+
+	start := time.Now()
+	var out map[string]interface{}
+	err := client.fetchWithRetry(context.Background(), server.URL+"/v2/summary", nil, &out)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	var networkErr *NetworkError
+	if !errors.As(err, &networkErr) {
+		t.Fatalf("expected NetworkError, got %T: %v", err, err)
+	}
+	if elapsed := time.Since(start); elapsed >= initialBackoff {
+		t.Errorf("expected no retry backoff, took %v", elapsed)
+	}
+}
+
+func TestFetchWithRetry_ConnectionRefusedRetried(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serverURL := server.URL
+	server.Close()
+
+	client := NewClient(testLogger())
+	client.SetAPIToken("test-token")
+
+	var out map[string]interface{}
+	err := client.fetchWithRetry(context.Background(), serverURL+"/v2/summary", nil, &out)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	var networkErr *NetworkError
+	if !errors.As(err, &networkErr) {
+		t.Fatalf("expected NetworkError, got %T: %v", err, err)
+	}
+	if err.Error() != "max retries exceeded: "+networkErr.Error() {
+		t.Errorf("expected retries to be exhausted, got: %v", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		expectError   bool
+		expectedCalls int
+	}{
+		{name: "valid token", status: http.StatusOK, expectedCalls: 1},
+		{name: "invalid token", status: http.StatusUnauthorized, expectError: true, expectedCalls: 1},
+		{name: "server error is not retried", status: http.StatusServiceUnavailable, expectError: true, expectedCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestCount int
+			var capturedPath string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestCount++
+				capturedPath = r.URL.Path
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"object":"user"}`))
+			}))
+			defer server.Close()
+
+			target, _ := url.Parse(server.URL)
+			client := NewClient(testLogger())
+			client.SetAPIToken("test-token")
+			client.httpClient = &http.Client{Transport: &rewriteTransport{target: target}}
+
+			err := client.Ping(context.Background())
+			if tt.expectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if requestCount != tt.expectedCalls {
+				t.Errorf("expected %d request(s), got %d", tt.expectedCalls, requestCount)
+			}
+			if capturedPath != "/v2/user" {
+				t.Errorf("expected request path /v2/user, got %s", capturedPath)
+			}
+		})
+	}
+}